@@ -0,0 +1,888 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Alert storage file in the database directory
+const alertsFile = "alerts.json"
+
+// Alert rule types
+const (
+	AlertAchievementBelow  = "achievement_below"  // Achievement of a KPI below Threshold percent
+	AlertConsecutiveMisses = "consecutive_misses" // KPI missed its target Threshold months in a row
+	AlertScoreDrop         = "score_drop"         // Role score fell more than Threshold points month-on-month
+)
+
+var alertRuleTypes = []string{AlertAchievementBelow, AlertConsecutiveMisses, AlertScoreDrop}
+
+// Alert statuses
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+	AlertResolved     = "resolved" // The condition no longer holds
+)
+
+var alertStatuses = []string{AlertOpen, AlertAcknowledged, AlertResolved}
+
+// AlertRule is a condition checked whenever a measurement is saved. It applies to
+// one KPI, or to every KPI of a role when only RoleID is set.
+type AlertRule struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	RoleID    int       `json:"role_id,omitempty"`
+	KPIID     int       `json:"kpi_id,omitempty"`
+	Threshold float64   `json:"threshold"` // Percent, months or points depending on Type
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Alert is raised when a rule's condition holds for a KPI or role and period.
+// Alerts are kept as history after they are acknowledged or resolved.
+type Alert struct {
+	ID             int        `json:"id"`
+	RuleID         int        `json:"rule_id"`
+	RuleName       string     `json:"rule_name"`
+	Type           string     `json:"type"`
+	Status         string     `json:"status"`
+	RoleID         int        `json:"role_id"`
+	KPIID          int        `json:"kpi_id,omitempty"` // 0 for role-level alerts
+	Period         string     `json:"period"`           // YYYY-MM
+	Message        string     `json:"message"`
+	Value          float64    `json:"value"`
+	Threshold      float64    `json:"threshold"`
+	TriggeredAt    time.Time  `json:"triggered_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	Note           string     `json:"note,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// alertStore is the content of the alerts file
+type alertStore struct {
+	Rules  []AlertRule `json:"rules"`
+	Alerts []Alert     `json:"alerts"`
+}
+
+// Alert rules and alerts, guarded by alertsMu
+var (
+	alertsMu   sync.Mutex
+	alertRules []AlertRule
+	alerts     []Alert
+)
+
+// appliesTo reports whether the rule covers a KPI
+func (rule AlertRule) appliesTo(kpi KPI) bool {
+	if rule.KPIID != 0 {
+		return rule.KPIID == kpi.ID
+	}
+	return rule.RoleID == kpi.RoleID
+}
+
+// loadAlerts loads the alert rules and alerts from the database directory
+func loadAlerts() error {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	store := alertStore{Rules: []AlertRule{}, Alerts: []Alert{}}
+	if err := readJSONFile(filepath.Join(appSettings.DatabasePath, alertsFile), &store); err != nil {
+		return err
+	}
+	alertRules, alerts = store.Rules, store.Alerts
+	return nil
+}
+
+// saveAlerts writes the alert rules and alerts. The caller holds alertsMu.
+func saveAlerts() error {
+	return writeJSONFile(filepath.Join(appSettings.DatabasePath, alertsFile), alertStore{Rules: alertRules, Alerts: alerts})
+}
+
+// alertCondition is the outcome of checking a rule for one KPI or role and period
+type alertCondition struct {
+	Holds   bool
+	Value   float64
+	Message string
+}
+
+// checkAchievementBelow checks an achievement_below rule for a KPI and period
+func checkAchievementBelow(rule AlertRule, kpi KPI, period time.Time) alertCondition {
+	m := getExistingMeasurement(kpi.ID, period)
+	if m == nil {
+		return alertCondition{}
+	}
+	achievement := calculateAchievement(kpi, m)
+	return alertCondition{
+		Holds: achievement < rule.Threshold,
+		Value: achievement,
+		Message: fmt.Sprintf("%s achieved %.1f%% in %s, below %.1f%%",
+			kpi.Name, achievement, period.Format("January 2006"), rule.Threshold),
+	}
+}
+
+// checkConsecutiveMisses checks a consecutive_misses rule for a KPI, counting
+// back from the period
+func checkConsecutiveMisses(rule AlertRule, kpi KPI, period time.Time) alertCondition {
+	months := int(rule.Threshold)
+	for i := 0; i < months; i++ {
+		m := getExistingMeasurement(kpi.ID, period.AddDate(0, -i, 0))
+		if m == nil || calculateAchievement(kpi, m) >= 100 {
+			return alertCondition{}
+		}
+	}
+	achievement := calculateAchievement(kpi, getExistingMeasurement(kpi.ID, period))
+	return alertCondition{
+		Holds: true,
+		Value: achievement,
+		Message: fmt.Sprintf("%s missed its target %d months in a row (%s - %s)",
+			kpi.Name, months, period.AddDate(0, 1-months, 0).Format("Jan 2006"), period.Format("Jan 2006")),
+	}
+}
+
+// checkScoreDrop checks a score_drop rule for a role and period against the previous month
+func checkScoreDrop(rule AlertRule, role Role, period time.Time) alertCondition {
+	roleKPIs := getKPIsByRoleID(role.ID)
+	current := calculateScoreResult(roleKPIs, period)
+	previous := calculateScoreResult(roleKPIs, period.AddDate(0, -1, 0))
+	if !current.HasData() || !previous.HasData() {
+		return alertCondition{}
+	}
+	drop := previous.Score - current.Score
+	return alertCondition{
+		Holds: drop > rule.Threshold,
+		Value: drop,
+		Message: fmt.Sprintf("%s score dropped %.1f points from %.1f to %.1f in %s",
+			role.Name, drop, previous.Score, current.Score, period.Format("January 2006")),
+	}
+}
+
+// evaluateAlerts checks the active rules affected by a saved measurement. A change
+// can also complete a streak or a drop in the following months, so those are
+// checked too. New alerts are published as alert.triggered events.
+func evaluateAlerts(m Measurement) {
+	kpi := getKPIByID(m.KPIID)
+	if kpi == nil {
+		return
+	}
+	role := getRoleByID(kpi.RoleID)
+	period := time.Date(m.Period.Year(), m.Period.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	changed := false
+	var triggered []Alert
+	apply := func(rule AlertRule, kpiID int, p time.Time, condition alertCondition) {
+		alert, updated := applyAlertCondition(rule, kpi.RoleID, kpiID, p, condition)
+		if alert != nil {
+			triggered = append(triggered, *alert)
+		}
+		changed = changed || updated
+	}
+
+	for _, rule := range alertRules {
+		if !rule.Active || !rule.appliesTo(*kpi) {
+			continue
+		}
+		switch rule.Type {
+		case AlertAchievementBelow:
+			apply(rule, kpi.ID, period, checkAchievementBelow(rule, *kpi, period))
+		case AlertConsecutiveMisses:
+			for i := 0; i < int(rule.Threshold); i++ {
+				p := period.AddDate(0, i, 0)
+				apply(rule, kpi.ID, p, checkConsecutiveMisses(rule, *kpi, p))
+			}
+		case AlertScoreDrop:
+			if role == nil {
+				continue
+			}
+			for i := 0; i < 2; i++ {
+				p := period.AddDate(0, i, 0)
+				apply(rule, 0, p, checkScoreDrop(rule, *role, p))
+			}
+		}
+	}
+
+	if changed {
+		if err := saveAlerts(); err != nil {
+			fmt.Printf("Error saving alerts: %v\n", err)
+		}
+	}
+
+	for _, alert := range triggered {
+		fmt.Printf("ALERT: %s\n", alert.Message)
+		appEvents.Publish(Event{Type: EventAlertTriggered, RoleID: alert.RoleID, KPIID: alert.KPIID, Period: alert.Period, Data: alert})
+	}
+}
+
+// applyAlertCondition raises, updates or resolves the alert of a rule for a KPI
+// (0 for the role) and period. It returns the new alert, if any, and whether
+// anything changed. The caller holds alertsMu.
+func applyAlertCondition(rule AlertRule, roleID, kpiID int, period time.Time, condition alertCondition) (*Alert, bool) {
+	key := period.Format("2006-01")
+
+	var existing *Alert
+	for i := range alerts {
+		a := &alerts[i]
+		if a.RuleID == rule.ID && a.KPIID == kpiID && a.RoleID == roleID && a.Period == key && a.Status != AlertResolved {
+			existing = a
+			break
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case condition.Holds && existing != nil:
+		if existing.Value == condition.Value && existing.Message == condition.Message {
+			return nil, false
+		}
+		existing.Value = condition.Value
+		existing.Message = condition.Message
+		existing.UpdatedAt = now
+		return nil, true
+	case condition.Holds:
+		alert := Alert{
+			ID:          1,
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			Type:        rule.Type,
+			Status:      AlertOpen,
+			RoleID:      roleID,
+			KPIID:       kpiID,
+			Period:      key,
+			Message:     condition.Message,
+			Value:       condition.Value,
+			Threshold:   rule.Threshold,
+			TriggeredAt: now,
+			UpdatedAt:   now,
+		}
+		for _, a := range alerts {
+			if a.ID >= alert.ID {
+				alert.ID = a.ID + 1
+			}
+		}
+		alerts = append(alerts, alert)
+		return &alert, true
+	case existing != nil:
+		existing.Status = AlertResolved
+		existing.ResolvedAt = &now
+		existing.UpdatedAt = now
+		return nil, true
+	}
+	return nil, false
+}
+
+// acknowledgeAlert marks an open alert as acknowledged. The caller holds alertsMu.
+func acknowledgeAlert(alert *Alert, by, note string) error {
+	if alert.Status != AlertOpen {
+		return fmt.Errorf("alert %d is %s", alert.ID, alert.Status)
+	}
+	previous := *alert
+	now := time.Now()
+	alert.Status = AlertAcknowledged
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedBy = by
+	alert.Note = note
+	alert.UpdatedAt = now
+	if err := saveAlerts(); err != nil {
+		*alert = previous
+		return fmt.Errorf("failed to save alerts: %v", err)
+	}
+	return nil
+}
+
+// countAlerts returns the number of alerts with a status
+func countAlerts(status string) int {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	count := 0
+	for _, a := range alerts {
+		if a.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// alertSubject returns the KPI or role name an alert is about
+func alertSubject(alert Alert) string {
+	if kpi := getKPIByID(alert.KPIID); kpi != nil {
+		return kpi.Name
+	}
+	if role := getRoleByID(alert.RoleID); role != nil {
+		return role.Name
+	}
+	return "-"
+}
+
+// truncateString shortens a string to at most n characters for table output
+func truncateString(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// ---------- Console menu ----------
+
+// handleAlerts manages the alerts menu
+func handleAlerts(scanner *bufio.Scanner) {
+	fmt.Println("\n=== Alerts ===")
+	fmt.Printf("%d open, %d acknowledged\n", countAlerts(AlertOpen), countAlerts(AlertAcknowledged))
+	fmt.Println("1. View Open Alerts")
+	fmt.Println("2. Acknowledge Alert")
+	fmt.Println("3. Alert History")
+	fmt.Println("4. View At-Risk KPIs")
+	fmt.Println("5. Manage Alert Rules")
+	fmt.Println("0. Back to Main Menu")
+
+	fmt.Print("\nEnter your choice: ")
+	scanner.Scan()
+	choice := scanner.Text()
+
+	switch choice {
+	case "1":
+		printAlerts(AlertOpen)
+	case "2":
+		acknowledgeAlertMenu(scanner)
+	case "3":
+		printAlerts("")
+	case "4":
+		printAtRiskKPIs()
+	case "5":
+		handleAlertRules(scanner)
+	case "0":
+		return
+	default:
+		fmt.Println("Invalid choice.")
+	}
+}
+
+// printAlerts lists the alerts with a status, or all of them, newest first
+func printAlerts(status string) {
+	alertsMu.Lock()
+	var list []Alert
+	for _, a := range alerts {
+		if status == "" || a.Status == status {
+			list = append(list, a)
+		}
+	}
+	alertsMu.Unlock()
+
+	if len(list) == 0 {
+		fmt.Println("\nNo alerts.")
+		return
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+
+	fmt.Printf("\n%-5s %-13s %-8s %-30s %s\n", "ID", "Status", "Period", "KPI / Role", "Message")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, a := range list {
+		fmt.Printf("%-5d %-13s %-8s %-30s %s\n", a.ID, a.Status, a.Period, truncateString(alertSubject(a), 30), a.Message)
+		if a.Status == AlertAcknowledged && (a.AcknowledgedBy != "" || a.Note != "") {
+			fmt.Printf("      acknowledged by %s: %s\n", a.AcknowledgedBy, a.Note)
+		}
+	}
+}
+
+// acknowledgeAlertMenu asks for an open alert and acknowledges it
+func acknowledgeAlertMenu(scanner *bufio.Scanner) {
+	printAlerts(AlertOpen)
+	if countAlerts(AlertOpen) == 0 {
+		return
+	}
+
+	fmt.Print("\nAlert ID to acknowledge (0 to cancel): ")
+	scanner.Scan()
+	id, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil || id == 0 {
+		return
+	}
+
+	fmt.Print("Your name: ")
+	scanner.Scan()
+	by := strings.TrimSpace(scanner.Text())
+	fmt.Print("Note (optional): ")
+	scanner.Scan()
+	note := strings.TrimSpace(scanner.Text())
+
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	for i := range alerts {
+		if alerts[i].ID == id {
+			if err := acknowledgeAlert(&alerts[i], by, note); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			fmt.Println("Alert acknowledged.")
+			return
+		}
+	}
+	fmt.Println("Alert not found.")
+}
+
+// printAtRiskKPIs lists the KPIs and roles with open or acknowledged alerts
+func printAtRiskKPIs() {
+	type atRisk struct {
+		subject string
+		role    string
+		count   int
+		latest  Alert
+	}
+
+	alertsMu.Lock()
+	bySubject := make(map[string]*atRisk)
+	var order []string
+	for _, a := range alerts {
+		if a.Status == AlertResolved {
+			continue
+		}
+		key := fmt.Sprintf("%d/%d", a.RoleID, a.KPIID)
+		entry, ok := bySubject[key]
+		if !ok {
+			entry = &atRisk{subject: alertSubject(a)}
+			if role := getRoleByID(a.RoleID); role != nil {
+				entry.role = role.Name
+			}
+			bySubject[key] = entry
+			order = append(order, key)
+		}
+		entry.count++
+		if a.Period >= entry.latest.Period {
+			entry.latest = a
+		}
+	}
+	alertsMu.Unlock()
+
+	if len(order) == 0 {
+		fmt.Println("\nNo KPIs at risk.")
+		return
+	}
+
+	sort.Slice(order, func(i, j int) bool { return bySubject[order[i]].count > bySubject[order[j]].count })
+
+	fmt.Printf("\n%-35s %-30s %-7s %s\n", "KPI / Role", "Role", "Alerts", "Latest")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, key := range order {
+		e := bySubject[key]
+		fmt.Printf("%-35s %-30s %-7d %s\n", truncateString(e.subject, 35), truncateString(e.role, 30), e.count, e.latest.Message)
+	}
+}
+
+// handleAlertRules lists, adds, enables, disables and deletes alert rules
+func handleAlertRules(scanner *bufio.Scanner) {
+	alertsMu.Lock()
+	fmt.Println("\n=== Alert Rules ===")
+	if len(alertRules) == 0 {
+		fmt.Println("No alert rules.")
+	}
+	for _, rule := range alertRules {
+		state := "active"
+		if !rule.Active {
+			state = "disabled"
+		}
+		fmt.Printf("%d. %s - %s\n", rule.ID, rule.Name, describeAlertRule(rule)+" ("+state+")")
+	}
+	alertsMu.Unlock()
+
+	fmt.Println("\n1. Add Rule")
+	fmt.Println("2. Enable/Disable Rule")
+	fmt.Println("3. Delete Rule")
+	fmt.Println("0. Back")
+
+	fmt.Print("\nEnter your choice: ")
+	scanner.Scan()
+	choice := scanner.Text()
+
+	switch choice {
+	case "1":
+		addAlertRuleMenu(scanner)
+	case "2", "3":
+		fmt.Print("Rule ID: ")
+		scanner.Scan()
+		id, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			fmt.Println("Invalid rule ID.")
+			return
+		}
+
+		alertsMu.Lock()
+		defer alertsMu.Unlock()
+		index := alertRuleIndex(id)
+		if index < 0 {
+			fmt.Println("Rule not found.")
+			return
+		}
+		previous := append([]AlertRule{}, alertRules...)
+		if choice == "2" {
+			alertRules[index].Active = !alertRules[index].Active
+		} else {
+			alertRules = append(alertRules[:index], alertRules[index+1:]...)
+		}
+		if err := saveAlerts(); err != nil {
+			alertRules = previous
+			fmt.Printf("Error saving alerts: %v\n", err)
+			return
+		}
+		fmt.Println("Alert rules updated.")
+	case "0":
+		return
+	default:
+		fmt.Println("Invalid choice.")
+	}
+}
+
+// addAlertRuleMenu asks for the type, scope and threshold of a new rule
+func addAlertRuleMenu(scanner *bufio.Scanner) {
+	fmt.Println("\nRule type:")
+	fmt.Println("1. KPI achievement below a percentage")
+	fmt.Println("2. KPI target missed several months in a row")
+	fmt.Println("3. Role score dropping by more than a number of points month-on-month")
+	fmt.Print("\nEnter your choice (0 to cancel): ")
+	scanner.Scan()
+	choice, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil || choice < 1 || choice > len(alertRuleTypes) {
+		return
+	}
+	rule := AlertRule{Type: alertRuleTypes[choice-1], Active: true}
+
+	role := selectRole(scanner)
+	if role == nil {
+		return
+	}
+	rule.RoleID = role.ID
+
+	if rule.Type != AlertScoreDrop {
+		roleKPIs := getKPIsByRoleID(role.ID)
+		fmt.Println("\n0. All KPIs of the role")
+		for i, kpi := range roleKPIs {
+			fmt.Printf("%d. %s\n", i+1, kpi.Name)
+		}
+		fmt.Print("\nEnter KPI number: ")
+		scanner.Scan()
+		index, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil || index < 0 || index > len(roleKPIs) {
+			fmt.Println("Invalid selection.")
+			return
+		}
+		if index > 0 {
+			rule.KPIID = roleKPIs[index-1].ID
+		}
+	}
+
+	switch rule.Type {
+	case AlertAchievementBelow:
+		fmt.Print("Alert when achievement is below (%): ")
+	case AlertConsecutiveMisses:
+		fmt.Print("Alert after this many missed months in a row (default 2): ")
+	case AlertScoreDrop:
+		fmt.Print("Alert when the score drops by more than (points): ")
+	}
+	scanner.Scan()
+	value := strings.TrimSpace(scanner.Text())
+	if value == "" && rule.Type == AlertConsecutiveMisses {
+		value = "2"
+	}
+	rule.Threshold, err = strconv.ParseFloat(value, 64)
+	if err != nil {
+		fmt.Println("Invalid number.")
+		return
+	}
+
+	fmt.Print("Rule name (optional): ")
+	scanner.Scan()
+	rule.Name = strings.TrimSpace(scanner.Text())
+
+	if _, errs := addAlertRule(rule); len(errs) > 0 {
+		fmt.Printf("Error: %v\n", errs)
+		return
+	}
+	fmt.Println("Alert rule added.")
+}
+
+// addAlertRule validates and stores a new rule
+func addAlertRule(rule AlertRule) (AlertRule, ValidationErrors) {
+	if errs := validateAlertRule(rule); len(errs) > 0 {
+		return rule, errs
+	}
+	if kpi := getKPIByID(rule.KPIID); kpi != nil {
+		rule.RoleID = kpi.RoleID
+	}
+	if rule.Name == "" {
+		rule.Name = describeAlertRule(rule)
+	}
+	rule.CreatedAt = time.Now()
+
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	rule.ID = 1
+	for _, r := range alertRules {
+		if r.ID >= rule.ID {
+			rule.ID = r.ID + 1
+		}
+	}
+	alertRules = append(alertRules, rule)
+
+	if err := saveAlerts(); err != nil {
+		alertRules = alertRules[:len(alertRules)-1]
+		return rule, ValidationErrors{{Field: "rule", Message: "Failed to save alerts: " + err.Error()}}
+	}
+	return rule, nil
+}
+
+// describeAlertRule returns a readable description of a rule
+func describeAlertRule(rule AlertRule) string {
+	scope := "all KPIs"
+	if kpi := getKPIByID(rule.KPIID); kpi != nil {
+		scope = kpi.Name
+	} else if role := getRoleByID(rule.RoleID); role != nil {
+		scope = "all KPIs of " + role.Name
+	}
+
+	switch rule.Type {
+	case AlertAchievementBelow:
+		return fmt.Sprintf("Achievement below %.1f%% for %s", rule.Threshold, scope)
+	case AlertConsecutiveMisses:
+		return fmt.Sprintf("%d consecutive missed targets for %s", int(rule.Threshold), scope)
+	case AlertScoreDrop:
+		name := "role"
+		if role := getRoleByID(rule.RoleID); role != nil {
+			name = role.Name
+		}
+		return fmt.Sprintf("Score drop of more than %.1f points for %s", rule.Threshold, name)
+	}
+	return rule.Type
+}
+
+// alertRuleIndex returns the index of the rule with the given ID or -1. The caller holds alertsMu.
+func alertRuleIndex(id int) int {
+	for i := range alertRules {
+		if alertRules[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ---------- REST API ----------
+
+// AcknowledgeRequest is the request body for acknowledging an alert
+type AcknowledgeRequest struct {
+	AcknowledgedBy string `json:"acknowledged_by"`
+	Note           string `json:"note"`
+}
+
+var alertComparators = map[string]func(a, b Alert) int{
+	"id":           func(a, b Alert) int { return compareInts(a.ID, b.ID) },
+	"period":       func(a, b Alert) int { return compareStrings(a.Period, b.Period) },
+	"triggered_at": func(a, b Alert) int { return compareTimes(a.TriggeredAt, b.TriggeredAt) },
+	"value":        func(a, b Alert) int { return compareFloats(a.Value, b.Value) },
+}
+
+// getAlerts returns the alert history, filtered by status, role_id, kpi_id, from,
+// to or q and newest first by default
+func getAlerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, perr := parseListParams(r.URL.Query(), []string{"status", "role_id", "kpi_id", "from", "to"}, sortFields(alertComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && !containsString(alertStatuses, status) {
+		writeParamError(w, &ParamError{"status", "must be one of " + strings.Join(alertStatuses, ", ")})
+		return
+	}
+
+	alertsMu.Lock()
+	filtered := []Alert{}
+	for _, a := range alerts {
+		if status != "" && a.Status != status {
+			continue
+		}
+		if params.RoleID != 0 && a.RoleID != params.RoleID {
+			continue
+		}
+		if params.KPIID != 0 && a.KPIID != params.KPIID {
+			continue
+		}
+		if !params.From.IsZero() && a.Period < params.From.Format("2006-01") {
+			continue
+		}
+		if !params.To.IsZero() && a.Period > params.To.Format("2006-01") {
+			continue
+		}
+		if !matchesText(params.Query, a.Message, a.RuleName) {
+			continue
+		}
+		filtered = append(filtered, a)
+	}
+	alertsMu.Unlock()
+
+	page, pagination := sortAndPage(filtered, params, alertComparators, "-id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
+
+// acknowledgeAlertAPI acknowledges an open alert
+func acknowledgeAlertAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	var request AcknowledgeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	for i := range alerts {
+		if alerts[i].ID != id {
+			continue
+		}
+		if alerts[i].Status != AlertOpen {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Alert is already %s", alerts[i].Status))
+			return
+		}
+		if err := acknowledgeAlert(&alerts[i], request.AcknowledgedBy, request.Note); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		json.NewEncoder(w).Encode(alerts[i])
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "Alert not found")
+}
+
+// getAlertRules returns the alert rules
+func getAlertRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	alertsMu.Lock()
+	rules := append([]AlertRule{}, alertRules...)
+	alertsMu.Unlock()
+
+	json.NewEncoder(w).Encode(rules)
+}
+
+// createAlertRule adds an alert rule
+func createAlertRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rule := AlertRule{Active: true} // Rules are active unless "active": false is sent
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	rule, errs := addAlertRule(rule)
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// updateAlertRule replaces an alert rule. Its existing alerts are kept.
+func updateAlertRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+
+	rule := AlertRule{Active: true} // Rules are active unless "active": false is sent
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := validateAlertRule(rule); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	index := alertRuleIndex(id)
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "Alert rule not found")
+		return
+	}
+
+	rule.ID = id
+	rule.CreatedAt = alertRules[index].CreatedAt
+	if kpi := getKPIByID(rule.KPIID); kpi != nil {
+		rule.RoleID = kpi.RoleID
+	}
+	if rule.Name == "" {
+		rule.Name = describeAlertRule(rule)
+	}
+	previous := alertRules[index]
+	alertRules[index] = rule
+
+	if err := saveAlerts(); err != nil {
+		alertRules[index] = previous
+		writeError(w, http.StatusInternalServerError, "Failed to save alerts: "+err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+// deleteAlertRule removes an alert rule. Its alerts stay in the history.
+func deleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	index := alertRuleIndex(id)
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "Alert rule not found")
+		return
+	}
+
+	previous := append([]AlertRule{}, alertRules...)
+	alertRules = append(alertRules[:index], alertRules[index+1:]...)
+
+	if err := saveAlerts(); err != nil {
+		alertRules = previous
+		writeError(w, http.StatusInternalServerError, "Failed to save alerts: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// setupAlertTest resets the test data and stores alerts in a temporary directory
+func setupAlertTest(t *testing.T) {
+	setupSubmissionTest(t)
+	previousRules, previousAlerts := alertRules, alerts
+	appSettings.DatabasePath = t.TempDir()
+	t.Cleanup(func() {
+		alertRules, alerts = previousRules, previousAlerts
+		discardPendingChanges()
+	})
+	if err := loadAlerts(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// month returns the first of a month in 2026
+func month(m time.Month) time.Time {
+	return time.Date(2026, m, 1, 0, 0, 0, 0, time.Local)
+}
+
+// addRevenue stores a measurement of the Revenue KPI
+func addRevenue(value float64, period time.Time) {
+	measurements = append(measurements, Measurement{ID: len(measurements) + 1, KPIID: 1, MetricValue: value, Period: period})
+}
+
+func TestCheckConsecutiveMisses(t *testing.T) {
+	setupAlertTest(t)
+	rule := AlertRule{ID: 1, Type: AlertConsecutiveMisses, KPIID: 1, Threshold: 3}
+	revenue := *getKPIByID(1)
+
+	addRevenue(90, month(3))
+	addRevenue(80, month(4))
+	if condition := checkConsecutiveMisses(rule, revenue, month(4)); condition.Holds {
+		t.Error("two misses should not complete a streak of three")
+	}
+
+	addRevenue(70, month(5))
+	condition := checkConsecutiveMisses(rule, revenue, month(5))
+	if !condition.Holds || condition.Value != 70 {
+		t.Errorf("condition %+v, want a streak with achievement 70", condition)
+	}
+
+	// A hit or a missing month breaks the streak
+	addRevenue(100, month(6))
+	addRevenue(50, month(7))
+	if condition := checkConsecutiveMisses(rule, revenue, month(7)); condition.Holds {
+		t.Error("a month on target should break the streak")
+	}
+	addRevenue(50, month(9))
+	addRevenue(50, month(10))
+	if condition := checkConsecutiveMisses(rule, revenue, month(10)); condition.Holds {
+		t.Error("a month without a measurement should break the streak")
+	}
+}
+
+func TestCheckScoreDrop(t *testing.T) {
+	setupAlertTest(t)
+	rule := AlertRule{ID: 1, Type: AlertScoreDrop, RoleID: 1, Threshold: 10}
+	sales := *getRoleByID(1)
+
+	if condition := checkScoreDrop(rule, sales, month(5)); condition.Holds {
+		t.Error("a month without data should not hold")
+	}
+
+	addRevenue(100, month(4))
+	if condition := checkScoreDrop(rule, sales, month(5)); condition.Holds {
+		t.Error("a missing current month should not hold")
+	}
+
+	addRevenue(95, month(5))
+	if condition := checkScoreDrop(rule, sales, month(5)); condition.Holds || condition.Value <= 0 {
+		t.Errorf("condition %+v, want a drop within the threshold", condition)
+	}
+
+	addRevenue(100, month(6))
+	addRevenue(60, month(7))
+	condition := checkScoreDrop(rule, sales, month(7))
+	if !condition.Holds || condition.Value <= rule.Threshold {
+		t.Errorf("condition %+v, want a drop above %.0f points", condition, rule.Threshold)
+	}
+}
+
+func TestApplyAlertConditionTransitions(t *testing.T) {
+	setupAlertTest(t)
+	rule := AlertRule{ID: 4, Name: "Revenue low", Type: AlertAchievementBelow, KPIID: 1, Threshold: 90}
+	may := month(5)
+
+	alert, changed := applyAlertCondition(rule, 1, 1, may, alertCondition{Holds: true, Value: 80, Message: "low"})
+	if alert == nil || !changed || alert.Status != AlertOpen || alert.ID != 1 || alert.Period != "2026-05" {
+		t.Fatalf("new alert %+v, want open alert 1 for 2026-05", alert)
+	}
+
+	// The same condition changes nothing, a new value updates the open alert
+	if alert, changed := applyAlertCondition(rule, 1, 1, may, alertCondition{Holds: true, Value: 80, Message: "low"}); alert != nil || changed {
+		t.Error("an unchanged condition should not change the alert")
+	}
+	if alert, changed := applyAlertCondition(rule, 1, 1, may, alertCondition{Holds: true, Value: 70, Message: "lower"}); alert != nil || !changed {
+		t.Error("a new value should update the open alert")
+	}
+	if len(alerts) != 1 || alerts[0].Value != 70 || alerts[0].Message != "lower" {
+		t.Fatalf("alerts %+v, want one alert with value 70", alerts)
+	}
+
+	// Acknowledged alerts are still resolved when the condition clears
+	if err := acknowledgeAlert(&alerts[0], "Dewi", "on it"); err != nil {
+		t.Fatal(err)
+	}
+	if _, changed := applyAlertCondition(rule, 1, 1, may, alertCondition{Value: 95}); !changed {
+		t.Error("a cleared condition should resolve the alert")
+	}
+	if alerts[0].Status != AlertResolved || alerts[0].ResolvedAt == nil {
+		t.Errorf("alert %+v, want resolved", alerts[0])
+	}
+	if _, changed := applyAlertCondition(rule, 1, 1, may, alertCondition{Value: 95}); changed {
+		t.Error("resolving twice should change nothing")
+	}
+
+	// A resolved alert is kept and the condition holding again raises a new one
+	alert, _ = applyAlertCondition(rule, 1, 1, may, alertCondition{Holds: true, Value: 60, Message: "low again"})
+	if alert == nil || alert.ID != 2 || len(alerts) != 2 {
+		t.Errorf("alert %+v with %d alerts, want new alert 2", alert, len(alerts))
+	}
+}
+
+func TestAcknowledgeAlert(t *testing.T) {
+	setupAlertTest(t)
+	alerts = []Alert{
+		{ID: 1, Status: AlertOpen},
+		{ID: 2, Status: AlertAcknowledged},
+		{ID: 3, Status: AlertResolved},
+	}
+
+	if err := acknowledgeAlert(&alerts[0], "Dewi", "checked"); err != nil {
+		t.Fatal(err)
+	}
+	if a := alerts[0]; a.Status != AlertAcknowledged || a.AcknowledgedAt == nil || a.AcknowledgedBy != "Dewi" || a.Note != "checked" {
+		t.Errorf("alert %+v, want acknowledged by Dewi", a)
+	}
+	for i := 1; i < 3; i++ {
+		if err := acknowledgeAlert(&alerts[i], "Dewi", ""); err == nil {
+			t.Errorf("acknowledging a %s alert should fail", alerts[i].Status)
+		}
+	}
+
+	// The change is saved
+	if err := loadAlerts(); err != nil {
+		t.Fatal(err)
+	}
+	if alerts[0].Status != AlertAcknowledged {
+		t.Errorf("alert status %s after reload, want acknowledged", alerts[0].Status)
+	}
+}
+
+func TestAlertsWaitForSave(t *testing.T) {
+	setupAlertTest(t)
+	alertRules = []AlertRule{{ID: 1, Name: "Revenue low", Type: AlertAchievementBelow, KPIID: 1, Threshold: 90, Active: true}}
+
+	saveMeasurement(1, 50, nil, "", month(5), "")
+	if len(alerts) != 0 {
+		t.Fatal("alert raised before the save")
+	}
+	discardPendingChanges()
+	publishPendingChanges()
+	if len(alerts) != 0 {
+		t.Fatal("alert raised for a discarded change")
+	}
+
+	saveMeasurement(1, 50, nil, "", month(5), "")
+	publishPendingChanges()
+	if len(alerts) != 1 || alerts[0].Status != AlertOpen {
+		t.Errorf("alerts %+v, want one open alert", alerts)
+	}
+}
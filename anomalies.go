@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Statistical outlier methods
+const (
+	AnomalyZScore = "zscore" // Distance from the mean in standard deviations
+	AnomalyIQR    = "iqr"    // Distance outside the interquartile range
+)
+
+var anomalyMethods = []string{AnomalyZScore, AnomalyIQR}
+
+// Anomaly checks
+const (
+	CheckZScore   = "zscore"
+	CheckIQR      = "iqr"
+	CheckScale    = "scale"    // Off by an order of magnitude from the usual values or the target
+	CheckFraction = "fraction" // A percentage entered as a fraction, e.g. 0.92 for 92%
+)
+
+var anomalyChecks = []string{CheckZScore, CheckIQR, CheckScale, CheckFraction}
+
+// anomalyScaleFactor is how far off a value must be to fail the scale check
+const anomalyScaleFactor = 10
+
+// Smallest accepted outlier settings, lower values flag ordinary measurements
+const (
+	minZScoreThreshold = 1.0
+	minIQRMultiplier   = 0.5
+	minAnomalyHistory  = 2 // The spread needs two values
+)
+
+// AnomalySettings configures the outlier check of entered measurements. The
+// defaults are set by defaultSettings.
+type AnomalySettings struct {
+	Method          string  `json:"method"`           // zscore or iqr
+	ZScoreThreshold float64 `json:"zscore_threshold"` // Standard deviations, default 3
+	IQRMultiplier   float64 `json:"iqr_multiplier"`   // Interquartile ranges beyond the quartiles, default 1.5
+	MinHistory      int     `json:"min_history"`      // Values of the KPI needed before the statistical check runs, default 4
+}
+
+// Anomaly is one reason a measurement value looks wrong
+type Anomaly struct {
+	Check   string  `json:"check"`
+	Message string  `json:"message"`
+	Score   float64 `json:"score,omitempty"` // z-score or interquartile ranges outside the range
+}
+
+// AnomalyFinding is a stored measurement that looks wrong
+type AnomalyFinding struct {
+	MeasurementID int       `json:"measurement_id"`
+	KPIID         int       `json:"kpi_id"`
+	KPIName       string    `json:"kpi_name"`
+	RoleID        int       `json:"role_id"`
+	RoleName      string    `json:"role_name"`
+	Period        string    `json:"period"`
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit"`
+	Anomalies     []Anomaly `json:"anomalies"`
+}
+
+// AnomalyWarningResponse is returned instead of saving a value that looks wrong
+type AnomalyWarningResponse struct {
+	Error     APIError  `json:"error"`
+	Anomalies []Anomaly `json:"anomalies"`
+}
+
+// validateAnomalySettings checks the outlier check settings
+func validateAnomalySettings(s AnomalySettings) error {
+	if !containsString(anomalyMethods, s.Method) {
+		return fmt.Errorf("anomaly method must be one of %s", strings.Join(anomalyMethods, ", "))
+	}
+	if s.ZScoreThreshold < minZScoreThreshold {
+		return fmt.Errorf("anomaly z-score threshold must be at least %g", minZScoreThreshold)
+	}
+	if s.IQRMultiplier < minIQRMultiplier {
+		return fmt.Errorf("anomaly IQR multiplier must be at least %g", minIQRMultiplier)
+	}
+	if s.MinHistory < minAnomalyHistory {
+		return fmt.Errorf("anomaly minimum history must be at least %d values", minAnomalyHistory)
+	}
+	return nil
+}
+
+// kpiValuesExcept returns the values of a KPI in data in every period but one
+func kpiValuesExcept(data []Measurement, kpiID int, period time.Time) []float64 {
+	var values []float64
+	for _, m := range data {
+		if m.KPIID == kpiID && !(m.Period.Year() == period.Year() && m.Period.Month() == period.Month()) {
+			values = append(values, m.MetricValue)
+		}
+	}
+	return values
+}
+
+// quartile returns the q-th quantile of sorted values by linear interpolation
+func quartile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// detectAnomalies checks a value of a KPI against the KPI's other values and its
+// target. The statistical check needs enough history; the unit and scale checks
+// catch typos such as 2 for 92 even on a new KPI.
+func detectAnomalies(kpi KPI, value float64, history []float64) []Anomaly {
+	var anomalies []Anomaly
+	settings := appSettings.Anomalies
+
+	sorted := append([]float64{}, history...)
+	sort.Float64s(sorted)
+
+	// The usual value: the median of the history, or the target while there is
+	// too little history for the median to be reliable
+	reference, referenceName := kpi.TargetValue, "target"
+	fromHistory := len(sorted) >= settings.MinHistory
+	if fromHistory {
+		reference, referenceName = quartile(sorted, 0.5), "usual value"
+	}
+
+	if kpi.Unit == "%" && value > 0 && value <= 1 && reference > 1 {
+		anomalies = append(anomalies, Anomaly{Check: CheckFraction,
+			Message: fmt.Sprintf("%.2f looks like a fraction, percentages are entered as 0-100 (%s %.2f)", value, referenceName, reference)})
+	} else if value > 0 && reference > 0 {
+		// Against the target only the unfavourable direction is suspicious
+		tooLow := value*anomalyScaleFactor <= reference
+		tooHigh := value >= reference*anomalyScaleFactor
+		if !fromHistory {
+			if lowerIsBetter(kpi) {
+				tooLow = false
+			} else {
+				tooHigh = false
+			}
+		}
+		if tooLow || tooHigh {
+			anomalies = append(anomalies, Anomaly{Check: CheckScale,
+				Message: fmt.Sprintf("%.2f is off by a factor of %d or more from the %s %.2f", value, anomalyScaleFactor, referenceName, reference)})
+		}
+	}
+
+	if len(history) < settings.MinHistory {
+		return anomalies
+	}
+
+	switch settings.Method {
+	case AnomalyZScore:
+		mean, stddev := meanAndStdDev(history)
+		if stddev == 0 {
+			break
+		}
+		z := (value - mean) / stddev
+		if math.Abs(z) > settings.ZScoreThreshold {
+			anomalies = append(anomalies, Anomaly{Check: CheckZScore, Score: z,
+				Message: fmt.Sprintf("%.2f is %.1f standard deviations from the average %.2f of %d other values", value, math.Abs(z), mean, len(history))})
+		}
+	case AnomalyIQR:
+		q1, q3 := quartile(sorted, 0.25), quartile(sorted, 0.75)
+		iqr := q3 - q1
+		if iqr == 0 {
+			break
+		}
+		low, high := q1-settings.IQRMultiplier*iqr, q3+settings.IQRMultiplier*iqr
+		if value < low || value > high {
+			distance := (low - value) / iqr
+			if value > high {
+				distance = (value - high) / iqr
+			}
+			anomalies = append(anomalies, Anomaly{Check: CheckIQR, Score: distance,
+				Message: fmt.Sprintf("%.2f is outside the usual range %.2f to %.2f of %d other values", value, low, high, len(history))})
+		}
+	}
+
+	return anomalies
+}
+
+// checkMeasurementValue checks a value about to be saved for a KPI and period
+func checkMeasurementValue(kpi KPI, value float64, period time.Time) []Anomaly {
+	return checkMeasurementValueFrom(kpi, value, period, measurements)
+}
+
+// checkMeasurementValueFrom checks a value against the KPI's other values in a set
+// of measurements, e.g. the measurements with a batch applied
+func checkMeasurementValueFrom(kpi KPI, value float64, period time.Time, data []Measurement) []Anomaly {
+	return detectAnomalies(kpi, value, kpiValuesExcept(data, kpi.ID, period))
+}
+
+// findAnomalies checks every stored measurement against the other values of its KPI
+func findAnomalies() []AnomalyFinding {
+	findings := []AnomalyFinding{}
+	for _, m := range measurements {
+		kpi := getKPIByID(m.KPIID)
+		if kpi == nil {
+			continue
+		}
+		anomalies := checkMeasurementValue(*kpi, m.MetricValue, m.Period)
+		if len(anomalies) == 0 {
+			continue
+		}
+
+		finding := AnomalyFinding{
+			MeasurementID: m.ID,
+			KPIID:         kpi.ID,
+			KPIName:       kpi.Name,
+			RoleID:        kpi.RoleID,
+			Period:        m.Period.Format("2006-01"),
+			Value:         m.MetricValue,
+			Unit:          kpi.Unit,
+			Anomalies:     anomalies,
+		}
+		if role := getRoleByID(kpi.RoleID); role != nil {
+			finding.RoleName = role.Name
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// anomalyMessages joins the messages of anomalies
+func anomalyMessages(anomalies []Anomaly) string {
+	messages := make([]string, len(anomalies))
+	for i, a := range anomalies {
+		messages[i] = a.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// writeAnomalyWarning writes a 428 response asking to confirm a value that looks wrong
+func writeAnomalyWarning(w http.ResponseWriter, anomalies []Anomaly) {
+	details := make([]FieldError, len(anomalies))
+	for i, a := range anomalies {
+		details[i] = FieldError{Field: "metric_value", Message: a.Message}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionRequired)
+	json.NewEncoder(w).Encode(AnomalyWarningResponse{
+		Error:     newAPIError(http.StatusPreconditionRequired, "The value looks anomalous, check it and resend with ?confirm=true to save it", details...),
+		Anomalies: anomalies,
+	})
+}
+
+// confirmAnomalies asks whether to save a value that looks wrong
+func confirmAnomalies(scanner *bufio.Scanner, anomalies []Anomaly) bool {
+	fmt.Println("\nWarning: this value looks anomalous:")
+	for _, a := range anomalies {
+		fmt.Printf("  - %s\n", a.Message)
+	}
+	fmt.Print("Save it anyway? (y/n): ")
+	scanner.Scan()
+	return strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+}
+
+var anomalyComparators = map[string]func(a, b AnomalyFinding) int{
+	"period":         func(a, b AnomalyFinding) int { return compareStrings(a.Period, b.Period) },
+	"measurement_id": func(a, b AnomalyFinding) int { return compareInts(a.MeasurementID, b.MeasurementID) },
+	"kpi_id":         func(a, b AnomalyFinding) int { return compareInts(a.KPIID, b.KPIID) },
+	"role_id":        func(a, b AnomalyFinding) int { return compareInts(a.RoleID, b.RoleID) },
+	"value":          func(a, b AnomalyFinding) int { return compareFloats(a.Value, b.Value) },
+}
+
+// filterAnomalies applies the list filters and a check name to the findings
+func filterAnomalies(findings []AnomalyFinding, params ListParams, check string) []AnomalyFinding {
+	filtered := []AnomalyFinding{}
+	for _, f := range findings {
+		if params.RoleID != 0 && f.RoleID != params.RoleID {
+			continue
+		}
+		if params.KPIID != 0 && f.KPIID != params.KPIID {
+			continue
+		}
+		if !params.From.IsZero() && f.Period < params.From.Format("2006-01") {
+			continue
+		}
+		if !params.To.IsZero() && f.Period > params.To.Format("2006-01") {
+			continue
+		}
+		if check != "" {
+			found := false
+			for _, a := range f.Anomalies {
+				found = found || a.Check == check
+			}
+			if !found {
+				continue
+			}
+		}
+		if !matchesText(params.Query, f.KPIName, f.RoleName) {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+	return filtered
+}
+
+// getAnomalies lists stored measurements that look wrong, newest period first
+func getAnomalies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, perr := parseListParams(r.URL.Query(), []string{"role_id", "kpi_id", "from", "to", "check"}, sortFields(anomalyComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+	check := r.URL.Query().Get("check")
+	if check != "" && !containsString(anomalyChecks, check) {
+		writeParamError(w, &ParamError{"check", "must be one of " + strings.Join(anomalyChecks, ", ")})
+		return
+	}
+
+	page, pagination := sortAndPage(filterAnomalies(findAnomalies(), params, check), params, anomalyComparators, "-period")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
+
+// printAnomalies prints an anomalies report
+func printAnomalies(w io.Writer, findings []AnomalyFinding) {
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "\nNo anomalous measurements found.")
+		return
+	}
+
+	fmt.Fprintf(w, "\n%-6s %-8s %-25s %-35s %10s  %s\n", "ID", "Period", "Role", "KPI", "Value", "Reason")
+	fmt.Fprintln(w, strings.Repeat("-", 130))
+	for _, f := range findings {
+		fmt.Fprintf(w, "%-6d %-8s %-25s %-35s %10.2f  %s\n", f.MeasurementID, f.Period, truncateString(f.RoleName, 25),
+			truncateString(f.KPIName, 35), f.Value, anomalyMessages(f.Anomalies))
+	}
+	fmt.Fprintf(w, "\n%d measurements to review.\n", len(findings))
+}
+
+// viewAnomalies shows the anomalies report from the interactive menu
+func viewAnomalies(scanner *bufio.Scanner) {
+	fmt.Println("\n=== Anomalies Report ===")
+	findings := findAnomalies()
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Period > findings[j].Period })
+	printAnomalies(os.Stdout, findings)
+}
+
+// runAnomaliesCommand prints the anomalies report
+func runAnomaliesCommand(args []string) int {
+	fs := flag.NewFlagSet("anomalies", flag.ContinueOnError)
+	roleID := fs.Int("role", 0, "only this role ID")
+	kpiID := fs.Int("kpi", 0, "only this KPI ID")
+	check := fs.String("check", "", "only this check: "+strings.Join(anomalyChecks, ", "))
+	format := fs.String("format", "txt", "output format: txt or json")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *check != "" && !containsString(anomalyChecks, *check) {
+		return usageError(fs, "unknown check %s", *check)
+	}
+	if *format != "txt" && *format != "json" {
+		return usageError(fs, "unsupported format %s", *format)
+	}
+
+	findings := filterAnomalies(findAnomalies(), ListParams{RoleID: *roleID, KPIID: *kpiID}, *check)
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Period > findings[j].Period })
+	if *format == "json" {
+		json.NewEncoder(cliOutput).Encode(findings)
+	} else {
+		printAnomalies(cliOutput, findings)
+	}
+	return exitOK
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// anomalyChecksOf returns the checks an anomaly list failed
+func anomalyChecksOf(anomalies []Anomaly) []string {
+	checks := []string{}
+	for _, a := range anomalies {
+		checks = append(checks, a.Check)
+	}
+	return checks
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	previous := appSettings
+	appSettings = defaultSettings()
+	defer func() { appSettings = previous }()
+
+	uptime := KPI{ID: 1, Name: "Uptime", Unit: "%", Operator: "≥", TargetValue: 90}
+	incidents := KPI{ID: 2, Name: "Incidents", Operator: "≤", TargetValue: 5}
+	usual := []float64{91, 92, 93, 92, 94, 92}
+
+	tests := []struct {
+		name    string
+		kpi     KPI
+		value   float64
+		history []float64
+		want    []string
+	}{
+		{"usual value", uptime, 93, usual, []string{}},
+		{"2 entered for about 92", uptime, 2, usual, []string{CheckScale, CheckZScore}},
+		{"fraction for a percentage", uptime, 0.92, usual, []string{CheckFraction, CheckZScore}},
+		{"ten times the usual value", uptime, 920, usual, []string{CheckScale, CheckZScore}},
+		{"moderate outlier", uptime, 80, usual, []string{CheckZScore}},
+
+		// Without enough history only the unfavourable direction against the target counts
+		{"new KPI far below its target", uptime, 2, []float64{92}, []string{CheckScale}},
+		{"new KPI far above its target", uptime, 950, nil, []string{}},
+		{"new lower-is-better KPI far above its target", incidents, 60, nil, []string{CheckScale}},
+		{"new lower-is-better KPI far below its target", incidents, 0.1, nil, []string{}},
+
+		// A constant history has no spread
+		{"constant history", incidents, 4, []float64{3, 3, 3, 3}, []string{}},
+	}
+	for _, tt := range tests {
+		got := anomalyChecksOf(detectAnomalies(tt.kpi, tt.value, tt.history))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: checks %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: checks %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDetectAnomaliesSettings(t *testing.T) {
+	previous := appSettings
+	appSettings = defaultSettings()
+	defer func() { appSettings = previous }()
+
+	uptime := KPI{ID: 1, Unit: "%", Operator: "≥", TargetValue: 90}
+	usual := []float64{90, 91, 92, 93, 94}
+
+	appSettings.Anomalies.Method = AnomalyIQR
+	anomalies := detectAnomalies(uptime, 99, usual)
+	if len(anomalies) != 1 || anomalies[0].Check != CheckIQR || anomalies[0].Score <= 0 {
+		t.Errorf("anomalies %+v, want an IQR outlier", anomalies)
+	}
+
+	// A stricter setting is used instead of the default
+	appSettings.Anomalies.IQRMultiplier = 3
+	if anomalies := detectAnomalies(uptime, 99, usual); len(anomalies) != 0 {
+		t.Errorf("anomalies %+v with a multiplier of 3, want none", anomalies)
+	}
+
+	appSettings.Anomalies.MinHistory = 6
+	if anomalies := detectAnomalies(uptime, 80, usual); len(anomalies) != 0 {
+		t.Errorf("anomalies %+v with too little history, want none", anomalies)
+	}
+}
+
+func TestValidateAnomalySettings(t *testing.T) {
+	valid := defaultSettings().Anomalies
+	if err := validateAnomalySettings(valid); err != nil {
+		t.Fatalf("default settings: %v", err)
+	}
+
+	for name, change := range map[string]func(s *AnomalySettings){
+		"no method":              func(s *AnomalySettings) { s.Method = "" },
+		"unknown method":         func(s *AnomalySettings) { s.Method = "mad" },
+		"zero z-score threshold": func(s *AnomalySettings) { s.ZScoreThreshold = 0 },
+		"zero IQR multiplier":    func(s *AnomalySettings) { s.IQRMultiplier = 0 },
+		"one value of history":   func(s *AnomalySettings) { s.MinHistory = 1 },
+	} {
+		s := valid
+		change(&s)
+		if err := validateAnomalySettings(s); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheckMeasurementValueIgnoresItsPeriod(t *testing.T) {
+	setupSubmissionTest(t)
+	for i, value := range []float64{92, 93, 91, 92} {
+		measurements = append(measurements, Measurement{ID: 10 + i, KPIID: 1, MetricValue: value, Period: time.Date(2026, time.Month(1+i), 1, 0, 0, 0, 0, time.Local)})
+	}
+
+	// Correcting April's value compares it with January to March only
+	april := time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)
+	if got := len(kpiValuesExcept(measurements, 1, april)); got != 3 {
+		t.Errorf("%d other values, want 3", got)
+	}
+	if anomalies := checkMeasurementValue(*getKPIByID(1), 2, april); len(anomalies) == 0 {
+		t.Error("2 for a KPI around 92 should be flagged")
+	}
+}
+
+func TestMeasurementBatchChecksAgainstBatch(t *testing.T) {
+	setupSubmissionTest(t)
+
+	// Revenue has no stored history, the batch brings four months of it
+	body := `[
+		{"kpi_id": 1, "period": "2026-05-01T00:00:00Z", "metric_value": 80},
+		{"kpi_id": 1, "period": "2026-01-01T00:00:00Z", "metric_value": 92},
+		{"kpi_id": 1, "period": "2026-02-01T00:00:00Z", "metric_value": 93},
+		{"kpi_id": 1, "period": "2026-03-01T00:00:00Z", "metric_value": 91},
+		{"kpi_id": 1, "period": "2026-04-01T00:00:00Z", "metric_value": 92}
+	]`
+	rec := httptest.NewRecorder()
+	createMeasurementBatch(rec, httptest.NewRequest("POST", "/api/measurements/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("status %d, want 428: %s", rec.Code, rec.Body)
+	}
+
+	var response BatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range response.Results {
+		if flagged := len(result.Anomalies) > 0; flagged != (i == 0) {
+			t.Errorf("entry %d: anomalies %+v", i, result.Anomalies)
+		}
+	}
+	if len(measurements) != 1 {
+		t.Errorf("%d measurements, want the batch not applied", len(measurements))
+	}
+}
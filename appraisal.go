@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const appraisalsSheet = "Appraisals"
+
+// notRated is used instead of a rating band when there is no data to rate
+const notRated = "Not Rated"
+
+// Appraisal weighting modes
+const (
+	AppraisalModeMonthly   = "monthly"
+	AppraisalModeQuarterly = "quarterly"
+)
+
+// RatingBand maps a minimum score to a performance rating
+type RatingBand struct {
+	Name     string  `json:"name"`
+	MinScore float64 `json:"min_score"`
+}
+
+// AppraisalConfig controls how monthly or quarterly scores are combined into a final score
+type AppraisalConfig struct {
+	Mode    string    `json:"mode"`    // "monthly" or "quarterly"
+	Weights []float64 `json:"weights"` // 12 weights for monthly, 4 for quarterly
+}
+
+// AppraisalPeriodScore is the score of one month or quarter within an appraisal
+type AppraisalPeriodScore struct {
+	Label    string  `json:"label"`
+	Score    float64 `json:"score"`
+	Coverage float64 `json:"coverage"`
+	Weight   float64 `json:"weight"`
+	HasData  bool    `json:"has_data"`
+}
+
+// Appraisal is the final annual appraisal of a role
+type Appraisal struct {
+	Year         int                    `json:"year"`
+	Role         Role                   `json:"role"`
+	Mode         string                 `json:"mode"`
+	PeriodScores []AppraisalPeriodScore `json:"period_scores"`
+	FinalScore   float64                `json:"final_score"`
+	Rating       string                 `json:"rating"`
+	Coverage     float64                `json:"coverage"` // Share of the configured period weight with data
+}
+
+// defaultRatingBands are used when no bands are configured for a role
+var defaultRatingBands = []RatingBand{
+	{Name: "Outstanding", MinScore: 95},
+	{Name: "Exceeds Expectations", MinScore: 85},
+	{Name: "Meets Expectations", MinScore: 70},
+	{Name: "Below Expectations", MinScore: 50},
+	{Name: "Unsatisfactory", MinScore: 0},
+}
+
+// getRatingBands returns the rating bands for a role sorted from highest to lowest
+func getRatingBands(roleID int) []RatingBand {
+	bands := defaultRatingBands
+	if roleBands, ok := appSettings.RoleRatingBands[roleID]; ok && len(roleBands) > 0 {
+		bands = roleBands
+	} else if len(appSettings.RatingBands) > 0 {
+		bands = appSettings.RatingBands
+	}
+
+	sorted := make([]RatingBand, len(bands))
+	copy(sorted, bands)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].MinScore > sorted[j].MinScore
+	})
+	return sorted
+}
+
+// getRating returns the rating band name for a role's score
+func getRating(roleID int, score float64) string {
+	bands := getRatingBands(roleID)
+	for _, band := range bands {
+		if score >= band.MinScore {
+			return band.Name
+		}
+	}
+	// Scores below every band fall into the lowest one
+	return bands[len(bands)-1].Name
+}
+
+// validateRatingBands checks that a set of rating bands is usable
+func validateRatingBands(bands []RatingBand) error {
+	seen := make(map[float64]bool)
+	for _, band := range bands {
+		if strings.TrimSpace(band.Name) == "" {
+			return fmt.Errorf("rating band name cannot be empty")
+		}
+		if band.MinScore < 0 || band.MinScore > 100 {
+			return fmt.Errorf("rating band '%s' must have a minimum score between 0 and 100", band.Name)
+		}
+		if seen[band.MinScore] {
+			return fmt.Errorf("duplicate rating band minimum score %.2f", band.MinScore)
+		}
+		seen[band.MinScore] = true
+	}
+	return nil
+}
+
+// getAppraisalConfig returns the configured appraisal weighting or equal quarterly weights
+func getAppraisalConfig() AppraisalConfig {
+	config := appSettings.Appraisal
+	if validateAppraisalConfig(config) != nil {
+		return AppraisalConfig{Mode: AppraisalModeQuarterly, Weights: []float64{25, 25, 25, 25}}
+	}
+	return config
+}
+
+// validateAppraisalConfig checks the appraisal mode and number of weights
+func validateAppraisalConfig(config AppraisalConfig) error {
+	expected := 0
+	switch config.Mode {
+	case AppraisalModeMonthly:
+		expected = 12
+	case AppraisalModeQuarterly:
+		expected = 4
+	default:
+		return fmt.Errorf("appraisal mode must be '%s' or '%s'", AppraisalModeMonthly, AppraisalModeQuarterly)
+	}
+
+	if len(config.Weights) != expected {
+		return fmt.Errorf("%s appraisal needs %d weights, got %d", config.Mode, expected, len(config.Weights))
+	}
+
+	total := 0.0
+	for _, weight := range config.Weights {
+		if weight < 0 {
+			return fmt.Errorf("appraisal weights cannot be negative")
+		}
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("appraisal weights cannot all be zero")
+	}
+
+	return nil
+}
+
+// buildAppraisal computes the final annual appraisal for a role
+func buildAppraisal(role Role, year int) Appraisal {
+	config := getAppraisalConfig()
+	roleKPIs := getKPIsByRoleID(role.ID)
+
+	appraisal := Appraisal{
+		Year:         year,
+		Role:         role,
+		Mode:         config.Mode,
+		PeriodScores: []AppraisalPeriodScore{},
+	}
+
+	// Months and quarters follow the fiscal year
+	for i, weight := range config.Weights {
+		var pr PeriodRange
+		var label string
+		if config.Mode == AppraisalModeMonthly {
+			month := fiscalYearRange(year).Start.AddDate(0, i, 0)
+			pr = PeriodRange{Start: month, End: month}
+			label = month.Format("Jan 2006")
+		} else {
+			pr = fiscalQuarterRange(year, i+1)
+			label = fmt.Sprintf("Q%d %d", i+1, year)
+		}
+
+		periodScore := AppraisalPeriodScore{
+			Label:    label,
+			Score:    averageRoleScore(roleKPIs, pr),
+			Coverage: averageRoleCoverage(roleKPIs, pr),
+			Weight:   weight,
+		}
+
+		for _, period := range periodsInRange(pr.Start, pr.End) {
+			if calculateScoreResult(roleKPIs, period).HasData() {
+				periodScore.HasData = true
+				break
+			}
+		}
+
+		appraisal.PeriodScores = append(appraisal.PeriodScores, periodScore)
+	}
+
+	// Periods without data are left out and the remaining weights renormalised
+	var weightedScore, usedWeight, totalWeight float64
+	for _, ps := range appraisal.PeriodScores {
+		totalWeight += ps.Weight
+		if ps.HasData {
+			weightedScore += ps.Score * ps.Weight
+			usedWeight += ps.Weight
+		}
+	}
+
+	if usedWeight > 0 {
+		appraisal.FinalScore = weightedScore / usedWeight
+	}
+	if totalWeight > 0 {
+		appraisal.Coverage = usedWeight / totalWeight * 100
+	}
+
+	appraisal.Rating = notRated
+	if usedWeight > 0 {
+		appraisal.Rating = getRating(role.ID, appraisal.FinalScore)
+	}
+
+	return appraisal
+}
+
+// buildAppraisals computes appraisals for every role with KPIs
+func buildAppraisals(year int) []Appraisal {
+	appraisals := []Appraisal{}
+	for _, role := range roles {
+		if len(getKPIsByRoleID(role.ID)) == 0 {
+			continue
+		}
+		appraisals = append(appraisals, buildAppraisal(role, year))
+	}
+	return appraisals
+}
+
+// getMeasurementYears returns the distinct fiscal years that have measurements
+func getMeasurementYears() []int {
+	var years []int
+	seen := make(map[int]bool)
+	for _, m := range measurements {
+		year := fiscalYearOf(m.Period)
+		if !seen[year] {
+			seen[year] = true
+			years = append(years, year)
+		}
+	}
+	sort.Ints(years)
+	return years
+}
+
+// writeAppraisalsSheet writes the appraisals of every year with data to the Excel file.
+// The sheet is derived data and is not read back by loadFromExcel.
+func writeAppraisalsSheet(f *excelize.File) {
+	f.NewSheet(appraisalsSheet)
+	f.SetSheetRow(appraisalsSheet, "A1", &[]interface{}{
+		"Year", "RoleID", "Role", "Mode", "Periods", "FinalScore", "Coverage", "Rating",
+	})
+
+	row := 2
+	for _, year := range getMeasurementYears() {
+		for _, appraisal := range buildAppraisals(year) {
+			var periods []string
+			for _, ps := range appraisal.PeriodScores {
+				if ps.HasData {
+					periods = append(periods, fmt.Sprintf("%s: %.2f", ps.Label, ps.Score))
+				}
+			}
+
+			f.SetSheetRow(appraisalsSheet, fmt.Sprintf("A%d", row), &[]interface{}{
+				appraisal.Year, appraisal.Role.ID, appraisal.Role.Name, appraisal.Mode,
+				strings.Join(periods, "; "), appraisal.FinalScore, appraisal.Coverage, appraisal.Rating,
+			})
+			row++
+		}
+	}
+
+	formatAsTable(f, appraisalsSheet, row-1, 8)
+}
+
+// viewAppraisals shows the final annual appraisals from the CLI
+func viewAppraisals(scanner *bufio.Scanner) {
+	fmt.Print("Enter year: ")
+	scanner.Scan()
+	yearStr := scanner.Text()
+
+	year, err := strconv.Atoi(yearStr)
+	if err != nil || year < 2000 || year > 2100 {
+		fmt.Println("Invalid year.")
+		return
+	}
+
+	config := getAppraisalConfig()
+	fmt.Printf("\n=== Annual Appraisals %d (%s weighting) ===\n\n", year, config.Mode)
+
+	for _, appraisal := range buildAppraisals(year) {
+		fmt.Printf("== %s ==\n", appraisal.Role.Name)
+
+		for _, ps := range appraisal.PeriodScores {
+			if ps.HasData {
+				fmt.Printf("  %-10s %8.2f%%  (coverage %.0f%%, weight %.1f)\n",
+					ps.Label, ps.Score, ps.Coverage, ps.Weight)
+			} else {
+				fmt.Printf("  %-10s %9s  (weight %.1f)\n", ps.Label, "-", ps.Weight)
+			}
+		}
+
+		fmt.Printf("Final Score: %.2f%% (coverage %.0f%%)\n", appraisal.FinalScore, appraisal.Coverage)
+		fmt.Printf("Rating: %s\n\n", appraisal.Rating)
+	}
+
+	// Wait for user to press enter
+	fmt.Print("\nPress Enter to continue...")
+	scanner.Scan()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetRating(t *testing.T) {
+	setupSubmissionTest(t)
+	global := []RatingBand{{Name: "Good", MinScore: 60}, {Name: "Poor", MinScore: 20}, {Name: "Great", MinScore: 90}}
+	sales := []RatingBand{{Name: "Hit", MinScore: 100}, {Name: "Miss", MinScore: 0}}
+
+	for _, tt := range []struct {
+		global []RatingBand
+		role   map[int][]RatingBand
+		score  float64
+		want   string
+	}{
+		{nil, nil, 95, "Outstanding"},
+		{nil, nil, 94.99, "Exceeds Expectations"},
+		{nil, nil, 70, "Meets Expectations"},
+		{nil, nil, 0, "Unsatisfactory"},
+		// Bands are sorted and scores below every band get the lowest
+		{global, nil, 90, "Great"},
+		{global, nil, 75, "Good"},
+		{global, nil, 10, "Poor"},
+		{global, map[int][]RatingBand{1: sales}, 99, "Miss"},
+		{global, map[int][]RatingBand{1: sales}, 100, "Hit"},
+		{global, map[int][]RatingBand{1: {}}, 75, "Good"},
+		{global, map[int][]RatingBand{2: sales}, 75, "Good"},
+	} {
+		appSettings.RatingBands = tt.global
+		appSettings.RoleRatingBands = tt.role
+		if rating := getRating(1, tt.score); rating != tt.want {
+			t.Errorf("score %v with %v / %v: %q, want %q", tt.score, tt.global, tt.role, rating, tt.want)
+		}
+	}
+}
+
+func TestValidateRatingBands(t *testing.T) {
+	for _, tt := range []struct {
+		bands []RatingBand
+		ok    bool
+	}{
+		{nil, true},
+		{defaultRatingBands, true},
+		{[]RatingBand{{Name: "Top", MinScore: 100}, {Name: "Rest", MinScore: 0}}, true},
+		{[]RatingBand{{Name: " ", MinScore: 50}}, false},
+		{[]RatingBand{{Name: "Top", MinScore: 101}}, false},
+		{[]RatingBand{{Name: "Bottom", MinScore: -1}}, false},
+		{[]RatingBand{{Name: "A", MinScore: 50}, {Name: "B", MinScore: 50}}, false},
+	} {
+		if err := validateRatingBands(tt.bands); (err == nil) != tt.ok {
+			t.Errorf("%v: error %v, want ok %v", tt.bands, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateAppraisalConfig(t *testing.T) {
+	twelve := []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	for _, tt := range []struct {
+		config AppraisalConfig
+		ok     bool
+	}{
+		{AppraisalConfig{Mode: AppraisalModeMonthly, Weights: twelve}, true},
+		{AppraisalConfig{Mode: AppraisalModeMonthly, Weights: twelve[:11]}, false},
+		{AppraisalConfig{Mode: AppraisalModeQuarterly, Weights: []float64{10, 20, 30, 40}}, true},
+		{AppraisalConfig{Mode: AppraisalModeQuarterly, Weights: []float64{0, 0, 0, 100}}, true},
+		{AppraisalConfig{Mode: AppraisalModeQuarterly, Weights: twelve}, false},
+		{AppraisalConfig{Mode: AppraisalModeQuarterly, Weights: []float64{50, 50, 50, -50}}, false},
+		{AppraisalConfig{Mode: AppraisalModeQuarterly, Weights: []float64{0, 0, 0, 0}}, false},
+		{AppraisalConfig{Mode: "yearly", Weights: []float64{100}}, false},
+		{AppraisalConfig{}, false},
+	} {
+		if err := validateAppraisalConfig(tt.config); (err == nil) != tt.ok {
+			t.Errorf("%+v: error %v, want ok %v", tt.config, err, tt.ok)
+		}
+	}
+}
+
+func TestBuildAppraisalQuarterly(t *testing.T) {
+	setupSubmissionTest(t)
+	kpis = kpis[:1]
+	measurements = nil
+	addRevenue(100, month(1))
+	addRevenue(80, month(4))
+	addRevenue(60, month(5))
+	appSettings.Appraisal = AppraisalConfig{Mode: AppraisalModeQuarterly, Weights: []float64{10, 20, 30, 40}}
+
+	// Q2 averages its measured months, Q3 and Q4 have no data and are left out
+	appraisal := buildAppraisal(roles[0], 2026)
+	for i, want := range []AppraisalPeriodScore{
+		{Label: "Q1 2026", Score: 100, Weight: 10, HasData: true},
+		{Label: "Q2 2026", Score: 70, Weight: 20, HasData: true},
+		{Label: "Q3 2026", Weight: 30},
+		{Label: "Q4 2026", Weight: 40},
+	} {
+		got := appraisal.PeriodScores[i]
+		got.Coverage = 0
+		if got != want {
+			t.Errorf("period %d: %+v, want %+v", i, got, want)
+		}
+	}
+	if roundScore(appraisal.FinalScore) != 80 || appraisal.Coverage != 30 || appraisal.Rating != "Meets Expectations" {
+		t.Errorf("appraisal %+v, want 80%% at 30%% coverage", appraisal)
+	}
+
+	if appraisal := buildAppraisal(roles[0], 2025); appraisal.Rating != notRated || appraisal.FinalScore != 0 || appraisal.Coverage != 0 {
+		t.Errorf("appraisal %+v, want not rated without data", appraisal)
+	}
+}
+
+func TestBuildAppraisalMonthlyFollowsFiscalYear(t *testing.T) {
+	setupSubmissionTest(t)
+	kpis = kpis[:1]
+	measurements = nil
+	appSettings.FiscalYearStartMonth = 4
+	appSettings.Appraisal = AppraisalConfig{Mode: AppraisalModeMonthly, Weights: []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 3}}
+	addRevenue(50, month(3))
+	addRevenue(100, month(4))
+	addRevenue(60, month(3).AddDate(1, 0, 0))
+
+	// March 2026 belongs to fiscal year 2025, March 2027 to 2026 with three times the weight
+	appraisal := buildAppraisal(roles[0], 2026)
+	if len(appraisal.PeriodScores) != 12 || appraisal.PeriodScores[0].Label != "Apr 2026" || appraisal.PeriodScores[11].Label != "Mar 2027" {
+		t.Fatalf("periods %+v, want April 2026 to March 2027", appraisal.PeriodScores)
+	}
+	if appraisal.FinalScore != 70 || roundScore(appraisal.Coverage) != 28.57 {
+		t.Errorf("appraisal %+v, want 70%% at 4/14 coverage", appraisal)
+	}
+
+	if years := getMeasurementYears(); len(years) != 2 || years[0] != 2025 || years[1] != 2026 {
+		t.Errorf("years %v, want fiscal years 2025 and 2026", years)
+	}
+}
+
+func TestAppraisalsAPI(t *testing.T) {
+	setupSubmissionTest(t)
+	roles = append(roles, Role{ID: 2, Name: "Support"})
+	appSettings.Appraisal = AppraisalConfig{Mode: AppraisalModeMonthly, Weights: []float64{1}}
+
+	rec := httptest.NewRecorder()
+	newAPIRouter().ServeHTTP(rec, httptest.NewRequest("GET", "/api/appraisals/2026", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var response struct {
+		Config     AppraisalConfig `json:"config"`
+		Appraisals []Appraisal     `json:"appraisals"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid configuration falls back to equal quarters, roles without KPIs are skipped
+	if response.Config.Mode != AppraisalModeQuarterly || len(response.Config.Weights) != 4 {
+		t.Errorf("config %+v, want the default quarterly weights", response.Config)
+	}
+	if len(response.Appraisals) != 1 || response.Appraisals[0].Role.Name != "Sales" {
+		t.Errorf("appraisals %+v, want only Sales", response.Appraisals)
+	}
+
+	for _, year := range []string{"1999", "abc"} {
+		rec := httptest.NewRecorder()
+		newAPIRouter().ServeHTTP(rec, httptest.NewRequest("GET", "/api/appraisals/"+year, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", year, rec.Code)
+		}
+	}
+}
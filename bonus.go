@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const bonusSheet = "Bonus Payout"
+
+// BonusBand maps a minimum yearly score to a bonus multiplier
+type BonusBand struct {
+	MinScore   float64 `json:"min_score"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// BonusPolicy describes how yearly scores are turned into bonus payouts
+type BonusPolicy struct {
+	Name                string      `json:"name"`
+	TargetBonusPercent  float64     `json:"target_bonus_percent"` // Percentage of base salary paid at multiplier 1.0
+	Bands               []BonusBand `json:"bands"`
+	MaxMultiplier       float64     `json:"max_multiplier"` // 0 means no cap
+	MaxPayout           float64     `json:"max_payout"`     // 0 means no cap
+	MinEmploymentMonths int         `json:"min_employment_months"`
+	ProRate             bool        `json:"pro_rate"` // Pro-rate payouts for employees hired during the year
+}
+
+// BonusResult is the bonus calculation for a single employee
+type BonusResult struct {
+	Employee         Employee `json:"employee"`
+	RoleName         string   `json:"role_name"`
+	YearScore        float64  `json:"year_score"`
+	Rating           string   `json:"rating"`
+	EmploymentMonths int      `json:"employment_months"`
+	Eligible         bool     `json:"eligible"`
+	IneligibleReason string   `json:"ineligible_reason,omitempty"`
+	Multiplier       float64  `json:"multiplier"`
+	ProRateFactor    float64  `json:"pro_rate_factor"`
+	TargetBonus      float64  `json:"target_bonus"`
+	Payout           float64  `json:"payout"`
+	Capped           bool     `json:"capped"`
+}
+
+// BonusRun is the result of evaluating a bonus policy for a year
+type BonusRun struct {
+	Year        int           `json:"year"`
+	Policy      BonusPolicy   `json:"policy"`
+	DryRun      bool          `json:"dry_run"`
+	Results     []BonusResult `json:"results"`
+	TotalPayout float64       `json:"total_payout"`
+	GeneratedAt time.Time     `json:"generated_at"`
+}
+
+// defaultBonusPolicy is used when no bonus policy is configured
+var defaultBonusPolicy = BonusPolicy{
+	Name:               "Default",
+	TargetBonusPercent: 10,
+	Bands: []BonusBand{
+		{MinScore: 95, Multiplier: 1.5},
+		{MinScore: 85, Multiplier: 1.2},
+		{MinScore: 70, Multiplier: 1.0},
+		{MinScore: 50, Multiplier: 0.5},
+		{MinScore: 0, Multiplier: 0},
+	},
+	MaxMultiplier:       1.5,
+	MinEmploymentMonths: 3,
+	ProRate:             true,
+}
+
+// getBonusPolicy returns the configured bonus policy or the default one
+func getBonusPolicy() BonusPolicy {
+	if appSettings.BonusPolicy != nil {
+		return *appSettings.BonusPolicy
+	}
+	return defaultBonusPolicy
+}
+
+// validateBonusPolicy checks that a bonus policy can be evaluated
+func validateBonusPolicy(policy BonusPolicy) error {
+	if len(policy.Bands) == 0 {
+		return fmt.Errorf("bonus policy needs at least one band")
+	}
+	if policy.TargetBonusPercent < 0 {
+		return fmt.Errorf("target bonus percent cannot be negative")
+	}
+	if policy.MaxMultiplier < 0 || policy.MaxPayout < 0 {
+		return fmt.Errorf("bonus caps cannot be negative")
+	}
+	if policy.MinEmploymentMonths < 0 {
+		return fmt.Errorf("minimum employment months cannot be negative")
+	}
+
+	seen := make(map[float64]bool)
+	for _, band := range policy.Bands {
+		if band.MinScore < 0 || band.MinScore > 100 {
+			return fmt.Errorf("bonus band minimum score must be between 0 and 100")
+		}
+		if band.Multiplier < 0 {
+			return fmt.Errorf("bonus multiplier cannot be negative")
+		}
+		if seen[band.MinScore] {
+			return fmt.Errorf("duplicate bonus band minimum score %.2f", band.MinScore)
+		}
+		seen[band.MinScore] = true
+	}
+
+	return nil
+}
+
+// bonusMultiplier returns the multiplier of the highest band the score reaches
+func bonusMultiplier(policy BonusPolicy, score float64) float64 {
+	bands := make([]BonusBand, len(policy.Bands))
+	copy(bands, policy.Bands)
+	sort.Slice(bands, func(i, j int) bool {
+		return bands[i].MinScore > bands[j].MinScore
+	})
+
+	for _, band := range bands {
+		if score >= band.MinScore {
+			return band.Multiplier
+		}
+	}
+	return 0
+}
+
+// employmentMonths returns the number of started months between the hire date and the end of the fiscal year
+func employmentMonths(hireDate time.Time, year int) int {
+	end := fiscalYearRange(year).End
+	months := (end.Year()-hireDate.Year())*12 + int(end.Month()) - int(hireDate.Month()) + 1
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+// monthsEmployedInYear returns the number of months of the fiscal year the employee was employed
+func monthsEmployedInYear(hireDate time.Time, year int) int {
+	months := employmentMonths(hireDate, year)
+	if months > 12 {
+		return 12
+	}
+	return months
+}
+
+// calculateBonus evaluates a bonus policy for one employee
+func calculateBonus(policy BonusPolicy, employee Employee, year int, appraisals map[int]Appraisal) BonusResult {
+	result := BonusResult{
+		Employee:         employee,
+		EmploymentMonths: employmentMonths(employee.HireDate, year),
+		ProRateFactor:    1,
+	}
+
+	appraisal, ok := appraisals[employee.RoleID]
+	if !ok {
+		result.IneligibleReason = "role has no KPIs"
+		return result
+	}
+
+	result.RoleName = appraisal.Role.Name
+	result.YearScore = appraisal.FinalScore
+	result.Rating = appraisal.Rating
+
+	// Someone hired after the fiscal year is never eligible, whatever the minimum
+	switch {
+	case result.EmploymentMonths == 0:
+		result.IneligibleReason = "hired after the fiscal year"
+		return result
+	case appraisal.Coverage == 0:
+		result.IneligibleReason = "no scores for the year"
+		return result
+	case result.EmploymentMonths < policy.MinEmploymentMonths:
+		result.IneligibleReason = fmt.Sprintf("employed %d months, minimum is %d",
+			result.EmploymentMonths, policy.MinEmploymentMonths)
+		return result
+	}
+
+	result.Eligible = true
+	result.Multiplier = bonusMultiplier(policy, appraisal.FinalScore)
+	if policy.MaxMultiplier > 0 && result.Multiplier > policy.MaxMultiplier {
+		result.Multiplier = policy.MaxMultiplier
+		result.Capped = true
+	}
+
+	if policy.ProRate {
+		result.ProRateFactor = float64(monthsEmployedInYear(employee.HireDate, year)) / 12
+	}
+
+	result.TargetBonus = employee.BaseSalary * policy.TargetBonusPercent / 100
+	result.Payout = result.TargetBonus * result.Multiplier * result.ProRateFactor
+	if policy.MaxPayout > 0 && result.Payout > policy.MaxPayout {
+		result.Payout = policy.MaxPayout
+		result.Capped = true
+	}
+	result.Payout = math.Round(result.Payout*100) / 100
+
+	return result
+}
+
+// runBonusPolicy evaluates a bonus policy for every employee. A dry run only
+// marks the result as a simulation, nothing is persisted either way.
+func runBonusPolicy(policy BonusPolicy, year int, dryRun bool) BonusRun {
+	run := BonusRun{
+		Year:        year,
+		Policy:      policy,
+		DryRun:      dryRun,
+		Results:     []BonusResult{},
+		GeneratedAt: time.Now(),
+	}
+
+	appraisals := make(map[int]Appraisal)
+	for _, appraisal := range buildAppraisals(year) {
+		appraisals[appraisal.Role.ID] = appraisal
+	}
+
+	for _, employee := range employees {
+		result := calculateBonus(policy, employee, year, appraisals)
+		run.Results = append(run.Results, result)
+		run.TotalPayout += result.Payout
+	}
+
+	return run
+}
+
+// writeBonusWorksheet writes a bonus run to an xlsx payout worksheet
+func writeBonusWorksheet(run BonusRun, path string) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	f.NewSheet(bonusSheet)
+	f.DeleteSheet("Sheet1")
+
+	f.SetSheetRow(bonusSheet, "A1", &[]interface{}{
+		"EmployeeID", "Employee", "Role", "YearScore", "Rating", "EmploymentMonths",
+		"Eligible", "Reason", "Multiplier", "ProRateFactor", "TargetBonus", "Payout", "Capped",
+	})
+
+	for i, r := range run.Results {
+		f.SetSheetRow(bonusSheet, fmt.Sprintf("A%d", i+2), &[]interface{}{
+			r.Employee.ID, r.Employee.Name, r.RoleName, math.Round(r.YearScore*100) / 100, r.Rating,
+			r.EmploymentMonths, r.Eligible, r.IneligibleReason, r.Multiplier, r.ProRateFactor,
+			r.TargetBonus, r.Payout, r.Capped,
+		})
+	}
+
+	totalRow := len(run.Results) + 2
+	f.SetSheetRow(bonusSheet, fmt.Sprintf("A%d", totalRow), &[]interface{}{
+		"", "TOTAL", "", "", "", "", "", "", "", "", "", run.TotalPayout, "",
+	})
+
+	formatAsTable(f, bonusSheet, totalRow, 13)
+
+	if err := f.SaveAs(path); err != nil {
+		return fmt.Errorf("failed to save bonus worksheet: %v", err)
+	}
+	return nil
+}
+
+// bonusWorksheetPath returns where the payout worksheet for a year is saved
+func bonusWorksheetPath(year int) string {
+	return filepath.Join(appSettings.DatabasePath, "reports", fmt.Sprintf("Bonus_Payout_%d.xlsx", year))
+}
+
+// displayBonusRun prints a bonus run to the console
+func displayBonusRun(run BonusRun) {
+	title := "Bonus Payout"
+	if run.DryRun {
+		title = "Bonus Payout Simulation (dry run)"
+	}
+	fmt.Printf("\n=== %s %d - Policy: %s ===\n\n", title, run.Year, run.Policy.Name)
+
+	fmt.Printf("%-25s %-35s %-8s %-10s %-8s %-12s %s\n",
+		"Employee", "Role", "Score", "Multiplier", "ProRate", "Payout", "Note")
+	fmt.Println(strings.Repeat("-", 120))
+
+	for _, r := range run.Results {
+		note := r.IneligibleReason
+		if r.Capped {
+			note = "capped"
+		}
+		fmt.Printf("%-25s %-35s %-8.2f %-10.2f %-8.2f %-12.2f %s\n",
+			r.Employee.Name, r.RoleName, r.YearScore, r.Multiplier, r.ProRateFactor, r.Payout, note)
+	}
+
+	if len(run.Results) == 0 {
+		fmt.Println("No employees found. Add employees to the Employees sheet of the Excel database.")
+	}
+
+	fmt.Printf("\nTotal payout: %.2f\n", run.TotalPayout)
+}
+
+// loadBonusPolicyFile reads a bonus policy from a JSON file
+func loadBonusPolicyFile(path string) (BonusPolicy, error) {
+	var policy BonusPolicy
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read policy file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse policy file: %v", err)
+	}
+
+	if err := validateBonusPolicy(policy); err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+// generateBonusWorksheet calculates bonuses from the CLI, optionally as a dry run
+func generateBonusWorksheet(scanner *bufio.Scanner) {
+	fmt.Print("Enter year: ")
+	scanner.Scan()
+	year, err := strconv.Atoi(scanner.Text())
+	if err != nil || year < 2000 || year > 2100 {
+		fmt.Println("Invalid year.")
+		return
+	}
+
+	fmt.Print("Policy JSON file to simulate (or press enter to use the configured policy): ")
+	scanner.Scan()
+	policyPath := strings.TrimSpace(scanner.Text())
+
+	if policyPath != "" {
+		// Dry run: evaluate the candidate policy without writing a worksheet
+		policy, err := loadBonusPolicyFile(policyPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		current := runBonusPolicy(getBonusPolicy(), year, false)
+		simulated := runBonusPolicy(policy, year, true)
+		displayBonusRun(simulated)
+		fmt.Printf("Current policy total: %.2f (difference %+.2f)\n",
+			current.TotalPayout, simulated.TotalPayout-current.TotalPayout)
+	} else {
+		run := runBonusPolicy(getBonusPolicy(), year, false)
+		displayBonusRun(run)
+
+		path := bonusWorksheetPath(year)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			fmt.Printf("Error creating reports directory: %v\n", err)
+			return
+		}
+		if err := writeBonusWorksheet(run, path); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Payout worksheet saved to: %s\n", path)
+	}
+
+	// Wait for user to press enter
+	fmt.Print("\nPress Enter to continue...")
+	scanner.Scan()
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bonusAppraisals returns the appraisal of the Sales role with a final score
+func bonusAppraisals(score float64) map[int]Appraisal {
+	return map[int]Appraisal{1: {Year: 2026, Role: Role{ID: 1, Name: "Sales"}, FinalScore: score, Coverage: 1}}
+}
+
+func TestCalculateBonusHiredAfterYear(t *testing.T) {
+	setupSubmissionTest(t)
+	policy := BonusPolicy{TargetBonusPercent: 10, Bands: []BonusBand{{MinScore: 0, Multiplier: 1}}}
+	employee := Employee{ID: 1, RoleID: 1, HireDate: time.Date(2027, 2, 1, 0, 0, 0, 0, time.Local), BaseSalary: 50000}
+
+	// Without a minimum and without pro-rating, nothing would stop a full payout
+	result := calculateBonus(policy, employee, 2026, bonusAppraisals(90))
+	if result.Eligible || result.Payout != 0 || result.EmploymentMonths != 0 {
+		t.Errorf("result %+v, want ineligible without payout", result)
+	}
+}
+
+func TestBonusMultiplier(t *testing.T) {
+	policy := BonusPolicy{Bands: []BonusBand{{MinScore: 50, Multiplier: 0.5}, {MinScore: 90, Multiplier: 1.5}, {MinScore: 70, Multiplier: 1}}}
+	for score, want := range map[float64]float64{100: 1.5, 90: 1.5, 89.99: 1, 70: 1, 50: 0.5, 49.99: 0} {
+		if multiplier := bonusMultiplier(policy, score); multiplier != want {
+			t.Errorf("score %v: multiplier %v, want %v", score, multiplier, want)
+		}
+	}
+}
+
+func TestValidateBonusPolicy(t *testing.T) {
+	bands := []BonusBand{{MinScore: 0, Multiplier: 1}}
+	for _, tt := range []struct {
+		name   string
+		policy BonusPolicy
+		ok     bool
+	}{
+		{"default", defaultBonusPolicy, true},
+		{"no bands", BonusPolicy{TargetBonusPercent: 10}, false},
+		{"negative target", BonusPolicy{TargetBonusPercent: -1, Bands: bands}, false},
+		{"negative multiplier cap", BonusPolicy{MaxMultiplier: -1, Bands: bands}, false},
+		{"negative payout cap", BonusPolicy{MaxPayout: -1, Bands: bands}, false},
+		{"negative minimum months", BonusPolicy{MinEmploymentMonths: -1, Bands: bands}, false},
+		{"band above 100", BonusPolicy{Bands: []BonusBand{{MinScore: 101, Multiplier: 1}}}, false},
+		{"negative multiplier", BonusPolicy{Bands: []BonusBand{{MinScore: 0, Multiplier: -1}}}, false},
+		{"duplicate band", BonusPolicy{Bands: []BonusBand{{MinScore: 50, Multiplier: 1}, {MinScore: 50, Multiplier: 2}}}, false},
+	} {
+		if err := validateBonusPolicy(tt.policy); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCalculateBonus(t *testing.T) {
+	setupSubmissionTest(t)
+	hired := func(year int, month time.Month, day int) Employee {
+		return Employee{ID: 1, RoleID: 1, HireDate: time.Date(year, month, day, 0, 0, 0, 0, time.Local), BaseSalary: 50000}
+	}
+	capped := defaultBonusPolicy
+	capped.Bands = []BonusBand{{MinScore: 0, Multiplier: 2}}
+	payoutCap := defaultBonusPolicy
+	payoutCap.MaxPayout = 5500
+	noProRate := defaultBonusPolicy
+	noProRate.ProRate = false
+
+	for _, tt := range []struct {
+		name       string
+		policy     BonusPolicy
+		employee   Employee
+		appraisals map[int]Appraisal
+		months     int
+		multiplier float64
+		factor     float64
+		payout     float64
+		capped     bool
+		reason     string
+	}{
+		{"full year", defaultBonusPolicy, hired(2024, 5, 1), bonusAppraisals(90), 32, 1.2, 1, 6000, false, ""},
+		{"below every band", defaultBonusPolicy, hired(2024, 5, 1), bonusAppraisals(40), 32, 0, 1, 0, false, ""},
+		{"multiplier cap", capped, hired(2024, 5, 1), bonusAppraisals(60), 32, 1.5, 1, 7500, true, ""},
+		{"payout cap", payoutCap, hired(2024, 5, 1), bonusAppraisals(96), 32, 1.5, 1, 5500, true, ""},
+		{"pro-rated", defaultBonusPolicy, hired(2026, 7, 20), bonusAppraisals(75), 6, 1, 0.5, 2500, false, ""},
+		{"not pro-rated", noProRate, hired(2026, 7, 20), bonusAppraisals(75), 6, 1, 1, 5000, false, ""},
+		{"minimum months reached", defaultBonusPolicy, hired(2026, 10, 31), bonusAppraisals(75), 3, 1, 0.25, 1250, false, ""},
+		{"below minimum months", defaultBonusPolicy, hired(2026, 11, 1), bonusAppraisals(75), 2, 0, 1, 0, false, "employed 2 months, minimum is 3"},
+		{"no scores", defaultBonusPolicy, hired(2024, 5, 1), map[int]Appraisal{1: {Role: Role{ID: 1}}}, 32, 0, 1, 0, false, "no scores for the year"},
+		{"role without KPIs", defaultBonusPolicy, hired(2024, 5, 1), map[int]Appraisal{}, 32, 0, 1, 0, false, "role has no KPIs"},
+		{"hired after the year", defaultBonusPolicy, hired(2027, 1, 1), bonusAppraisals(75), 0, 0, 1, 0, false, "hired after the fiscal year"},
+	} {
+		result := calculateBonus(tt.policy, tt.employee, 2026, tt.appraisals)
+		if result.EmploymentMonths != tt.months || result.Multiplier != tt.multiplier || result.ProRateFactor != tt.factor {
+			t.Errorf("%s: months %d multiplier %v factor %v, want %d %v %v",
+				tt.name, result.EmploymentMonths, result.Multiplier, result.ProRateFactor, tt.months, tt.multiplier, tt.factor)
+		}
+		if result.Payout != tt.payout || result.Capped != tt.capped || result.IneligibleReason != tt.reason || result.Eligible != (tt.reason == "") {
+			t.Errorf("%s: payout %v capped %v reason %q, want %v %v %q",
+				tt.name, result.Payout, result.Capped, result.IneligibleReason, tt.payout, tt.capped, tt.reason)
+		}
+	}
+}
+
+func TestEmploymentMonthsFollowFiscalYear(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.FiscalYearStartMonth = 4
+
+	// Fiscal year 2026 runs from April 2026 to March 2027
+	for _, tt := range []struct {
+		hired        time.Time
+		months, year int
+	}{
+		{month(1), 15, 12},
+		{month(4), 12, 12},
+		{month(10), 6, 6},
+		{month(3).AddDate(1, 0, 0), 1, 1},
+		{month(4).AddDate(1, 0, 0), 0, 0},
+	} {
+		if months, inYear := employmentMonths(tt.hired, 2026), monthsEmployedInYear(tt.hired, 2026); months != tt.months || inYear != tt.year {
+			t.Errorf("hired %s: %d months, %d in the year, want %d and %d", tt.hired.Format("2006-01"), months, inYear, tt.months, tt.year)
+		}
+	}
+}
+
+func TestRunBonusPolicy(t *testing.T) {
+	setupSubmissionTest(t)
+	kpis = kpis[:1]
+	measurements = nil
+	for m := time.January; m <= time.December; m++ {
+		addRevenue(90, month(m))
+	}
+	employees = []Employee{
+		{ID: 1, Name: "Ana", RoleID: 1, HireDate: month(1).AddDate(-1, 0, 0), BaseSalary: 50000},
+		{ID: 2, Name: "Budi", RoleID: 1, HireDate: month(7), BaseSalary: 40000},
+		{ID: 3, Name: "Citra", RoleID: 2, HireDate: month(1), BaseSalary: 40000},
+	}
+
+	run := runBonusPolicy(defaultBonusPolicy, 2026, true)
+	if !run.DryRun || len(run.Results) != 3 || run.TotalPayout != 8400 {
+		t.Fatalf("run %+v, want a dry run paying 6000 + 2400", run)
+	}
+	if run.Results[2].Eligible || run.Results[2].IneligibleReason != "role has no KPIs" {
+		t.Errorf("result %+v, want Citra ineligible", run.Results[2])
+	}
+}
+
+func TestBonusAPI(t *testing.T) {
+	setupSubmissionTest(t)
+	employees[0].HireDate = month(1).AddDate(-1, 0, 0)
+	employees[0].BaseSalary = 50000
+
+	for _, tt := range []struct {
+		method, url, body string
+		status            int
+	}{
+		{"GET", "/api/bonus/2026", "", http.StatusOK},
+		{"GET", "/api/bonus/2026?format=xlsx", "", http.StatusOK},
+		{"GET", "/api/bonus/2026?format=pdf", "", http.StatusBadRequest},
+		{"GET", "/api/bonus/1999", "", http.StatusBadRequest},
+		{"POST", "/api/bonus/2026/simulate", `{"target_bonus_percent": 20, "bands": [{"min_score": 0, "multiplier": 1}]}`, http.StatusOK},
+		{"POST", "/api/bonus/2026/simulate", `{"target_bonus_percent": 20, "bands": []}`, http.StatusUnprocessableEntity},
+		{"POST", "/api/bonus/2026/simulate", `{"bands": `, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		newAPIRouter().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.url, rec.Code, tt.status, rec.Body)
+		}
+	}
+}
+
+func TestCreateEmployeeSaveFailure(t *testing.T) {
+	setupSubmissionTest(t)
+	failExcelSaves(t)
+
+	body := `{"name": "Eka", "role_id": 1, "hire_date": "2026-03-01T00:00:00Z", "base_salary": 40000}`
+	rec := httptest.NewRecorder()
+	createEmployee(rec, httptest.NewRequest("POST", "/api/employees", strings.NewReader(body)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500: %s", rec.Code, rec.Body)
+	}
+	if len(employees) != 1 || employees[0].Name != "Dewi" {
+		t.Errorf("employees %+v, want the new employee rolled back", employees)
+	}
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Exit codes for non-interactive commands
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// cliOutput receives command results. Progress messages printed with fmt.Print*
+// are redirected to stderr while a command runs so results can be piped.
+var cliOutput io.Writer = os.Stdout
+
+// cliUsage is printed for "help" and unknown commands
+const cliUsage = `Usage: kpi-tracker [config flags] <command> [flags]
+
+Commands:
+  interactive   Start the interactive menu and REST API (default without a command)
+  serve         Start the REST API server only (headless, stops on SIGTERM)
+  report        Generate a report
+  send-report   Email a report to the distribution lists
+  measure add   Add or update a KPI measurement
+  measure import
+                Import measurements from a CSV or xlsx file
+  import        Replace the database with an Excel workbook
+  export        Export the database to xlsx or json
+  backup        Create a timestamped backup of the Excel database
+  submissions   List missing measurements and send reminders
+  forecast      Forecast year-end KPI achievement
+  anomalies     List measurements that look anomalous
+  simulate      Score a what-if scenario and solve for a needed KPI value
+  period        Close a month against changes, reopen it or list closed months
+  help          Show this help
+
+Config flags override KPI_* environment variables, which override the
+settings file. Run "kpi-tracker -h" to list them and "kpi-tracker <command> -h"
+for the flags of a command.
+`
+
+// runCLI parses the config flags, runs a subcommand and returns the process exit code
+func runCLI(args []string) int {
+	global := flag.NewFlagSet("kpi-tracker", flag.ContinueOnError)
+	registerConfigFlags(global)
+	global.Usage = func() {
+		fmt.Fprint(os.Stderr, cliUsage)
+		fmt.Fprintln(os.Stderr, "\nConfig flags:")
+		global.PrintDefaults()
+	}
+	global.SetOutput(os.Stderr)
+	if err := global.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	args = global.Args()
+	if len(args) == 0 {
+		runInteractive()
+		return exitOK
+	}
+
+	command := args[0]
+	args = args[1:]
+
+	switch command {
+	case "interactive":
+		runInteractive()
+		return exitOK
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, cliUsage)
+		return exitOK
+	case "serve", "report", "measure", "import", "export", "backup", "submissions", "send-report", "forecast", "anomalies", "simulate", "period":
+		// Handled below
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n%s", command, cliUsage)
+		return exitUsage
+	}
+
+	// Keep stdout for command output only
+	stdout := os.Stdout
+	cliOutput = stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	if err := initApp(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	switch command {
+	case "serve":
+		return runServeCommand(args)
+	case "report":
+		return runReportCommand(args)
+	case "send-report":
+		return runSendReportCommand(args)
+	case "measure":
+		return runMeasureCommand(args)
+	case "import":
+		return runImportCommand(args)
+	case "export":
+		return runExportCommand(args)
+	case "backup":
+		return runBackupCommand(args)
+	case "submissions":
+		return runSubmissionsCommand(args)
+	case "forecast":
+		return runForecastCommand(args)
+	case "anomalies":
+		return runAnomaliesCommand(args)
+	case "simulate":
+		return runSimulateCommand(args)
+	case "period":
+		return runPeriodCommand(args)
+	}
+
+	return exitUsage
+}
+
+// parseFlags parses command flags and maps errors to exit codes
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// usageError prints a usage error for a command
+func usageError(fs *flag.FlagSet, format string, a ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", a...)
+	fs.Usage()
+	return exitUsage
+}
+
+// runServeCommand starts the REST API without the interactive menu
+func runServeCommand(args []string) int {
+	config := serverConfigFromSettings()
+
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.StringVar(&config.Addr, "addr", config.Addr, "listen address")
+	fs.DurationVar(&config.ReadTimeout, "read-timeout", config.ReadTimeout, "maximum duration for reading a request")
+	fs.DurationVar(&config.WriteTimeout, "write-timeout", config.WriteTimeout, "maximum duration for writing a response")
+	fs.DurationVar(&config.IdleTimeout, "idle-timeout", config.IdleTimeout, "keep-alive idle timeout")
+	fs.DurationVar(&config.ShutdownTimeout, "shutdown-timeout", config.ShutdownTimeout, "time allowed to drain requests on shutdown")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if err := runServer(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runReportCommand generates a report and writes it to a file or stdout
+func runReportCommand(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	reportType := fs.String("type", "monthly", "report type: monthly, quarterly, yearly, custom or compare")
+	year := fs.Int("year", time.Now().Year(), "report year")
+	month := fs.Int("month", 0, "month (1-12) for monthly reports")
+	quarter := fs.Int("quarter", 0, "quarter (1-4) for quarterly reports")
+	from := fs.String("from", "", "start period YYYY-MM for custom reports")
+	to := fs.String("to", "", "end period YYYY-MM for custom reports")
+	baseFrom := fs.String("base-from", "", "baseline start period YYYY-MM for compare reports")
+	baseTo := fs.String("base-to", "", "baseline end period YYYY-MM for compare reports")
+	compareFrom := fs.String("compare-from", "", "comparison start period YYYY-MM for compare reports")
+	compareTo := fs.String("compare-to", "", "comparison end period YYYY-MM for compare reports")
+	format := fs.String("format", "txt", "output format: txt, csv or html (compare also supports json)")
+	out := fs.String("out", "", "output file (default: stdout)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *format != "txt" && *format != "csv" && *format != "html" &&
+		!(*format == "json" && *reportType == "compare") {
+		return usageError(fs, "unsupported format %s", *format)
+	}
+	if *year < 2000 || *year > 2100 {
+		return usageError(fs, "invalid year %d", *year)
+	}
+
+	var content string
+	switch *reportType {
+	case "monthly":
+		if *month < 1 || *month > 12 {
+			return usageError(fs, "--month must be between 1 and 12")
+		}
+		period := time.Date(*year, time.Month(*month), 1, 0, 0, 0, 0, time.Local)
+		content = generateReport(period, period, *format)
+	case "quarterly":
+		if *quarter < 1 || *quarter > 4 {
+			return usageError(fs, "--quarter must be between 1 and 4")
+		}
+		startPeriod := time.Date(*year, time.Month((*quarter-1)*3+1), 1, 0, 0, 0, 0, time.Local)
+		endPeriod := time.Date(*year, time.Month(*quarter*3), 1, 0, 0, 0, 0, time.Local)
+		content = generateReport(startPeriod, endPeriod, *format)
+	case "yearly":
+		startPeriod := time.Date(*year, 1, 1, 0, 0, 0, 0, time.Local)
+		endPeriod := time.Date(*year, 12, 1, 0, 0, 0, 0, time.Local)
+		content = generateReport(startPeriod, endPeriod, *format)
+	case "custom":
+		pr, err := parseCLIPeriodRange(*from, *to)
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+		content = generateReport(pr.Start, pr.End, *format)
+	case "compare":
+		base, err := parseCLIPeriodRange(*baseFrom, *baseTo)
+		if err != nil {
+			return usageError(fs, "baseline: %v", err)
+		}
+		compare, err := parseCLIPeriodRange(*compareFrom, *compareTo)
+		if err != nil {
+			return usageError(fs, "comparison: %v", err)
+		}
+		if *format == "json" {
+			data, err := json.MarshalIndent(buildComparisonReport(base, compare), "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitError
+			}
+			content = string(data) + "\n"
+		} else {
+			content = generateComparisonReport(base, compare, *format)
+		}
+	default:
+		return usageError(fs, "unknown report type %s", *reportType)
+	}
+
+	return writeCommandOutput(content, *out)
+}
+
+// parseCLIPeriodRange parses a from/to pair of YYYY-MM periods; to defaults to from
+func parseCLIPeriodRange(from, to string) (PeriodRange, error) {
+	if from == "" {
+		return PeriodRange{}, fmt.Errorf("start period is required")
+	}
+
+	start, err := parsePeriodString(from)
+	if err != nil {
+		return PeriodRange{}, err
+	}
+
+	end := start
+	if to != "" {
+		end, err = parsePeriodString(to)
+		if err != nil {
+			return PeriodRange{}, err
+		}
+	}
+
+	if end.Before(start) {
+		return PeriodRange{}, fmt.Errorf("end period cannot be before start period")
+	}
+
+	return PeriodRange{Start: start, End: end}, nil
+}
+
+// writeCommandOutput writes command output to a file or to stdout
+func writeCommandOutput(content, out string) int {
+	if out == "" {
+		fmt.Fprint(cliOutput, content)
+		return exitOK
+	}
+
+	if dir := filepath.Dir(out); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
+			return exitError
+		}
+	}
+
+	if err := os.WriteFile(out, []byte(content), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(os.Stderr, "Output written to: %s\n", out)
+	return exitOK
+}
+
+// measureUsage is printed when the "measure" subcommand is missing or unknown
+const measureUsage = `Usage:
+  kpi-tracker measure add --kpi ID --period YYYY-MM --value N [--notes TEXT]
+  kpi-tracker measure add --kpi ID --period YYYY-MM --inputs name=N,... [--notes TEXT]
+  kpi-tracker measure import --file FILE [--map field=Column,...] [--dry-run] [--skip-errors]
+`
+
+// runMeasureCommand handles the "measure" subcommands
+func runMeasureCommand(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "add":
+			return runMeasureAddCommand(args[1:])
+		case "import":
+			return runMeasureImportCommand(args[1:])
+		}
+	}
+
+	fmt.Fprint(os.Stderr, measureUsage)
+	return exitUsage
+}
+
+// runMeasureAddCommand adds or updates a single measurement
+func runMeasureAddCommand(args []string) int {
+	fs := flag.NewFlagSet("measure add", flag.ContinueOnError)
+	kpiID := fs.Int("kpi", 0, "KPI ID")
+	periodStr := fs.String("period", "", "period YYYY-MM")
+	value := fs.Float64("value", 0, "measured value")
+	inputsSpec := fs.String("inputs", "", "raw inputs of a derived KPI, e.g. leavers=3,avg_headcount=40")
+	notes := fs.String("notes", "", "optional notes")
+	confirm := fs.Bool("confirm", false, "save a value that looks anomalous")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	valueSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "value" {
+			valueSet = true
+		}
+	})
+	inputs, err := parseFormulaInputs(*inputsSpec)
+	if err != nil {
+		return usageError(fs, "--inputs: %v", err)
+	}
+	if k := getKPIByID(*kpiID); k != nil && isDerivedKPI(*k) {
+		if valueSet {
+			return usageError(fs, "KPI %d is computed from %s, use --inputs instead of --value", k.ID, k.Formula)
+		}
+	} else if !valueSet {
+		return usageError(fs, "--value is required")
+	}
+	if len(inputs) == 0 {
+		inputs = nil
+	}
+
+	period, err := parsePeriodString(*periodStr)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	// Same rules as the interactive input and the REST API
+	kpi, computed, errs := validateMeasurementInput(*kpiID, *value, inputs, period)
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", errs)
+		return exitError
+	}
+	if anomalies := checkMeasurementValue(*kpi, computed, period); len(anomalies) > 0 && !*confirm {
+		fmt.Fprintf(os.Stderr, "Error: the value looks anomalous, check it and rerun with --confirm: %s\n", anomalyMessages(anomalies))
+		return exitError
+	}
+
+	saveMeasurement(kpi.ID, computed, inputs, kpi.Unit, period, *notes)
+
+	if err := saveToExcel(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving data to Excel: %v\n", err)
+		return exitError
+	}
+
+	data, _ := json.MarshalIndent(getExistingMeasurement(kpi.ID, period), "", "  ")
+	fmt.Fprintln(cliOutput, string(data))
+	return exitOK
+}
+
+// runMeasureImportCommand imports measurements from a CSV or xlsx file
+func runMeasureImportCommand(args []string) int {
+	fs := flag.NewFlagSet("measure import", flag.ContinueOnError)
+	file := fs.String("file", "", "CSV or xlsx file to import")
+	format := fs.String("format", "", "file format: csv or xlsx (default from the file extension)")
+	sheet := fs.String("sheet", "", "xlsx sheet (default the first sheet)")
+	mapSpec := fs.String("map", "", "column mapping, e.g. kpi=Indicator,value=Actual (fields: kpi_id, kpi, role, employee, period, value, notes)")
+	dryRun := fs.Bool("dry-run", false, "validate and show inserts and updates without saving")
+	skipErrors := fs.Bool("skip-errors", false, "import the valid rows even if some rows fail")
+	output := fs.String("output", "text", "result format: text or json")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *file == "" {
+		return usageError(fs, "--file is required")
+	}
+	if *output != "text" && *output != "json" {
+		return usageError(fs, "--output must be text or json")
+	}
+
+	mapping, err := parseImportMapping(*mapSpec)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	defer f.Close()
+
+	result, err := importMeasurements(f, *file, ImportOptions{
+		Format:     *format,
+		Sheet:      *sheet,
+		Mapping:    mapping,
+		DryRun:     *dryRun,
+		SkipErrors: *skipErrors,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *output == "json" {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(cliOutput, string(data))
+	} else {
+		fmt.Fprint(cliOutput, formatImportReport(result))
+	}
+
+	if result.Failed > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// getKPIByID returns the KPI with the given ID or nil
+func getKPIByID(id int) *KPI {
+	for i := range kpis {
+		if kpis[i].ID == id {
+			return &kpis[i]
+		}
+	}
+	return nil
+}
+
+// runImportCommand replaces the Excel database with another workbook
+func runImportCommand(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "Excel workbook to import")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *file == "" {
+		return usageError(fs, "--file is required")
+	}
+
+	if err := importExcelDB(*file); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(cliOutput, "Imported %d roles, %d KPIs and %d measurements from %s\n",
+		len(roles), len(kpis), len(measurements), *file)
+	return exitOK
+}
+
+// importExcelDB validates a workbook, backs up the current database and replaces it
+func importExcelDB(path string) error {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	for _, sheet := range []string{rolesSheet, kpisSheet, measurementsSheet} {
+		if index, err := f.GetSheetIndex(sheet); err != nil || index < 0 {
+			f.Close()
+			return fmt.Errorf("%s has no %s sheet", path, sheet)
+		}
+	}
+	f.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	if _, err := os.Stat(getExcelDBPath()); err == nil {
+		if _, err := backupExcelDB(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(getExcelDBPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write database: %v", err)
+	}
+
+	return loadFromExcel()
+}
+
+// runExportCommand exports the database as xlsx or json
+func runExportCommand(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "xlsx", "export format: xlsx or json")
+	out := fs.String("out", "", "output file (required for xlsx, default stdout for json)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	switch *format {
+	case "xlsx":
+		if *out == "" {
+			return usageError(fs, "--out is required for xlsx exports")
+		}
+		// Save first so the export contains everything in memory
+		if err := saveToExcel(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving data to Excel: %v\n", err)
+			return exitError
+		}
+		data, err := os.ReadFile(getExcelDBPath())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading Excel database: %v\n", err)
+			return exitError
+		}
+		return writeCommandOutput(string(data), *out)
+	case "json":
+		export := struct {
+			Roles        []Role        `json:"roles"`
+			Employees    []Employee    `json:"employees"`
+			KPIs         []KPI         `json:"kpis"`
+			Measurements []Measurement `json:"measurements"`
+			ExportedAt   time.Time     `json:"exported_at"`
+		}{
+			Roles:        roles,
+			Employees:    employees,
+			KPIs:         kpis,
+			Measurements: measurements,
+			ExportedAt:   time.Now(),
+		}
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		return writeCommandOutput(string(data)+"\n", *out)
+	default:
+		return usageError(fs, "unsupported format %s", *format)
+	}
+}
+
+// runBackupCommand creates a backup of the Excel database
+func runBackupCommand(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	backupPath, err := backupExcelDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintln(cliOutput, backupPath)
+	return exitOK
+}
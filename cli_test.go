@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupCLITest uses the submission test data with a temporary database and
+// captures the command output
+func setupCLITest(t *testing.T) *bytes.Buffer {
+	setupSubmissionTest(t)
+	appSettings.DatabasePath = t.TempDir()
+	t.Cleanup(discardPendingChanges)
+
+	previous := cliOutput
+	t.Cleanup(func() { cliOutput = previous })
+	var out bytes.Buffer
+	cliOutput = &out
+	return &out
+}
+
+func TestRunCLIUsage(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"help"}, exitOK},
+		{[]string{"-h"}, exitOK},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"--no-such-flag", "report"}, exitUsage},
+		{[]string{"--port"}, exitUsage},
+	} {
+		if code := runCLI(tt.args); code != tt.code {
+			t.Errorf("%v: exit code %d, want %d", tt.args, code, tt.code)
+		}
+	}
+}
+
+func TestReportCommand(t *testing.T) {
+	out := setupCLITest(t)
+	addRevenue(80, month(1))
+	dir := t.TempDir()
+	blocked := filepath.Join(dir, "file")
+	os.WriteFile(blocked, nil, 0600)
+
+	for _, tt := range []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"--type", "monthly", "--year", "2026", "--month", "1"}, exitOK, "Jan 2026: 80.00%"},
+		{[]string{"--type", "quarterly", "--year", "2026", "--quarter", "1", "--format", "csv"}, exitOK, "Sales"},
+		{[]string{"--type", "custom", "--from", "2026-01", "--to", "2026-02", "--format", "html"}, exitOK, "<html>"},
+		{[]string{"--type", "compare", "--base-from", "2026-01", "--compare-from", "2026-02", "--format", "json"}, exitOK, `"compare_measured": false`},
+		{[]string{"--type", "monthly", "--year", "2026", "--month", "1", "--out", filepath.Join(dir, "reports", "jan.txt")}, exitOK, ""},
+		{[]string{"--type", "monthly", "--year", "2026", "--month", "1", "--out", filepath.Join(blocked, "jan.txt")}, exitError, ""},
+		{[]string{"--type", "monthly", "--year", "2026"}, exitUsage, ""},
+		{[]string{"--type", "quarterly", "--quarter", "5"}, exitUsage, ""},
+		{[]string{"--type", "monthly", "--month", "1", "--year", "1999"}, exitUsage, ""},
+		{[]string{"--type", "monthly", "--month", "1", "--format", "pdf"}, exitUsage, ""},
+		{[]string{"--type", "monthly", "--month", "1", "--format", "json"}, exitUsage, ""},
+		{[]string{"--type", "weekly"}, exitUsage, ""},
+		{[]string{"--type", "custom"}, exitUsage, ""},
+		{[]string{"--type", "custom", "--from", "2026-03", "--to", "2026-01"}, exitUsage, ""},
+		{[]string{"--type", "compare", "--base-from", "2026-01"}, exitUsage, ""},
+		{[]string{"--month", "x"}, exitUsage, ""},
+		{[]string{"monthly"}, exitUsage, ""},
+		{[]string{"-h"}, exitOK, ""},
+	} {
+		out.Reset()
+		if code := runReportCommand(tt.args); code != tt.code {
+			t.Errorf("%v: exit code %d, want %d", tt.args, code, tt.code)
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("%v: output %q, want %q", tt.args, out.String(), tt.want)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "reports", "jan.txt")); err != nil || !strings.Contains(string(data), "Jan 2026: 80.00%") {
+		t.Errorf("report file %q, %v, want the monthly report", data, err)
+	}
+}
+
+func TestMeasureCommand(t *testing.T) {
+	out := setupCLITest(t)
+
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{nil, exitUsage},
+		{[]string{"remove"}, exitUsage},
+		{[]string{"add", "--kpi", "1", "--period", "2026-03"}, exitUsage},
+		{[]string{"add", "--kpi", "1", "--period", "March", "--value", "90"}, exitUsage},
+		{[]string{"add", "--kpi", "1", "--period", "2026-03", "--value", "90", "--inputs", "x"}, exitUsage},
+		{[]string{"add", "--kpi", "9", "--period", "2026-03", "--value", "90"}, exitError},
+		{[]string{"add", "--kpi", "1", "--period", "2026-03", "--value", "-5"}, exitError},
+		{[]string{"import"}, exitUsage},
+		{[]string{"import", "--file", "missing.csv"}, exitError},
+		{[]string{"import", "--file", "missing.csv", "--output", "xml"}, exitUsage},
+	} {
+		if code := runMeasureCommand(tt.args); code != tt.code {
+			t.Errorf("%v: exit code %d, want %d", tt.args, code, tt.code)
+		}
+	}
+	if len(measurements) != 1 {
+		t.Errorf("measurements %+v, want nothing saved by failed commands", measurements)
+	}
+
+	out.Reset()
+	if code := runMeasureCommand([]string{"add", "--kpi", "1", "--period", "2026-03", "--value", "90", "--notes", "late"}); code != exitOK {
+		t.Fatalf("exit code %d, want %d", code, exitOK)
+	}
+	var saved Measurement
+	if err := json.Unmarshal(out.Bytes(), &saved); err != nil || saved.KPIID != 1 || saved.MetricValue != 90 || saved.Notes != "late" {
+		t.Errorf("output %q, %v, want the saved measurement", out.String(), err)
+	}
+	if _, err := os.Stat(getExcelDBPath()); err != nil {
+		t.Errorf("database not saved: %v", err)
+	}
+}
+
+func TestExportAndBackupCommands(t *testing.T) {
+	out := setupCLITest(t)
+
+	for _, tt := range []struct {
+		run  func([]string) int
+		args []string
+		code int
+	}{
+		{runExportCommand, []string{"--format", "xlsx"}, exitUsage},
+		{runExportCommand, []string{"--format", "pdf"}, exitUsage},
+		{runExportCommand, []string{"--format", "xlsx", "--out", filepath.Join(t.TempDir(), "export.xlsx")}, exitOK},
+		{runBackupCommand, []string{"now"}, exitUsage},
+		{runBackupCommand, nil, exitOK},
+	} {
+		if code := tt.run(tt.args); code != tt.code {
+			t.Errorf("%v: exit code %d, want %d", tt.args, code, tt.code)
+		}
+	}
+	if !strings.HasPrefix(out.String(), filepath.Join(appSettings.DatabasePath, "excel_backups")) {
+		t.Errorf("output %q, want the backup path", out.String())
+	}
+
+	out.Reset()
+	if code := runExportCommand([]string{"--format", "json"}); code != exitOK {
+		t.Fatalf("exit code %d, want %d", code, exitOK)
+	}
+	var export struct {
+		Roles        []Role        `json:"roles"`
+		Measurements []Measurement `json:"measurements"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &export); err != nil || len(export.Roles) != 1 || len(export.Measurements) != 1 {
+		t.Errorf("export %+v, %v, want the test data", export, err)
+	}
+}
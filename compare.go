@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// PeriodRange represents an inclusive range of months
+type PeriodRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// KPIComparison holds the change of a single KPI between two period ranges
+type KPIComparison struct {
+	KPI                KPI     `json:"kpi"`
+	BaseValue          float64 `json:"base_value"`
+	CompareValue       float64 `json:"compare_value"`
+	ValueDelta         float64 `json:"value_delta"`
+	BaseAchievement    float64 `json:"base_achievement"`
+	CompareAchievement float64 `json:"compare_achievement"`
+	AchievementDelta   float64 `json:"achievement_delta"`
+	BaseMeasured       bool    `json:"base_measured"`
+	CompareMeasured    bool    `json:"compare_measured"`
+	Improved           bool    `json:"improved"`
+	Declined           bool    `json:"declined"`
+}
+
+// RoleComparison holds the change of a role's overall score between two period ranges
+type RoleComparison struct {
+	Role            Role            `json:"role"`
+	BaseScore       float64         `json:"base_score"`
+	CompareScore    float64         `json:"compare_score"`
+	BaseCoverage    float64         `json:"base_coverage"`
+	CompareCoverage float64         `json:"compare_coverage"`
+	BaseMeasured    bool            `json:"base_measured"`
+	CompareMeasured bool            `json:"compare_measured"`
+	ScoreDelta      float64         `json:"score_delta"`
+	Improved        bool            `json:"improved"`
+	Declined        bool            `json:"declined"`
+	KPIs            []KPIComparison `json:"kpis"`
+}
+
+// ComparisonReport represents a period-over-period comparison for all roles
+type ComparisonReport struct {
+	Base        PeriodRange      `json:"base"`
+	Compare     PeriodRange      `json:"compare"`
+	Roles       []RoleComparison `json:"roles"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// Label returns a human readable label for the period range
+func (p PeriodRange) Label() string {
+	if p.Start.Year() == p.End.Year() && p.Start.Month() == p.End.Month() {
+		return p.Start.Format("Jan 2006")
+	}
+	return fmt.Sprintf("%s - %s", p.Start.Format("Jan 2006"), p.End.Format("Jan 2006"))
+}
+
+// periodsInRange returns the first day of every month between start and end (inclusive)
+func periodsInRange(startPeriod, endPeriod time.Time) []time.Time {
+	var periods []time.Time
+	currentPeriod := time.Date(startPeriod.Year(), startPeriod.Month(), 1, 0, 0, 0, 0, time.Local)
+	for currentPeriod.Before(endPeriod) || currentPeriod.Equal(endPeriod) {
+		periods = append(periods, currentPeriod)
+		currentPeriod = currentPeriod.AddDate(0, 1, 0)
+	}
+	return periods
+}
+
+// fiscalYearStart returns the first month of the fiscal year, January when unset
+func fiscalYearStart() time.Month {
+	if appSettings.FiscalYearStartMonth < 1 || appSettings.FiscalYearStartMonth > 12 {
+		return time.January
+	}
+	return time.Month(appSettings.FiscalYearStartMonth)
+}
+
+// fiscalYearRange returns the twelve months of a fiscal year. A fiscal year is
+// named after the calendar year it starts in.
+func fiscalYearRange(year int) PeriodRange {
+	start := time.Date(year, fiscalYearStart(), 1, 0, 0, 0, 0, time.Local)
+	return PeriodRange{Start: start, End: start.AddDate(0, 11, 0)}
+}
+
+// fiscalQuarterRange returns the three months of a quarter (1-4) of a fiscal year
+func fiscalQuarterRange(year, quarter int) PeriodRange {
+	start := fiscalYearRange(year).Start.AddDate(0, (quarter-1)*3, 0)
+	return PeriodRange{Start: start, End: start.AddDate(0, 2, 0)}
+}
+
+// fiscalYearOf returns the fiscal year a period falls in
+func fiscalYearOf(period time.Time) int {
+	if period.Month() < fiscalYearStart() {
+		return period.Year() - 1
+	}
+	return period.Year()
+}
+
+// fiscalQuarterOf returns the fiscal quarter (1-4) a period falls in
+func fiscalQuarterOf(period time.Time) int {
+	offset := (int(period.Month()) - int(fiscalYearStart()) + 12) % 12
+	return offset/3 + 1
+}
+
+// parsePeriodString parses a period in "YYYY-MM" format
+func parsePeriodString(value string) (time.Time, error) {
+	period, err := time.ParseInLocation("2006-01", strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid period '%s', expected YYYY-MM", value)
+	}
+	if period.Year() < 2000 || period.Year() > 2100 {
+		return time.Time{}, fmt.Errorf("invalid period '%s', year must be between 2000 and 2100", value)
+	}
+	return period, nil
+}
+
+// lowerIsBetter reports whether a lower metric value is an improvement for the KPI
+func lowerIsBetter(kpi KPI) bool {
+	return kpi.Operator == "≤" || kpi.Operator == "<="
+}
+
+// averageKPIValue returns the average metric value and achievement of a KPI over a period range
+func averageKPIValue(kpi KPI, pr PeriodRange) (value, achievement float64, measured bool) {
+	return averageKPIValueFrom(kpi, pr, measurements)
+}
+
+// averageKPIValueFrom averages a KPI's value and achievement over a period range in the given measurements
+func averageKPIValueFrom(kpi KPI, pr PeriodRange, data []Measurement) (value, achievement float64, measured bool) {
+	var count int
+	for _, period := range periodsInRange(pr.Start, pr.End) {
+		measurement := findMeasurementIn(data, kpi.ID, period)
+		if measurement == nil {
+			continue
+		}
+		value += measurement.MetricValue
+		achievement += calculateAchievement(kpi, measurement)
+		count++
+	}
+
+	if count == 0 {
+		return 0, 0, false
+	}
+
+	return value / float64(count), achievement / float64(count), true
+}
+
+// averageRoleScore returns the average overall score of a role over the months with data
+func averageRoleScore(roleKPIs []KPI, pr PeriodRange) float64 {
+	return averageRoleScoreFrom(roleKPIs, pr, measurements)
+}
+
+// averageRoleScoreFrom averages the overall score over the months with data in the given measurements
+func averageRoleScoreFrom(roleKPIs []KPI, pr PeriodRange, data []Measurement) float64 {
+	var total float64
+	var monthsWithData int
+	for _, period := range periodsInRange(pr.Start, pr.End) {
+		result := calculateScoreResultFrom(roleKPIs, period, data)
+		if result.HasData() {
+			total += result.Score
+			monthsWithData++
+		}
+	}
+
+	if monthsWithData == 0 {
+		return 0
+	}
+
+	return total / float64(monthsWithData)
+}
+
+// roleHasData reports whether any month in the range has data for the role
+func roleHasData(roleKPIs []KPI, pr PeriodRange) bool {
+	for _, period := range periodsInRange(pr.Start, pr.End) {
+		if calculateScoreResult(roleKPIs, period).HasData() {
+			return true
+		}
+	}
+	return false
+}
+
+// averageRoleCoverage returns the average weight coverage of a role over every month in the range
+func averageRoleCoverage(roleKPIs []KPI, pr PeriodRange) float64 {
+	periods := periodsInRange(pr.Start, pr.End)
+	if len(periods) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, period := range periods {
+		total += calculateCoverage(roleKPIs, period)
+	}
+
+	return total / float64(len(periods))
+}
+
+// compareKPI compares a KPI between two period ranges
+func compareKPI(kpi KPI, base, compare PeriodRange) KPIComparison {
+	baseValue, baseAchievement, baseMeasured := averageKPIValue(kpi, base)
+	compareValue, compareAchievement, compareMeasured := averageKPIValue(kpi, compare)
+
+	result := KPIComparison{
+		KPI:                kpi,
+		BaseValue:          baseValue,
+		CompareValue:       compareValue,
+		BaseAchievement:    baseAchievement,
+		CompareAchievement: compareAchievement,
+		BaseMeasured:       baseMeasured,
+		CompareMeasured:    compareMeasured,
+	}
+
+	// Deltas are only meaningful when both ranges have data
+	if !baseMeasured || !compareMeasured {
+		return result
+	}
+
+	result.ValueDelta = compareValue - baseValue
+	result.AchievementDelta = compareAchievement - baseAchievement
+
+	// Use the operator to decide which direction is better (e.g. fewer days is better)
+	if lowerIsBetter(kpi) {
+		result.Improved = result.ValueDelta < 0
+		result.Declined = result.ValueDelta > 0
+	} else {
+		result.Improved = result.ValueDelta > 0
+		result.Declined = result.ValueDelta < 0
+	}
+
+	return result
+}
+
+// buildComparisonReport compares every role and KPI between two period ranges
+func buildComparisonReport(base, compare PeriodRange) ComparisonReport {
+	report := ComparisonReport{
+		Base:        base,
+		Compare:     compare,
+		Roles:       []RoleComparison{},
+		GeneratedAt: time.Now(),
+	}
+
+	for _, role := range roles {
+		roleKPIs := getKPIsByRoleID(role.ID)
+		if len(roleKPIs) == 0 {
+			continue
+		}
+
+		roleComparison := RoleComparison{
+			Role:            role,
+			BaseScore:       averageRoleScore(roleKPIs, base),
+			CompareScore:    averageRoleScore(roleKPIs, compare),
+			BaseCoverage:    averageRoleCoverage(roleKPIs, base),
+			CompareCoverage: averageRoleCoverage(roleKPIs, compare),
+			BaseMeasured:    roleHasData(roleKPIs, base),
+			CompareMeasured: roleHasData(roleKPIs, compare),
+			KPIs:            []KPIComparison{},
+		}
+
+		// A score of 0% is a real score, only a range without data has no delta
+		if roleComparison.BaseMeasured && roleComparison.CompareMeasured {
+			roleComparison.ScoreDelta = roleComparison.CompareScore - roleComparison.BaseScore
+			roleComparison.Improved = roleComparison.ScoreDelta > 0
+			roleComparison.Declined = roleComparison.ScoreDelta < 0
+		}
+
+		for _, kpi := range roleKPIs {
+			roleComparison.KPIs = append(roleComparison.KPIs, compareKPI(kpi, base, compare))
+		}
+
+		report.Roles = append(report.Roles, roleComparison)
+	}
+
+	return report
+}
+
+// comparisonTrend returns a short label describing the direction of a change
+func comparisonTrend(improved, declined bool) string {
+	switch {
+	case improved:
+		return "Improved"
+	case declined:
+		return "Declined"
+	default:
+		return "No change"
+	}
+}
+
+// formatComparisonValue formats a value or "-" if it was not measured
+func formatComparisonValue(value float64, measured bool) string {
+	if !measured {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// generateComparisonReport generates a comparison report in the given format
+func generateComparisonReport(base, compare PeriodRange, format string) string {
+	report := buildComparisonReport(base, compare)
+
+	switch format {
+	case "txt":
+		return generateTextComparisonReport(report)
+	case "csv":
+		return generateCSVComparisonReport(report)
+	case "html":
+		return generateHTMLComparisonReport(report)
+	default:
+		return "Unsupported format"
+	}
+}
+
+// generateTextComparisonReport generates a text comparison report
+func generateTextComparisonReport(report ComparisonReport) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("KPI COMPARISON REPORT: %s vs %s\n",
+		report.Compare.Label(), report.Base.Label()))
+	sb.WriteString("==========================================\n\n")
+	sb.WriteString(fmt.Sprintf("Generated: %s\n\n", report.GeneratedAt.Format("2006-01-02 15:04:05")))
+
+	for _, rc := range report.Roles {
+		sb.WriteString(fmt.Sprintf("ROLE: %s\n", rc.Role.Name))
+		sb.WriteString("----------------------------------------\n")
+		sb.WriteString(fmt.Sprintf("Overall score: %.2f%% -> %.2f%% (%+.2f, %s)\n",
+			rc.BaseScore, rc.CompareScore, rc.ScoreDelta, comparisonTrend(rc.Improved, rc.Declined)))
+		sb.WriteString(fmt.Sprintf("Coverage: %.0f%% -> %.0f%%\n\n", rc.BaseCoverage, rc.CompareCoverage))
+
+		for _, kc := range rc.KPIs {
+			sb.WriteString(fmt.Sprintf("KPI: %s\n", kc.KPI.Name))
+			if !kc.BaseMeasured || !kc.CompareMeasured {
+				sb.WriteString(fmt.Sprintf("  Value: %s -> %s (insufficient data)\n\n",
+					formatComparisonValue(kc.BaseValue, kc.BaseMeasured),
+					formatComparisonValue(kc.CompareValue, kc.CompareMeasured)))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("  Value: %.2f -> %.2f %s (%+.2f)\n",
+				kc.BaseValue, kc.CompareValue, kc.KPI.Unit, kc.ValueDelta))
+			sb.WriteString(fmt.Sprintf("  Achievement: %.2f%% -> %.2f%% (%+.2f)\n",
+				kc.BaseAchievement, kc.CompareAchievement, kc.AchievementDelta))
+			sb.WriteString(fmt.Sprintf("  Trend: %s\n\n", comparisonTrend(kc.Improved, kc.Declined)))
+		}
+	}
+
+	return sb.String()
+}
+
+// generateCSVComparisonReport generates a CSV comparison report
+func generateCSVComparisonReport(report ComparisonReport) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Role,KPI,Unit,Operator,Base Value (%s),Compare Value (%s),Value Delta,"+
+		"Base Achievement,Compare Achievement,Achievement Delta,Trend\n",
+		report.Base.Label(), report.Compare.Label()))
+
+	for _, rc := range report.Roles {
+		for _, kc := range rc.KPIs {
+			sb.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,%s,",
+				csvField(rc.Role.Name), csvField(kc.KPI.Name), kc.KPI.Unit, kc.KPI.Operator,
+				formatComparisonValue(kc.BaseValue, kc.BaseMeasured),
+				formatComparisonValue(kc.CompareValue, kc.CompareMeasured)))
+
+			if kc.BaseMeasured && kc.CompareMeasured {
+				sb.WriteString(fmt.Sprintf("%.2f,%.2f%%,%.2f%%,%.2f,%s\n",
+					kc.ValueDelta, kc.BaseAchievement, kc.CompareAchievement,
+					kc.AchievementDelta, comparisonTrend(kc.Improved, kc.Declined)))
+			} else {
+				sb.WriteString(",,,,Insufficient data\n")
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("%s,OVERALL SCORE,%%,,%.2f%%,%.2f%%,%.2f,,,,%s\n",
+			csvField(rc.Role.Name), rc.BaseScore, rc.CompareScore, rc.ScoreDelta,
+			comparisonTrend(rc.Improved, rc.Declined)))
+		sb.WriteString(fmt.Sprintf("%s,COVERAGE,%%,,%.0f%%,%.0f%%,,,,,\n",
+			csvField(rc.Role.Name), rc.BaseCoverage, rc.CompareCoverage))
+	}
+
+	return sb.String()
+}
+
+// generateHTMLComparisonReport generates an HTML comparison report
+func generateHTMLComparisonReport(report ComparisonReport) string {
+	var sb strings.Builder
+
+	sb.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>KPI Comparison Report</title>
+  <style>
+    body { font-family: Arial, sans-serif; margin: 20px; }
+    h1, h2, h3 { color: #333; }
+    table { border-collapse: collapse; width: 100%; margin-bottom: 20px; }
+    th, td { border: 1px solid #ddd; padding: 8px; text-align: left; }
+    th { background-color: #f2f2f2; }
+    tr:nth-child(even) { background-color: #f9f9f9; }
+    .good { color: green; }
+    .bad { color: red; }
+  </style>
+</head>
+<body>
+  <h1>KPI Comparison Report</h1>
+`)
+	sb.WriteString(fmt.Sprintf("  <p>Comparing %s against %s</p>\n",
+		html.EscapeString(report.Compare.Label()), html.EscapeString(report.Base.Label())))
+	sb.WriteString(fmt.Sprintf("  <p>Generated: %s</p>\n", report.GeneratedAt.Format("2006-01-02 15:04:05")))
+
+	for _, rc := range report.Roles {
+		sb.WriteString(fmt.Sprintf("<h2>%s</h2>", html.EscapeString(rc.Role.Name)))
+		sb.WriteString(fmt.Sprintf("<p>Overall score: %.2f%% &rarr; <span class=\"%s\">%.2f%% (%+.2f)</span></p>",
+			rc.BaseScore, comparisonClass(rc.Improved, rc.Declined), rc.CompareScore, rc.ScoreDelta))
+		sb.WriteString(fmt.Sprintf("<p>Coverage: %.0f%% &rarr; %.0f%%</p>", rc.BaseCoverage, rc.CompareCoverage))
+
+		sb.WriteString("<table><tr><th>KPI</th><th>Target</th>")
+		sb.WriteString(fmt.Sprintf("<th>%s</th><th>%s</th>",
+			html.EscapeString(report.Base.Label()), html.EscapeString(report.Compare.Label())))
+		sb.WriteString("<th>Delta</th><th>Achievement Delta</th><th>Trend</th></tr>")
+
+		for _, kc := range rc.KPIs {
+			sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td>",
+				html.EscapeString(kc.KPI.Name), html.EscapeString(kc.KPI.Target),
+				formatComparisonValue(kc.BaseValue, kc.BaseMeasured),
+				formatComparisonValue(kc.CompareValue, kc.CompareMeasured)))
+
+			if kc.BaseMeasured && kc.CompareMeasured {
+				sb.WriteString(fmt.Sprintf("<td>%+.2f</td><td>%+.2f%%</td><td class=\"%s\">%s</td></tr>",
+					kc.ValueDelta, kc.AchievementDelta, comparisonClass(kc.Improved, kc.Declined),
+					comparisonTrend(kc.Improved, kc.Declined)))
+			} else {
+				sb.WriteString("<td>-</td><td>-</td><td>Insufficient data</td></tr>")
+			}
+		}
+
+		sb.WriteString("</table>")
+	}
+
+	sb.WriteString(`</body>
+</html>`)
+
+	return sb.String()
+}
+
+// comparisonClass returns the CSS class used to colour a change
+func comparisonClass(improved, declined bool) string {
+	switch {
+	case improved:
+		return "good"
+	case declined:
+		return "bad"
+	default:
+		return ""
+	}
+}
+
+// csvField quotes a value for CSV output when needed
+func csvField(value string) string {
+	if strings.ContainsAny(value, ",\"\n") {
+		return "\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
+	}
+	return value
+}
+
+// selectPeriodRange asks the user for a start and end period
+func selectPeriodRange(scanner *bufio.Scanner, label string) (PeriodRange, bool) {
+	fmt.Printf("\nSelect %s start period:\n", label)
+	startPeriod := selectPeriod(scanner)
+	if startPeriod.IsZero() {
+		return PeriodRange{}, false
+	}
+
+	fmt.Printf("Select %s end period:\n", label)
+	endPeriod := selectPeriod(scanner)
+	if endPeriod.IsZero() {
+		return PeriodRange{}, false
+	}
+
+	if endPeriod.Before(startPeriod) {
+		fmt.Println("End period cannot be before start period.")
+		return PeriodRange{}, false
+	}
+
+	return PeriodRange{Start: startPeriod, End: endPeriod}, true
+}
+
+// generateComparisonReportMenu generates a comparison report from the CLI
+func generateComparisonReportMenu(scanner *bufio.Scanner) {
+	fmt.Println("\n=== Comparison Report ===")
+
+	base, ok := selectPeriodRange(scanner, "baseline")
+	if !ok {
+		return
+	}
+
+	compare, ok := selectPeriodRange(scanner, "comparison")
+	if !ok {
+		return
+	}
+
+	format := selectReportFormat(scanner)
+	if format == "" {
+		return
+	}
+
+	fmt.Printf("\nGenerating comparison report %s vs %s...\n", compare.Label(), base.Label())
+
+	report := generateComparisonReport(base, compare, format)
+
+	saveReport(report, fmt.Sprintf("Comparison_Report_%s-%s_vs_%s-%s.%s",
+		compare.Start.Format("Jan2006"), compare.End.Format("Jan2006"),
+		base.Start.Format("Jan2006"), base.End.Format("Jan2006"), format))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareKPI(t *testing.T) {
+	setupSubmissionTest(t)
+	revenue := *getKPIByID(1)
+	leadTime := KPI{ID: 3, RoleID: 1, Name: "Lead time", Unit: "days", Operator: "≤", TargetValue: 5, Weight: 10}
+	base := PeriodRange{Start: month(1), End: month(3)}
+	compare := PeriodRange{Start: month(4), End: month(6)}
+
+	for _, tt := range []struct {
+		name              string
+		kpi               KPI
+		base, compare     []float64
+		delta             float64
+		improved, decline bool
+		measured          bool
+	}{
+		{"higher is better", revenue, []float64{80, 100}, []float64{120}, 30, true, false, true},
+		{"higher drops", revenue, []float64{90}, []float64{60, 90}, -15, false, true, true},
+		{"no change", revenue, []float64{100}, []float64{100}, 0, false, false, true},
+		{"lower is better", leadTime, []float64{6}, []float64{4}, -2, true, false, true},
+		{"lower rises", leadTime, []float64{4}, []float64{7}, 3, false, true, true},
+		{"compare not measured", revenue, []float64{80}, nil, 0, false, false, false},
+		{"base not measured", leadTime, nil, []float64{3}, 0, false, false, false},
+	} {
+		measurements = nil
+		for i, value := range tt.base {
+			measurements = append(measurements, Measurement{ID: len(measurements) + 1, KPIID: tt.kpi.ID, MetricValue: value, Period: base.Start.AddDate(0, i, 0)})
+		}
+		for i, value := range tt.compare {
+			measurements = append(measurements, Measurement{ID: len(measurements) + 1, KPIID: tt.kpi.ID, MetricValue: value, Period: compare.Start.AddDate(0, i, 0)})
+		}
+
+		result := compareKPI(tt.kpi, base, compare)
+		if result.ValueDelta != tt.delta || result.Improved != tt.improved || result.Declined != tt.decline {
+			t.Errorf("%s: delta %v improved %v declined %v, want %v %v %v",
+				tt.name, result.ValueDelta, result.Improved, result.Declined, tt.delta, tt.improved, tt.decline)
+		}
+		if measured := result.BaseMeasured && result.CompareMeasured; measured != tt.measured {
+			t.Errorf("%s: measured %v, want %v", tt.name, measured, tt.measured)
+		}
+	}
+}
+
+func TestBuildComparisonReport(t *testing.T) {
+	setupSubmissionTest(t)
+	roles = append(roles, Role{ID: 2, Name: "Support"})
+	kpis = kpis[:1]
+	measurements = nil
+	addRevenue(80, month(1))
+	addRevenue(0, month(4))
+
+	report := buildComparisonReport(PeriodRange{Start: month(1), End: month(1)}, PeriodRange{Start: month(4), End: month(4)})
+	if len(report.Roles) != 1 {
+		t.Fatalf("roles %+v, want only Sales, Support has no KPIs", report.Roles)
+	}
+
+	// A score of 0% is a real score and counts as a decline
+	sales := report.Roles[0]
+	if !sales.BaseMeasured || !sales.CompareMeasured || sales.ScoreDelta != -80 || !sales.Declined {
+		t.Errorf("sales %+v, want a decline of 80", sales)
+	}
+	if len(sales.KPIs) != 1 || sales.KPIs[0].AchievementDelta != -80 {
+		t.Errorf("KPIs %+v, want an achievement delta of -80", sales.KPIs)
+	}
+
+	// A range without data has no delta
+	report = buildComparisonReport(PeriodRange{Start: month(1), End: month(1)}, PeriodRange{Start: month(6), End: month(6)})
+	sales = report.Roles[0]
+	if sales.CompareMeasured || sales.ScoreDelta != 0 || sales.Improved || sales.Declined {
+		t.Errorf("sales %+v, want no delta without data", sales)
+	}
+}
+
+func TestGenerateComparisonReport(t *testing.T) {
+	setupSubmissionTest(t)
+	roles[0].Name = "Sales, North"
+	addRevenue(80, month(1))
+	addRevenue(100, month(4))
+	base := PeriodRange{Start: month(1), End: month(1)}
+	compare := PeriodRange{Start: month(4), End: month(5)}
+
+	for _, tt := range []struct {
+		format string
+		want   []string
+	}{
+		{"txt", []string{"KPI COMPARISON REPORT: Apr 2026 - May 2026 vs Jan 2026", "Value: 80.00 -> 100.00  (+20.00)", "Trend: Improved", "Value: - -> - (insufficient data)"}},
+		{"csv", []string{"Base Value (Jan 2026)", `"Sales, North",Revenue,,≥,80.00,100.00,20.00`, `"Sales, North",Audit,,≥,-,-,,,,,Insufficient data`}},
+		{"html", []string{"<h2>Sales, North</h2>", "Comparing Apr 2026 - May 2026 against Jan 2026"}},
+		{"pdf", []string{"Unsupported format"}},
+	} {
+		content := generateComparisonReport(base, compare, tt.format)
+		for _, want := range tt.want {
+			if !strings.Contains(content, want) {
+				t.Errorf("%s report missing %q:\n%s", tt.format, want, content)
+			}
+		}
+	}
+}
+
+func TestComparisonReportAPI(t *testing.T) {
+	setupSubmissionTest(t)
+	for query, want := range map[string]int{
+		"base_start=2026-01&compare_start=2026-04":                             http.StatusOK,
+		"base_start=2026-01&base_end=2026-03&compare_start=2026-04&format=csv": http.StatusOK,
+		"base_start=2026-03&base_end=2026-01&compare_start=2026-04":            http.StatusBadRequest,
+		"base_start=2026-01":                       http.StatusBadRequest,
+		"base_start=2026-13&compare_start=2026-04": http.StatusBadRequest,
+		"base_start=2026-01&compare_start=2026-04&compare_end=1999-01&format=html": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		getComparisonReport(rec, httptest.NewRequest("GET", "/api/reports/compare?"+query, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status %d, want %d: %s", query, rec.Code, want, rec.Body)
+		}
+	}
+}
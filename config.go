@@ -0,0 +1,353 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration sources, from lowest to highest precedence
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// Storage backends
+const storageExcel = "excel"
+
+// ServerSettings holds the REST API server settings
+type ServerSettings struct {
+	Addr            string `json:"addr"`
+	TLSCertFile     string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile      string `json:"tls_key_file,omitempty"`
+	ReadTimeout     string `json:"read_timeout"`     // Go duration, e.g. "15s"
+	WriteTimeout    string `json:"write_timeout"`    // Go duration, e.g. "30s"
+	ShutdownTimeout string `json:"shutdown_timeout"` // Go duration, e.g. "15s"
+}
+
+// configOption is a setting that can be overridden from the environment or a flag
+type configOption struct {
+	Name        string // Flag name, also used as the key in configSources
+	Env         string
+	Description string
+	get         func(s *Settings) string
+	set         func(s *Settings, value string) error
+}
+
+// configOptions lists every setting that can be layered. Precedence is
+// flags > environment (KPI_*) > settings file > defaults.
+var configOptions = []configOption{
+	{
+		Name: "data-dir", Env: "KPI_DATA_DIR", Description: "directory of the Excel database, reports and backups",
+		get: func(s *Settings) string { return s.DatabasePath },
+		set: func(s *Settings, v string) error { s.DatabasePath = v; return nil },
+	},
+	{
+		Name: "addr", Env: "KPI_ADDR", Description: "REST API listen address",
+		get: func(s *Settings) string { return s.Server.Addr },
+		set: func(s *Settings, v string) error { s.Server.Addr = v; return nil },
+	},
+	{
+		Name: "tls-cert", Env: "KPI_TLS_CERT", Description: "TLS certificate file (enables HTTPS with --tls-key)",
+		get: func(s *Settings) string { return s.Server.TLSCertFile },
+		set: func(s *Settings, v string) error { s.Server.TLSCertFile = v; return nil },
+	},
+	{
+		Name: "tls-key", Env: "KPI_TLS_KEY", Description: "TLS private key file",
+		get: func(s *Settings) string { return s.Server.TLSKeyFile },
+		set: func(s *Settings, v string) error { s.Server.TLSKeyFile = v; return nil },
+	},
+	{
+		Name: "read-timeout", Env: "KPI_READ_TIMEOUT", Description: "maximum duration for reading a request",
+		get: func(s *Settings) string { return s.Server.ReadTimeout },
+		set: func(s *Settings, v string) error { s.Server.ReadTimeout = v; return nil },
+	},
+	{
+		Name: "write-timeout", Env: "KPI_WRITE_TIMEOUT", Description: "maximum duration for writing a response",
+		get: func(s *Settings) string { return s.Server.WriteTimeout },
+		set: func(s *Settings, v string) error { s.Server.WriteTimeout = v; return nil },
+	},
+	{
+		Name: "shutdown-timeout", Env: "KPI_SHUTDOWN_TIMEOUT", Description: "time allowed to drain requests on shutdown",
+		get: func(s *Settings) string { return s.Server.ShutdownTimeout },
+		set: func(s *Settings, v string) error { s.Server.ShutdownTimeout = v; return nil },
+	},
+	{
+		Name: "cors-origins", Env: "KPI_CORS_ORIGINS", Description: "comma-separated allowed CORS origins",
+		get: func(s *Settings) string { return strings.Join(s.CORSOrigins, ",") },
+		set: func(s *Settings, v string) error {
+			s.CORSOrigins = nil
+			for _, origin := range strings.Split(v, ",") {
+				if origin = strings.TrimSpace(origin); origin != "" {
+					s.CORSOrigins = append(s.CORSOrigins, origin)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name: "storage", Env: "KPI_STORAGE", Description: "storage backend (excel)",
+		get: func(s *Settings) string { return s.StorageBackend },
+		set: func(s *Settings, v string) error { s.StorageBackend = v; return nil },
+	},
+	{
+		Name: "backup-retention", Env: "KPI_BACKUP_RETENTION", Description: "number of Excel backups to keep (0 keeps all)",
+		get: func(s *Settings) string { return strconv.Itoa(s.BackupRetention) },
+		set: func(s *Settings, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("backup retention must be a number")
+			}
+			s.BackupRetention = n
+			return nil
+		},
+	},
+	{
+		Name: "fiscal-year-start", Env: "KPI_FISCAL_YEAR_START", Description: "first month of the fiscal year (1-12)",
+		get: func(s *Settings) string { return strconv.Itoa(s.FiscalYearStartMonth) },
+		set: func(s *Settings, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("fiscal year start must be a month number")
+			}
+			s.FiscalYearStartMonth = n
+			return nil
+		},
+	},
+	{
+		Name: "scoring-policy", Env: "KPI_SCORING_POLICY", Description: "missing-data scoring policy",
+		get: func(s *Settings) string { return s.ScoringPolicy },
+		set: func(s *Settings, v string) error { s.ScoringPolicy = v; return nil },
+	},
+	{
+		Name: "submission-deadline", Env: "KPI_SUBMISSION_DEADLINE", Description: "day of the following month by which measurements are due (1-28)",
+		get: func(s *Settings) string { return strconv.Itoa(s.SubmissionDeadlineDay) },
+		set: func(s *Settings, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("submission deadline must be a day number")
+			}
+			s.SubmissionDeadlineDay = n
+			return nil
+		},
+	},
+	{
+		Name: "smtp-host", Env: "KPI_SMTP_HOST", Description: "SMTP server for email notifications",
+		get: func(s *Settings) string { return s.SMTP.Host },
+		set: func(s *Settings, v string) error { s.SMTP.Host = v; return nil },
+	},
+	{
+		Name: "smtp-port", Env: "KPI_SMTP_PORT", Description: "SMTP server port",
+		get: func(s *Settings) string { return strconv.Itoa(s.SMTP.Port) },
+		set: func(s *Settings, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("SMTP port must be a number")
+			}
+			s.SMTP.Port = n
+			return nil
+		},
+	},
+	{
+		Name: "smtp-username", Env: "KPI_SMTP_USERNAME", Description: "SMTP user name (empty for no authentication)",
+		get: func(s *Settings) string { return s.SMTP.Username },
+		set: func(s *Settings, v string) error { s.SMTP.Username = v; return nil },
+	},
+	{
+		Name: "smtp-password", Env: "KPI_SMTP_PASSWORD", Description: "SMTP password",
+		get: func(s *Settings) string { return s.SMTP.Password },
+		set: func(s *Settings, v string) error { s.SMTP.Password = v; return nil },
+	},
+	{
+		Name: "smtp-from", Env: "KPI_SMTP_FROM", Description: "sender address of email notifications",
+		get: func(s *Settings) string { return s.SMTP.From },
+		set: func(s *Settings, v string) error { s.SMTP.From = v; return nil },
+	},
+}
+
+// configFlags holds the values of configuration flags given on the command line
+var configFlags = make(map[string]string)
+
+// configSources records where the effective value of each option came from
+var configSources = make(map[string]string)
+
+// defaultSettings returns the settings used when nothing else is configured
+func defaultSettings() Settings {
+	return Settings{
+		DatabasePath: dbDir,
+		Server: ServerSettings{
+			Addr:            ":8080",
+			ReadTimeout:     "15s",
+			WriteTimeout:    "30s",
+			ShutdownTimeout: "15s",
+		},
+		CORSOrigins:           []string{"*"},
+		StorageBackend:        storageExcel,
+		FiscalYearStartMonth:  1,
+		ScoringPolicy:         PolicyExclude,
+		SubmissionDeadlineDay: 5,
+		SMTP:                  SMTPSettings{Port: 25},
+		ReportDistribution:    ReportDistributionSettings{Attachments: []string{"csv", "xlsx"}},
+		Anomalies:             AnomalySettings{Method: AnomalyZScore, ZScoreThreshold: 3, IQRMultiplier: 1.5, MinHistory: 4},
+	}
+}
+
+// registerConfigFlags adds a flag for every config option to a flag set
+func registerConfigFlags(fs *flag.FlagSet) {
+	fs.StringVar(&settingsPath, "config", settingsPath, "settings file (env KPI_CONFIG)")
+	for _, option := range configOptions {
+		name := option.Name
+		fs.Func(name, fmt.Sprintf("%s (env %s)", option.Description, option.Env), func(value string) error {
+			configFlags[name] = value
+			return nil
+		})
+	}
+}
+
+// applyConfigLayers applies the environment and flag overrides on top of the settings
+// loaded from the file and records the source of every option
+func applyConfigLayers(s *Settings) error {
+	defaults := defaultSettings()
+	for _, option := range configOptions {
+		source := sourceDefault
+		if option.get(s) != option.get(&defaults) {
+			source = sourceFile
+		}
+
+		if value, ok := os.LookupEnv(option.Env); ok && value != "" {
+			if err := option.set(s, value); err != nil {
+				return fmt.Errorf("%s: %v", option.Env, err)
+			}
+			source = sourceEnv
+		}
+
+		if value, ok := configFlags[option.Name]; ok {
+			if err := option.set(s, value); err != nil {
+				return fmt.Errorf("--%s: %v", option.Name, err)
+			}
+			source = sourceFlag
+		}
+
+		configSources[option.Name] = source
+	}
+	return nil
+}
+
+// isOverridden reports whether an option's effective value comes from the environment or a flag
+func isOverridden(name string) bool {
+	source := configSources[name]
+	return source == sourceEnv || source == sourceFlag
+}
+
+// validateSettings checks the effective settings and returns the first problem found
+func validateSettings(s Settings) error {
+	if strings.TrimSpace(s.DatabasePath) == "" {
+		return fmt.Errorf("data directory cannot be empty")
+	}
+
+	if strings.TrimSpace(s.Server.Addr) == "" {
+		return fmt.Errorf("listen address cannot be empty")
+	}
+	if !strings.Contains(s.Server.Addr, ":") {
+		return fmt.Errorf("listen address '%s' must be host:port or :port", s.Server.Addr)
+	}
+
+	if (s.Server.TLSCertFile == "") != (s.Server.TLSKeyFile == "") {
+		return fmt.Errorf("TLS needs both a certificate and a key file")
+	}
+	for _, path := range []string{s.Server.TLSCertFile, s.Server.TLSKeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("TLS file '%s' not found", path)
+		}
+	}
+
+	timeouts := []struct {
+		name  string
+		value string
+	}{
+		{"read timeout", s.Server.ReadTimeout},
+		{"write timeout", s.Server.WriteTimeout},
+		{"shutdown timeout", s.Server.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		d, err := time.ParseDuration(timeout.value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%s '%s' must be a positive duration such as 30s", timeout.name, timeout.value)
+		}
+	}
+
+	if len(s.CORSOrigins) == 0 {
+		return fmt.Errorf("at least one CORS origin is required, use * to allow all")
+	}
+	for _, origin := range s.CORSOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("CORS origin '%s' must start with http:// or https://", origin)
+		}
+	}
+
+	if s.StorageBackend != storageExcel {
+		return fmt.Errorf("unsupported storage backend '%s', available: %s", s.StorageBackend, storageExcel)
+	}
+
+	if s.BackupRetention < 0 {
+		return fmt.Errorf("backup retention cannot be negative")
+	}
+
+	if s.FiscalYearStartMonth < 1 || s.FiscalYearStartMonth > 12 {
+		return fmt.Errorf("fiscal year start month must be between 1 and 12")
+	}
+
+	if !isValidScoringPolicy(s.ScoringPolicy) {
+		return fmt.Errorf("invalid scoring policy '%s'", s.ScoringPolicy)
+	}
+
+	if s.SubmissionDeadlineDay < 1 || s.SubmissionDeadlineDay > 28 {
+		return fmt.Errorf("submission deadline day must be between 1 and 28")
+	}
+
+	if err := validateNotificationSettings(s); err != nil {
+		return err
+	}
+	if err := validateAnomalySettings(s.Anomalies); err != nil {
+		return err
+	}
+	return validateJobConfigs(s.Jobs)
+}
+
+// serverConfigFromSettings converts the server settings into a ServerConfig.
+// The settings are validated on startup so the durations always parse.
+func serverConfigFromSettings() ServerConfig {
+	config := defaultServerConfig()
+	config.Addr = appSettings.Server.Addr
+	config.TLSCertFile = appSettings.Server.TLSCertFile
+	config.TLSKeyFile = appSettings.Server.TLSKeyFile
+
+	if d, err := time.ParseDuration(appSettings.Server.ReadTimeout); err == nil {
+		config.ReadTimeout = d
+	}
+	if d, err := time.ParseDuration(appSettings.Server.WriteTimeout); err == nil {
+		config.WriteTimeout = d
+	}
+	if d, err := time.ParseDuration(appSettings.Server.ShutdownTimeout); err == nil {
+		config.ShutdownTimeout = d
+	}
+
+	return config
+}
+
+// getConfigSources returns a copy of the option sources
+func getConfigSources() map[string]string {
+	sources := make(map[string]string, len(configSources))
+	for name, source := range configSources {
+		sources[name] = source
+	}
+	return sources
+}
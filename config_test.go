@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupSettingsTest starts from the default settings and writes them to a temporary file
+func setupSettingsTest(t *testing.T) {
+	previousSettings, previousPath := appSettings, settingsPath
+	t.Cleanup(func() {
+		appSettings, settingsPath = previousSettings, previousPath
+	})
+	dir := t.TempDir()
+	appSettings = defaultSettings()
+	appSettings.DatabasePath = dir
+	settingsPath = filepath.Join(dir, settingsFile)
+}
+
+func TestUpdateSettingsKeepsOmittedFields(t *testing.T) {
+	setupSettingsTest(t)
+	appSettings.ScoringPolicy = PolicyZero
+	appSettings.RoleScoringPolicies = map[int]string{1: PolicyExclude}
+	appSettings.RatingBands = []RatingBand{{Name: "Meets", MinScore: 0}, {Name: "Exceeds", MinScore: 90}}
+	appSettings.RoleRatingBands = map[int][]RatingBand{1: {{Name: "All", MinScore: 0}}}
+	appSettings.Appraisal = AppraisalConfig{Mode: "quarterly", Weights: []float64{1, 1, 1, 2}}
+	appSettings.BonusPolicy = &BonusPolicy{Name: "Standard", TargetBonusPercent: 10, Bands: []BonusBand{{MinScore: 0, Multiplier: 1}}}
+	appSettings.BackupRetention = 7
+	before := appSettings
+
+	rec := httptest.NewRecorder()
+	updateSettings(rec, httptest.NewRequest("PUT", "/api/settings", strings.NewReader(`{"submission_deadline_day": 10}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if appSettings.SubmissionDeadlineDay != 10 {
+		t.Errorf("submission deadline day %d, want 10", appSettings.SubmissionDeadlineDay)
+	}
+	after := appSettings
+	after.SubmissionDeadlineDay = before.SubmissionDeadlineDay
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("settings changed:\n%+v\nwant\n%+v", after, before)
+	}
+
+	// A field in the request replaces the current value, also with an empty one
+	rec = httptest.NewRecorder()
+	updateSettings(rec, httptest.NewRequest("PUT", "/api/settings", strings.NewReader(`{"rating_bands": [], "backup_retention": 0}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if len(appSettings.RatingBands) != 0 || appSettings.BackupRetention != 0 {
+		t.Errorf("rating bands %v, backup retention %d, want both cleared", appSettings.RatingBands, appSettings.BackupRetention)
+	}
+	if appSettings.BonusPolicy == nil || len(appSettings.RoleScoringPolicies) != 1 {
+		t.Error("fields left out of the second request changed")
+	}
+}
+
+func TestUpdateSettingsRejectedLeavesSettings(t *testing.T) {
+	setupSettingsTest(t)
+	appSettings.RoleScoringPolicies = map[int]string{1: PolicyExclude}
+	appSettings.RatingBands = []RatingBand{{Name: "Meets", MinScore: 0}}
+
+	rec := httptest.NewRecorder()
+	body := `{"role_scoring_policies": {"2": "bogus"}, "rating_bands": [{"name": "Other", "min_score": 0}]}`
+	updateSettings(rec, httptest.NewRequest("PUT", "/api/settings", strings.NewReader(body)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", rec.Code)
+	}
+	if len(appSettings.RoleScoringPolicies) != 1 || appSettings.RatingBands[0].Name != "Meets" {
+		t.Errorf("rejected request changed the settings: %v %v", appSettings.RoleScoringPolicies, appSettings.RatingBands)
+	}
+}
+
+// setupConfigLayers isolates the flag overrides and recorded sources, and writes
+// a settings file with the given JSON unless it is empty
+func setupConfigLayers(t *testing.T, file string) {
+	setupSettingsTest(t)
+	previousFlags, previousSources, previousFile := configFlags, configSources, fileSettings
+	t.Cleanup(func() {
+		configFlags, configSources, fileSettings = previousFlags, previousSources, previousFile
+	})
+	configFlags = make(map[string]string)
+	configSources = make(map[string]string)
+	for _, option := range configOptions {
+		t.Setenv(option.Env, "")
+	}
+	if file != "" {
+		if err := os.WriteFile(settingsPath, []byte(file), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConfigLayerPrecedence(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		file   string
+		env    string
+		flag   string
+		want   string
+		source string
+	}{
+		{"default", "", "", "", ":8080", sourceDefault},
+		{"file", `{"server": {"addr": ":8081"}}`, "", "", ":8081", sourceFile},
+		{"env over file", `{"server": {"addr": ":8081"}}`, ":8082", "", ":8082", sourceEnv},
+		{"flag over env", `{"server": {"addr": ":8081"}}`, ":8082", ":8083", ":8083", sourceFlag},
+		{"flag over default", "", "", ":8083", ":8083", sourceFlag},
+		{"file equal to the default", `{"server": {"addr": ":8080"}}`, "", "", ":8080", sourceDefault},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfigLayers(t, tt.file)
+			dataDir := filepath.Dir(settingsPath)
+			t.Setenv("KPI_DATA_DIR", dataDir)
+			t.Setenv("KPI_ADDR", tt.env)
+			if tt.flag != "" {
+				configFlags["addr"] = tt.flag
+			}
+
+			if err := loadSettings(); err != nil {
+				t.Fatal(err)
+			}
+			if appSettings.Server.Addr != tt.want || configSources["addr"] != tt.source {
+				t.Errorf("addr %q from %s, want %q from %s", appSettings.Server.Addr, configSources["addr"], tt.want, tt.source)
+			}
+			if appSettings.DatabasePath != dataDir || configSources["data-dir"] != sourceEnv {
+				t.Errorf("data dir %q from %s, want %q from the environment", appSettings.DatabasePath, configSources["data-dir"], dataDir)
+			}
+			if isOverridden("addr") != (tt.source == sourceEnv || tt.source == sourceFlag) {
+				t.Errorf("overridden %v with source %s", isOverridden("addr"), tt.source)
+			}
+		})
+	}
+}
+
+func TestConfigLayerErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		file string
+		env  map[string]string
+		flag map[string]string
+		want string
+	}{
+		{"invalid file", `{"server": `, nil, nil, "invalid settings file"},
+		{"env not a number", "", map[string]string{"KPI_BACKUP_RETENTION": "many"}, nil, "KPI_BACKUP_RETENTION"},
+		{"flag not a number", "", nil, map[string]string{"fiscal-year-start": "April"}, "--fiscal-year-start"},
+		{"invalid value from env", "", map[string]string{"KPI_STORAGE": "postgres"}, nil, "unsupported storage backend"},
+		{"invalid value from flag", "", nil, map[string]string{"addr": "8080"}, "must be host:port"},
+		{"invalid value from file", `{"fiscal_year_start_month": 13}`, nil, nil, "fiscal year start month"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfigLayers(t, tt.file)
+			t.Setenv("KPI_DATA_DIR", filepath.Dir(settingsPath))
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			for name, value := range tt.flag {
+				configFlags[name] = value
+			}
+			before := appSettings
+
+			err := loadSettings()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %q", err, tt.want)
+			}
+			if !reflect.DeepEqual(appSettings, before) {
+				t.Error("invalid configuration changed the settings")
+			}
+		})
+	}
+}
+
+func TestSaveSettingsKeepsOverridesOutOfFile(t *testing.T) {
+	setupConfigLayers(t, `{"server": {"addr": ":8081"}, "backup_retention": 3}`)
+	t.Setenv("KPI_DATA_DIR", filepath.Dir(settingsPath))
+	t.Setenv("KPI_ADDR", ":9000")
+	configFlags["backup-retention"] = "10"
+	if err := loadSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	appSettings.SubmissionDeadlineDay = 12
+	saveSettings()
+
+	var saved Settings
+	if err := readJSONFile(settingsPath, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Server.Addr != ":8081" || saved.BackupRetention != 3 || saved.DatabasePath != dbDir {
+		t.Errorf("saved %q, %d, %q, want the file values of the overridden options", saved.Server.Addr, saved.BackupRetention, saved.DatabasePath)
+	}
+	if saved.SubmissionDeadlineDay != 12 {
+		t.Errorf("saved submission deadline %d, want 12", saved.SubmissionDeadlineDay)
+	}
+	if appSettings.Server.Addr != ":9000" || appSettings.BackupRetention != 10 {
+		t.Errorf("effective %q, %d, want the overrides kept", appSettings.Server.Addr, appSettings.BackupRetention)
+	}
+
+	rec := httptest.NewRecorder()
+	getSettings(rec, httptest.NewRequest("GET", "/api/settings", nil))
+	var response struct {
+		Sources map[string]string `json:"sources"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Sources["addr"] != sourceEnv || response.Sources["backup-retention"] != sourceFlag || response.Sources["smtp-port"] != sourceDefault {
+		t.Errorf("sources %v, want env, flag and default", response.Sources)
+	}
+}
+
+func TestRegisterConfigFlags(t *testing.T) {
+	setupConfigLayers(t, "")
+	fs := flag.NewFlagSet("kpi-tracker", flag.ContinueOnError)
+	registerConfigFlags(fs)
+
+	config := filepath.Join(t.TempDir(), "other.json")
+	if err := fs.Parse([]string{"--config", config, "--addr", ":9001", "--cors-origins", "https://a.example, https://b.example", "report"}); err != nil {
+		t.Fatal(err)
+	}
+	if settingsPath != config || configFlags["addr"] != ":9001" || fs.Arg(0) != "report" {
+		t.Errorf("settings path %q, flags %v, args %v", settingsPath, configFlags, fs.Args())
+	}
+
+	t.Setenv("KPI_DATA_DIR", t.TempDir())
+	if err := loadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(appSettings.CORSOrigins, []string{"https://a.example", "https://b.example"}) {
+		t.Errorf("CORS origins %v, want both origins", appSettings.CORSOrigins)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression:
+// minute hour day-of-month month day-of-week
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays uint64 // Bit n set when value n matches
+	anyHour, anyDay, anyWeekday            bool   // Field started with *, for the day matching and DST rules
+}
+
+// cronMacros are the supported shorthand schedules
+var cronMacros = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+var cronMonthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+var cronWeekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseCron parses a cron expression. Fields accept *, numbers, names of months
+// and weekdays, ranges (1-5), lists (1,15) and steps (*/15, 8-18/2). Sunday is
+// 0 or 7. As in cron, a job runs when either the day of the month or the day of
+// the week matches if both are restricted.
+//
+// Daylight saving time is handled like cron too: a time skipped when the clocks
+// go forward runs at the end of the gap, and a time repeated when they go back
+// runs once. Schedules whose hour field starts with * follow the clock instead.
+func parseCron(expr string) (cronSchedule, error) {
+	var s cronSchedule
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return s, fmt.Errorf("cron expression '%s' must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return s, fmt.Errorf("minute: %v", err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return s, fmt.Errorf("hour: %v", err)
+	}
+	if s.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return s, fmt.Errorf("day of month: %v", err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return s, fmt.Errorf("month: %v", err)
+	}
+	if s.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return s, fmt.Errorf("day of week: %v", err)
+	}
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1 // 7 is Sunday too
+	}
+	s.anyHour = strings.HasPrefix(fields[1], "*")
+	s.anyDay = strings.HasPrefix(fields[2], "*")
+	s.anyWeekday = strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+// parseCronField parses one field into a bit set of the matching values.
+// names, when given, are accepted for the values starting at min.
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+
+	value := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return i + min, nil
+			}
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("'%s' is not between %d and %d", s, min, max)
+		}
+		return n, nil
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		var from, to int
+		switch {
+		case rangePart == "*":
+			from, to = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if to, err = value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if to < from {
+				return 0, fmt.Errorf("range '%s' ends before it starts", rangePart)
+			}
+		default:
+			n, err := value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			from, to = n, n
+			if step > 1 {
+				to = max // 5/15 means every 15 starting at 5
+			}
+		}
+
+		for n := from; n <= to; n += step {
+			bits |= 1 << n
+		}
+	}
+
+	return bits, nil
+}
+
+// matchesDay reports whether the schedule runs on the day of t. A field starting
+// with * does not restrict the day, so only the other field has to match.
+func (s cronSchedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<t.Day()) != 0
+	weekday := s.weekdays&(1<<int(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// skippedBefore reports whether the clocks went forward just before t, skipping
+// an hour in which the schedule runs
+func (s cronSchedule) skippedBefore(t time.Time) bool {
+	before := t.Add(-time.Minute)
+	from := before.Hour() + 1
+	if before.Day() != t.Day() {
+		from = 0
+	}
+	for hour := from; hour < t.Hour(); hour++ {
+		if s.hours&(1<<hour) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatedTime reports whether the clock already showed t an hour earlier,
+// before it went back
+func repeatedTime(t time.Time) bool {
+	earlier := t.Add(-time.Hour)
+	return earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute()
+}
+
+// Next returns the first time after t at which the schedule runs, or the zero
+// time if there is none within five years (e.g. February 30)
+func (s cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.anyHour && s.skippedBefore(t) {
+			return t
+		}
+		if s.hours&(1<<t.Hour()) == 0 {
+			// Add the minutes left instead of using time.Date, which may pick the
+			// second of two equal clock times
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if s.minutes&(1<<t.Minute()) == 0 || (!s.anyHour && repeatedTime(t)) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
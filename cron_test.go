@@ -0,0 +1,145 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// cronTime parses "2006-01-02 15:04 -0700" in a location
+func cronTime(t *testing.T, s string, loc *time.Location) time.Time {
+	parsed, err := time.Parse("2006-01-02 15:04 -0700", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.In(loc)
+}
+
+// cronNextTest is the expected next run of a schedule after a time
+type cronNextTest struct {
+	expr, after, want string // want is empty when the schedule never runs
+}
+
+// checkCronNext runs Next tests in a location
+func checkCronNext(t *testing.T, tests []cronNextTest, loc *time.Location) {
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		got := schedule.Next(cronTime(t, tt.after, loc))
+		if tt.want == "" {
+			if !got.IsZero() {
+				t.Errorf("%s after %s: %s, want never", tt.expr, tt.after, got)
+			}
+			continue
+		}
+		if want := cronTime(t, tt.want, loc); !got.Equal(want) {
+			t.Errorf("%s after %s: %s, want %s", tt.expr, tt.after, got, want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@fortnightly",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("%q should not parse", expr)
+		}
+	}
+}
+
+func TestCronFields(t *testing.T) {
+	// 2026-01-01 is a Thursday
+	checkCronNext(t, []cronNextTest{
+		// Steps over a range, from a start value and over the whole field
+		{"*/15 8-18/2 * * *", "2026-01-01 08:50 +0000", "2026-01-01 10:00 +0000"},
+		{"*/15 8-18/2 * * *", "2026-01-01 18:45 +0000", "2026-01-02 08:00 +0000"},
+		{"5/15 * * * *", "2026-01-01 10:00 +0000", "2026-01-01 10:05 +0000"},
+		{"5/15 * * * *", "2026-01-01 10:50 +0000", "2026-01-01 11:05 +0000"},
+		{"0,30 9 * * *", "2026-01-01 09:00 +0000", "2026-01-01 09:30 +0000"},
+
+		// Month and weekday names, in any case
+		{"0 9 * feb mon", "2026-01-01 00:00 +0000", "2026-02-02 09:00 +0000"},
+		{"0 9 * FEB-Mar Sun", "2026-03-01 09:00 +0000", "2026-03-08 09:00 +0000"},
+
+		// 7 and 0 are both Sunday
+		{"0 0 * * 7", "2026-01-01 00:00 +0000", "2026-01-04 00:00 +0000"},
+		{"0 0 * * 0", "2026-01-01 00:00 +0000", "2026-01-04 00:00 +0000"},
+		{"0 0 * * 5-7", "2026-01-03 00:00 +0000", "2026-01-04 00:00 +0000"},
+
+		// Macros
+		{"@weekly", "2026-01-01 00:00 +0000", "2026-01-04 00:00 +0000"},
+		{"@hourly", "2026-01-01 10:59 +0000", "2026-01-01 11:00 +0000"},
+		{"@yearly", "2026-01-01 00:00 +0000", "2027-01-01 00:00 +0000"},
+	}, time.UTC)
+}
+
+func TestCronDayMatching(t *testing.T) {
+	checkCronNext(t, []cronNextTest{
+		// Both day fields restricted: the 13th or a Friday
+		{"0 0 13 * 5", "2026-01-01 00:00 +0000", "2026-01-02 00:00 +0000"},
+		{"0 0 13 * 5", "2026-01-10 00:00 +0000", "2026-01-13 00:00 +0000"},
+		{"0 0 13 * 5", "2026-01-13 00:00 +0000", "2026-01-16 00:00 +0000"},
+
+		// One day field starting with *: both have to match
+		{"0 0 13 * *", "2026-01-14 00:00 +0000", "2026-02-13 00:00 +0000"},
+		{"0 0 * * 5", "2026-01-02 00:00 +0000", "2026-01-09 00:00 +0000"},
+		{"0 0 */2 * 5", "2026-01-01 00:00 +0000", "2026-01-09 00:00 +0000"},
+		{"0 0 13 * */5", "2026-01-01 00:00 +0000", "2026-02-13 00:00 +0000"},
+	}, time.UTC)
+}
+
+func TestCronNextAcrossMonths(t *testing.T) {
+	checkCronNext(t, []cronNextTest{
+		{"@monthly", "2026-01-31 12:00 +0000", "2026-02-01 00:00 +0000"},
+		{"0 0 1 * *", "2026-12-15 00:00 +0000", "2027-01-01 00:00 +0000"},
+		{"59 23 31 12 *", "2026-12-31 23:59 +0000", "2027-12-31 23:59 +0000"},
+
+		// Months without the day are skipped
+		{"0 0 31 * *", "2026-01-31 00:00 +0000", "2026-03-31 00:00 +0000"},
+		{"0 0 30 * *", "2026-01-30 00:00 +0000", "2026-03-30 00:00 +0000"},
+		{"0 0 29 2 *", "2026-03-01 00:00 +0000", "2028-02-29 00:00 +0000"},
+		{"0 0 30 2 *", "2026-01-01 00:00 +0000", ""},
+	}, time.UTC)
+}
+
+func TestCronNextAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	// The clocks go forward from 02:00 to 03:00 on 2026-03-29 and back from
+	// 03:00 to 02:00 on 2026-10-25
+	checkCronNext(t, []cronNextTest{
+		// A skipped time runs at the end of the gap, once
+		{"30 2 * * *", "2026-03-29 00:00 +0100", "2026-03-29 03:00 +0200"},
+		{"30 2 * * *", "2026-03-29 03:00 +0200", "2026-03-30 02:30 +0200"},
+		{"0,30 2 * * *", "2026-03-29 01:59 +0100", "2026-03-29 03:00 +0200"},
+		{"0 3 * * *", "2026-03-29 00:00 +0100", "2026-03-29 03:00 +0200"},
+
+		// Schedules following the clock have no catch-up run
+		{"30 * * * *", "2026-03-29 01:30 +0100", "2026-03-29 03:30 +0200"},
+
+		// A repeated time runs the first time only
+		{"30 2 * * *", "2026-10-25 00:00 +0200", "2026-10-25 02:30 +0200"},
+		{"30 2 * * *", "2026-10-25 02:30 +0200", "2026-10-26 02:30 +0100"},
+		{"0 1-3 * * *", "2026-10-25 02:00 +0200", "2026-10-25 03:00 +0100"},
+
+		// Schedules following the clock run in both hours
+		{"*/30 * * * *", "2026-10-25 02:30 +0200", "2026-10-25 02:00 +0100"},
+		{"0 * * * *", "2026-10-25 02:00 +0200", "2026-10-25 02:00 +0100"},
+	}, berlin)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// APIError is the body of every error response of the REST API:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "details": [{"field": "metric_value", "message": "..."}]}}
+type APIError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// ErrorResponse wraps an APIError
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// Error codes by HTTP status
+var errorCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusPreconditionFailed:    "precondition_failed",
+	http.StatusPreconditionRequired:  "confirmation_required",
+	http.StatusUnprocessableEntity:   "validation_failed",
+	http.StatusInternalServerError:   "internal_error",
+	http.StatusServiceUnavailable:    "unavailable",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+}
+
+// newAPIError creates an APIError with the code for the status
+func newAPIError(status int, message string, details ...FieldError) APIError {
+	code, ok := errorCodes[status]
+	if !ok {
+		code = "error"
+	}
+	return APIError{Code: code, Message: message, Details: details}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string, details ...FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: newAPIError(status, message, details...)})
+}
+
+// writeValidationError writes a 422 response listing every invalid field
+func writeValidationError(w http.ResponseWriter, errs ValidationErrors) {
+	writeError(w, http.StatusUnprocessableEntity, "Validation failed", errs...)
+}
+
+// notFoundHandler answers unknown routes with a JSON error
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "No route for "+r.URL.Path)
+}
+
+// methodNotAllowedHandler answers unsupported methods with a JSON error
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, "Method "+r.Method+" is not allowed for "+r.URL.Path)
+}
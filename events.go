@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types published on the event bus
+const (
+	EventMeasurementCreated = "measurement.created"
+	EventMeasurementUpdated = "measurement.updated"
+	EventBelowTarget        = "measurement.below_target"
+	EventKPICreated         = "kpi.created"
+	EventKPIUpdated         = "kpi.updated"
+	EventKPIDeleted         = "kpi.deleted"
+	EventDataReloaded       = "data.reloaded"
+	EventAlertTriggered     = "alert.triggered"
+	EventPeriodClosed       = "period.closed"
+	EventPeriodReopened     = "period.reopened"
+)
+
+var eventTypes = []string{
+	EventMeasurementCreated, EventMeasurementUpdated, EventBelowTarget,
+	EventKPICreated, EventKPIUpdated, EventKPIDeleted,
+	EventDataReloaded, EventAlertTriggered, EventPeriodClosed, EventPeriodReopened,
+}
+
+// Event history kept for clients reconnecting with Last-Event-ID
+const eventHistorySize = 256
+
+// Event is a change to the data, sent to subscribers such as /api/events
+type Event struct {
+	ID     int64       `json:"id"`
+	Type   string      `json:"type"`
+	RoleID int         `json:"role_id,omitempty"` // 0 when the event is not about one role
+	KPIID  int         `json:"kpi_id,omitempty"`
+	Period string      `json:"period,omitempty"` // YYYY-MM
+	Data   interface{} `json:"data,omitempty"`
+	Time   time.Time   `json:"time"`
+}
+
+// EventFilter selects the events a subscriber receives. Empty fields match everything.
+type EventFilter struct {
+	RoleIDs []int
+	Types   []string
+}
+
+// Matches reports whether an event passes the filter. Events that are not about
+// a single role, such as reloads, affect every role and always match the role filter.
+func (f EventFilter) Matches(event Event) bool {
+	if len(f.Types) > 0 && !containsString(f.Types, event.Type) {
+		return false
+	}
+	if len(f.RoleIDs) > 0 && event.RoleID != 0 {
+		for _, id := range f.RoleIDs {
+			if id == event.RoleID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// EventBus delivers events to subscribers without blocking the publisher
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      int64
+	history     []Event
+	subscribers map[chan Event]EventFilter
+}
+
+// appEvents is the event bus of the application
+var appEvents = newEventBus()
+
+// newEventBus creates an empty event bus
+func newEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan Event]EventFilter)}
+}
+
+// Publish assigns the event an ID and sends it to every matching subscriber.
+// Subscribers that fall behind miss events rather than slowing down data entry.
+func (b *EventBus) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = b.nextID
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > eventHistorySize {
+		b.history = b.history[len(b.history)-eventHistorySize:]
+	}
+
+	for ch, filter := range b.subscribers {
+		if !filter.Matches(event) {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the matching events published after the
+// event with ID lastID (0 for new events only) and a function to unsubscribe
+func (b *EventBus) Subscribe(filter EventFilter, lastID int64) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, 64)
+	if lastID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastID && filter.Matches(event) && len(ch) < cap(ch) {
+				ch <- event
+			}
+		}
+	}
+	b.subscribers[ch] = filter
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// CloseAll ends every subscription, e.g. so open streams do not delay a shutdown
+func (b *EventBus) CloseAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// BelowTargetData is the data of a measurement.below_target event
+type BelowTargetData struct {
+	Measurement Measurement `json:"measurement"`
+	KPI         KPI         `json:"kpi"`
+	Achievement float64     `json:"achievement_percent"`
+	Previous    *float64    `json:"previous_achievement_percent,omitempty"` // Unset for new measurements
+}
+
+// publishMeasurementEvent publishes the creation or update of a measurement. When
+// the measurement misses its KPI target and the previous value did not, it also
+// publishes measurement.below_target.
+func publishMeasurementEvent(eventType string, m Measurement, previous *Measurement) {
+	event := Event{Type: eventType, KPIID: m.KPIID, Period: m.Period.Format("2006-01"), Data: m}
+	kpi := getKPIByID(m.KPIID)
+	if kpi != nil {
+		event.RoleID = kpi.RoleID
+	}
+	appEvents.Publish(event)
+
+	if kpi == nil {
+		return
+	}
+	achievement := calculateAchievement(*kpi, &m)
+	if achievement >= 100 {
+		return
+	}
+	data := BelowTargetData{Measurement: m, KPI: *kpi, Achievement: achievement}
+	if previous != nil {
+		before := calculateAchievement(*kpi, previous)
+		if before < 100 {
+			return
+		}
+		data.Previous = &before
+	}
+	event.Type = EventBelowTarget
+	event.Data = data
+	appEvents.Publish(event)
+}
+
+// measurementChange is a saved measurement whose events and alert checks wait for
+// the data to be written to Excel. Previous is nil for a new measurement.
+type measurementChange struct {
+	Measurement Measurement
+	Previous    *Measurement
+}
+
+// pendingChanges are the measurement changes since the last save, guarded by dataMu
+var pendingChanges []measurementChange
+
+// recordMeasurementChange queues the events of a created or updated measurement
+// until the next successful save
+func recordMeasurementChange(m Measurement, previous *Measurement) {
+	pendingChanges = append(pendingChanges, measurementChange{Measurement: m, Previous: previous})
+}
+
+// publishPendingChanges publishes the events of the queued measurement changes and
+// evaluates the alert rules on them. saveToExcel calls it once the data is written,
+// so clients are never told about a change that a failed save rolls back.
+func publishPendingChanges() {
+	changes := pendingChanges
+	pendingChanges = nil
+	for _, change := range changes {
+		eventType := EventMeasurementCreated
+		if change.Previous != nil {
+			eventType = EventMeasurementUpdated
+		}
+		publishMeasurementEvent(eventType, change.Measurement, change.Previous)
+		evaluateAlerts(change.Measurement)
+	}
+}
+
+// discardPendingChanges drops the queued events and alert checks after the changes were rolled back
+func discardPendingChanges() {
+	pendingChanges = nil
+}
+
+// publishKPIEvent publishes a change to a KPI definition
+func publishKPIEvent(eventType string, kpi KPI) {
+	appEvents.Publish(Event{Type: eventType, RoleID: kpi.RoleID, KPIID: kpi.ID, Data: kpi})
+}
+
+// parseEventFilter parses the role_id and type query parameters of /api/events,
+// both of which accept comma-separated lists
+func parseEventFilter(r *http.Request) (EventFilter, *ParamError) {
+	var filter EventFilter
+	query := r.URL.Query()
+
+	for name := range query {
+		if name != "role_id" && name != "type" && name != "last_event_id" {
+			return filter, &ParamError{name, "unknown parameter, allowed: role_id, type, last_event_id"}
+		}
+	}
+
+	if value := query.Get("role_id"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || getRoleByID(id) == nil {
+				return filter, &ParamError{"role_id", fmt.Sprintf("unknown role '%s'", part)}
+			}
+			filter.RoleIDs = append(filter.RoleIDs, id)
+		}
+	}
+
+	if value := query.Get("type"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			eventType := strings.TrimSpace(part)
+			if !containsString(eventTypes, eventType) {
+				return filter, &ParamError{"type", fmt.Sprintf("unknown event type '%s', allowed: %s", eventType, strings.Join(eventTypes, ", "))}
+			}
+			filter.Types = append(filter.Types, eventType)
+		}
+	}
+
+	return filter, nil
+}
+
+// streamEvents sends the events of the bus as server-sent events. Clients that
+// reconnect with Last-Event-ID receive the recent events they missed. lockData
+// leaves the stream unlocked, so only the role lookup of the filter takes dataMu.
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	dataMu.RLock()
+	filter, perr := parseEventFilter(r)
+	dataMu.RUnlock()
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	lastIDValue := r.Header.Get("Last-Event-ID")
+	if lastIDValue == "" {
+		lastIDValue = r.URL.Query().Get("last_event_id")
+	}
+	lastID, _ := strconv.ParseInt(lastIDValue, 10, 64)
+
+	controller := http.NewResponseController(w)
+	// The server write timeout would otherwise cut off long-lived streams
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ch, unsubscribe := appEvents.Subscribe(filter, lastID)
+	defer unsubscribe()
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			// Comments keep proxies from closing an idle connection
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// receive returns the events waiting on a subscription
+func receive(ch <-chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestEventFilterMatches(t *testing.T) {
+	created := Event{Type: EventMeasurementCreated, RoleID: 1}
+	reload := Event{Type: EventDataReloaded}
+	tests := []struct {
+		name   string
+		filter EventFilter
+		event  Event
+		want   bool
+	}{
+		{"empty filter", EventFilter{}, created, true},
+		{"matching role", EventFilter{RoleIDs: []int{2, 1}}, created, true},
+		{"other role", EventFilter{RoleIDs: []int{2}}, created, false},
+		{"event about all roles", EventFilter{RoleIDs: []int{2}}, reload, true},
+		{"matching type", EventFilter{Types: []string{EventMeasurementCreated}}, created, true},
+		{"other type", EventFilter{Types: []string{EventKPIDeleted}}, created, false},
+		{"type and role must both match", EventFilter{RoleIDs: []int{1}, Types: []string{EventKPIDeleted}}, created, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(tt.event); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEventBusDeliversMatchingEvents(t *testing.T) {
+	bus := newEventBus()
+	ch, unsubscribe := bus.Subscribe(EventFilter{RoleIDs: []int{1}}, 0)
+
+	bus.Publish(Event{Type: EventMeasurementCreated, RoleID: 1})
+	bus.Publish(Event{Type: EventMeasurementCreated, RoleID: 2})
+	bus.Publish(Event{Type: EventDataReloaded})
+
+	events := receive(ch)
+	if len(events) != 2 || events[0].ID != 1 || events[1].ID != 3 {
+		t.Fatalf("received %+v, want events 1 and 3", events)
+	}
+	if events[0].Time.IsZero() {
+		t.Error("published event has no time")
+	}
+
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Error("channel still open after unsubscribe")
+	}
+	// Unsubscribing twice or publishing afterwards must not panic
+	unsubscribe()
+	bus.Publish(Event{Type: EventDataReloaded})
+}
+
+func TestEventBusReplaysAfterLastEventID(t *testing.T) {
+	bus := newEventBus()
+	for i := 0; i < 4; i++ {
+		bus.Publish(Event{Type: EventMeasurementUpdated, RoleID: 1 + i%2})
+	}
+
+	// A reconnecting client gets the matching events it missed
+	ch, unsubscribe := bus.Subscribe(EventFilter{RoleIDs: []int{1}}, 1)
+	defer unsubscribe()
+	events := receive(ch)
+	if len(events) != 1 || events[0].ID != 3 {
+		t.Fatalf("replayed %+v, want event 3", events)
+	}
+
+	// New clients only get new events
+	fresh, unsubscribeFresh := bus.Subscribe(EventFilter{}, 0)
+	defer unsubscribeFresh()
+	if events := receive(fresh); len(events) != 0 {
+		t.Errorf("new subscriber replayed %d events", len(events))
+	}
+
+	bus.Publish(Event{Type: EventDataReloaded})
+	if events := receive(ch); len(events) != 1 || events[0].ID != 5 {
+		t.Errorf("received %+v after replay, want event 5", events)
+	}
+}
+
+func TestEventBusHistoryIsBounded(t *testing.T) {
+	bus := newEventBus()
+	for i := 0; i < eventHistorySize+10; i++ {
+		bus.Publish(Event{Type: EventDataReloaded})
+	}
+	if len(bus.history) != eventHistorySize || bus.history[0].ID != 11 {
+		t.Errorf("history has %d events from %d, want %d from 11", len(bus.history), bus.history[0].ID, eventHistorySize)
+	}
+}
+
+func TestEventBusCloseAll(t *testing.T) {
+	bus := newEventBus()
+	ch, unsubscribe := bus.Subscribe(EventFilter{}, 0)
+	bus.CloseAll()
+	if _, ok := <-ch; ok {
+		t.Error("channel still open after CloseAll")
+	}
+	unsubscribe()
+}
+
+func TestMeasurementEventsWaitForSave(t *testing.T) {
+	setupSubmissionTest(t)
+	t.Cleanup(discardPendingChanges)
+	ch, unsubscribe := appEvents.Subscribe(EventFilter{RoleIDs: []int{1}}, 0)
+	defer unsubscribe()
+
+	may := time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local)
+	saveMeasurement(1, 80, nil, "", may, "")
+	if events := receive(ch); len(events) != 0 {
+		t.Fatalf("%d events published before the save", len(events))
+	}
+
+	// A failed save rolls the change back and nothing is published
+	discardPendingChanges()
+	publishPendingChanges()
+	if events := receive(ch); len(events) != 0 {
+		t.Fatalf("%d events published for a discarded change", len(events))
+	}
+
+	saveMeasurement(1, 120, nil, "", may, "")
+	saveMeasurement(1, 70, nil, "", may, "")
+	publishPendingChanges()
+	events := receive(ch)
+	if len(events) != 3 {
+		t.Fatalf("received %+v, want updated, updated, below_target", events)
+	}
+	if events[0].Type != EventMeasurementUpdated || events[1].Type != EventMeasurementUpdated || events[2].Type != EventBelowTarget {
+		t.Errorf("event types %s, %s, %s", events[0].Type, events[1].Type, events[2].Type)
+	}
+	if data, ok := events[2].Data.(BelowTargetData); !ok || data.Previous == nil || *data.Previous != 100 {
+		t.Errorf("below_target data %+v, want previous achievement 100", events[2].Data)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"time"
 
@@ -16,6 +17,7 @@ const (
 	rolesSheet        = "Roles"
 	kpisSheet         = "KPIs"
 	measurementsSheet = "Measurements"
+	employeesSheet    = "Employees"
 )
 
 // getExcelDBPath returns the full path to the Excel database file
@@ -55,26 +57,33 @@ func createEmptyExcelDB() error {
 	f.NewSheet(rolesSheet)
 	f.NewSheet(kpisSheet)
 	f.NewSheet(measurementsSheet)
+	f.NewSheet(employeesSheet)
 	f.DeleteSheet("Sheet1")
 
 	// Set up headers for Roles sheet
-	f.SetSheetRow(rolesSheet, "A1", &[]interface{}{"ID", "Name", "Description"})
+	f.SetSheetRow(rolesSheet, "A1", &[]interface{}{"ID", "Name", "Description", "Department"})
 
 	// Set up headers for KPIs sheet
 	f.SetSheetRow(kpisSheet, "A1", &[]interface{}{
 		"ID", "RoleID", "Category", "Name", "Description",
-		"Metric", "Unit", "Target", "TargetValue", "Operator", "Weight",
+		"Metric", "Unit", "Target", "TargetValue", "Operator", "Weight", "Frequency", "Formula",
 	})
 
 	// Set up headers for Measurements sheet
 	f.SetSheetRow(measurementsSheet, "A1", &[]interface{}{
-		"ID", "KPIID", "MetricValue", "Unit", "Period", "Notes", "CreatedAt",
+		"ID", "KPIID", "MetricValue", "Unit", "Period", "Notes", "CreatedAt", "Inputs",
+	})
+
+	// Set up headers for Employees sheet
+	f.SetSheetRow(employeesSheet, "A1", &[]interface{}{
+		"ID", "Name", "RoleID", "HireDate", "BaseSalary",
 	})
 
 	// Format headers as tables
-	formatAsTable(f, rolesSheet, 1, 3)
-	formatAsTable(f, kpisSheet, 1, 11)
-	formatAsTable(f, measurementsSheet, 1, 7)
+	formatAsTable(f, rolesSheet, 1, 4)
+	formatAsTable(f, kpisSheet, 1, 13)
+	formatAsTable(f, measurementsSheet, 1, 8)
+	formatAsTable(f, employeesSheet, 1, 5)
 
 	// Save the Excel file
 	excelPath := getExcelDBPath()
@@ -124,10 +133,12 @@ func loadFromExcel() error {
 	debugJSON, _ := json.MarshalIndent(debugData, "", "  ")
 	os.WriteFile(filepath.Join(appSettings.DatabasePath, "excel_debug.json"), debugJSON, 0644)
 
-	// Clear existing data
+	// Clear existing data, unsaved changes are gone with it
 	roles = []Role{}
+	employees = []Employee{}
 	kpis = []KPI{}
 	measurements = []Measurement{}
+	discardPendingChanges()
 
 	// Load roles
 	rows, err := f.GetRows(rolesSheet)
@@ -155,6 +166,11 @@ func loadFromExcel() error {
 			Description: row[2],
 		}
 
+		// Department was added later, older databases only have three columns
+		if len(row) > 3 {
+			role.Department = row[3]
+		}
+
 		roles = append(roles, role)
 	}
 
@@ -207,6 +223,13 @@ func loadFromExcel() error {
 			Operator:    row[9],
 			Weight:      weight,
 		}
+		// Workbooks created before the Frequency column default to monthly
+		if len(row) > 11 {
+			kpi.Frequency = row[11]
+		}
+		if len(row) > 12 {
+			kpi.Formula = row[12]
+		}
 
 		kpis = append(kpis, kpi)
 	}
@@ -266,11 +289,68 @@ func loadFromExcel() error {
 			CreatedAt:   createdAt,
 		}
 
+		// Raw inputs of derived KPIs, a column added after the others
+		if len(row) > 7 && row[7] != "" {
+			inputs, err := parseFormulaInputs(row[7])
+			if err != nil {
+				fmt.Printf("Warning: Invalid inputs '%s' in row %d, ignoring them\n", row[7], i+1)
+			} else {
+				measurement.Inputs = inputs
+			}
+		}
+
 		measurements = append(measurements, measurement)
 	}
 
-	fmt.Printf("Loaded %d roles, %d KPIs, and %d measurements from Excel database\n",
-		len(roles), len(kpis), len(measurements))
+	// Load employees (the sheet does not exist in older databases)
+	rows, err = f.GetRows(employeesSheet)
+	if err == nil {
+		for i, row := range rows {
+			if i == 0 { // Skip header row
+				continue
+			}
+			if len(row) < 4 {
+				continue // Skip incomplete rows
+			}
+
+			id, err := strconv.Atoi(row[0])
+			if err != nil {
+				fmt.Printf("Warning: Invalid employee ID '%s' in row %d, skipping\n", row[0], i+1)
+				continue
+			}
+
+			roleID, err := strconv.Atoi(row[2])
+			if err != nil {
+				fmt.Printf("Warning: Invalid Role ID '%s' in row %d, skipping\n", row[2], i+1)
+				continue
+			}
+
+			hireDate, err := time.Parse("2006-01-02", row[3])
+			if err != nil {
+				fmt.Printf("Warning: Invalid hire date '%s' in row %d, skipping\n", row[3], i+1)
+				continue
+			}
+
+			baseSalary := 0.0
+			if len(row) > 4 {
+				baseSalary, _ = strconv.ParseFloat(row[4], 64)
+			}
+
+			employees = append(employees, Employee{
+				ID:         id,
+				Name:       row[1],
+				RoleID:     roleID,
+				HireDate:   hireDate,
+				BaseSalary: baseSalary,
+			})
+		}
+	}
+
+	fmt.Printf("Loaded %d roles, %d employees, %d KPIs, and %d measurements from Excel database\n",
+		len(roles), len(employees), len(kpis), len(measurements))
+	appEvents.Publish(Event{Type: EventDataReloaded, Data: map[string]int{
+		"roles": len(roles), "employees": len(employees), "kpis": len(kpis), "measurements": len(measurements),
+	}})
 	return nil
 }
 
@@ -291,41 +371,58 @@ func saveToExcel() error {
 	f.DeleteSheet("Sheet1")
 
 	// Save Roles
-	f.SetSheetRow(rolesSheet, "A1", &[]interface{}{"ID", "Name", "Description"})
+	f.SetSheetRow(rolesSheet, "A1", &[]interface{}{"ID", "Name", "Description", "Department"})
 	for i, role := range roles {
 		row := fmt.Sprintf("A%d", i+2)
-		f.SetSheetRow(rolesSheet, row, &[]interface{}{role.ID, role.Name, role.Description})
+		f.SetSheetRow(rolesSheet, row, &[]interface{}{role.ID, role.Name, role.Description, role.Department})
 	}
 
 	// Save KPIs
 	f.SetSheetRow(kpisSheet, "A1", &[]interface{}{
 		"ID", "RoleID", "Category", "Name", "Description",
-		"Metric", "Unit", "Target", "TargetValue", "Operator", "Weight",
+		"Metric", "Unit", "Target", "TargetValue", "Operator", "Weight", "Frequency", "Formula",
 	})
 	for i, kpi := range kpis {
 		row := fmt.Sprintf("A%d", i+2)
 		f.SetSheetRow(kpisSheet, row, &[]interface{}{
 			kpi.ID, kpi.RoleID, kpi.Category, kpi.Name, kpi.Description,
-			kpi.Metric, kpi.Unit, kpi.Target, kpi.TargetValue, kpi.Operator, kpi.Weight,
+			kpi.Metric, kpi.Unit, kpi.Target, kpi.TargetValue, kpi.Operator, kpi.Weight, kpi.Frequency, kpi.Formula,
 		})
 	}
 
 	// Save Measurements
 	f.SetSheetRow(measurementsSheet, "A1", &[]interface{}{
-		"ID", "KPIID", "MetricValue", "Unit", "Period", "Notes", "CreatedAt",
+		"ID", "KPIID", "MetricValue", "Unit", "Period", "Notes", "CreatedAt", "Inputs",
 	})
 	for i, m := range measurements {
 		row := fmt.Sprintf("A%d", i+2)
 		f.SetSheetRow(measurementsSheet, row, &[]interface{}{
 			m.ID, m.KPIID, m.MetricValue, m.Unit,
 			m.Period.Format("2006-01-02"), m.Notes, m.CreatedAt.Format("2006-01-02 15:04:05"),
+			formatFormulaInputs(m.Inputs),
+		})
+	}
+
+	// Save Employees
+	f.NewSheet(employeesSheet)
+	f.SetSheetRow(employeesSheet, "A1", &[]interface{}{
+		"ID", "Name", "RoleID", "HireDate", "BaseSalary",
+	})
+	for i, e := range employees {
+		row := fmt.Sprintf("A%d", i+2)
+		f.SetSheetRow(employeesSheet, row, &[]interface{}{
+			e.ID, e.Name, e.RoleID, e.HireDate.Format("2006-01-02"), e.BaseSalary,
 		})
 	}
 
 	// Format as tables for better viewing
-	formatAsTable(f, rolesSheet, len(roles)+1, 3)
-	formatAsTable(f, kpisSheet, len(kpis)+1, 11)
-	formatAsTable(f, measurementsSheet, len(measurements)+1, 7)
+	formatAsTable(f, rolesSheet, len(roles)+1, 4)
+	formatAsTable(f, kpisSheet, len(kpis)+1, 13)
+	formatAsTable(f, measurementsSheet, len(measurements)+1, 8)
+	formatAsTable(f, employeesSheet, len(employees)+1, 5)
+
+	// Derived sheets
+	writeAppraisalsSheet(f)
 
 	// Save the Excel file
 	excelPath := getExcelDBPath()
@@ -340,21 +437,9 @@ func saveToExcel() error {
 
 	// Create a backup of the existing Excel file if it exists
 	if _, err := os.Stat(excelPath); err == nil {
-		backupDir := filepath.Join(appSettings.DatabasePath, "excel_backups")
-		if _, err := os.Stat(backupDir); os.IsNotExist(err) {
-			os.MkdirAll(backupDir, 0755)
-		}
-
-		timestamp := time.Now().Format("20060102_150405")
-		backupPath := filepath.Join(backupDir, fmt.Sprintf("kpi_database_backup_%s.xlsx", timestamp))
-
-		// Copy the file
-		data, err := os.ReadFile(excelPath)
+		backupPath, err := backupExcelDB()
 		if err == nil {
-			err = os.WriteFile(backupPath, data, 0644)
-			if err == nil {
-				fmt.Printf("Created backup of Excel database: %s\n", backupPath)
-			}
+			fmt.Printf("Created backup of Excel database: %s\n", backupPath)
 		}
 	}
 
@@ -364,9 +449,59 @@ func saveToExcel() error {
 	}
 
 	fmt.Printf("Saved data to Excel database: %s\n", excelPath)
+	publishPendingChanges()
 	return nil
 }
 
+// backupExcelDB copies the current Excel database into the excel_backups directory
+func backupExcelDB() (string, error) {
+	excelPath := getExcelDBPath()
+
+	backupDir := filepath.Join(appSettings.DatabasePath, "excel_backups")
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(backupDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create backup directory: %v", err)
+		}
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	backupPath := filepath.Join(backupDir, fmt.Sprintf("kpi_database_backup_%s.xlsx", timestamp))
+
+	// Copy the file
+	data, err := os.ReadFile(excelPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Excel database: %v", err)
+	}
+
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	pruneExcelBackups(backupDir)
+
+	return backupPath, nil
+}
+
+// pruneExcelBackups deletes the oldest backups beyond the configured retention
+func pruneExcelBackups(backupDir string) {
+	if appSettings.BackupRetention <= 0 {
+		return
+	}
+
+	// Backup names contain a sortable timestamp
+	backups, err := filepath.Glob(filepath.Join(backupDir, "kpi_database_backup_*.xlsx"))
+	if err != nil || len(backups) <= appSettings.BackupRetention {
+		return
+	}
+	sort.Strings(backups)
+
+	for _, old := range backups[:len(backups)-appSettings.BackupRetention] {
+		if err := os.Remove(old); err != nil {
+			fmt.Printf("Warning: could not remove old backup %s: %v\n", old, err)
+		}
+	}
+}
+
 // formatAsTable formats a sheet as a table for better viewing
 func formatAsTable(f *excelize.File, sheet string, rows, cols int) {
 	// Set header style
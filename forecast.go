@@ -0,0 +1,670 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Forecast methods
+const (
+	ForecastLinear        = "linear"         // Least-squares trend over the history
+	ForecastMovingAverage = "moving_average" // Mean of the last values
+	ForecastSeasonalNaive = "seasonal_naive" // Value of the same period a year earlier, else the last value
+)
+
+var forecastMethods = []string{ForecastLinear, ForecastMovingAverage, ForecastSeasonalNaive}
+
+// Confidence level of forecast intervals and the default moving average window
+const (
+	forecastConfidence          = 95.0
+	defaultMovingAverageWindow  = 3
+	maxMovingAverageWindow      = 24
+	forecastSeasonLength        = 12 // Months
+	forecastNotEnoughHistoryFmt = "not enough history for %s, %d values"
+	forecastNoIntervalNote      = "too little history to estimate an interval"
+)
+
+// tQuantiles95 holds the two-sided 95% quantiles of the t distribution for 1 to 30
+// degrees of freedom; above that the normal quantile is used
+var tQuantiles95 = []float64{12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042}
+
+// ForecastOptions selects what to forecast
+type ForecastOptions struct {
+	Year   int // Fiscal year
+	Method string
+	AsOf   time.Time // Last period treated as known, later periods are forecast
+	Window int       // Values averaged by the moving average
+	RoleID int       // 0 for every role
+	KPIID  int       // 0 for every KPI
+}
+
+// ForecastPoint is the actual or forecast value of a KPI for one due period of the
+// fiscal year. Low and High are left out when there is no interval.
+type ForecastPoint struct {
+	Period  string   `json:"period"` // YYYY-MM
+	Value   float64  `json:"value"`
+	Low     *float64 `json:"low,omitempty"`
+	High    *float64 `json:"high,omitempty"`
+	Actual  bool     `json:"actual"`
+	Missing bool     `json:"missing,omitempty"` // Past period without a measurement, not counted
+}
+
+// KPIForecast projects the year-end result of a KPI against its cumulative annual
+// target. The low and high values are left out when the history is too short to
+// estimate an interval.
+type KPIForecast struct {
+	KPIID     int     `json:"kpi_id"`
+	KPIName   string  `json:"kpi_name"`
+	Unit      string  `json:"unit"`
+	Operator  string  `json:"operator"`
+	Weight    float64 `json:"weight"`
+	Frequency string  `json:"frequency"`
+	Method    string  `json:"method"`
+	History   int     `json:"history"` // Values the forecast is based on
+	Projected bool    `json:"projected"`
+	Note      string  `json:"note,omitempty"`
+
+	Points         []ForecastPoint `json:"points"`
+	AnnualTarget   float64         `json:"annual_target"` // Target value times the counted due periods
+	YTDActual      float64         `json:"ytd_actual"`
+	MissingPeriods int             `json:"missing_periods"`
+
+	ProjectedTotal       float64  `json:"projected_total"`
+	ProjectedLow         *float64 `json:"projected_low,omitempty"`
+	ProjectedHigh        *float64 `json:"projected_high,omitempty"`
+	ProjectedAchievement float64  `json:"projected_achievement"`
+	AchievementLow       *float64 `json:"achievement_low,omitempty"`
+	AchievementHigh      *float64 `json:"achievement_high,omitempty"`
+	RequiredValue        *float64 `json:"required_value,omitempty"` // Average per remaining period that meets the annual target, a maximum for lower-is-better KPIs
+}
+
+// RoleForecast projects the year-end score of a role from its KPI forecasts. The
+// score has an interval when every projected KPI has one.
+type RoleForecast struct {
+	RoleID         int           `json:"role_id"`
+	RoleName       string        `json:"role_name"`
+	ProjectedScore float64       `json:"projected_score"`
+	ScoreLow       *float64      `json:"score_low,omitempty"`
+	ScoreHigh      *float64      `json:"score_high,omitempty"`
+	Coverage       float64       `json:"coverage"` // Percentage of KPI weight with a projection
+	KPIs           []KPIForecast `json:"kpis"`
+}
+
+// ForecastReport holds the year-end forecasts of every selected role
+type ForecastReport struct {
+	Year        int            `json:"year"` // Fiscal year
+	Method      string         `json:"method"`
+	AsOf        string         `json:"as_of"`
+	Confidence  float64        `json:"confidence"`
+	Roles       []RoleForecast `json:"roles"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// defaultForecastOptions forecasts the current fiscal year from the last closed month
+func defaultForecastOptions(now time.Time) ForecastOptions {
+	return ForecastOptions{
+		Year:   fiscalYearOf(now),
+		Method: ForecastLinear,
+		AsOf:   lastClosedPeriod(now),
+		Window: defaultMovingAverageWindow,
+	}
+}
+
+// tQuantile returns the 95% t quantile for the degrees of freedom. Without
+// degrees of freedom the spread is unknown and there is no quantile.
+func tQuantile(df int) (float64, bool) {
+	if df < 1 {
+		return 0, false
+	}
+	if df <= len(tQuantiles95) {
+		return tQuantiles95[df-1], true
+	}
+	return 1.96, true
+}
+
+// kpiPeriodValue returns the measurement submitted for a due period, taken from
+// any month the period covers
+func kpiPeriodValue(kpi KPI, period time.Time) *Measurement {
+	for i := 0; i < frequencyMonths(kpiFrequency(kpi)); i++ {
+		if m := getExistingMeasurement(kpi.ID, period.AddDate(0, -i, 0)); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// forecastObservation is one known value of a KPI
+type forecastObservation struct {
+	period time.Time
+	value  float64
+}
+
+// monthIndex numbers months consecutively for the trend
+func monthIndex(period time.Time) float64 {
+	return float64(period.Year()*12 + int(period.Month()) - 1)
+}
+
+// kpiHistory returns the values of a KPI for its due periods up to asOf
+func kpiHistory(kpi KPI, asOf time.Time) []forecastObservation {
+	var first time.Time
+	for _, m := range measurements {
+		if m.KPIID == kpi.ID && !m.Period.After(asOf) && (first.IsZero() || m.Period.Before(first)) {
+			first = m.Period
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	var history []forecastObservation
+	for _, period := range periodsInRange(first, asOf) {
+		if !isKPIDue(kpi, period) {
+			continue
+		}
+		if m := kpiPeriodValue(kpi, period); m != nil {
+			history = append(history, forecastObservation{period, m.MetricValue})
+		}
+	}
+	return history
+}
+
+// forecastModel predicts a value and its standard error for a future period.
+// known holds actual and already forecast values by period.
+type forecastModel func(period time.Time, known map[string]float64) (value, stderr float64)
+
+// buildForecastModel fits a method to the history. It also returns the degrees of
+// freedom of the error estimate and a note when the method had to fall back.
+func buildForecastModel(method string, history []forecastObservation, window int) (forecastModel, int, string, error) {
+	n := len(history)
+	values := make([]float64, n)
+	for i, o := range history {
+		values[i] = o.value
+	}
+
+	switch method {
+	case ForecastLinear:
+		if n < 2 {
+			return nil, 0, "", fmt.Errorf(forecastNotEnoughHistoryFmt, method, n)
+		}
+		var xMean, yMean float64
+		for _, o := range history {
+			xMean += monthIndex(o.period)
+			yMean += o.value
+		}
+		xMean /= float64(n)
+		yMean /= float64(n)
+
+		var sxx, sxy float64
+		for _, o := range history {
+			dx := monthIndex(o.period) - xMean
+			sxx += dx * dx
+			sxy += dx * (o.value - yMean)
+		}
+		slope := sxy / sxx
+		intercept := yMean - slope*xMean
+
+		var sse float64
+		for _, o := range history {
+			residual := o.value - (intercept + slope*monthIndex(o.period))
+			sse += residual * residual
+		}
+		var s float64
+		if n > 2 {
+			s = math.Sqrt(sse / float64(n-2))
+		}
+
+		return func(period time.Time, known map[string]float64) (float64, float64) {
+			x := monthIndex(period)
+			return intercept + slope*x, s * math.Sqrt(1+1/float64(n)+(x-xMean)*(x-xMean)/sxx)
+		}, n - 2, "", nil
+
+	case ForecastMovingAverage:
+		if n < 1 {
+			return nil, 0, "", fmt.Errorf(forecastNotEnoughHistoryFmt, method, n)
+		}
+		last := values[max(0, n-window):]
+		mean, s := meanAndStdDev(last)
+		return func(period time.Time, known map[string]float64) (float64, float64) {
+			return mean, s * math.Sqrt(1+1/float64(len(last)))
+		}, len(last) - 1, "", nil
+
+	case ForecastSeasonalNaive:
+		if n < 1 {
+			return nil, 0, "", fmt.Errorf(forecastNotEnoughHistoryFmt, method, n)
+		}
+		// The spread of year-over-year changes, or of the values without a full season
+		byPeriod := make(map[string]float64)
+		for _, o := range history {
+			byPeriod[o.period.Format("2006-01")] = o.value
+		}
+		var changes []float64
+		for _, o := range history {
+			if previous, ok := byPeriod[o.period.AddDate(0, -forecastSeasonLength, 0).Format("2006-01")]; ok {
+				changes = append(changes, o.value-previous)
+			}
+		}
+		note := ""
+		spread := changes
+		if len(changes) < 2 {
+			spread = values
+			note = "less than two year-over-year changes, the interval uses the spread of the values"
+		}
+		_, s := meanAndStdDev(spread)
+		lastValue := values[n-1]
+
+		return func(period time.Time, known map[string]float64) (float64, float64) {
+			if value, ok := known[period.AddDate(0, -forecastSeasonLength, 0).Format("2006-01")]; ok {
+				return value, s
+			}
+			return lastValue, s
+		}, len(spread) - 1, note, nil
+	}
+
+	return nil, 0, "", fmt.Errorf("unknown forecast method '%s'", method)
+}
+
+// meanAndStdDev returns the mean and sample standard deviation of values
+func meanAndStdDev(values []float64) (mean, stddev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sum / float64(len(values)-1))
+}
+
+// forecastKPI forecasts the remaining due periods of a KPI in the fiscal year and
+// projects the year-end total against the cumulative annual target
+func forecastKPI(kpi KPI, opts ForecastOptions) KPIForecast {
+	f := KPIForecast{
+		KPIID:     kpi.ID,
+		KPIName:   kpi.Name,
+		Unit:      kpi.Unit,
+		Operator:  kpi.Operator,
+		Weight:    kpi.Weight,
+		Frequency: kpiFrequency(kpi),
+		Method:    opts.Method,
+		Points:    []ForecastPoint{},
+	}
+
+	history := kpiHistory(kpi, opts.AsOf)
+	f.History = len(history)
+	nonNegative := true
+	known := make(map[string]float64)
+	for _, o := range history {
+		known[o.period.Format("2006-01")] = o.value
+		if o.value < 0 {
+			nonNegative = false
+		}
+	}
+
+	var future []time.Time
+	counted := 0
+	year := fiscalYearRange(opts.Year)
+	for period := year.Start; !period.After(year.End); period = period.AddDate(0, 1, 0) {
+		if !isKPIDue(kpi, period) {
+			continue
+		}
+		if period.After(opts.AsOf) {
+			future = append(future, period)
+			continue
+		}
+		point := ForecastPoint{Period: period.Format("2006-01"), Actual: true}
+		if value, ok := known[point.Period]; ok {
+			point.Value, point.Low, point.High = value, &value, &value
+			f.YTDActual += value
+			counted++
+		} else {
+			point.Missing = true
+			f.MissingPeriods++
+		}
+		f.Points = append(f.Points, point)
+	}
+
+	var variance float64
+	interval := true
+	if len(future) > 0 {
+		model, df, note, err := buildForecastModel(opts.Method, history, opts.Window)
+		if err != nil {
+			f.Note = err.Error()
+			return f
+		}
+		f.Note = note
+		var t float64
+		if t, interval = tQuantile(df); !interval {
+			f.Note = forecastNoIntervalNote
+		}
+
+		for _, period := range future {
+			value, stderr := model(period, known)
+			if nonNegative && value < 0 {
+				value = 0
+			}
+			point := ForecastPoint{Period: period.Format("2006-01"), Value: value}
+			if interval {
+				halfWidth := t * stderr
+				low, high := value-halfWidth, value+halfWidth
+				if nonNegative && low < 0 {
+					low = 0
+				}
+				point.Low, point.High = &low, &high
+				variance += halfWidth * halfWidth
+			}
+			known[point.Period] = value
+			f.Points = append(f.Points, point)
+
+			f.ProjectedTotal += value
+			counted++
+		}
+	}
+
+	if counted == 0 {
+		f.Note = "no measurements in the year"
+		return f
+	}
+
+	f.ProjectedTotal += f.YTDActual
+	if interval {
+		// Errors of the periods are treated as independent
+		halfWidth := math.Sqrt(variance)
+		low, high := f.ProjectedTotal-halfWidth, f.ProjectedTotal+halfWidth
+		if nonNegative && low < 0 {
+			low = 0
+		}
+		f.ProjectedLow, f.ProjectedHigh = &low, &high
+	}
+	f.AnnualTarget = kpi.TargetValue * float64(counted)
+
+	if !hasNumericTarget(kpi) {
+		if f.Note == "" {
+			f.Note = "no numeric target"
+		}
+		return f
+	}
+	f.Projected = true
+
+	annual := kpi
+	annual.TargetValue = f.AnnualTarget
+	achievement := func(total float64) float64 {
+		return calculateAchievement(annual, &Measurement{MetricValue: total})
+	}
+	f.ProjectedAchievement = achievement(f.ProjectedTotal)
+	if interval {
+		low, high := achievement(*f.ProjectedLow), achievement(*f.ProjectedHigh)
+		low, high = math.Min(low, high), math.Max(low, high)
+		f.AchievementLow, f.AchievementHigh = &low, &high
+	}
+
+	if len(future) > 0 {
+		required := math.Max(0, (f.AnnualTarget-f.YTDActual)/float64(len(future)))
+		f.RequiredValue = &required
+	}
+
+	return f
+}
+
+// buildForecastReport forecasts every selected KPI and combines them into role
+// scores, leaving out KPIs without a projection like the exclude scoring policy
+func buildForecastReport(opts ForecastOptions) ForecastReport {
+	report := ForecastReport{
+		Year:        opts.Year,
+		Method:      opts.Method,
+		AsOf:        opts.AsOf.Format("2006-01"),
+		Confidence:  forecastConfidence,
+		Roles:       []RoleForecast{},
+		GeneratedAt: time.Now(),
+	}
+
+	for _, role := range roles {
+		if opts.RoleID != 0 && role.ID != opts.RoleID {
+			continue
+		}
+
+		rf := RoleForecast{RoleID: role.ID, RoleName: role.Name, KPIs: []KPIForecast{}}
+		var score, low, high, projectedWeight, totalWeight float64
+		interval := true
+		for _, kpi := range getKPIsByRoleID(role.ID) {
+			if opts.KPIID != 0 && kpi.ID != opts.KPIID {
+				continue
+			}
+			f := forecastKPI(kpi, opts)
+			rf.KPIs = append(rf.KPIs, f)
+
+			totalWeight += kpi.Weight
+			if f.Projected {
+				score += f.ProjectedAchievement * kpi.Weight
+				projectedWeight += kpi.Weight
+				if f.AchievementLow == nil {
+					interval = false
+					continue
+				}
+				low += *f.AchievementLow * kpi.Weight
+				high += *f.AchievementHigh * kpi.Weight
+			}
+		}
+		if len(rf.KPIs) == 0 {
+			continue
+		}
+
+		if projectedWeight > 0 {
+			rf.ProjectedScore = score / projectedWeight
+			if interval {
+				scoreLow, scoreHigh := low/projectedWeight, high/projectedWeight
+				rf.ScoreLow, rf.ScoreHigh = &scoreLow, &scoreHigh
+			}
+		}
+		if totalWeight > 0 {
+			rf.Coverage = projectedWeight / totalWeight * 100
+		}
+		report.Roles = append(report.Roles, rf)
+	}
+
+	return report
+}
+
+// validateForecastOptions checks the options and returns the first invalid parameter
+func validateForecastOptions(opts ForecastOptions) *ParamError {
+	if opts.Year < 2000 || opts.Year > 2100 {
+		return &ParamError{"year", "must be between 2000 and 2100"}
+	}
+	if !containsString(forecastMethods, opts.Method) {
+		return &ParamError{"method", "must be one of " + strings.Join(forecastMethods, ", ")}
+	}
+	if opts.Window < 1 || opts.Window > maxMovingAverageWindow {
+		return &ParamError{"window", fmt.Sprintf("must be a number between 1 and %d", maxMovingAverageWindow)}
+	}
+	if opts.RoleID != 0 && getRoleByID(opts.RoleID) == nil {
+		return &ParamError{"role_id", "role not found"}
+	}
+	if opts.KPIID != 0 {
+		kpi := getKPIByID(opts.KPIID)
+		if kpi == nil {
+			return &ParamError{"kpi_id", "KPI not found"}
+		}
+		if opts.RoleID != 0 && kpi.RoleID != opts.RoleID {
+			return &ParamError{"kpi_id", "KPI does not belong to the role"}
+		}
+	}
+	return nil
+}
+
+// parseForecastQuery parses the query of a forecast request
+func parseForecastQuery(query url.Values, now time.Time) (ForecastOptions, *ParamError) {
+	opts := defaultForecastOptions(now)
+	allowed := []string{"year", "method", "as_of", "window", "role_id", "kpi_id"}
+	for name := range query {
+		if !containsString(allowed, name) {
+			return opts, &ParamError{name, "unknown parameter, allowed: " + strings.Join(allowed, ", ")}
+		}
+	}
+
+	for name, target := range map[string]*int{"year": &opts.Year, "window": &opts.Window, "role_id": &opts.RoleID, "kpi_id": &opts.KPIID} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return opts, &ParamError{name, "must be a positive number"}
+			}
+			*target = n
+		}
+	}
+	if value := query.Get("method"); value != "" {
+		opts.Method = value
+	}
+	if value := query.Get("as_of"); value != "" {
+		asOf, err := parsePeriodString(value)
+		if err != nil {
+			return opts, &ParamError{"as_of", err.Error()}
+		}
+		opts.AsOf = asOf
+	}
+
+	return opts, validateForecastOptions(opts)
+}
+
+// getForecast returns year-end forecasts per KPI and role
+func getForecast(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	opts, perr := parseForecastQuery(r.URL.Query(), time.Now())
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	json.NewEncoder(w).Encode(buildForecastReport(opts))
+}
+
+// formatForecastRange formats a projected value with its interval, if any
+func formatForecastRange(value float64, low, high *float64) string {
+	if low == nil || high == nil {
+		return fmt.Sprintf("%.2f", value)
+	}
+	return fmt.Sprintf("%.2f (%.2f-%.2f)", value, *low, *high)
+}
+
+// printForecast prints a forecast report as text
+func printForecast(w io.Writer, report ForecastReport) {
+	fmt.Fprintf(w, "\nYear-end forecast %d (%s, as of %s, %.0f%% interval)\n", report.Year, report.Method, report.AsOf, report.Confidence)
+	if len(report.Roles) == 0 {
+		fmt.Fprintln(w, "\nNo roles with KPIs.")
+		return
+	}
+
+	for _, rf := range report.Roles {
+		score := fmt.Sprintf("%.2f%%", rf.ProjectedScore)
+		if rf.ScoreLow != nil {
+			score += fmt.Sprintf(" (%.2f%%-%.2f%%)", *rf.ScoreLow, *rf.ScoreHigh)
+		}
+		fmt.Fprintf(w, "\n%s: projected score %s, coverage %.0f%%\n", rf.RoleName, score, rf.Coverage)
+		fmt.Fprintf(w, "%-35s %12s %28s %12s %24s %12s\n", "KPI", "YTD", "Projected", "Target", "Achievement %", "Needed/period")
+		fmt.Fprintln(w, strings.Repeat("-", 130))
+		for _, f := range rf.KPIs {
+			if !f.Projected {
+				fmt.Fprintf(w, "%-35s %12.2f   %s\n", truncateString(f.KPIName, 35), f.YTDActual, f.Note)
+				continue
+			}
+			needed := "-"
+			if f.RequiredValue != nil {
+				needed = fmt.Sprintf("%.2f", *f.RequiredValue)
+			}
+			fmt.Fprintf(w, "%-35s %12.2f %28s %12.2f %24s %12s\n", truncateString(f.KPIName, 35), f.YTDActual,
+				formatForecastRange(f.ProjectedTotal, f.ProjectedLow, f.ProjectedHigh), f.AnnualTarget,
+				formatForecastRange(f.ProjectedAchievement, f.AchievementLow, f.AchievementHigh), needed)
+		}
+	}
+}
+
+// handleForecast shows year-end forecasts from the interactive menu
+func handleForecast(scanner *bufio.Scanner) {
+	fmt.Println("\n=== Year-end Forecast ===")
+	opts := defaultForecastOptions(time.Now())
+
+	fmt.Printf("Fiscal year (default: %d): ", opts.Year)
+	scanner.Scan()
+	if value := strings.TrimSpace(scanner.Text()); value != "" {
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			fmt.Println("Invalid year.")
+			return
+		}
+		opts.Year = year
+	}
+
+	fmt.Println("\nForecast method:")
+	for i, method := range forecastMethods {
+		fmt.Printf("%d. %s\n", i+1, method)
+	}
+	fmt.Print("Enter your choice (default: 1): ")
+	scanner.Scan()
+	if value := strings.TrimSpace(scanner.Text()); value != "" {
+		choice, err := strconv.Atoi(value)
+		if err != nil || choice < 1 || choice > len(forecastMethods) {
+			fmt.Println("Invalid choice.")
+			return
+		}
+		opts.Method = forecastMethods[choice-1]
+	}
+
+	if perr := validateForecastOptions(opts); perr != nil {
+		fmt.Printf("Error: %s %s\n", perr.Parameter, perr.Message)
+		return
+	}
+	printForecast(os.Stdout, buildForecastReport(opts))
+}
+
+// runForecastCommand prints year-end forecasts
+func runForecastCommand(args []string) int {
+	defaults := defaultForecastOptions(time.Now())
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	year := fs.Int("year", defaults.Year, "fiscal year to forecast")
+	method := fs.String("method", defaults.Method, "forecast method: "+strings.Join(forecastMethods, ", "))
+	asOf := fs.String("as-of", defaults.AsOf.Format("2006-01"), "last known period YYYY-MM, later periods are forecast")
+	window := fs.Int("window", defaults.Window, "values averaged by moving_average")
+	roleID := fs.Int("role", 0, "only forecast this role ID")
+	kpiID := fs.Int("kpi", 0, "only forecast this KPI ID")
+	format := fs.String("format", "txt", "output format: txt or json")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	opts := ForecastOptions{Year: *year, Method: *method, Window: *window, RoleID: *roleID, KPIID: *kpiID}
+	var err error
+	if opts.AsOf, err = parsePeriodString(*asOf); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if perr := validateForecastOptions(opts); perr != nil {
+		return usageError(fs, "--%s %s", strings.ReplaceAll(strings.TrimSuffix(perr.Parameter, "_id"), "_", "-"), perr.Message)
+	}
+	if *format != "txt" && *format != "json" {
+		return usageError(fs, "unsupported format %s", *format)
+	}
+
+	report := buildForecastReport(opts)
+	if *format == "json" {
+		json.NewEncoder(cliOutput).Encode(report)
+	} else {
+		printForecast(cliOutput, report)
+	}
+	return exitOK
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForecastFollowsFiscalYear(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.FiscalYearStartMonth = 4
+	for i, value := range []float64{90, 110, 100} {
+		measurements = append(measurements, Measurement{ID: 10 + i, KPIID: 1, MetricValue: value, Period: time.Date(2026, time.Month(4+i), 1, 0, 0, 0, 0, time.Local)})
+	}
+
+	opts := ForecastOptions{Year: 2026, Method: ForecastLinear, AsOf: time.Date(2026, 6, 1, 0, 0, 0, 0, time.Local), Window: 3}
+	f := forecastKPI(*getKPIByID(1), opts)
+	if len(f.Points) != 12 || f.Points[0].Period != "2026-04" || f.Points[11].Period != "2027-03" {
+		t.Fatalf("points %+v, want April 2026 to March 2027", f.Points)
+	}
+	if f.YTDActual != 300 || f.MissingPeriods != 0 {
+		t.Errorf("year to date %.2f with %d missing, want 300 with none", f.YTDActual, f.MissingPeriods)
+	}
+	if f.ProjectedLow == nil || f.Points[3].Low == nil || *f.Points[3].Low >= f.Points[3].Value {
+		t.Errorf("forecast %+v, want an interval", f)
+	}
+
+	if defaultForecastOptions(time.Date(2027, 2, 10, 0, 0, 0, 0, time.Local)).Year != 2026 {
+		t.Error("February 2027 should default to fiscal year 2026")
+	}
+}
+
+func TestForecastWithoutIntervalAddsNote(t *testing.T) {
+	setupSubmissionTest(t)
+	for i, value := range []float64{90, 110} {
+		measurements = append(measurements, Measurement{ID: 10 + i, KPIID: 1, MetricValue: value, Period: time.Date(2026, time.Month(1+i), 1, 0, 0, 0, 0, time.Local)})
+	}
+	asOf := time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)
+
+	// Two values fit a line exactly and one value has no spread
+	for _, opts := range []ForecastOptions{
+		{Year: 2026, Method: ForecastLinear, AsOf: asOf, Window: 3},
+		{Year: 2026, Method: ForecastMovingAverage, AsOf: asOf, Window: 1},
+	} {
+		f := forecastKPI(*getKPIByID(1), opts)
+		if !f.Projected || f.Note != forecastNoIntervalNote {
+			t.Errorf("%s: projected %v with note %q, want a projection without interval", opts.Method, f.Projected, f.Note)
+		}
+		if f.ProjectedLow != nil || f.AchievementLow != nil || f.Points[2].Low != nil {
+			t.Errorf("%s: forecast has an interval", opts.Method)
+		}
+	}
+
+	report := buildForecastReport(ForecastOptions{Year: 2026, Method: ForecastLinear, AsOf: asOf, Window: 3, KPIID: 1})
+	if len(report.Roles) != 1 || report.Roles[0].ScoreLow != nil {
+		t.Errorf("roles %+v, want a score without interval", report.Roles)
+	}
+}
+
+func TestValidateForecastOptionsKPI(t *testing.T) {
+	setupSubmissionTest(t)
+	roles = append(roles, Role{ID: 2, Name: "Support"})
+	opts := defaultForecastOptions(time.Now())
+
+	opts.KPIID = 99
+	if perr := validateForecastOptions(opts); perr == nil || perr.Parameter != "kpi_id" {
+		t.Errorf("unknown KPI: %v, want a kpi_id error", perr)
+	}
+	opts.KPIID, opts.RoleID = 1, 2
+	if perr := validateForecastOptions(opts); perr == nil || perr.Parameter != "kpi_id" {
+		t.Errorf("KPI of another role: %v, want a kpi_id error", perr)
+	}
+	opts.RoleID = 1
+	if perr := validateForecastOptions(opts); perr != nil {
+		t.Errorf("valid options: %v", perr)
+	}
+}
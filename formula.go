@@ -0,0 +1,668 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+// Derived KPIs have a formula instead of a typed-in value, e.g.
+// "leavers / avg_headcount * 100". Names in the formula are raw inputs entered
+// with the measurement, except kpi_<id>, which is the value of another KPI for
+// the same period. Formulas are parsed and evaluated here, never executed.
+
+// kpiRefPrefix starts the names that refer to another KPI, e.g. kpi_12
+const kpiRefPrefix = "kpi_"
+
+// maxFormulaLength limits the size, and so the nesting depth, of a formula
+const maxFormulaLength = 500
+
+// formulaFunctions are the functions a formula can call, with their argument count
+// (-1 for one or more). round rounds to two decimals.
+var formulaFunctions = map[string]int{
+	"min":   -1,
+	"max":   -1,
+	"abs":   1,
+	"round": 1,
+}
+
+// formulaNode is a node of a parsed formula
+type formulaNode struct {
+	op    byte // 'n' number, 'v' name, 'f' function call, '~' negation, or + - * /
+	value float64
+	name  string
+	args  []*formulaNode
+}
+
+// formulaParser is a recursive descent parser over a formula's tokens
+type formulaParser struct {
+	tokens []string
+	pos    int
+}
+
+// tokenizeFormula splits a formula into numbers, names, operators and parentheses
+func tokenizeFormula(src string) ([]string, error) {
+	var tokens []string
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/(),", r):
+			tokens = append(tokens, string(r))
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", r)
+		}
+	}
+	return tokens, nil
+}
+
+// parseFormula parses a formula of numbers, names, + - * /, parentheses and the
+// functions min, max, abs and round
+func parseFormula(src string) (*formulaNode, error) {
+	if len(src) > maxFormulaLength {
+		return nil, fmt.Errorf("formula is longer than %d characters", maxFormulaLength)
+	}
+	tokens, err := tokenizeFormula(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("formula is empty")
+	}
+
+	p := &formulaParser{tokens: tokens}
+	node, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s'", p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+// peek returns the next token, or "" at the end
+func (p *formulaParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// expression parses terms joined by + and -
+func (p *formulaParser) expression() (*formulaNode, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &formulaNode{op: op[0], args: []*formulaNode{left, right}}
+	}
+	return left, nil
+}
+
+// term parses factors joined by * and /
+func (p *formulaParser) term() (*formulaNode, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "*" || op == "/"; op = p.peek() {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = &formulaNode{op: op[0], args: []*formulaNode{left, right}}
+	}
+	return left, nil
+}
+
+// factor parses a signed number, name, function call or parenthesized expression
+func (p *formulaParser) factor() (*formulaNode, error) {
+	token := p.peek()
+	p.pos++
+
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("formula ends unexpectedly")
+	case token == "-":
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return &formulaNode{op: '~', args: []*formulaNode{operand}}, nil
+	case token == "+":
+		return p.factor()
+	case token == "(":
+		node, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return node, nil
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", token)
+		}
+		return &formulaNode{op: 'n', value: value}, nil
+	case token[0] == '_' || unicode.IsLetter(rune(token[0])):
+		if p.peek() != "(" {
+			return &formulaNode{op: 'v', name: token}, nil
+		}
+		p.pos++
+		return p.call(token)
+	}
+	return nil, fmt.Errorf("unexpected '%s'", token)
+}
+
+// call parses the arguments of a function call after the opening parenthesis
+func (p *formulaParser) call(name string) (*formulaNode, error) {
+	arity, ok := formulaFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s'", name)
+	}
+
+	node := &formulaNode{op: 'f', name: name}
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if p.peek() != ")" {
+		return nil, fmt.Errorf("missing ')' after the arguments of %s", name)
+	}
+	p.pos++
+
+	if arity >= 0 && len(node.args) != arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, arity, len(node.args))
+	}
+	return node, nil
+}
+
+// names appends the names used in the formula
+func (n *formulaNode) names(names []string) []string {
+	if n.op == 'v' && !containsString(names, n.name) {
+		names = append(names, n.name)
+	}
+	for _, arg := range n.args {
+		names = arg.names(names)
+	}
+	return names
+}
+
+// eval evaluates the formula with the given values of its names
+func (n *formulaNode) eval(values map[string]float64) (float64, error) {
+	switch n.op {
+	case 'n':
+		return n.value, nil
+	case 'v':
+		value, ok := values[n.name]
+		if !ok {
+			return 0, fmt.Errorf("no value for '%s'", n.name)
+		}
+		return value, nil
+	}
+
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(values)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = value
+	}
+
+	switch n.op {
+	case '~':
+		return -args[0], nil
+	case '+':
+		return args[0] + args[1], nil
+	case '-':
+		return args[0] - args[1], nil
+	case '*':
+		return args[0] * args[1], nil
+	case '/':
+		if args[1] == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return args[0] / args[1], nil
+	}
+
+	switch n.name {
+	case "min", "max":
+		result := args[0]
+		for _, value := range args[1:] {
+			if n.name == "min" {
+				result = math.Min(result, value)
+			} else {
+				result = math.Max(result, value)
+			}
+		}
+		return result, nil
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "round":
+		return math.Round(args[0]*100) / 100, nil
+	}
+	return 0, fmt.Errorf("unknown function '%s'", n.name)
+}
+
+// isDerivedKPI reports whether a KPI's value is computed from a formula
+func isDerivedKPI(kpi KPI) bool {
+	return strings.TrimSpace(kpi.Formula) != ""
+}
+
+// hasNumericTarget reports whether achievement can be measured against the KPI's
+// target. Derived KPIs may be tracked without one.
+func hasNumericTarget(kpi KPI) bool {
+	return kpi.TargetValue != 0
+}
+
+// parseKPIRef returns the KPI ID of a kpi_<id> name
+func parseKPIRef(name string) (int, bool) {
+	if !strings.HasPrefix(name, kpiRefPrefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(name, kpiRefPrefix))
+	return id, err == nil
+}
+
+// kpiFormulaNames returns the raw inputs of a derived KPI in the order they appear
+// in the formula, and the sorted IDs of the KPIs it refers to. Both are empty for
+// other KPIs or formulas that do not parse.
+func kpiFormulaNames(kpi KPI) (inputs []string, refs []int) {
+	if !isDerivedKPI(kpi) {
+		return nil, nil
+	}
+	node, err := parseFormula(kpi.Formula)
+	if err != nil {
+		return nil, nil
+	}
+	for _, name := range node.names(nil) {
+		if id, ok := parseKPIRef(name); ok {
+			refs = append(refs, id)
+		} else {
+			inputs = append(inputs, name)
+		}
+	}
+	sort.Ints(refs)
+	return inputs, refs
+}
+
+// validateFormula checks the formula of a KPI: it must parse, refer only to
+// existing KPIs and not depend on itself, directly or through other KPIs
+func validateFormula(kpi KPI) error {
+	node, err := parseFormula(kpi.Formula)
+	if err != nil {
+		return err
+	}
+	for _, name := range node.names(nil) {
+		if strings.HasPrefix(name, kpiRefPrefix) {
+			id, ok := parseKPIRef(name)
+			if !ok {
+				return fmt.Errorf("'%s' is not a KPI reference, use %s<id>", name, kpiRefPrefix)
+			}
+			if id == kpi.ID && kpi.ID != 0 {
+				return fmt.Errorf("formula cannot refer to the KPI itself")
+			}
+			if getKPIByID(id) == nil {
+				return fmt.Errorf("KPI %d in '%s' not found", id, name)
+			}
+		}
+	}
+
+	// Follow the references with the new formula in place of the stored one
+	formulaOf := func(id int) KPI {
+		if id == kpi.ID {
+			return kpi
+		}
+		if k := getKPIByID(id); k != nil {
+			return *k
+		}
+		return KPI{}
+	}
+	visited := make(map[int]bool)
+	var dependsOnKPI func(id int) bool
+	dependsOnKPI = func(id int) bool {
+		_, refs := kpiFormulaNames(formulaOf(id))
+		for _, ref := range refs {
+			if ref == kpi.ID {
+				return true
+			}
+			if !visited[ref] {
+				visited[ref] = true
+				if dependsOnKPI(ref) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if kpi.ID != 0 && dependsOnKPI(kpi.ID) {
+		return fmt.Errorf("formula depends on the KPI itself")
+	}
+	return nil
+}
+
+// computeDerivedValue evaluates the formula of a derived KPI for a period with the
+// given raw inputs and the values of the referenced KPIs in the measurements
+func computeDerivedValue(kpi KPI, inputs map[string]float64, period time.Time) (float64, error) {
+	return computeDerivedValueFrom(kpi, inputs, period, measurements)
+}
+
+// computeDerivedValueFrom evaluates a formula with the values of the referenced
+// KPIs taken from a set of measurements, e.g. a batch or a scenario
+func computeDerivedValueFrom(kpi KPI, inputs map[string]float64, period time.Time, data []Measurement) (float64, error) {
+	node, err := parseFormula(kpi.Formula)
+	if err != nil {
+		return 0, fmt.Errorf("invalid formula: %v", err)
+	}
+
+	required, refs := kpiFormulaNames(kpi)
+	values := make(map[string]float64)
+	var missing []string
+	for _, name := range required {
+		value, ok := inputs[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		values[name] = value
+	}
+	if len(missing) > 0 {
+		return 0, fmt.Errorf("missing inputs: %s", strings.Join(missing, ", "))
+	}
+	for name := range inputs {
+		if !containsString(required, name) {
+			return 0, fmt.Errorf("'%s' is not an input of the formula %s", name, kpi.Formula)
+		}
+	}
+	for _, id := range refs {
+		m := findMeasurementIn(data, id, period)
+		if m == nil {
+			return 0, fmt.Errorf("KPI %d has no value for %s", id, period.Format("2006-01"))
+		}
+		values[fmt.Sprintf("%s%d", kpiRefPrefix, id)] = m.MetricValue
+	}
+
+	value, err := node.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("formula has no finite value")
+	}
+	return value, nil
+}
+
+// resolveMeasurementValue returns the value to store for a KPI: the typed-in value,
+// or for a derived KPI the formula's value over the inputs and the referenced KPIs
+// in data. Inputs are only accepted for derived KPIs.
+func resolveMeasurementValue(kpi KPI, value float64, inputs map[string]float64, period time.Time, data []Measurement) (float64, error) {
+	if !isDerivedKPI(kpi) {
+		if len(inputs) > 0 {
+			return 0, fmt.Errorf("KPI %d has no formula, send metric_value instead", kpi.ID)
+		}
+		return value, nil
+	}
+	return computeDerivedValueFrom(kpi, inputs, period, data)
+}
+
+// getKPIDependents returns the derived KPIs whose formula refers to a KPI
+func getKPIDependents(kpiID int) []KPI {
+	var dependents []KPI
+	for _, kpi := range kpis {
+		_, refs := kpiFormulaNames(kpi)
+		for _, ref := range refs {
+			if ref == kpiID {
+				dependents = append(dependents, kpi)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// recomputeDerivedKPI recomputes the value of a derived KPI for a period from the
+// stored raw inputs and the current values of the KPIs it refers to. A KPI with
+// raw inputs is only recomputed once they have been entered.
+func recomputeDerivedKPI(kpi KPI, period time.Time) {
+	// Closed periods keep their values
+	if isPeriodClosed(period) {
+		return
+	}
+	existing := getExistingMeasurement(kpi.ID, period)
+	stored, ok := storedFormulaInputs(kpi, existing)
+	if !ok {
+		return
+	}
+	notes := "Computed from " + kpi.Formula
+	if existing != nil {
+		notes = existing.Notes
+	}
+
+	value, err := computeDerivedValue(kpi, stored, period)
+	if err != nil {
+		// Without a stored value this only means a referenced KPI has no value yet
+		if existing != nil {
+			fmt.Printf("Warning: could not recompute KPI %d for %s: %v\n", kpi.ID, period.Format("2006-01"), err)
+		}
+		return
+	}
+	if existing != nil && existing.MetricValue == value && len(existing.Inputs) == len(stored) {
+		return
+	}
+	saveMeasurement(kpi.ID, value, stored, kpi.Unit, period, notes)
+}
+
+// storedFormulaInputs returns the raw inputs of a derived KPI's stored value.
+// Inputs a changed formula no longer uses are dropped. ok is false when the
+// formula has raw inputs but there is no stored value to take them from.
+func storedFormulaInputs(kpi KPI, existing *Measurement) (stored map[string]float64, ok bool) {
+	inputs, _ := kpiFormulaNames(kpi)
+	if existing == nil {
+		return nil, len(inputs) == 0
+	}
+	for _, name := range inputs {
+		if value, ok := existing.Inputs[name]; ok {
+			if stored == nil {
+				stored = make(map[string]float64)
+			}
+			stored[name] = value
+		}
+	}
+	return stored, true
+}
+
+// recomputeDependents recomputes the derived KPIs that refer to a KPI after its
+// value for a period changed. saveMeasurement calls it, so chains of derived KPIs
+// are followed; validateFormula rules out cycles.
+func recomputeDependents(kpiID int, period time.Time) {
+	for _, kpi := range getKPIDependents(kpiID) {
+		recomputeDerivedKPI(kpi, period)
+	}
+}
+
+// recomputeKPI recomputes every stored value of a derived KPI after its formula
+// changed, and for a formula over other KPIs only, fills in the periods in which
+// they all have values
+func recomputeKPI(kpi KPI) {
+	if !isDerivedKPI(kpi) {
+		return
+	}
+
+	var periods []time.Time
+	addPeriod := func(period time.Time) {
+		for _, p := range periods {
+			if p.Equal(period) {
+				return
+			}
+		}
+		periods = append(periods, period)
+	}
+	inputs, refs := kpiFormulaNames(kpi)
+	for _, m := range measurements {
+		if m.KPIID == kpi.ID || (len(inputs) == 0 && len(refs) > 0 && m.KPIID == refs[0]) {
+			addPeriod(m.Period)
+		}
+	}
+
+	for _, period := range periods {
+		recomputeDerivedKPI(kpi, period)
+	}
+}
+
+// parseFormulaInputs parses raw inputs such as "leavers=3, avg_headcount=40"
+func parseFormulaInputs(value string) (map[string]float64, error) {
+	inputs := make(map[string]float64)
+	for _, item := range splitList(value) {
+		name, number, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected name=value, got '%s'", item)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in '%s'", item)
+		}
+		inputs[strings.TrimSpace(name)] = v
+	}
+	return inputs, nil
+}
+
+// formatFormulaInputs formats raw inputs sorted by name, the reverse of parseFormulaInputs
+func formatFormulaInputs(inputs map[string]float64) string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + strconv.FormatFloat(inputs[name], 'f', -1, 64)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// KPIFormula describes the formula of a derived KPI
+type KPIFormula struct {
+	KPIID      int      `json:"kpi_id"`
+	Formula    string   `json:"formula"`
+	Inputs     []string `json:"inputs"`     // Raw inputs to send with a measurement
+	KPIIDs     []int    `json:"kpi_ids"`    // KPIs the formula refers to
+	Dependents []int    `json:"dependents"` // Derived KPIs that refer to this KPI
+}
+
+// getKPIFormula returns the inputs of a KPI's formula and the KPIs that depend on it
+func getKPIFormula(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid KPI ID")
+		return
+	}
+
+	kpi := getKPIByID(id)
+	if kpi == nil {
+		writeError(w, http.StatusNotFound, "KPI not found")
+		return
+	}
+
+	inputs, refs := kpiFormulaNames(*kpi)
+	formula := KPIFormula{KPIID: kpi.ID, Formula: kpi.Formula, Inputs: []string{}, KPIIDs: []int{}, Dependents: []int{}}
+	formula.Inputs = append(formula.Inputs, inputs...)
+	formula.KPIIDs = append(formula.KPIIDs, refs...)
+	for _, dependent := range getKPIDependents(kpi.ID) {
+		formula.Dependents = append(formula.Dependents, dependent.ID)
+	}
+	json.NewEncoder(w).Encode(formula)
+}
+
+// inputFormulaValues asks for the raw inputs of a derived KPI, offering the stored
+// ones as defaults, and returns the computed value
+func inputFormulaValues(scanner *bufio.Scanner, kpi KPI, period time.Time, existing *Measurement) (float64, map[string]float64, bool) {
+	fmt.Printf("Formula: %s\n", kpi.Formula)
+
+	names, _ := kpiFormulaNames(kpi)
+	if len(names) == 0 {
+		fmt.Println("Computed automatically from the KPIs in its formula.")
+		return 0, nil, false
+	}
+
+	inputs := make(map[string]float64)
+	for _, name := range names {
+		current, hasCurrent := 0.0, false
+		if existing != nil {
+			current, hasCurrent = existing.Inputs[name]
+		}
+		if hasCurrent {
+			fmt.Printf("  %s [%g]: ", name, current)
+		} else {
+			fmt.Printf("  %s (enter to skip the KPI): ", name)
+		}
+		scanner.Scan()
+		text := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case text == "" && hasCurrent:
+			inputs[name] = current
+		case text == "":
+			return 0, nil, false
+		default:
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				fmt.Println("Invalid number. Skipping.")
+				return 0, nil, false
+			}
+			inputs[name] = value
+		}
+	}
+
+	value, err := computeDerivedValue(kpi, inputs, period)
+	if err != nil {
+		fmt.Printf("%v. Skipping.\n", err)
+		return 0, nil, false
+	}
+	fmt.Printf("Computed value: %.2f %s\n", value, kpi.Unit)
+	return value, inputs, true
+}
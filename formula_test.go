@@ -0,0 +1,281 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupFormulaTest adds two derived KPIs to the test data: Margin is half the
+// revenue and Bonus is the margin plus a raw input
+func setupFormulaTest(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.DatabasePath = t.TempDir()
+	t.Cleanup(discardPendingChanges)
+	kpis = append(kpis,
+		KPI{ID: 3, RoleID: 1, Name: "Margin", Operator: "≥", TargetValue: 50, Weight: 25, Formula: "kpi_1 / 2"},
+		KPI{ID: 4, RoleID: 1, Name: "Bonus", Operator: "≥", TargetValue: 60, Weight: 25, Formula: "kpi_3 + extra"},
+	)
+}
+
+// evalFormula parses and evaluates a formula
+func evalFormula(src string, values map[string]float64) (float64, error) {
+	node, err := parseFormula(src)
+	if err != nil {
+		return 0, err
+	}
+	return node.eval(values)
+}
+
+func TestTokenizeFormula(t *testing.T) {
+	tokens, err := tokenizeFormula("round(leavers/ avg_headcount*100) - kpi_12 + .5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"round", "(", "leavers", "/", "avg_headcount", "*", "100", ")", "-", "kpi_12", "+", ".5"}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("tokens %q, want %q", tokens, want)
+	}
+
+	for _, src := range []string{"a % b", "a; b", "a = 1"} {
+		if _, err := tokenizeFormula(src); err == nil {
+			t.Errorf("%q should not tokenize", src)
+		}
+	}
+}
+
+func TestFormulaEvaluation(t *testing.T) {
+	values := map[string]float64{"a": 6, "b": 3}
+	for src, want := range map[string]float64{
+		"2 + 3 * 4":         14,
+		"(2 + 3) * 4":       20,
+		"10 - 4 - 3":        3,
+		"24 / 4 / 2":        3,
+		"-a + b":            -3,
+		"-(a - b) * 2":      -6,
+		"a * -b":            -18,
+		"--a":               6,
+		"min(a, b, 4)":      3,
+		"max(a)":            6,
+		"abs(b - a)":        3,
+		"round(a / 7)":      0.86,
+		"a / b * 100 - 100": 100,
+	} {
+		got, err := evalFormula(src, values)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+		} else if got != want {
+			t.Errorf("%s = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestFormulaErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"a +",
+		"(a + b",
+		"a b",
+		"abs(a, b)",
+		"round()",
+		"min()",
+		"sqrt(a)",
+		strings.Repeat("a+", maxFormulaLength/2) + "a",
+	} {
+		if _, err := parseFormula(src); err == nil {
+			t.Errorf("%q should not parse", src)
+		}
+	}
+
+	if _, err := evalFormula("a / (b - 3)", map[string]float64{"a": 1, "b": 3}); err == nil {
+		t.Error("division by zero should fail")
+	}
+	if _, err := evalFormula("a + c", map[string]float64{"a": 1}); err == nil {
+		t.Error("a missing value should fail")
+	}
+}
+
+func TestValidateFormula(t *testing.T) {
+	setupFormulaTest(t)
+	for _, tt := range []struct {
+		kpi KPI
+		ok  bool
+	}{
+		{KPI{ID: 5, Formula: "kpi_1 + kpi_4"}, true},
+		{KPI{Formula: "kpi_3 * 2"}, true},
+		{KPI{ID: 5, Formula: "kpi_9"}, false},
+		{KPI{ID: 5, Formula: "kpi_x"}, false},
+		{KPI{ID: 3, Formula: "kpi_3 + 1"}, false},
+		// Margin referring to Bonus, which already depends on Margin
+		{KPI{ID: 3, Formula: "kpi_4 / 2"}, false},
+		// Revenue becoming derived from Bonus closes a longer cycle
+		{KPI{ID: 1, Formula: "kpi_4"}, false},
+	} {
+		if err := validateFormula(tt.kpi); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.kpi.Formula, err, tt.ok)
+		}
+	}
+}
+
+func TestComputeDerivedValue(t *testing.T) {
+	setupFormulaTest(t)
+	may := month(5)
+	margin, bonus := *getKPIByID(3), *getKPIByID(4)
+
+	if _, err := computeDerivedValue(margin, nil, may); err == nil {
+		t.Error("a referenced KPI without a value should fail")
+	}
+	addRevenue(200, may)
+	if value, err := computeDerivedValue(margin, nil, may); err != nil || value != 100 {
+		t.Errorf("margin %v, %v, want 100", value, err)
+	}
+	if _, err := computeDerivedValue(margin, map[string]float64{"extra": 1}, may); err == nil {
+		t.Error("an input the formula does not use should fail")
+	}
+	if _, err := computeDerivedValue(bonus, nil, may); err == nil {
+		t.Error("a missing input should fail")
+	}
+
+	ratio := KPI{ID: 5, Formula: "kpi_1 / (total - 1)"}
+	if _, err := computeDerivedValue(ratio, map[string]float64{"total": 1}, may); err == nil {
+		t.Error("division by zero should fail")
+	}
+	overflow := KPI{ID: 5, Formula: "kpi_1 * big * big"}
+	if _, err := computeDerivedValue(overflow, map[string]float64{"big": 1e200}, may); err == nil {
+		t.Error("a value that is not finite should fail")
+	}
+}
+
+func TestRecomputeChain(t *testing.T) {
+	setupFormulaTest(t)
+	may := month(5)
+
+	// Bonus needs its raw input before it is computed
+	saveMeasurement(1, 200, nil, "", may, "")
+	if m := getExistingMeasurement(3, may); m == nil || m.MetricValue != 100 {
+		t.Fatalf("margin %+v, want 100", m)
+	}
+	if m := getExistingMeasurement(4, may); m != nil {
+		t.Fatalf("bonus %+v computed without its input", m)
+	}
+
+	saveMeasurement(4, 110, map[string]float64{"extra": 10}, "", may, "")
+	saveMeasurement(1, 300, nil, "", may, "")
+	if m := getExistingMeasurement(3, may); m == nil || m.MetricValue != 150 {
+		t.Errorf("margin %+v, want 150", m)
+	}
+	if m := getExistingMeasurement(4, may); m == nil || m.MetricValue != 160 || m.Inputs["extra"] != 10 {
+		t.Errorf("bonus %+v, want 160 with extra 10", m)
+	}
+}
+
+func TestMeasurementBatchResolvesReferences(t *testing.T) {
+	setupFormulaTest(t)
+
+	// Bonus refers to Margin and Margin to Revenue, all in the same batch and
+	// listed before the entries they need
+	body := `[
+		{"kpi_id": 4, "period": "2026-05-01T00:00:00Z", "inputs": {"extra": 5}},
+		{"kpi_id": 3, "period": "2026-05-01T00:00:00Z"},
+		{"kpi_id": 1, "period": "2026-05-01T00:00:00Z", "metric_value": 120}
+	]`
+	rec := httptest.NewRecorder()
+	createMeasurementBatch(rec, httptest.NewRequest("POST", "/api/measurements/batch?confirm=true", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	may := time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local)
+	for id, want := range map[int]float64{1: 120, 3: 60, 4: 65} {
+		if m := getExistingMeasurement(id, may); m == nil || m.MetricValue != want {
+			t.Errorf("KPI %d: %+v, want %v", id, m, want)
+		}
+	}
+}
+
+func TestSimulateDerivedKPIs(t *testing.T) {
+	setupFormulaTest(t)
+	may := month(5)
+	saveMeasurement(1, 100, nil, "", may, "")
+	saveMeasurement(4, 60, map[string]float64{"extra": 10}, "", may, "")
+	discardPendingChanges()
+
+	result, errs := simulate(SimulationRequest{RoleID: 1, Start: "2026-05", Values: []SimulationValue{{KPIID: 1, Value: 160}}})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for _, kpi := range result.KPIs {
+		if want, ok := map[int]float64{1: 160, 3: 80, 4: 90}[kpi.KPIID]; ok && kpi.Value != want {
+			t.Errorf("KPI %d: %v, want %v", kpi.KPIID, kpi.Value, want)
+		}
+	}
+	if m := getExistingMeasurement(3, may); m == nil || m.MetricValue != 50 {
+		t.Errorf("saved margin %+v, want 50", m)
+	}
+
+	_, errs = simulate(SimulationRequest{RoleID: 1, Start: "2026-05", Values: []SimulationValue{{KPIID: 3, Value: 80}}})
+	if len(errs) != 1 || errs[0].Field != "values[0].kpi_id" {
+		t.Errorf("errors %v, want values[0].kpi_id", errs)
+	}
+	_, errs = simulate(SimulationRequest{RoleID: 1, Start: "2026-05", Solve: &SimulationSolve{KPIID: 4, TargetScore: 90}})
+	if len(errs) != 1 || errs[0].Field != "solve.kpi_id" {
+		t.Errorf("errors %v, want solve.kpi_id", errs)
+	}
+
+	// Solving for Revenue counts the margin it moves, which needs 60 here
+	target := 60.0
+	result, errs = simulate(SimulationRequest{RoleID: 1, Start: "2026-05", KPIs: []SimulationKPIChange{{KPIID: 3, TargetValue: &target}},
+		Solve: &SimulationSolve{KPIID: 1, TargetScore: 100}})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if s := result.Solution; s == nil || !s.Achievable || s.RequiredValue == nil || *s.RequiredValue != 120 {
+		t.Errorf("solution %+v, want a minimum of 120", result.Solution)
+	}
+}
+
+func TestDerivedKPIWithoutTarget(t *testing.T) {
+	setupFormulaTest(t)
+	may := month(5)
+	saveMeasurement(1, 80, nil, "", may, "")
+	saveMeasurement(4, 50, map[string]float64{"extra": 10}, "", may, "")
+	before := calculateScoreResultFrom(getKPIsByRoleID(1), may, measurements)
+
+	// A derived KPI tracked without a target is left out of the score
+	tracked := KPI{ID: 5, RoleID: 1, Name: "Double", Category: "Quantitative", Unit: "x", Operator: "≥", Weight: 10, Formula: "kpi_1 * 2"}
+	if errs := validateKPI(tracked); len(errs) > 0 {
+		t.Fatalf("derived KPI without a target: %v", errs)
+	}
+	kpis = append(kpis, tracked)
+	recomputeKPI(tracked)
+	if m := getExistingMeasurement(5, may); m == nil || m.MetricValue != 160 {
+		t.Fatalf("tracked value %+v, want 160", m)
+	}
+
+	after := calculateScoreResultFrom(getKPIsByRoleID(1), may, measurements)
+	if after.TotalKPIs != before.TotalKPIs || after.TotalKPIs != 4 {
+		t.Errorf("total KPIs %d, want %d", after.TotalKPIs, before.TotalKPIs)
+	}
+	if after.Score != before.Score || after.Coverage != before.Coverage || after.MeasuredKPIs != before.MeasuredKPIs {
+		t.Errorf("score %+v, want %+v", after, before)
+	}
+	if achievement := calculateAchievement(tracked, getExistingMeasurement(5, may)); achievement != 0 {
+		t.Errorf("achievement %v, want 0 without a target", achievement)
+	}
+
+	// With every scored KPI measured the score is complete
+	appSettings.ScoringPolicy = PolicyIncomplete
+	saveMeasurement(2, 100, nil, "", may, "")
+	if result := calculateScoreResultFrom(getKPIsByRoleID(1), may, measurements); result.Incomplete {
+		t.Errorf("result %+v, want complete", result)
+	}
+
+	// Other KPIs still need a target
+	tracked.Formula = ""
+	if errs := validateKPI(tracked); len(errs) != 1 || errs[0].Field != "target_value" {
+		t.Errorf("errors %v, want target_value", errs)
+	}
+}
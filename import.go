@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Import fields that can be mapped to file columns
+const (
+	importFieldKPIID    = "kpi_id"
+	importFieldKPI      = "kpi"
+	importFieldRole     = "role"
+	importFieldEmployee = "employee"
+	importFieldPeriod   = "period"
+	importFieldValue    = "value"
+	importFieldNotes    = "notes"
+)
+
+// importFieldAliases are the column headers recognised for each field when no mapping is given
+var importFieldAliases = map[string][]string{
+	importFieldKPIID:    {"kpi_id", "kpi id", "kpiid"},
+	importFieldKPI:      {"kpi", "kpi name", "kpi_name"},
+	importFieldRole:     {"role", "role name", "role_id", "role id"},
+	importFieldEmployee: {"employee", "employee name", "employee_id", "employee id"},
+	importFieldPeriod:   {"period", "month"},
+	importFieldValue:    {"value", "metric_value", "metric value", "actual"},
+	importFieldNotes:    {"notes", "note", "comment", "comments"},
+}
+
+// Import row actions
+const (
+	importActionInsert = "insert"
+	importActionUpdate = "update"
+	importActionError  = "error"
+)
+
+// ImportOptions controls how a measurement file is read and applied
+type ImportOptions struct {
+	Format     string            // "csv" or "xlsx", taken from the file name when empty
+	Sheet      string            // xlsx sheet, the first sheet when empty
+	Mapping    map[string]string // field -> column header, overrides the aliases
+	DryRun     bool              // Validate and report without saving
+	SkipErrors bool              // Apply the valid rows even if some rows fail
+}
+
+// ImportRowResult is the outcome of one data row
+type ImportRowResult struct {
+	Row     int     `json:"row"` // Row number in the file, the header is row 1
+	KPIID   int     `json:"kpi_id,omitempty"`
+	KPIName string  `json:"kpi_name,omitempty"`
+	Period  string  `json:"period,omitempty"`
+	Value   float64 `json:"value"`
+	Notes   string  `json:"notes,omitempty"`
+	Action  string  `json:"action"`
+	Error   string  `json:"error,omitempty"`
+	Warning string  `json:"warning,omitempty"` // The value looks anomalous, see anomalies.go
+}
+
+// ImportResult summarises a measurement import
+type ImportResult struct {
+	DryRun   bool              `json:"dry_run"`
+	Applied  bool              `json:"applied"`
+	Inserted int               `json:"inserted"`
+	Updated  int               `json:"updated"`
+	Failed   int               `json:"failed"`
+	Rows     []ImportRowResult `json:"rows"`
+}
+
+// parseImportMapping parses a mapping such as "value=Actual,period=Month"
+func parseImportMapping(spec string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid mapping '%s', expected field=Column", pair)
+		}
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+		if _, ok := importFieldAliases[field]; !ok {
+			return nil, fmt.Errorf("unknown import field '%s'", field)
+		}
+		mapping[field] = strings.TrimSpace(parts[1])
+	}
+
+	return mapping, nil
+}
+
+// importFormat returns the import format from the option or the file name
+func importFormat(format, filename string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+	switch format {
+	case "csv", "xlsx":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported import format '%s', use csv or xlsx", format)
+	}
+}
+
+// readImportRows reads all rows of a CSV or xlsx file
+func readImportRows(r io.Reader, format, sheet string) ([][]string, error) {
+	if format == "csv" {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %v", err)
+		}
+		// Spreadsheet programs often add a byte order mark
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %v", err)
+		}
+		return rows, nil
+	}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xlsx: %v", err)
+	}
+	defer f.Close()
+
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	}
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet '%s': %v", sheet, err)
+	}
+	return rows, nil
+}
+
+// resolveImportColumns maps each import field to a column index of the header row
+func resolveImportColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := make(map[string]int)
+	for field, aliases := range importFieldAliases {
+		if column, ok := mapping[field]; ok {
+			i, found := index[strings.ToLower(column)]
+			if !found {
+				return nil, fmt.Errorf("column '%s' mapped to %s not found", column, field)
+			}
+			columns[field] = i
+			continue
+		}
+		for _, alias := range aliases {
+			if i, found := index[alias]; found {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	if _, ok := columns[importFieldPeriod]; !ok {
+		return nil, fmt.Errorf("missing period column")
+	}
+	if _, ok := columns[importFieldValue]; !ok {
+		return nil, fmt.Errorf("missing value column")
+	}
+	_, hasID := columns[importFieldKPIID]
+	_, hasName := columns[importFieldKPI]
+	if !hasID && !hasName {
+		return nil, fmt.Errorf("missing KPI column, map kpi_id or kpi")
+	}
+
+	return columns, nil
+}
+
+// importPeriodLayouts are the accepted period formats, normalised to the first of the month
+var importPeriodLayouts = []string{"2006-01", "2006-01-02", "Jan 2006", "January 2006", "01/2006", "1/2/2006", "1-2-06"}
+
+// parseImportPeriod parses a period cell
+func parseImportPeriod(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range importPeriodLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid period '%s', use YYYY-MM", value)
+}
+
+// findImportRole finds a role by ID or name
+func findImportRole(value string) *Role {
+	value = strings.TrimSpace(value)
+	id, idErr := strconv.Atoi(value)
+	for i, role := range roles {
+		if (idErr == nil && role.ID == id) || strings.EqualFold(role.Name, value) {
+			return &roles[i]
+		}
+	}
+	return nil
+}
+
+// findImportEmployee finds an employee by ID or name
+func findImportEmployee(value string) *Employee {
+	value = strings.TrimSpace(value)
+	id, idErr := strconv.Atoi(value)
+	for i, employee := range employees {
+		if (idErr == nil && employee.ID == id) || strings.EqualFold(employee.Name, value) {
+			return &employees[i]
+		}
+	}
+	return nil
+}
+
+// resolveImportKPI finds the KPI of a row. KPI names are shared between roles, so a
+// name needs the role or employee column unless it is unique.
+func resolveImportKPI(cell func(string) string) (*KPI, error) {
+	roleID := 0
+	if value := cell(importFieldRole); value != "" {
+		role := findImportRole(value)
+		if role == nil {
+			return nil, fmt.Errorf("role '%s' not found", value)
+		}
+		roleID = role.ID
+	}
+	if value := cell(importFieldEmployee); value != "" {
+		employee := findImportEmployee(value)
+		if employee == nil {
+			return nil, fmt.Errorf("employee '%s' not found", value)
+		}
+		if roleID != 0 && employee.RoleID != roleID {
+			return nil, fmt.Errorf("employee '%s' does not hold the given role", value)
+		}
+		roleID = employee.RoleID
+	}
+
+	if value := cell(importFieldKPIID); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KPI ID '%s'", value)
+		}
+		kpi := getKPIByID(id)
+		if kpi == nil {
+			return nil, fmt.Errorf("KPI %d not found", id)
+		}
+		if roleID != 0 && kpi.RoleID != roleID {
+			return nil, fmt.Errorf("KPI %d does not belong to the given role", id)
+		}
+		return kpi, nil
+	}
+
+	name := cell(importFieldKPI)
+	if name == "" {
+		return nil, fmt.Errorf("KPI is empty")
+	}
+
+	var matches []*KPI
+	for i, kpi := range kpis {
+		if strings.EqualFold(kpi.Name, name) && (roleID == 0 || kpi.RoleID == roleID) {
+			matches = append(matches, &kpis[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("KPI '%s' not found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("KPI '%s' exists for %d roles, add a role or employee column", name, len(matches))
+	}
+}
+
+// parseImportValue parses a value cell, allowing a trailing percent sign
+func parseImportValue(value string) (float64, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "%")
+	if value == "" {
+		return 0, fmt.Errorf("value is empty")
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", value)
+	}
+	return v, nil
+}
+
+// errImportNotSaved is wrapped by the error of an import whose rows were valid
+// but could not be saved
+var errImportNotSaved = fmt.Errorf("failed to save to Excel")
+
+// importMeasurements validates every row of a measurement file and, unless this
+// is a dry run, saves the valid rows and the Excel database once. By default a
+// single failing row stops the whole import. The caller holds dataMu.
+func importMeasurements(r io.Reader, filename string, options ImportOptions) (ImportResult, error) {
+	result := ImportResult{DryRun: options.DryRun, Rows: []ImportRowResult{}}
+
+	format, err := importFormat(options.Format, filename)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := readImportRows(r, format, options.Sheet)
+	if err != nil {
+		return result, err
+	}
+	if len(rows) < 2 {
+		return result, fmt.Errorf("the file has no data rows")
+	}
+
+	columns, err := resolveImportColumns(rows[0], options.Mapping)
+	if err != nil {
+		return result, err
+	}
+
+	// Rows for the same KPI and period within the file would overwrite each other
+	seen := make(map[string]int)
+
+	for i, row := range rows[1:] {
+		rowNumber := i + 2
+		cell := func(field string) string {
+			col, ok := columns[field]
+			if !ok || col >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[col])
+		}
+
+		// Skip blank lines
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		rowResult := ImportRowResult{Row: rowNumber, Notes: cell(importFieldNotes)}
+		rowErr := func() error {
+			kpi, err := resolveImportKPI(cell)
+			if err != nil {
+				return err
+			}
+			rowResult.KPIID = kpi.ID
+			rowResult.KPIName = kpi.Name
+			if isDerivedKPI(*kpi) {
+				return fmt.Errorf("KPI %d is computed from %s, enter its inputs instead", kpi.ID, kpi.Formula)
+			}
+
+			period, err := parseImportPeriod(cell(importFieldPeriod))
+			if err != nil {
+				return err
+			}
+			rowResult.Period = period.Format("2006-01")
+			if isPeriodClosed(period) {
+				return fmt.Errorf("period %s is closed", rowResult.Period)
+			}
+
+			value, err := parseImportValue(cell(importFieldValue))
+			if err != nil {
+				return err
+			}
+			rowResult.Value = value
+
+			// Same rules as the interactive input
+			if err := validateMeasurementValue(*kpi, value); err != nil {
+				return err
+			}
+			if anomalies := checkMeasurementValue(*kpi, value, period); len(anomalies) > 0 {
+				rowResult.Warning = anomalyMessages(anomalies)
+			}
+
+			key := fmt.Sprintf("%d/%s", kpi.ID, rowResult.Period)
+			if first, ok := seen[key]; ok {
+				return fmt.Errorf("duplicate of row %d", first)
+			}
+			seen[key] = rowNumber
+
+			return nil
+		}()
+
+		switch {
+		case rowErr != nil:
+			rowResult.Action = importActionError
+			rowResult.Error = rowErr.Error()
+			result.Failed++
+		case getExistingMeasurement(rowResult.KPIID, mustParsePeriod(rowResult.Period)) != nil:
+			rowResult.Action = importActionUpdate
+			result.Updated++
+		default:
+			rowResult.Action = importActionInsert
+			result.Inserted++
+		}
+
+		result.Rows = append(result.Rows, rowResult)
+	}
+
+	if options.DryRun || (result.Failed > 0 && !options.SkipErrors) {
+		return result, nil
+	}
+
+	// Keep a copy so a failed save leaves the data unchanged
+	previous := make([]Measurement, len(measurements))
+	copy(previous, measurements)
+
+	for _, row := range result.Rows {
+		if row.Action == importActionError {
+			continue
+		}
+		kpi := getKPIByID(row.KPIID)
+		saveMeasurement(kpi.ID, row.Value, nil, kpi.Unit, mustParsePeriod(row.Period), row.Notes)
+	}
+
+	if result.Inserted+result.Updated > 0 {
+		if err := saveToExcel(); err != nil {
+			measurements = previous
+			discardPendingChanges()
+			return result, fmt.Errorf("%w: %v", errImportNotSaved, err)
+		}
+	}
+	result.Applied = true
+
+	return result, nil
+}
+
+// mustParsePeriod parses a period already validated as YYYY-MM
+func mustParsePeriod(period string) time.Time {
+	t, _ := parsePeriodString(period)
+	return t
+}
+
+// formatImportReport formats an import result as a plain-text report
+func formatImportReport(result ImportResult) string {
+	var sb strings.Builder
+
+	for _, row := range result.Rows {
+		switch row.Action {
+		case importActionError:
+			sb.WriteString(fmt.Sprintf("Row %-4d ERROR   %s\n", row.Row, row.Error))
+		default:
+			sb.WriteString(fmt.Sprintf("Row %-4d %-7s %s %s = %.2f\n",
+				row.Row, strings.ToUpper(row.Action), row.Period, row.KPIName, row.Value))
+			if row.Warning != "" {
+				sb.WriteString(fmt.Sprintf("         WARNING %s\n", row.Warning))
+			}
+		}
+	}
+
+	status := "applied"
+	switch {
+	case result.DryRun:
+		status = "dry run, nothing saved"
+	case !result.Applied:
+		status = "not applied because of errors"
+	}
+	sb.WriteString(fmt.Sprintf("\n%d inserts, %d updates, %d errors (%s)\n",
+		result.Inserted, result.Updated, result.Failed, status))
+
+	return sb.String()
+}
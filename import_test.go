@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// failExcelSaves points the database at a path below a file, so saveToExcel fails
+func failExcelSaves(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	appSettings.DatabasePath = filepath.Join(file, "data")
+}
+
+// importRequest builds a multipart import request with a CSV file and form fields
+func importRequest(t *testing.T, csv string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "measurements.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(csv))
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	form.Close()
+
+	r := httptest.NewRequest("POST", "/api/measurements/import", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return r
+}
+
+func TestImportSaveFailure(t *testing.T) {
+	setupSubmissionTest(t)
+	t.Cleanup(discardPendingChanges)
+	failExcelSaves(t)
+
+	rec := httptest.NewRecorder()
+	importMeasurementsAPI(rec, importRequest(t, "kpi_id,period,value\n1,2026-03,120\n", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500: %s", rec.Code, rec.Body)
+	}
+	if len(measurements) != 1 {
+		t.Errorf("%d measurements, want the imported row rolled back", len(measurements))
+	}
+}
+
+// setupImportTest adds a Support role that also has a Revenue KPI, so KPI names
+// are ambiguous without a role or employee column
+func setupImportTest(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.DatabasePath = t.TempDir()
+	t.Cleanup(discardPendingChanges)
+	roles = append(roles, Role{ID: 2, Name: "Support"})
+	employees = append(employees, Employee{ID: 8, Name: "Eka", RoleID: 2})
+	kpis = append(kpis, KPI{ID: 5, RoleID: 2, Name: "Revenue", Operator: "≥", TargetValue: 50, Weight: 100, Unit: "%"})
+}
+
+func TestParseImportMapping(t *testing.T) {
+	for _, tt := range []struct {
+		spec string
+		want map[string]string
+		ok   bool
+	}{
+		{"", map[string]string{}, true},
+		{"value=Actual, Period = Month ", map[string]string{"value": "Actual", "period": "Month"}, true},
+		{"kpi=Indicator Name", map[string]string{"kpi": "Indicator Name"}, true},
+		{"value", nil, false},
+		{"value=", nil, false},
+		{"amount=Actual", nil, false},
+	} {
+		mapping, err := parseImportMapping(tt.spec)
+		if (err == nil) != tt.ok || (tt.ok && !reflect.DeepEqual(mapping, tt.want)) {
+			t.Errorf("%q: %v, %v, want %v", tt.spec, mapping, err, tt.want)
+		}
+	}
+}
+
+func TestParseImportPeriod(t *testing.T) {
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	for _, value := range []string{"2026-03", "2026-03-17", "Mar 2026", "March 2026", "03/2026", " 2026-03 "} {
+		if period, err := parseImportPeriod(value); err != nil || !period.Equal(march) {
+			t.Errorf("%q: %v, %v, want March 2026", value, period, err)
+		}
+	}
+	for _, value := range []string{"", "2026", "2026-13", "Q1 2026"} {
+		if _, err := parseImportPeriod(value); err == nil {
+			t.Errorf("%q should not parse", value)
+		}
+	}
+}
+
+func TestResolveImportColumns(t *testing.T) {
+	for _, tt := range []struct {
+		header  []string
+		mapping map[string]string
+		want    map[string]int
+		ok      bool
+	}{
+		{[]string{"KPI ID", "Month", "Actual"}, nil, map[string]int{"kpi_id": 0, "period": 1, "value": 2}, true},
+		{[]string{" kpi name ", "Role", "Period", "Metric Value", "Comment"}, nil,
+			map[string]int{"kpi": 0, "role": 1, "period": 2, "value": 3, "notes": 4}, true},
+		{[]string{"Indicator", "When", "Result", "Value"}, map[string]string{"kpi": "indicator", "period": "When", "value": "Result"},
+			map[string]int{"kpi": 0, "period": 1, "value": 2}, true},
+		{[]string{"kpi_id", "period"}, nil, nil, false},
+		{[]string{"kpi_id", "value"}, nil, nil, false},
+		{[]string{"period", "value"}, nil, nil, false},
+		{[]string{"kpi_id", "period", "value"}, map[string]string{"value": "Actual"}, nil, false},
+	} {
+		columns, err := resolveImportColumns(tt.header, tt.mapping)
+		if (err == nil) != tt.ok || (tt.ok && !reflect.DeepEqual(columns, tt.want)) {
+			t.Errorf("%q with %v: %v, %v, want %v", tt.header, tt.mapping, columns, err, tt.want)
+		}
+	}
+}
+
+func TestImportMeasurements(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		csv      string
+		options  ImportOptions
+		actions  []string
+		applied  bool
+		errorRow string
+	}{
+		{
+			name:    "aliases, percent values and a byte order mark",
+			csv:     "\xef\xbb\xbfKPI Name,Role,Month,Actual,Comment\nRevenue,Sales,Mar 2026,95%,late\n\nRevenue,support,2026-03,40,\n",
+			actions: []string{importActionInsert, importActionInsert},
+			applied: true,
+		},
+		{
+			name:    "employee column and an update",
+			csv:     "employee,kpi,period,value\nDewi,Audit,2026-02,95\n8,Revenue,2026-03,40\n",
+			actions: []string{importActionUpdate, importActionInsert},
+			applied: true,
+		},
+		{
+			name:     "one bad row stops the import",
+			csv:      "kpi_id,period,value\n1,2026-03,120\n9,2026-03,10\n",
+			actions:  []string{importActionInsert, importActionError},
+			errorRow: "KPI 9 not found",
+		},
+		{
+			name:     "skip errors applies the valid rows",
+			csv:      "kpi_id,period,value\n1,2026-03,120\n5,2026-03,140\n",
+			options:  ImportOptions{SkipErrors: true},
+			actions:  []string{importActionInsert, importActionError},
+			applied:  true,
+			errorRow: "Percentage must be between 0 and 100",
+		},
+		{
+			name:     "duplicate rows",
+			csv:      "kpi_id,period,value\n1,2026-03,120\n1,Mar 2026,130\n",
+			actions:  []string{importActionInsert, importActionError},
+			errorRow: "duplicate of row 2",
+		},
+		{
+			name:     "name shared by two roles",
+			csv:      "kpi,period,value\nRevenue,2026-03,120\nAudit,2026-03,90\n",
+			actions:  []string{importActionError, importActionInsert},
+			errorRow: "KPI 'Revenue' exists for 2 roles",
+		},
+		{
+			name:     "employee of another role",
+			csv:      "kpi_id,employee,period,value\n1,Eka,2026-03,120\n",
+			actions:  []string{importActionError},
+			errorRow: "KPI 1 does not belong to the given role",
+		},
+		{
+			name:    "dry run",
+			csv:     "kpi_id,period,value\n1,2026-03,120\n2,2026-02,95\n",
+			options: ImportOptions{DryRun: true},
+			actions: []string{importActionInsert, importActionUpdate},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setupImportTest(t)
+			before := append([]Measurement(nil), measurements...)
+
+			result, err := importMeasurements(strings.NewReader(tt.csv), "measurements.csv", tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var actions []string
+			errorRow := ""
+			for _, row := range result.Rows {
+				actions = append(actions, row.Action)
+				if row.Error != "" {
+					errorRow = row.Error
+				}
+			}
+			if !reflect.DeepEqual(actions, tt.actions) || result.Applied != tt.applied || !strings.HasPrefix(errorRow, tt.errorRow) {
+				t.Errorf("actions %v applied %v error %q, want %v %v %q", actions, result.Applied, errorRow, tt.actions, tt.applied, tt.errorRow)
+			}
+
+			if !tt.applied && !reflect.DeepEqual(measurements, before) {
+				t.Errorf("measurements %+v, want nothing saved", measurements)
+			}
+			if tt.applied {
+				for _, row := range result.Rows {
+					m := getExistingMeasurement(row.KPIID, mustParsePeriod(row.Period))
+					if saved := m != nil && m.MetricValue == row.Value; saved != (row.Action != importActionError) {
+						t.Errorf("row %d: measurement %+v after %s", row.Row, m, row.Action)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestImportMeasurementsFileErrors(t *testing.T) {
+	setupImportTest(t)
+	for _, tt := range []struct {
+		filename string
+		content  string
+		options  ImportOptions
+	}{
+		{"measurements.txt", "kpi_id,period,value\n1,2026-03,120\n", ImportOptions{}},
+		{"measurements.csv", "kpi_id,period,value\n", ImportOptions{}},
+		{"measurements.csv", "kpi_id,period\n1,2026-03\n", ImportOptions{}},
+		{"measurements.csv", "kpi_id,period,value\n\"1,2026-03,120\n", ImportOptions{}},
+		{"measurements.csv", "not a workbook", ImportOptions{Format: "xlsx"}},
+	} {
+		if _, err := importMeasurements(strings.NewReader(tt.content), tt.filename, tt.options); err == nil {
+			t.Errorf("%s %q: want an error", tt.filename, tt.content)
+		}
+	}
+}
+
+func TestImportMeasurementsXLSX(t *testing.T) {
+	setupImportTest(t)
+	f := excelize.NewFile()
+	f.NewSheet("Actuals")
+	f.SetSheetRow("Actuals", "A1", &[]interface{}{"KPI ID", "Period", "Value"})
+	f.SetSheetRow("Actuals", "A2", &[]interface{}{1, "2026-04", 110})
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := importMeasurements(bytes.NewReader(buf.Bytes()), "actuals.xlsx", ImportOptions{}); err == nil {
+		t.Error("the empty first sheet should have no data rows")
+	}
+	result, err := importMeasurements(bytes.NewReader(buf.Bytes()), "actuals.xlsx", ImportOptions{Sheet: "Actuals"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Applied || result.Inserted != 1 {
+		t.Errorf("result %+v, want one insert", result)
+	}
+	if m := getExistingMeasurement(1, time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)); m == nil || m.MetricValue != 110 {
+		t.Errorf("measurement %+v, want 110", m)
+	}
+}
+
+func TestImportMeasurementsAPI(t *testing.T) {
+	setupImportTest(t)
+	for _, tt := range []struct {
+		csv    string
+		fields map[string]string
+		status int
+		field  string
+	}{
+		{"kpi_id,period,value\n1,2026-03,120\n", map[string]string{"dry_run": "true"}, http.StatusOK, ""},
+		{"Indicator,When,Result\nAudit,2026-03,90\n", map[string]string{"map": "kpi=Indicator,period=When,value=Result"}, http.StatusOK, ""},
+		{"kpi_id,period,value\n1,2026-03,120\n1,2026-13,120\n", nil, http.StatusUnprocessableEntity, "row 3"},
+		{"kpi_id,period,value\n1,2026-03,120\n1,2026-13,120\n", map[string]string{"skip_errors": "true"}, http.StatusOK, ""},
+		{"kpi_id,period,value\n1,2026-03,120\n", map[string]string{"map": "amount=Actual"}, http.StatusBadRequest, ""},
+		{"kpi_id,period\n1,2026-03\n", nil, http.StatusBadRequest, ""},
+	} {
+		rec := httptest.NewRecorder()
+		importMeasurementsAPI(rec, importRequest(t, tt.csv, tt.fields))
+		if rec.Code != tt.status {
+			t.Errorf("%q %v: status %d, want %d: %s", tt.csv, tt.fields, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if tt.field == "" {
+			continue
+		}
+		var response ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Error.Details) != 1 || response.Error.Details[0].Field != tt.field {
+			t.Errorf("details %+v, want %s", response.Error.Details, tt.field)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	importMeasurementsAPI(rec, httptest.NewRequest("POST", "/api/measurements/import", strings.NewReader("kpi_id,period,value")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d without a multipart form, want 400", rec.Code)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -21,6 +20,10 @@ func handleInputKPI(scanner *bufio.Scanner) {
 	if period.IsZero() {
 		return
 	}
+	if isPeriodClosed(period) {
+		fmt.Printf("%s is closed, reopen it to change its measurements.\n", period.Format("January 2006"))
+		return
+	}
 
 	// Get KPIs for the selected role
 	roleKPIs := getKPIsByRoleID(selectedRole.ID)
@@ -135,60 +138,53 @@ func inputKPIValue(scanner *bufio.Scanner, kpi KPI, period time.Time) {
 		fmt.Printf("Current value: %.2f %s\n", existingMeasurement.MetricValue, existingMeasurement.Unit)
 	}
 
-	// Provide guidance on the expected input
-	var unitText string
-	switch kpi.Unit {
-	case "days":
-		unitText = "days"
-	case "%":
-		unitText = "percentage"
-	case "score":
-		unitText = "score (0-10)"
-	default:
-		unitText = kpi.Unit
-	}
+	// Derived KPIs are computed from their raw inputs
+	var value float64
+	var inputs map[string]float64
+	if isDerivedKPI(kpi) {
+		var ok bool
+		if value, inputs, ok = inputFormulaValues(scanner, kpi, period, existingMeasurement); !ok {
+			return
+		}
+	} else {
+		// Provide guidance on the expected input
+		var unitText string
+		switch kpi.Unit {
+		case "days":
+			unitText = "days"
+		case "%":
+			unitText = "percentage"
+		case "score":
+			unitText = "score (0-10)"
+		default:
+			unitText = kpi.Unit
+		}
 
-	fmt.Printf("Enter value (%s) or press enter to skip: ", unitText)
-	scanner.Scan()
-	valueStr := scanner.Text()
+		fmt.Printf("Enter value (%s) or press enter to skip: ", unitText)
+		scanner.Scan()
+		valueStr := scanner.Text()
 
-	if valueStr == "" {
-		return
-	}
+		if valueStr == "" {
+			return
+		}
 
-	value, err := strconv.ParseFloat(valueStr, 64)
-	if err != nil {
-		fmt.Println("Invalid number. Skipping.")
-		return
+		var err error
+		value, err = strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			fmt.Println("Invalid number. Skipping.")
+			return
+		}
 	}
 
 	// Input validation based on metric type
-	valid := true
-	switch kpi.Unit {
-	case "%":
-		if value < 0 || value > 100 {
-			fmt.Println("Percentage must be between 0 and 100. Skipping.")
-			valid = false
-		}
-	case "score":
-		if value < 0 || value > 10 {
-			fmt.Println("Score must be between 0 and 10. Skipping.")
-			valid = false
-		}
-	case "days":
-		if value < 0 {
-			fmt.Println("Days cannot be negative. Skipping.")
-			valid = false
-		}
-	default:
-		// For other units, just ensure it's not negative
-		if value < 0 && !strings.Contains(kpi.Metric, "error") { // Errors can be negative
-			fmt.Println("Value cannot be negative. Skipping.")
-			valid = false
-		}
+	if err := validateMeasurementValue(kpi, value); err != nil {
+		fmt.Printf("%v. Skipping.\n", err)
+		return
 	}
 
-	if !valid {
+	// Typos such as 2 for 92 need a confirmation
+	if anomalies := checkMeasurementValue(kpi, value, period); len(anomalies) > 0 && !confirmAnomalies(scanner, anomalies) {
+		fmt.Println("Skipping.")
 		return
 	}
 
@@ -197,33 +193,43 @@ func inputKPIValue(scanner *bufio.Scanner, kpi KPI, period time.Time) {
 	notes := scanner.Text()
 
 	// Save the measurement
-	saveMeasurement(kpi.ID, value, kpi.Unit, period, notes)
+	saveMeasurement(kpi.ID, value, inputs, kpi.Unit, period, notes)
 }
 
 // getExistingMeasurement retrieves an existing measurement for a KPI and period
 func getExistingMeasurement(kpiID int, period time.Time) *Measurement {
-	for i, m := range measurements {
+	return findMeasurementIn(measurements, kpiID, period)
+}
+
+// findMeasurementIn returns the measurement of a KPI for a period from a set of measurements
+func findMeasurementIn(data []Measurement, kpiID int, period time.Time) *Measurement {
+	for i, m := range data {
 		// Check if the measurement is for the same KPI and same month/year
 		if m.KPIID == kpiID &&
 			m.Period.Year() == period.Year() &&
 			m.Period.Month() == period.Month() {
-			return &measurements[i]
+			return &data[i]
 		}
 	}
 	return nil
 }
 
-// saveMeasurement saves a new KPI measurement
-func saveMeasurement(kpiID int, value float64, unit string, period time.Time, notes string) {
+// saveMeasurement saves a new KPI measurement and recomputes the derived KPIs that
+// refer to it. inputs are the raw inputs of a derived KPI, nil for other KPIs.
+// Its events and alert checks follow the next successful saveToExcel. The caller holds dataMu.
+func saveMeasurement(kpiID int, value float64, inputs map[string]float64, unit string, period time.Time, notes string) {
 	// Check if measurement already exists
 	existingMeasurement := getExistingMeasurement(kpiID, period)
 
 	if existingMeasurement != nil {
 		// Update existing measurement
+		previous := *existingMeasurement
 		existingMeasurement.MetricValue = value
+		existingMeasurement.Inputs = inputs
 		existingMeasurement.Unit = unit
 		existingMeasurement.Notes = notes
 		fmt.Printf("Updated measurement: KPI ID %d, Value %.2f %s\n", kpiID, value, unit)
+		recordMeasurementChange(*existingMeasurement, &previous)
 	} else {
 		// Create new measurement
 		newID := 1
@@ -244,10 +250,14 @@ func saveMeasurement(kpiID int, value float64, unit string, period time.Time, no
 			Period:      period,
 			Notes:       notes,
 			CreatedAt:   time.Now(),
+			Inputs:      inputs,
 		}
 
 		// Add to measurements slice
 		measurements = append(measurements, measurement)
 		fmt.Printf("Saved new measurement: KPI ID %d, Value %.2f %s\n", kpiID, value, unit)
+		recordMeasurementChange(measurement, nil)
 	}
+
+	recomputeDependents(kpiID, period)
 }
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Actions a scheduled job can run
+const (
+	JobSendReport  = "send_report"  // Email a report, see report_mail.go
+	JobBackup      = "backup"       // Back up the Excel database
+	JobReminders   = "reminders"    // Remind roles with missing measurements, see submissions.go
+	JobClosePeriod = "close_period" // Close the previous month for changes, see periods.go
+)
+
+var jobActions = []string{JobSendReport, JobBackup, JobReminders, JobClosePeriod}
+
+// Job run statuses
+const (
+	JobRunning   = "running"
+	JobSucceeded = "success"
+	JobFailed    = "failed"
+	JobSkipped   = "skipped" // The previous run had not finished
+)
+
+var jobRunStatuses = []string{JobRunning, JobSucceeded, JobFailed, JobSkipped}
+
+// How a job run was started
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Job history file in the database directory and the number of runs kept
+const jobRunsFile = "job_runs.json"
+
+var jobRunsKept = 500
+
+// JobConfig is a recurring job configured in the settings
+type JobConfig struct {
+	Name        string             `json:"name"`
+	Schedule    string             `json:"schedule"` // Cron expression, e.g. "0 7 6 * *" or "@monthly"
+	Action      string             `json:"action"`
+	Report      *ReportSendRequest `json:"report,omitempty"`       // send_report: the report, default the last closed month
+	OverdueOnly bool               `json:"overdue_only,omitempty"` // reminders: only roles past the deadline
+	Paused      bool               `json:"paused,omitempty"`
+}
+
+// JobRun records one run of a job
+type JobRun struct {
+	ID         int64      `json:"id"`
+	Job        string     `json:"job"`
+	Action     string     `json:"action"`
+	Trigger    string     `json:"trigger"` // schedule or manual
+	Status     string     `json:"status"`
+	Result     string     `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+}
+
+// JobStatus is a configured job with its scheduling state
+type JobStatus struct {
+	JobConfig
+	Scheduled bool       `json:"scheduled"` // False while paused or when the scheduler is not running
+	Running   bool       `json:"running"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+	LastRun   *JobRun    `json:"last_run,omitempty"`
+}
+
+// errJobRunning is returned when a job is started while its previous run is unfinished
+var errJobRunning = fmt.Errorf("job is already running")
+
+// Scheduler runs the configured jobs at their cron schedule. A job never runs
+// twice at the same time: a run that comes due while the previous one is still
+// going is recorded as skipped.
+type Scheduler struct {
+	mu        sync.Mutex
+	started   bool
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	jobs      []JobConfig // Copy of the configured jobs, set by Start and Reload
+	running   map[string]bool
+	next      map[string]time.Time // Next scheduled run per job, also kept while paused
+	runs      []JobRun
+	nextRunID int64
+}
+
+// appScheduler is the job scheduler of the application, started in server mode
+var appScheduler = &Scheduler{running: make(map[string]bool), next: make(map[string]time.Time)}
+
+// findJob returns the configured job with a name
+func findJob(name string) (JobConfig, bool) {
+	for _, job := range appSettings.Jobs {
+		if job.Name == name {
+			return job, true
+		}
+	}
+	return JobConfig{}, false
+}
+
+// validateJobConfigs checks the scheduled jobs of the settings
+func validateJobConfigs(jobs []JobConfig) error {
+	names := make(map[string]bool)
+	for _, job := range jobs {
+		if strings.TrimSpace(job.Name) == "" || strings.ContainsAny(job.Name, "/?#") {
+			return fmt.Errorf("job name '%s' must be non-empty and cannot contain / ? or #", job.Name)
+		}
+		if names[job.Name] {
+			return fmt.Errorf("duplicate job name '%s'", job.Name)
+		}
+		names[job.Name] = true
+
+		schedule, err := parseCron(job.Schedule)
+		if err != nil {
+			return fmt.Errorf("job '%s': %v", job.Name, err)
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return fmt.Errorf("job '%s': schedule '%s' never runs", job.Name, job.Schedule)
+		}
+		if !containsString(jobActions, job.Action) {
+			return fmt.Errorf("job '%s': unknown action '%s', available: %s", job.Name, job.Action, strings.Join(jobActions, ", "))
+		}
+		if job.Report != nil {
+			if job.Action != JobSendReport {
+				return fmt.Errorf("job '%s': report options only apply to %s jobs", job.Name, JobSendReport)
+			}
+			if job.Report.Type != "" && !containsString(mailReportTypes, job.Report.Type) {
+				return fmt.Errorf("job '%s': unknown report type '%s'", job.Name, job.Report.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// loadJobRuns loads the job history. Runs left unfinished by a previous process are marked failed.
+func loadJobRuns() error {
+	s := appScheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs = []JobRun{}
+	if err := readJSONFile(filepath.Join(appSettings.DatabasePath, jobRunsFile), &s.runs); err != nil {
+		return err
+	}
+
+	s.nextRunID = 0
+	for i := range s.runs {
+		if s.runs[i].ID > s.nextRunID {
+			s.nextRunID = s.runs[i].ID
+		}
+		if s.runs[i].Status == JobRunning {
+			s.runs[i].Status = JobFailed
+			s.runs[i].Error = "interrupted by a shutdown"
+		}
+	}
+	return nil
+}
+
+// saveJobRuns writes the job history. The caller holds s.mu.
+func (s *Scheduler) saveJobRuns() {
+	if len(s.runs) > jobRunsKept {
+		s.runs = s.runs[len(s.runs)-jobRunsKept:]
+	}
+	if err := writeJSONFile(filepath.Join(appSettings.DatabasePath, jobRunsFile), s.runs); err != nil {
+		fmt.Printf("Error saving job history: %v\n", err)
+	}
+}
+
+// Start runs the scheduler in the background until Stop is called
+func (s *Scheduler) Start() {
+	dataMu.RLock()
+	jobs := append([]JobConfig(nil), appSettings.Jobs...)
+	dataMu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.stop = make(chan struct{})
+	s.jobs = jobs
+	s.next = make(map[string]time.Time)
+
+	go func() {
+		s.tick(time.Now())
+		for {
+			// Wake up at the start of every minute
+			now := time.Now()
+			timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case t := <-timer.C:
+				s.tick(t)
+			}
+		}
+	}()
+	fmt.Printf("Scheduler started with %d jobs\n", len(s.jobs))
+}
+
+// Stop stops scheduling new runs and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// Reload makes the scheduler pick up changed jobs. Jobs whose schedule did not
+// change keep their next run, so a run due in the current minute is not lost.
+func (s *Scheduler) Reload(jobs []JobConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules := make(map[string]string)
+	for _, job := range jobs {
+		schedules[job.Name] = job.Schedule
+	}
+	for _, job := range s.jobs {
+		if schedules[job.Name] != job.Schedule {
+			delete(s.next, job.Name)
+		}
+	}
+	s.jobs = append([]JobConfig(nil), jobs...)
+
+	if s.started {
+		go s.tick(time.Now())
+	}
+}
+
+// tick starts the jobs that are due at now and schedules their next run. Paused
+// jobs are scheduled without running, so a resumed job keeps its next run.
+func (s *Scheduler) tick(now time.Time) {
+	s.mu.Lock()
+	jobs := s.jobs
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		schedule, err := parseCron(job.Schedule)
+		if err != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		if !s.started {
+			s.mu.Unlock()
+			return
+		}
+		next, ok := s.next[job.Name]
+		due := ok && !next.IsZero() && !now.Before(next)
+		if !ok || due {
+			s.next[job.Name] = schedule.Next(now)
+		}
+		s.mu.Unlock()
+
+		if due && !job.Paused {
+			if run, err := s.begin(job, TriggerSchedule); err == nil {
+				go s.execute(job, run)
+			}
+		}
+	}
+}
+
+// begin records the start of a job run. If the job is still running it returns
+// errJobRunning, and a scheduled run is recorded as skipped.
+func (s *Scheduler) begin(job JobConfig, trigger string) (JobRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextRunID++
+	run := JobRun{ID: s.nextRunID, Job: job.Name, Action: job.Action, Trigger: trigger, StartedAt: time.Now()}
+
+	if s.running[job.Name] {
+		if trigger == TriggerManual {
+			s.nextRunID--
+			return run, errJobRunning
+		}
+		run.Status = JobSkipped
+		run.Error = "previous run still running"
+		run.FinishedAt = &run.StartedAt
+		s.runs = append(s.runs, run)
+		s.saveJobRuns()
+		fmt.Printf("Job %s skipped: previous run still running\n", job.Name)
+		return run, errJobRunning
+	}
+
+	s.running[job.Name] = true
+	s.wg.Add(1)
+	run.Status = JobRunning
+	s.runs = append(s.runs, run)
+	s.saveJobRuns()
+	return run, nil
+}
+
+// execute runs a started job and records its result
+func (s *Scheduler) execute(job JobConfig, run JobRun) {
+	defer s.wg.Done()
+
+	result, err := runJobAction(job, run.StartedAt)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.DurationMS = finished.Sub(run.StartedAt).Milliseconds()
+	run.Result = result
+	run.Status = JobSucceeded
+	if err != nil {
+		run.Status = JobFailed
+		run.Error = err.Error()
+		fmt.Printf("Job %s failed: %v\n", job.Name, err)
+	}
+
+	for i := range s.runs {
+		if s.runs[i].ID == run.ID {
+			s.runs[i] = run
+		}
+	}
+	delete(s.running, job.Name)
+	s.saveJobRuns()
+}
+
+// runJobAction performs the action of a job and describes the result. It takes
+// dataMu itself and releases it before sending emails or reminders, so a slow
+// mail server does not hold up changes to the data.
+func runJobAction(job JobConfig, now time.Time) (string, error) {
+	switch job.Action {
+	case JobBackup:
+		dataMu.RLock()
+		path, err := backupExcelDB()
+		dataMu.RUnlock()
+		if err != nil {
+			return "", err
+		}
+		return "created " + filepath.Base(path), nil
+
+	case JobClosePeriod:
+		dataMu.Lock()
+		defer dataMu.Unlock()
+		period := lastClosedPeriod(now)
+		if isPeriodClosed(period) {
+			return fmt.Sprintf("%s was already closed", period.Format("2006-01")), nil
+		}
+		closed, err := closePeriod(period)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("closed %s with %d measurements", closed.Period, closed.Measurements), nil
+
+	case JobSendReport:
+		var req ReportSendRequest
+		if job.Report != nil {
+			req = *job.Report
+		}
+		req.DryRun = false
+
+		dataMu.RLock()
+		pr, mails, notifier, err := buildJobReport(req, now)
+		dataMu.RUnlock()
+		if err != nil {
+			return "", err
+		}
+		deliveries := deliverReportMails(mails, notifier, false)
+		sent, failures := 0, []string{}
+		for _, d := range deliveries {
+			if d.Error != "" {
+				failures = append(failures, fmt.Sprintf("%s: %s", strings.Join(d.Recipients, ", "), d.Error))
+			} else {
+				sent++
+			}
+		}
+		result := fmt.Sprintf("sent %d of %d emails of the %s report", sent, len(deliveries), pr.Label())
+		if len(failures) > 0 {
+			return result, fmt.Errorf("%s", strings.Join(failures, "; "))
+		}
+		return result, nil
+
+	case JobReminders:
+		period := lastClosedPeriod(now)
+		dataMu.RLock()
+		notifiers := reminderNotifiers()
+		reminders := buildReminders(period, nil, job.OverdueOnly)
+		dataMu.RUnlock()
+		if len(notifiers) == 0 {
+			return "", fmt.Errorf("no reminder notifiers configured")
+		}
+		results := deliverReminders(reminders, false, notifiers)
+		sent, failures := 0, []string{}
+		for _, r := range results {
+			if r.Error != "" {
+				failures = append(failures, fmt.Sprintf("%s via %s: %s", r.RoleName, r.Notifier, r.Error))
+			} else {
+				sent++
+			}
+		}
+		result := fmt.Sprintf("sent %d reminders for %s", sent, period.Format("2006-01"))
+		if len(failures) > 0 {
+			return result, fmt.Errorf("%s", strings.Join(failures, "; "))
+		}
+		return result, nil
+	}
+
+	return "", fmt.Errorf("unknown action '%s'", job.Action)
+}
+
+// buildJobReport renders the emails of a send_report job. The caller holds dataMu.
+func buildJobReport(req ReportSendRequest, now time.Time) (PeriodRange, []reportMail, Notifier, error) {
+	if errs := validateReportSendRequest(req); len(errs) > 0 {
+		return PeriodRange{}, nil, nil, errs
+	}
+	pr, err := resolveReportPeriod(req, now)
+	if err != nil {
+		return pr, nil, nil, err
+	}
+	if !smtpConfigured() {
+		return pr, nil, nil, fmt.Errorf("SMTP is not configured")
+	}
+	mails, err := buildReportMails(pr, req)
+	if err != nil {
+		return pr, nil, nil, err
+	}
+	return pr, mails, SMTPNotifier{Settings: appSettings.SMTP}, nil
+}
+
+// jobStatus returns the scheduling state of a job
+func (s *Scheduler) jobStatus(job JobConfig) JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := JobStatus{JobConfig: job, Scheduled: s.started && !job.Paused, Running: s.running[job.Name]}
+	if next, ok := s.next[job.Name]; ok && status.Scheduled && !next.IsZero() {
+		status.NextRun = &next
+	} else if schedule, err := parseCron(job.Schedule); err == nil && !job.Paused {
+		if next := schedule.Next(time.Now()); !next.IsZero() {
+			status.NextRun = &next
+		}
+	}
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		if s.runs[i].Job == job.Name {
+			last := s.runs[i]
+			status.LastRun = &last
+			break
+		}
+	}
+	return status
+}
+
+// setJobPaused pauses or resumes a job and saves the settings. The caller holds
+// dataMu; the scheduler works on its own copy of the jobs.
+func setJobPaused(name string, paused bool) (JobConfig, bool) {
+	for i := range appSettings.Jobs {
+		if appSettings.Jobs[i].Name == name {
+			appSettings.Jobs[i].Paused = paused
+			saveSettings()
+			appScheduler.Reload(appSettings.Jobs)
+			return appSettings.Jobs[i], true
+		}
+	}
+	return JobConfig{}, false
+}
+
+// getJobs lists the configured jobs with their next and last run
+func getJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	statuses := []JobStatus{}
+	for _, job := range appSettings.Jobs {
+		statuses = append(statuses, appScheduler.jobStatus(job))
+	}
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// getJob returns one job with its next and last run
+func getJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, ok := findJob(mux.Vars(r)["name"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	json.NewEncoder(w).Encode(appScheduler.jobStatus(job))
+}
+
+// runJob starts a job now. The job runs in the background; its result appears in
+// the job history.
+func runJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, ok := findJob(mux.Vars(r)["name"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	run, err := appScheduler.begin(job, TriggerManual)
+	if err != nil {
+		writeError(w, http.StatusConflict, "Job is already running")
+		return
+	}
+	go appScheduler.execute(job, run)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// pauseJob stops scheduling a job until it is resumed
+func pauseJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, ok := setJobPaused(mux.Vars(r)["name"], true)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	json.NewEncoder(w).Encode(appScheduler.jobStatus(job))
+}
+
+// resumeJob schedules a paused job again
+func resumeJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, ok := setJobPaused(mux.Vars(r)["name"], false)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	json.NewEncoder(w).Encode(appScheduler.jobStatus(job))
+}
+
+var jobRunComparators = map[string]func(a, b JobRun) int{
+	"id":          func(a, b JobRun) int { return compareInts(int(a.ID), int(b.ID)) },
+	"job":         func(a, b JobRun) int { return compareStrings(a.Job, b.Job) },
+	"started_at":  func(a, b JobRun) int { return compareTimes(a.StartedAt, b.StartedAt) },
+	"duration_ms": func(a, b JobRun) int { return compareInts(int(a.DurationMS), int(b.DurationMS)) },
+}
+
+// getJobRuns returns the job history, filtered by job or status and newest first by default
+func getJobRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, perr := parseListParams(r.URL.Query(), []string{"job", "status"}, sortFields(jobRunComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+	name := r.URL.Query().Get("job")
+	status := r.URL.Query().Get("status")
+	if status != "" && !containsString(jobRunStatuses, status) {
+		writeParamError(w, &ParamError{"status", "must be one of " + strings.Join(jobRunStatuses, ", ")})
+		return
+	}
+
+	appScheduler.mu.Lock()
+	filtered := []JobRun{}
+	for _, run := range appScheduler.runs {
+		if name != "" && run.Job != name {
+			continue
+		}
+		if status != "" && run.Status != status {
+			continue
+		}
+		if !matchesText(params.Query, run.Job, run.Result, run.Error) {
+			continue
+		}
+		filtered = append(filtered, run)
+	}
+	appScheduler.mu.Unlock()
+
+	page, pagination := sortAndPage(filtered, params, jobRunComparators, "-id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
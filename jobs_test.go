@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestScheduler returns a scheduler of jobs that all run next at due
+func newTestScheduler(due time.Time, jobs ...JobConfig) *Scheduler {
+	s := &Scheduler{jobs: jobs, running: make(map[string]bool), next: make(map[string]time.Time)}
+	for _, job := range jobs {
+		s.next[job.Name] = due
+	}
+	return s
+}
+
+func TestSchedulerReloadKeepsNextRuns(t *testing.T) {
+	due := time.Date(2026, 5, 6, 7, 0, 0, 0, time.Local)
+	s := newTestScheduler(due,
+		JobConfig{Name: "backup", Schedule: "0 7 * * *", Action: JobBackup},
+		JobConfig{Name: "report", Schedule: "0 7 6 * *", Action: JobSendReport},
+		JobConfig{Name: "old", Schedule: "@daily", Action: JobBackup},
+	)
+
+	// Pausing or resuming keeps the next run, a new schedule or a removed job drops it
+	s.Reload([]JobConfig{
+		{Name: "backup", Schedule: "0 7 * * *", Action: JobBackup, Paused: true},
+		{Name: "report", Schedule: "0 8 6 * *", Action: JobSendReport},
+	})
+	if next, ok := s.next["backup"]; !ok || !next.Equal(due) {
+		t.Errorf("backup next run %v, want %v", next, due)
+	}
+	if _, ok := s.next["report"]; ok {
+		t.Error("report kept the next run of its old schedule")
+	}
+	if _, ok := s.next["old"]; ok {
+		t.Error("removed job kept its next run")
+	}
+
+	// The scheduler has its own copy of the jobs
+	jobs := []JobConfig{{Name: "backup", Schedule: "0 7 * * *", Action: JobBackup}}
+	s.Reload(jobs)
+	jobs[0].Paused = true
+	if s.jobs[0].Paused {
+		t.Error("changing the settings changed the scheduler's jobs")
+	}
+}
+
+func TestSchedulerTickSkipsPausedJobs(t *testing.T) {
+	due := time.Date(2026, 5, 6, 7, 0, 0, 0, time.Local)
+	s := newTestScheduler(due, JobConfig{Name: "backup", Schedule: "0 7 * * *", Action: JobBackup, Paused: true})
+	s.started = true
+
+	s.tick(due)
+	if len(s.runs) != 0 || s.running["backup"] {
+		t.Fatal("paused job was started")
+	}
+	// The next run moves on, so resuming does not run the missed one
+	if next := s.next["backup"]; !next.Equal(due.AddDate(0, 0, 1)) {
+		t.Errorf("next run %v, want the next day", next)
+	}
+}
+
+func TestClosePeriodJob(t *testing.T) {
+	setupSubmissionTest(t)
+	previous := closedPeriods
+	t.Cleanup(func() { closedPeriods = previous })
+	appSettings.DatabasePath = t.TempDir()
+	closedPeriods = []ClosedPeriod{}
+	job := JobConfig{Name: "close", Schedule: "0 0 10 * *", Action: JobClosePeriod}
+
+	// Run on March 10th, the job closes February with its one measurement
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	result, err := runJobAction(job, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "closed 2026-02 with 1 measurements" || !isPeriodClosed(month(2)) {
+		t.Errorf("result %q, want February closed", result)
+	}
+
+	// A second run finds the period closed, which is not a failure
+	if result, err := runJobAction(job, now); err != nil || result != "2026-02 was already closed" {
+		t.Errorf("second run %q, %v", result, err)
+	}
+	if len(closedPeriods) != 1 {
+		t.Errorf("closed periods %+v, want one", closedPeriods)
+	}
+
+	// The closed period is saved
+	if err := loadClosedPeriods(); err != nil || len(closedPeriods) != 1 {
+		t.Errorf("closed periods %+v after reload, %v", closedPeriods, err)
+	}
+}
+
+func TestRemindersJobSendsWithoutLock(t *testing.T) {
+	setupSubmissionTest(t)
+
+	// The webhook receiver changes the data while the reminder is being sent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locked := make(chan struct{})
+		go func() {
+			dataMu.Lock()
+			dataMu.Unlock()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+		case <-time.After(2 * time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	appSettings.Reminders = ReminderSettings{Notifiers: []string{NotifierWebhook}, WebhookURL: server.URL}
+
+	result, err := runJobAction(JobConfig{Name: "remind", Action: JobReminders}, time.Date(2026, 4, 10, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("%s: %v", result, err)
+	}
+	if result != "sent 1 reminders for 2026-03" {
+		t.Errorf("result %q, want one reminder sent", result)
+	}
+}
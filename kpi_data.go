@@ -4,12 +4,12 @@ package main
 func initializeKPIData() {
 	// Initialize roles
 	roles = []Role{
-		{ID: 1, Name: "Admin Bank Supervisor", Description: "Supervises administrative banking operations"},
-		{ID: 2, Name: "General Manager", Description: "Manages overall operations and facilities"},
-		{ID: 3, Name: "Corporate Communication Supervisor", Description: "Handles corporate communications and social media"},
-		{ID: 4, Name: "HRBP Supervisor", Description: "Human Resources Business Partner supervisor"},
-		{ID: 5, Name: "HR Operations Supervisor", Description: "Supervises HR operations and payroll"},
-		{ID: 6, Name: "IT Supervisor", Description: "Manages IT projects and infrastructure"},
+		{ID: 1, Name: "Admin Bank Supervisor", Description: "Supervises administrative banking operations", Department: "Operations"},
+		{ID: 2, Name: "General Manager", Description: "Manages overall operations and facilities", Department: "Management"},
+		{ID: 3, Name: "Corporate Communication Supervisor", Description: "Handles corporate communications and social media", Department: "Corporate Communication"},
+		{ID: 4, Name: "HRBP Supervisor", Description: "Human Resources Business Partner supervisor", Department: "Human Resources"},
+		{ID: 5, Name: "HR Operations Supervisor", Description: "Supervises HR operations and payroll", Department: "Human Resources"},
+		{ID: 6, Name: "IT Supervisor", Description: "Manages IT projects and infrastructure", Department: "Information Technology"},
 	}
 
 	// Initialize KPIs for Admin Bank Supervisor
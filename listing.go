@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page size limits for list endpoints
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// Query parameters shared by every list endpoint
+var commonListParams = []string{"q", "sort", "limit", "cursor"}
+
+// ListParams holds the parsed filter, sort and pagination parameters of a list request
+type ListParams struct {
+	RoleID   int // 0 when not filtered
+	KPIID    int
+	Category string
+	From     time.Time // Zero when not filtered
+	To       time.Time
+	Year     int
+	Month    int
+	Query    string // Lower-case text search
+	Sort     []string
+	Limit    int
+	Offset   int
+}
+
+// Pagination is returned with every list response
+type Pagination struct {
+	Total      int    `json:"total"` // Items matching the filters
+	Count      int    `json:"count"` // Items in this page
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListResponse wraps one page of a collection
+type ListResponse struct {
+	Data       interface{} `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+// ParamError describes an invalid query parameter
+type ParamError struct {
+	Parameter string `json:"parameter"`
+	Message   string `json:"message"`
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid parameter '%s': %s", e.Parameter, e.Message)
+}
+
+// writeParamError writes a 400 response describing an invalid query parameter
+func writeParamError(w http.ResponseWriter, err *ParamError) {
+	writeError(w, http.StatusBadRequest, "Invalid query parameter", FieldError{Field: err.Parameter, Message: err.Message})
+}
+
+// parseListParams parses the query of a list request. Only the common parameters,
+// the given filters and sorting by the given fields are accepted; anything else is
+// rejected so a typo never returns a silently unfiltered list.
+func parseListParams(query url.Values, filters []string, sortFields []string) (ListParams, *ParamError) {
+	params := ListParams{Limit: defaultListLimit}
+
+	allowed := make(map[string]bool)
+	for _, name := range append(append([]string{}, commonListParams...), filters...) {
+		allowed[name] = true
+	}
+	for name := range query {
+		if !allowed[name] {
+			return params, &ParamError{name, fmt.Sprintf("unknown parameter, allowed: %s",
+				strings.Join(append(append([]string{}, filters...), commonListParams...), ", "))}
+		}
+	}
+
+	positiveInt := func(name string, max int) (int, *ParamError) {
+		value := query.Get(name)
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || (max > 0 && n > max) {
+			if max > 0 {
+				return 0, &ParamError{name, fmt.Sprintf("must be a number between 1 and %d", max)}
+			}
+			return 0, &ParamError{name, "must be a positive number"}
+		}
+		return n, nil
+	}
+
+	var perr *ParamError
+	if params.RoleID, perr = positiveInt("role_id", 0); perr != nil {
+		return params, perr
+	}
+	if params.KPIID, perr = positiveInt("kpi_id", 0); perr != nil {
+		return params, perr
+	}
+	if params.Year, perr = positiveInt("year", 9999); perr != nil {
+		return params, perr
+	}
+	if params.Month, perr = positiveInt("month", 12); perr != nil {
+		return params, perr
+	}
+
+	if value := query.Get("category"); value != "" {
+		if value != "Quantitative" && value != "Qualitative" {
+			return params, &ParamError{"category", "must be Quantitative or Qualitative"}
+		}
+		params.Category = value
+	}
+
+	for _, name := range []string{"from", "to"} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		period, err := parsePeriodString(value)
+		if err != nil {
+			return params, &ParamError{name, "must be a period in YYYY-MM format"}
+		}
+		if name == "from" {
+			params.From = period
+		} else {
+			params.To = period
+		}
+	}
+	if !params.From.IsZero() && !params.To.IsZero() && params.To.Before(params.From) {
+		return params, &ParamError{"to", "cannot be before from"}
+	}
+
+	params.Query = strings.ToLower(strings.TrimSpace(query.Get("q")))
+
+	if value := query.Get("sort"); value != "" {
+		for _, key := range strings.Split(value, ",") {
+			field := strings.TrimPrefix(strings.TrimSpace(key), "-")
+			if !containsString(sortFields, field) {
+				return params, &ParamError{"sort", fmt.Sprintf("cannot sort by '%s', allowed: %s", field, strings.Join(sortFields, ", "))}
+			}
+			params.Sort = append(params.Sort, strings.TrimSpace(key))
+		}
+	}
+
+	if query.Get("limit") != "" {
+		if params.Limit, perr = positiveInt("limit", maxListLimit); perr != nil {
+			return params, perr
+		}
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		offset, err := decodeCursor(value)
+		if err != nil {
+			return params, &ParamError{"cursor", "invalid cursor, use next_cursor from a previous page"}
+		}
+		params.Offset = offset
+	}
+
+	return params, nil
+}
+
+// containsString reports whether a slice contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeCursor returns an opaque cursor for an offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset of a cursor
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), "o:") {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), "o:"))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return offset, nil
+}
+
+// matchesText reports whether any of the fields contains the lower-case search text
+func matchesText(query string, fields ...string) bool {
+	if query == "" {
+		return true
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareInts and the helpers below return -1, 0 or 1 for use in sort comparators
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func compareTimes(a, b time.Time) int {
+	return a.Compare(b)
+}
+
+// sortAndPage sorts the items by the requested fields, falling back to the given
+// default order, and returns the requested page with its pagination metadata
+func sortAndPage[T any](items []T, params ListParams, comparators map[string]func(a, b T) int, defaultSort string) ([]T, Pagination) {
+	keys := params.Sort
+	if len(keys) == 0 {
+		keys = []string{defaultSort}
+	}
+
+	sorted := make([]T, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		for _, key := range keys {
+			descending := strings.HasPrefix(key, "-")
+			c := comparators[strings.TrimPrefix(key, "-")](sorted[i], sorted[j])
+			if descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	pagination := Pagination{Total: len(sorted), Limit: params.Limit}
+
+	start := params.Offset
+	if start > len(sorted) {
+		start = len(sorted)
+	}
+	end := start + params.Limit
+	if end > len(sorted) {
+		end = len(sorted)
+	}
+
+	page := sorted[start:end]
+	pagination.Count = len(page)
+	if end < len(sorted) {
+		pagination.NextCursor = encodeCursor(end)
+	}
+
+	return page, pagination
+}
+
+// Sort comparators of each collection
+var roleComparators = map[string]func(a, b Role) int{
+	"id":         func(a, b Role) int { return compareInts(a.ID, b.ID) },
+	"name":       func(a, b Role) int { return compareStrings(a.Name, b.Name) },
+	"department": func(a, b Role) int { return compareStrings(a.Department, b.Department) },
+}
+
+var employeeComparators = map[string]func(a, b Employee) int{
+	"id":          func(a, b Employee) int { return compareInts(a.ID, b.ID) },
+	"name":        func(a, b Employee) int { return compareStrings(a.Name, b.Name) },
+	"role_id":     func(a, b Employee) int { return compareInts(a.RoleID, b.RoleID) },
+	"hire_date":   func(a, b Employee) int { return compareTimes(a.HireDate, b.HireDate) },
+	"base_salary": func(a, b Employee) int { return compareFloats(a.BaseSalary, b.BaseSalary) },
+}
+
+var kpiComparators = map[string]func(a, b KPI) int{
+	"id":       func(a, b KPI) int { return compareInts(a.ID, b.ID) },
+	"name":     func(a, b KPI) int { return compareStrings(a.Name, b.Name) },
+	"role_id":  func(a, b KPI) int { return compareInts(a.RoleID, b.RoleID) },
+	"category": func(a, b KPI) int { return compareStrings(a.Category, b.Category) },
+	"weight":   func(a, b KPI) int { return compareFloats(a.Weight, b.Weight) },
+}
+
+var measurementComparators = map[string]func(a, b Measurement) int{
+	"id":           func(a, b Measurement) int { return compareInts(a.ID, b.ID) },
+	"kpi_id":       func(a, b Measurement) int { return compareInts(a.KPIID, b.KPIID) },
+	"period":       func(a, b Measurement) int { return compareTimes(a.Period, b.Period) },
+	"metric_value": func(a, b Measurement) int { return compareFloats(a.MetricValue, b.MetricValue) },
+	"created_at":   func(a, b Measurement) int { return compareTimes(a.CreatedAt, b.CreatedAt) },
+}
+
+// sortFields returns the sortable field names of a comparator map
+func sortFields[T any](comparators map[string]func(a, b T) int) []string {
+	fields := make([]string, 0, len(comparators))
+	for field := range comparators {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// filterMeasurements returns the measurements matching the list parameters
+func filterMeasurements(params ListParams) []Measurement {
+	filtered := []Measurement{}
+	for _, m := range measurements {
+		if params.KPIID != 0 && m.KPIID != params.KPIID {
+			continue
+		}
+		if params.Year != 0 && m.Period.Year() != params.Year {
+			continue
+		}
+		if params.Month != 0 && int(m.Period.Month()) != params.Month {
+			continue
+		}
+
+		period := time.Date(m.Period.Year(), m.Period.Month(), 1, 0, 0, 0, 0, time.Local)
+		if !params.From.IsZero() && period.Before(params.From) {
+			continue
+		}
+		if !params.To.IsZero() && period.After(params.To) {
+			continue
+		}
+
+		if params.RoleID != 0 || params.Category != "" || params.Query != "" {
+			kpi := getKPIByID(m.KPIID)
+			if kpi == nil {
+				continue
+			}
+			if params.RoleID != 0 && kpi.RoleID != params.RoleID {
+				continue
+			}
+			if params.Category != "" && kpi.Category != params.Category {
+				continue
+			}
+			if !matchesText(params.Query, m.Notes, kpi.Name) {
+				continue
+			}
+		}
+
+		filtered = append(filtered, m)
+	}
+	return filtered
+}
+
+// filterKPIs returns the KPIs matching the list parameters
+func filterKPIs(params ListParams) []KPI {
+	filtered := []KPI{}
+	for _, kpi := range kpis {
+		if params.RoleID != 0 && kpi.RoleID != params.RoleID {
+			continue
+		}
+		if params.KPIID != 0 && kpi.ID != params.KPIID {
+			continue
+		}
+		if params.Category != "" && kpi.Category != params.Category {
+			continue
+		}
+		if !matchesText(params.Query, kpi.Name, kpi.Description, kpi.Metric) {
+			continue
+		}
+		filtered = append(filtered, kpi)
+	}
+	return filtered
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseListParams(t *testing.T) {
+	filters := []string{"role_id", "kpi_id", "category", "from", "to", "year", "month"}
+	fields := sortFields(measurementComparators)
+
+	params, perr := parseListParams(url.Values{
+		"role_id": {"1"}, "kpi_id": {"2"}, "category": {"Qualitative"}, "from": {"2026-01"}, "to": {"2026-03"},
+		"year": {"2026"}, "month": {"2"}, "q": {"  Audit "}, "sort": {"-period, id"}, "limit": {"5"}, "cursor": {encodeCursor(10)},
+	}, filters, fields)
+	want := ListParams{
+		RoleID: 1, KPIID: 2, Category: "Qualitative", From: month(1), To: month(3), Year: 2026, Month: 2,
+		Query: "audit", Sort: []string{"-period", "id"}, Limit: 5, Offset: 10,
+	}
+	if perr != nil || !reflect.DeepEqual(params, want) {
+		t.Errorf("params %+v, %v, want %+v", params, perr, want)
+	}
+	if params, _ := parseListParams(url.Values{}, filters, fields); params.Limit != defaultListLimit || params.Offset != 0 || params.Sort != nil {
+		t.Errorf("params %+v, want the defaults", params)
+	}
+
+	for _, tt := range []struct {
+		query     url.Values
+		parameter string
+	}{
+		// Filters not offered by the endpoint are rejected rather than ignored
+		{url.Values{"status": {"open"}}, "status"},
+		{url.Values{"role": {"1"}}, "role"},
+		{url.Values{"role_id": {"0"}}, "role_id"},
+		{url.Values{"kpi_id": {"x"}}, "kpi_id"},
+		{url.Values{"year": {"10000"}}, "year"},
+		{url.Values{"month": {"13"}}, "month"},
+		{url.Values{"category": {"quantitative"}}, "category"},
+		{url.Values{"from": {"2026-13"}}, "from"},
+		{url.Values{"from": {"2026-03"}, "to": {"2026-01"}}, "to"},
+		{url.Values{"sort": {"name"}}, "sort"},
+		{url.Values{"sort": {"period,-notes"}}, "sort"},
+		{url.Values{"limit": {"0"}}, "limit"},
+		{url.Values{"limit": {"1001"}}, "limit"},
+		{url.Values{"cursor": {"10"}}, "cursor"},
+		{url.Values{"cursor": {encodeCursor(-1)}}, "cursor"},
+	} {
+		if _, perr := parseListParams(tt.query, filters, fields); perr == nil || perr.Parameter != tt.parameter {
+			t.Errorf("%v: error %v, want one for %q", tt.query, perr, tt.parameter)
+		}
+	}
+}
+
+func TestSortAndPage(t *testing.T) {
+	items := []Employee{
+		{ID: 1, Name: "eka", RoleID: 2},
+		{ID: 2, Name: "Budi", RoleID: 1},
+		{ID: 3, Name: "Ayu", RoleID: 2},
+		{ID: 4, Name: "Citra", RoleID: 1},
+		{ID: 5, Name: "Dewi", RoleID: 2},
+	}
+	ids := func(page []Employee) []int {
+		var ids []int
+		for _, e := range page {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	for _, tt := range []struct {
+		sort []string
+		want []int
+	}{
+		{nil, []int{1, 2, 3, 4, 5}},
+		{[]string{"-id"}, []int{5, 4, 3, 2, 1}},
+		// Names compare without case
+		{[]string{"name"}, []int{3, 2, 4, 5, 1}},
+		{[]string{"role_id", "-name"}, []int{4, 2, 1, 5, 3}},
+	} {
+		page, pagination := sortAndPage(items, ListParams{Sort: tt.sort, Limit: 10}, employeeComparators, "id")
+		if !reflect.DeepEqual(ids(page), tt.want) || pagination != (Pagination{Total: 5, Count: 5, Limit: 10}) {
+			t.Errorf("sort %v: %v %+v, want %v on one page", tt.sort, ids(page), pagination, tt.want)
+		}
+	}
+	if items[0].ID != 1 || items[1].ID != 2 {
+		t.Errorf("items %+v, want the input left unsorted", items)
+	}
+
+	// Following next_cursor visits every item once and the last page has no cursor
+	params := ListParams{Sort: []string{"name"}, Limit: 2}
+	var visited []int
+	for pages := 1; ; pages++ {
+		page, pagination := sortAndPage(items, params, employeeComparators, "id")
+		visited = append(visited, ids(page)...)
+		if pagination.Total != 5 || pagination.Count != len(page) {
+			t.Errorf("page %d: %+v", pages, pagination)
+		}
+		if pagination.NextCursor == "" {
+			if pages != 3 {
+				t.Errorf("%d pages, want 3", pages)
+			}
+			break
+		}
+		offset, err := decodeCursor(pagination.NextCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.Offset = offset
+	}
+	if !reflect.DeepEqual(visited, []int{3, 2, 4, 5, 1}) {
+		t.Errorf("visited %v, want every employee by name", visited)
+	}
+
+	if page, pagination := sortAndPage(items, ListParams{Limit: 2, Offset: 9}, employeeComparators, "id"); len(page) != 0 || pagination.Count != 0 || pagination.NextCursor != "" {
+		t.Errorf("page %v %+v, want an empty last page past the end", page, pagination)
+	}
+}
+
+func TestFilterMeasurements(t *testing.T) {
+	setupSubmissionTest(t)
+	kpis[1].Category = "Qualitative"
+	roles = append(roles, Role{ID: 2, Name: "Support"})
+	kpis = append(kpis, KPI{ID: 3, RoleID: 2, Name: "Tickets", Operator: "≥", TargetValue: 10, Weight: 100})
+	addRevenue(80, month(1))
+	addRevenue(90, month(3))
+	measurements = append(measurements,
+		Measurement{ID: 4, KPIID: 3, MetricValue: 12, Period: month(3), Notes: "Backlog cleared"},
+		Measurement{ID: 5, KPIID: 1, MetricValue: 70, Period: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)})
+
+	for _, tt := range []struct {
+		params ListParams
+		want   []int
+	}{
+		{ListParams{}, []int{1, 2, 3, 4, 5}},
+		{ListParams{KPIID: 1}, []int{2, 3, 5}},
+		{ListParams{RoleID: 2}, []int{4}},
+		{ListParams{Category: "Qualitative"}, []int{1}},
+		{ListParams{Year: 2026, Month: 3}, []int{3, 4}},
+		{ListParams{Month: 3}, []int{3, 4, 5}},
+		{ListParams{From: month(2)}, []int{1, 3, 4}},
+		{ListParams{From: month(1), To: month(2)}, []int{1, 2}},
+		// Text search covers notes and the KPI name
+		{ListParams{Query: "backlog"}, []int{4}},
+		{ListParams{Query: "revenue", Year: 2026}, []int{2, 3}},
+		{ListParams{RoleID: 3}, nil},
+	} {
+		var ids []int
+		for _, m := range filterMeasurements(tt.params) {
+			ids = append(ids, m.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%+v: %v, want %v", tt.params, ids, tt.want)
+		}
+	}
+}
+
+func TestListEndpoints(t *testing.T) {
+	setupSubmissionTest(t)
+	addRevenue(80, month(1))
+	addRevenue(90, month(3))
+
+	get := func(url string) (*httptest.ResponseRecorder, ListResponse) {
+		rec := httptest.NewRecorder()
+		newAPIRouter().ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		var response ListResponse
+		if rec.Code == http.StatusOK {
+			json.NewDecoder(rec.Body).Decode(&response)
+		}
+		return rec, response
+	}
+
+	rec, response := get("/api/measurements?kpi_id=1&sort=-metric_value&limit=1")
+	if rec.Code != http.StatusOK || response.Pagination.Total != 2 || response.Pagination.Count != 1 || response.Pagination.NextCursor == "" {
+		t.Fatalf("status %d, %+v, want the first of two pages", rec.Code, response)
+	}
+	if data := response.Data.([]interface{}); data[0].(map[string]interface{})["metric_value"] != 90.0 {
+		t.Errorf("data %v, want the highest value first", data)
+	}
+	_, response = get("/api/measurements?kpi_id=1&sort=-metric_value&limit=1&cursor=" + response.Pagination.NextCursor)
+	if data := response.Data.([]interface{}); len(data) != 1 || data[0].(map[string]interface{})["metric_value"] != 80.0 || response.Pagination.NextCursor != "" {
+		t.Errorf("response %+v, want the last page with the lowest value", response)
+	}
+
+	if _, response = get("/api/kpis?q=aud"); response.Pagination.Total != 1 {
+		t.Errorf("response %+v, want only Audit", response)
+	}
+	if _, response = get("/api/kpis/1/measurements?from=2026-02"); response.Pagination.Total != 1 {
+		t.Errorf("response %+v, want only the March revenue", response)
+	}
+
+	for _, tt := range []struct {
+		url, parameter string
+	}{
+		{"/api/measurements?status=open", "status"},
+		{"/api/measurements?sort=notes", "sort"},
+		{"/api/kpis?year=2026", "year"},
+		{"/api/roles?limit=-1", "limit"},
+		{"/api/employees?cursor=abc", "cursor"},
+	} {
+		rec, _ := get(tt.url)
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if rec.Code != http.StatusBadRequest || len(response.Error.Details) != 1 || response.Error.Details[0].Field != tt.parameter {
+			t.Errorf("%s: status %d, %+v, want 400 for %q", tt.url, rec.Code, response, tt.parameter)
+		}
+	}
+}
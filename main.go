@@ -3,20 +3,24 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
 func main() {
-	// Setup signal handling for graceful shutdown
-	setupSignalHandling()
+	// Subcommands run non-interactively, e.g. "kpi-tracker report --type monthly".
+	// Without a command the interactive menu is started.
+	os.Exit(runCLI(os.Args[1:]))
+}
 
+// initApp loads the settings and the Excel database
+func initApp() error {
 	// Create or load database directory
 	err := initDatabase()
 	if err != nil {
-		fmt.Printf("Error initializing database: %v\n", err)
-		return
+		return fmt.Errorf("error initializing database: %v", err)
 	}
 	fmt.Println("Database directory initialized at:", appSettings.DatabasePath)
 
@@ -30,22 +34,55 @@ func main() {
 	// Initialize Excel database and load data
 	err = initExcelDatabase()
 	if err != nil {
-		fmt.Printf("Error initializing Excel database: %v\n", err)
-		return
+		return fmt.Errorf("error initializing Excel database: %v", err)
 	}
 	fmt.Println("Excel database loaded successfully")
 
+	if err := loadWebhooks(); err != nil {
+		return fmt.Errorf("error loading webhooks: %v", err)
+	}
+	if err := loadAlerts(); err != nil {
+		return fmt.Errorf("error loading alerts: %v", err)
+	}
+	if err := loadJobRuns(); err != nil {
+		return fmt.Errorf("error loading job history: %v", err)
+	}
+	if err := loadClosedPeriods(); err != nil {
+		return fmt.Errorf("error loading closed periods: %v", err)
+	}
+
+	return nil
+}
+
+// runInteractive runs the menu-driven console interface together with the REST API
+func runInteractive() {
+	// Setup signal handling for graceful shutdown
+	setupSignalHandling()
+
+	if err := initApp(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
 	// Start the REST API server in a separate goroutine
 	go startRESTServer()
-	fmt.Println("REST API server started on http://localhost:8080")
 
-	// Main program loop
-	scanner := bufio.NewScanner(os.Stdin)
+	// Main program loop. The menu holds dataMu except while it waits for input.
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	scanner := bufio.NewScanner(consoleReader{os.Stdin})
 	for {
 		displayMainMenu()
 
-		fmt.Print("Enter your choice (1-5): ")
-		scanner.Scan()
+		fmt.Print("Enter your choice (1-6): ")
+		if !scanner.Scan() {
+			// stdin was closed (e.g. no terminal attached), use "serve" for headless mode
+			fmt.Println("\nInput closed. Saving data before exit...")
+			if err := saveToExcel(); err != nil {
+				fmt.Printf("Error saving data to Excel: %v\n", err)
+			}
+			return
+		}
 		choice := scanner.Text()
 
 		switch choice {
@@ -58,6 +95,8 @@ func main() {
 		case "4":
 			handleSettings(scanner)
 		case "5":
+			handleAlerts(scanner)
+		case "6":
 			fmt.Println("Exiting program...")
 			err := saveToExcel()
 			if err != nil {
@@ -70,6 +109,19 @@ func main() {
 	}
 }
 
+// consoleReader reads the console input with dataMu released. The console menu
+// holds dataMu while it shows or changes data and lets API requests and jobs in
+// while it waits for the user, so a menu action never sees a half-made change.
+type consoleReader struct {
+	r io.Reader
+}
+
+func (c consoleReader) Read(p []byte) (int, error) {
+	dataMu.Unlock()
+	defer dataMu.Lock()
+	return c.r.Read(p)
+}
+
 // setupSignalHandling sets up handlers for system signals to ensure data is saved
 // on unexpected termination
 func setupSignalHandling() {
@@ -79,6 +131,7 @@ func setupSignalHandling() {
 	go func() {
 		<-c
 		fmt.Println("\nReceived termination signal. Saving data before exit...")
+		dataMu.Lock()
 		err := saveToExcel()
 		if err != nil {
 			fmt.Printf("Error saving data to Excel: %v\n", err)
@@ -93,5 +146,10 @@ func displayMainMenu() {
 	fmt.Println("2. View Current KPIs")
 	fmt.Println("3. Generate Reports")
 	fmt.Println("4. Settings")
-	fmt.Println("5. Exit")
+	if open := countAlerts(AlertOpen); open > 0 {
+		fmt.Printf("5. Alerts (%d open)\n", open)
+	} else {
+		fmt.Println("5. Alerts")
+	}
+	fmt.Println("6. Exit")
 }
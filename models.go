@@ -1,6 +1,7 @@
 package main
 
 import (
+	"sync"
 	"time"
 )
 
@@ -9,6 +10,16 @@ type Role struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Department  string `json:"department"`
+}
+
+// Employee represents a person holding a role
+type Employee struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	RoleID     int       `json:"role_id"`
+	HireDate   time.Time `json:"hire_date"`
+	BaseSalary float64   `json:"base_salary"` // Annual base salary used for bonus payouts
 }
 
 // KPI represents a Key Performance Indicator
@@ -21,20 +32,23 @@ type KPI struct {
 	Metric      string  `json:"metric"`
 	Unit        string  `json:"unit"` // The unit of measurement
 	Target      string  `json:"target"`
-	TargetValue float64 `json:"target_value"` // Numerical target value
-	Operator    string  `json:"operator"`     // "≤", "≥", "=", etc.
-	Weight      float64 `json:"weight"`       // In percentage
+	TargetValue float64 `json:"target_value"`        // Numerical target value
+	Operator    string  `json:"operator"`            // "≤", "≥", "=", etc.
+	Weight      float64 `json:"weight"`              // In percentage
+	Frequency   string  `json:"frequency,omitempty"` // "monthly" (default), "quarterly" or "yearly", see submissions.go
+	Formula     string  `json:"formula,omitempty"`   // Derived KPIs only, e.g. "leavers / avg_headcount * 100", see formula.go
 }
 
 // Measurement represents an actual KPI measurement
 type Measurement struct {
-	ID          int       `json:"id"`
-	KPIID       int       `json:"kpi_id"`
-	MetricValue float64   `json:"metric_value"`
-	Unit        string    `json:"unit"` // e.g., "days", "%", "count"
-	Period      time.Time `json:"period"`
-	Notes       string    `json:"notes"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int                `json:"id"`
+	KPIID       int                `json:"kpi_id"`
+	MetricValue float64            `json:"metric_value"`
+	Unit        string             `json:"unit"` // e.g., "days", "%", "count"
+	Period      time.Time          `json:"period"`
+	Notes       string             `json:"notes"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Inputs      map[string]float64 `json:"inputs,omitempty"` // Raw inputs of a derived KPI's formula
 }
 
 // Achievement represents the calculation of a KPI achievement
@@ -57,10 +71,49 @@ type Report struct {
 // Settings for the application
 type Settings struct {
 	DatabasePath string `json:"database_path"`
-	ExcelDBPath  string `json:"excel_db_path"`
+
+	// Server and storage configuration, see config.go
+	Server               ServerSettings `json:"server"`
+	CORSOrigins          []string       `json:"cors_origins"`
+	StorageBackend       string         `json:"storage_backend"`
+	BackupRetention      int            `json:"backup_retention"` // Number of Excel backups kept, 0 keeps all
+	FiscalYearStartMonth int            `json:"fiscal_year_start_month"`
+
+	// Missing-data policy for overall scores, see scoring.go
+	ScoringPolicy       string         `json:"scoring_policy,omitempty"`
+	RoleScoringPolicies map[int]string `json:"role_scoring_policies,omitempty"`
+
+	// Rating bands and annual appraisal weighting, see appraisal.go
+	RatingBands     []RatingBand         `json:"rating_bands,omitempty"`
+	RoleRatingBands map[int][]RatingBand `json:"role_rating_bands,omitempty"`
+	Appraisal       AppraisalConfig      `json:"appraisal"`
+
+	// Bonus policy evaluated against yearly scores, see bonus.go
+	BonusPolicy *BonusPolicy `json:"bonus_policy,omitempty"`
+
+	// Submission deadline and reminder notifications, see submissions.go
+	SubmissionDeadlineDay int              `json:"submission_deadline_day"` // Day of the following month
+	SMTP                  SMTPSettings     `json:"smtp"`
+	Reminders             ReminderSettings `json:"reminders"`
+
+	// Distribution lists of emailed reports, see report_mail.go
+	ReportDistribution ReportDistributionSettings `json:"report_distribution"`
+
+	// Outlier check of entered measurements, see anomalies.go
+	Anomalies AnomalySettings `json:"anomalies"`
+
+	// Recurring jobs run by the scheduler in server mode, see jobs.go
+	Jobs []JobConfig `json:"jobs,omitempty"`
 }
 
 // Global variables to store data
 var roles []Role
+var employees []Employee
 var kpis []KPI
 var measurements []Measurement
+
+// dataMu guards the data above, the settings and the other loaded data. API
+// requests hold it for their whole duration (see lockData), the console menu
+// whenever it is not waiting for input (see consoleReader) and scheduled jobs
+// around each read or change (see runJobAction).
+var dataMu sync.RWMutex
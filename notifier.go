@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification channels that can be listed in the reminder settings
+const (
+	NotifierSMTP    = "smtp"
+	NotifierWebhook = "webhook"
+)
+
+var notifierTypes = []string{NotifierSMTP, NotifierWebhook}
+
+// secretMask replaces passwords and secrets in API responses. Sending it back
+// in an update keeps the stored value.
+const secretMask = "********"
+
+// smtpTimeout limits a whole SMTP conversation, a variable so tests can shorten it
+var smtpTimeout = 30 * time.Second
+
+// SMTPSettings configures the SMTP server used for email notifications
+type SMTPSettings struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username,omitempty"` // Empty for servers without authentication
+	Password string `json:"password,omitempty"` // Prefer KPI_SMTP_PASSWORD over storing it in the file
+	From     string `json:"from"`
+}
+
+// ReminderSettings selects the notifiers used for submission reminders and their recipients
+type ReminderSettings struct {
+	Notifiers         []string         `json:"notifiers"`                    // "smtp" and/or "webhook"
+	Recipients        map[int][]string `json:"recipients,omitempty"`         // Email addresses per role ID
+	DefaultRecipients []string         `json:"default_recipients,omitempty"` // For roles without recipients
+	WebhookURL        string           `json:"webhook_url,omitempty"`
+	WebhookSecret     string           `json:"webhook_secret,omitempty"` // Signs the payload like webhook subscriptions
+}
+
+// ReportDistributionSettings lists who receives emailed reports, see report_mail.go
+type ReportDistributionSettings struct {
+	Recipients        map[int][]string `json:"recipients,omitempty"`         // Receive the report of their role
+	DefaultRecipients []string         `json:"default_recipients,omitempty"` // Receive the report of all roles
+	Attachments       []string         `json:"attachments"`                  // Formats attached to the HTML body: csv, xlsx
+}
+
+// Notification is a message for one or more people
+type Notification struct {
+	Type        string       // Event type sent by the webhook notifier, e.g. submission.reminder
+	Subject     string       // Email subject
+	Body        string       // Plain text body
+	HTML        string       // Optional HTML body, sent instead of the plain text one by email
+	To          []string     // Email recipients, not used by the webhook notifier
+	Attachments []Attachment // Email attachments, not used by the webhook notifier
+	Data        interface{}  // Structured data for the webhook notifier
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Notifier delivers notifications over one channel
+type Notifier interface {
+	Name() string
+	Notify(n Notification) error
+}
+
+// SMTPNotifier sends notifications as email
+type SMTPNotifier struct {
+	Settings SMTPSettings
+}
+
+// Name returns the notifier name used in the settings
+func (SMTPNotifier) Name() string { return NotifierSMTP }
+
+// Notify sends the notification to its recipients. STARTTLS is used when the
+// server offers it, as net/smtp refuses to send credentials in plain text.
+func (s SMTPNotifier) Notify(n Notification) error {
+	if len(n.To) == 0 {
+		return fmt.Errorf("no email recipients configured")
+	}
+
+	host := s.Settings.Host
+	addr := net.JoinHostPort(host, strconv.Itoa(s.Settings.Port))
+	conn, err := net.DialTimeout("tcp", addr, smtpTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake with %s failed: %v", addr, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+	if s.Settings.Username != "" {
+		auth := smtp.PlainAuth("", s.Settings.Username, s.Settings.Password, host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(s.Settings.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address '%s'", s.Settings.From)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		address, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("invalid recipient '%s'", to)
+		}
+		if err := client.Rcpt(address.Address); err != nil {
+			return fmt.Errorf("recipient %s rejected: %v", to, err)
+		}
+	}
+
+	wc, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(buildEmail(s.Settings.From, n)); err != nil {
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildEmail formats an email message with CRLF line endings. Messages with an
+// HTML body or attachments are sent as multipart/mixed.
+func buildEmail(from string, n Notification) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if n.HTML == "" && len(n.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		b.WriteString(crlfText(n.Body))
+		return b.Bytes()
+	}
+
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	contentType, body := "text/plain; charset=utf-8", n.Body
+	if n.HTML != "" {
+		contentType, body = "text/html; charset=utf-8", n.HTML
+	}
+	part, _ := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	qp := quotedprintable.NewWriter(part)
+	qp.Write([]byte(body))
+	qp.Close()
+
+	for _, a := range n.Attachments {
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		writeBase64Lines(part, a.Data)
+	}
+	mw.Close()
+
+	return b.Bytes()
+}
+
+// crlfText converts a text body to CRLF line endings ending with a line break
+func crlfText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return strings.ReplaceAll(text, "\n", "\r\n")
+}
+
+// writeBase64Lines writes data base64 encoded in lines of 76 characters, as MIME requires
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// WebhookNotifier posts notifications as JSON, signed like webhook subscriptions
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+}
+
+// Name returns the notifier name used in the settings
+func (WebhookNotifier) Name() string { return NotifierWebhook }
+
+// Notify posts the notification once. Failures are returned rather than retried,
+// reminders can simply be sent again.
+func (h WebhookNotifier) Notify(n Notification) error {
+	payload := struct {
+		Type    string      `json:"type"`
+		Subject string      `json:"subject"`
+		Body    string      `json:"body"`
+		Data    interface{} `json:"data,omitempty"`
+		Time    time.Time   `json:"time"`
+	}{n.Type, n.Subject, n.Body, n.Data, time.Now()}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kpi-tracker-webhook")
+	req.Header.Set("X-KPI-Event", n.Type)
+	req.Header.Set("X-KPI-Timestamp", strconv.FormatInt(timestamp, 10))
+	if h.Secret != "" {
+		req.Header.Set("X-KPI-Signature", signWebhookPayload(h.Secret, timestamp, body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// reminderNotifiers returns the notifiers enabled in the reminder settings
+func reminderNotifiers() []Notifier {
+	var notifiers []Notifier
+	for _, name := range appSettings.Reminders.Notifiers {
+		switch name {
+		case NotifierSMTP:
+			notifiers = append(notifiers, SMTPNotifier{Settings: appSettings.SMTP})
+		case NotifierWebhook:
+			notifiers = append(notifiers, WebhookNotifier{URL: appSettings.Reminders.WebhookURL, Secret: appSettings.Reminders.WebhookSecret})
+		}
+	}
+	return notifiers
+}
+
+// reminderRecipients returns the email addresses for a role, falling back to the default recipients
+func reminderRecipients(roleID int) []string {
+	if to := appSettings.Reminders.Recipients[roleID]; len(to) > 0 {
+		return to
+	}
+	return appSettings.Reminders.DefaultRecipients
+}
+
+// validateNotificationSettings checks the SMTP and reminder settings
+func validateNotificationSettings(s Settings) error {
+	if s.SMTP.Port < 1 || s.SMTP.Port > 65535 {
+		return fmt.Errorf("SMTP port must be between 1 and 65535")
+	}
+	if s.SMTP.From != "" {
+		if _, err := mail.ParseAddress(s.SMTP.From); err != nil {
+			return fmt.Errorf("invalid SMTP sender address '%s'", s.SMTP.From)
+		}
+	}
+
+	for _, name := range s.Reminders.Notifiers {
+		switch name {
+		case NotifierSMTP:
+			if s.SMTP.Host == "" || s.SMTP.From == "" {
+				return fmt.Errorf("the smtp notifier needs an SMTP host and sender address")
+			}
+		case NotifierWebhook:
+			u, err := url.Parse(s.Reminders.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("the webhook notifier needs an absolute http or https webhook URL")
+			}
+		default:
+			return fmt.Errorf("unknown notifier '%s', available: %s", name, strings.Join(notifierTypes, ", "))
+		}
+	}
+
+	for _, format := range s.ReportDistribution.Attachments {
+		if !containsString(reportAttachmentFormats, format) {
+			return fmt.Errorf("unknown report attachment '%s', available: %s", format, strings.Join(reportAttachmentFormats, ", "))
+		}
+	}
+
+	addresses := append([]string{}, s.Reminders.DefaultRecipients...)
+	addresses = append(addresses, s.ReportDistribution.DefaultRecipients...)
+	for _, to := range s.Reminders.Recipients {
+		addresses = append(addresses, to...)
+	}
+	for _, to := range s.ReportDistribution.Recipients {
+		addresses = append(addresses, to...)
+	}
+	for _, address := range addresses {
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid recipient '%s'", address)
+		}
+	}
+
+	return nil
+}
+
+// maskNotificationSecrets hides the SMTP password and reminder webhook secret
+func maskNotificationSecrets(s Settings) Settings {
+	if s.SMTP.Password != "" {
+		s.SMTP.Password = secretMask
+	}
+	if s.Reminders.WebhookSecret != "" {
+		s.Reminders.WebhookSecret = secretMask
+	}
+	return s
+}
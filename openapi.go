@@ -0,0 +1,626 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// apiParam is a query parameter of an API operation. Path parameters are taken
+// from the {name} segments of the path.
+type apiParam struct {
+	Name        string
+	Type        string // "string", "integer", "number" or "boolean"
+	Description string
+	Required    bool
+}
+
+// apiOperation documents one method of a route registered in newAPIRouter
+type apiOperation struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Query       []apiParam
+	Request     interface{} // Zero value of the JSON request body, nil if none
+	Multipart   bool        // Request is a multipart upload instead of JSON
+	Response    interface{} // Zero value of the JSON response, nil for a generic object
+	List        bool        // Response is a page of Response items
+	Status      int         // Success status, 200 when zero
+	ContentType []string    // Extra response content types, e.g. text/csv
+	Stream      bool        // Response items are sent as server-sent events
+	Errors      []int
+}
+
+// Reusable parameter sets
+var (
+	listQuery = []apiParam{
+		{Name: "q", Type: "string", Description: "Text search"},
+		{Name: "sort", Type: "string", Description: "Comma-separated fields, prefix with - for descending"},
+		{Name: "limit", Type: "integer", Description: fmt.Sprintf("Page size (default %d, max %d)", defaultListLimit, maxListLimit)},
+		{Name: "cursor", Type: "string", Description: "next_cursor of the previous page"},
+	}
+	periodRangeQuery = []apiParam{
+		{Name: "start", Type: "string", Description: "First period, YYYY-MM", Required: true},
+		{Name: "end", Type: "string", Description: "Last period, YYYY-MM (defaults to start)"},
+		{Name: "min_coverage", Type: "number", Description: "Minimum coverage percentage to be ranked"},
+	}
+	reportFormatQuery = []apiParam{
+		{Name: "format", Type: "string", Description: "json (default), txt, csv or html"},
+	}
+	reportContentTypes = []string{"text/plain", "text/csv", "text/html"}
+)
+
+// withListQuery adds the common list parameters to a set of filters
+func withListQuery(filters ...apiParam) []apiParam {
+	return append(filters, listQuery...)
+}
+
+// apiOperations describes every route of the REST API
+var apiOperations = []apiOperation{
+	// Roles
+	{Method: "GET", Path: "/api/roles", Tag: "Roles", Summary: "List roles", Response: Role{}, List: true,
+		Query: withListQuery(apiParam{Name: "role_id", Type: "integer"})},
+	{Method: "GET", Path: "/api/roles/{id}", Tag: "Roles", Summary: "Get a role", Response: Role{}, Errors: []int{404}},
+	{Method: "PUT", Path: "/api/roles/{id}", Tag: "Roles", Summary: "Update the name, description and department of a role",
+		Request: Role{}, Response: Role{}, Errors: []int{404, 422}},
+
+	// Employees
+	{Method: "GET", Path: "/api/employees", Tag: "Employees", Summary: "List employees", Response: Employee{}, List: true,
+		Query: withListQuery(apiParam{Name: "role_id", Type: "integer"})},
+	{Method: "POST", Path: "/api/employees", Tag: "Employees", Summary: "Create an employee", Request: Employee{}, Response: Employee{},
+		Status: http.StatusCreated, Errors: []int{422}},
+
+	// KPIs
+	{Method: "GET", Path: "/api/kpis", Tag: "KPIs", Summary: "List KPIs", Response: KPI{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "role_id", Type: "integer"},
+			apiParam{Name: "kpi_id", Type: "integer"},
+			apiParam{Name: "category", Type: "string", Description: "Quantitative or Qualitative"})},
+	{Method: "POST", Path: "/api/kpis", Tag: "KPIs", Summary: "Create a KPI", Request: KPI{}, Response: KPI{},
+		Status: http.StatusCreated, Errors: []int{422}},
+	{Method: "GET", Path: "/api/kpis/{id}", Tag: "KPIs", Summary: "Get a KPI", Response: KPI{}, Errors: []int{404}},
+	{Method: "PUT", Path: "/api/kpis/{id}", Tag: "KPIs", Summary: "Update a KPI", Request: KPI{}, Response: KPI{},
+		Errors: []int{404, 422}},
+	{Method: "DELETE", Path: "/api/kpis/{id}", Tag: "KPIs", Summary: "Delete a KPI without measurements that no formula refers to",
+		Status: http.StatusNoContent, Errors: []int{404, 409}},
+	{Method: "GET", Path: "/api/kpis/{id}/formula", Tag: "KPIs", Summary: "Raw inputs and referenced KPIs of a derived KPI's formula, and the KPIs derived from it",
+		Response: KPIFormula{}, Errors: []int{404}},
+	{Method: "GET", Path: "/api/roles/{id}/kpis", Tag: "KPIs", Summary: "List the KPIs of a role", Response: KPI{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "kpi_id", Type: "integer"},
+			apiParam{Name: "category", Type: "string", Description: "Quantitative or Qualitative"})},
+
+	// Measurements
+	{Method: "GET", Path: "/api/measurements", Tag: "Measurements", Summary: "List measurements", Response: Measurement{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "role_id", Type: "integer"},
+			apiParam{Name: "kpi_id", Type: "integer"},
+			apiParam{Name: "category", Type: "string"},
+			apiParam{Name: "from", Type: "string", Description: "First period, YYYY-MM"},
+			apiParam{Name: "to", Type: "string", Description: "Last period, YYYY-MM"},
+			apiParam{Name: "year", Type: "integer"},
+			apiParam{Name: "month", Type: "integer"})},
+	{Method: "POST", Path: "/api/measurements", Tag: "Measurements", Summary: "Create a measurement",
+		Query: []apiParam{
+			{Name: "upsert", Type: "boolean", Description: "Update the existing measurement instead of returning 409"},
+			{Name: "confirm", Type: "boolean", Description: "Save values that look anomalous instead of returning 428"},
+		},
+		Request: MeasurementInput{}, Response: Measurement{}, Status: http.StatusCreated, Errors: []int{409, 422, 428}},
+	{Method: "POST", Path: "/api/measurements/import", Tag: "Measurements", Summary: "Import measurements from a CSV or xlsx upload",
+		Multipart: true, Response: ImportResult{}, Errors: []int{422}},
+	{Method: "POST", Path: "/api/measurements/batch", Tag: "Measurements", Summary: "Create or update several measurements, all or none",
+		Query:   []apiParam{{Name: "confirm", Type: "boolean", Description: "Save values that look anomalous instead of returning 428"}},
+		Request: []MeasurementInput{}, Response: BatchResponse{}, Errors: []int{422, 428}},
+	{Method: "GET", Path: "/api/measurements/anomalies", Tag: "Measurements", Summary: "Measurements that look anomalous against their KPI's other values and target",
+		Response: AnomalyFinding{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "role_id", Type: "integer"},
+			apiParam{Name: "kpi_id", Type: "integer"},
+			apiParam{Name: "from", Type: "string", Description: "First period, YYYY-MM"},
+			apiParam{Name: "to", Type: "string", Description: "Last period, YYYY-MM"},
+			apiParam{Name: "check", Type: "string", Description: strings.Join(anomalyChecks, ", ")})},
+	{Method: "GET", Path: "/api/measurements/{id}", Tag: "Measurements", Summary: "Get a measurement and its ETag",
+		Response: Measurement{}, Errors: []int{404}},
+	{Method: "PUT", Path: "/api/measurements/{id}", Tag: "Measurements", Summary: "Replace a measurement (send If-Match)",
+		Query:   []apiParam{{Name: "confirm", Type: "boolean", Description: "Save values that look anomalous instead of returning 428"}},
+		Request: MeasurementUpdate{}, Response: Measurement{}, Errors: []int{404, 412, 422, 428}},
+	{Method: "PATCH", Path: "/api/measurements/{id}", Tag: "Measurements", Summary: "Update some fields of a measurement (send If-Match)",
+		Query:   []apiParam{{Name: "confirm", Type: "boolean", Description: "Save values that look anomalous instead of returning 428"}},
+		Request: MeasurementPatch{}, Response: Measurement{}, Errors: []int{404, 412, 422, 428}},
+	{Method: "GET", Path: "/api/kpis/{id}/measurements", Tag: "Measurements", Summary: "List the measurements of a KPI",
+		Response: Measurement{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "from", Type: "string", Description: "First period, YYYY-MM"},
+			apiParam{Name: "to", Type: "string", Description: "Last period, YYYY-MM"},
+			apiParam{Name: "year", Type: "integer"},
+			apiParam{Name: "month", Type: "integer"})},
+
+	// Reports
+	{Method: "GET", Path: "/api/reports/monthly/{year}/{month}", Tag: "Reports", Summary: "Monthly report per role",
+		Query: reportFormatQuery, ContentType: reportContentTypes},
+	{Method: "GET", Path: "/api/reports/quarterly/{year}/{quarter}", Tag: "Reports", Summary: "Report of a fiscal quarter",
+		Query: reportFormatQuery, ContentType: reportContentTypes},
+	{Method: "GET", Path: "/api/reports/yearly/{year}", Tag: "Reports", Summary: "Report of a fiscal year",
+		Query: reportFormatQuery, ContentType: reportContentTypes},
+	{Method: "POST", Path: "/api/reports/custom", Tag: "Reports", Summary: "Report for a custom period range",
+		Request: CustomReportRequest{}, ContentType: reportContentTypes, Errors: []int{422}},
+	{Method: "GET", Path: "/api/reports/compare", Tag: "Reports", Summary: "Compare two period ranges",
+		Query: append([]apiParam{
+			{Name: "base_start", Type: "string", Required: true},
+			{Name: "base_end", Type: "string"},
+			{Name: "compare_start", Type: "string", Required: true},
+			{Name: "compare_end", Type: "string"},
+		}, reportFormatQuery...),
+		Response: ComparisonReport{}, ContentType: reportContentTypes},
+	{Method: "POST", Path: "/api/reports/send", Tag: "Reports", Summary: "Email a report as HTML with CSV/xlsx attachments to the distribution lists",
+		Request: ReportSendRequest{}, Response: ReportSendResponse{}, Errors: []int{409, 422}},
+
+	// Dashboard
+	{Method: "GET", Path: "/api/dashboard/overview", Tag: "Dashboard", Summary: "Score overview of every role for a month",
+		Query: []apiParam{{Name: "year", Type: "integer"}, {Name: "month", Type: "integer"}}},
+	{Method: "GET", Path: "/api/dashboard/trends", Tag: "Dashboard", Summary: "Monthly score trends for a year",
+		Query: []apiParam{{Name: "year", Type: "integer"}}},
+	{Method: "GET", Path: "/api/forecast", Tag: "Dashboard", Summary: "Year-end forecast of KPI achievement against cumulative annual targets",
+		Query: []apiParam{
+			{Name: "year", Type: "integer", Description: "Fiscal year, defaults to the current one"},
+			{Name: "method", Type: "string", Description: strings.Join(forecastMethods, ", ") + " (default linear)"},
+			{Name: "as_of", Type: "string", Description: "Last known period YYYY-MM, defaults to the last closed month"},
+			{Name: "window", Type: "integer", Description: fmt.Sprintf("Values averaged by moving_average (default %d)", defaultMovingAverageWindow)},
+			{Name: "role_id", Type: "integer"},
+			{Name: "kpi_id", Type: "integer"},
+		},
+		Response: ForecastReport{}},
+	{Method: "POST", Path: "/api/simulate", Tag: "Dashboard", Summary: "Score a what-if scenario of hypothetical values, weights and targets without saving it",
+		Request: SimulationRequest{}, Response: SimulationResult{}, Errors: []int{422}},
+
+	// Events
+	{Method: "GET", Path: "/api/events", Tag: "Events", Summary: "Stream of data changes as server-sent events",
+		Query: []apiParam{
+			{Name: "role_id", Type: "string", Description: "Comma-separated role IDs; events about all roles are always sent"},
+			{Name: "type", Type: "string", Description: "Comma-separated event types: " + strings.Join(eventTypes, ", ")},
+			{Name: "last_event_id", Type: "integer", Description: "Resume after this event, like the Last-Event-ID header"},
+		},
+		Response: Event{}, Stream: true},
+
+	// Period close
+	{Method: "GET", Path: "/api/periods/closed", Tag: "Periods", Summary: "Closed months", Response: []ClosedPeriod{}},
+	{Method: "POST", Path: "/api/periods/{period}/close", Tag: "Periods", Summary: "Close a month (YYYY-MM) against measurement changes",
+		Response: ClosedPeriod{}, Errors: []int{409}},
+	{Method: "POST", Path: "/api/periods/{period}/reopen", Tag: "Periods", Summary: "Reopen a closed month for changes",
+		Response: ClosedPeriod{}, Errors: []int{404}},
+
+	// Alerts
+	{Method: "GET", Path: "/api/alerts", Tag: "Alerts", Summary: "Alert history, newest first", Response: Alert{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "status", Type: "string", Description: strings.Join(alertStatuses, ", ")},
+			apiParam{Name: "role_id", Type: "integer"},
+			apiParam{Name: "kpi_id", Type: "integer"},
+			apiParam{Name: "from", Type: "string", Description: "First period, YYYY-MM"},
+			apiParam{Name: "to", Type: "string", Description: "Last period, YYYY-MM"})},
+	{Method: "POST", Path: "/api/alerts/{id}/acknowledge", Tag: "Alerts", Summary: "Acknowledge an open alert",
+		Request: AcknowledgeRequest{}, Response: Alert{}, Errors: []int{404, 409}},
+	{Method: "GET", Path: "/api/alerts/rules", Tag: "Alerts", Summary: "List alert rules", Response: []AlertRule{}},
+	{Method: "POST", Path: "/api/alerts/rules", Tag: "Alerts", Summary: "Add an alert rule for a KPI or role",
+		Request: AlertRule{}, Response: AlertRule{}, Status: http.StatusCreated, Errors: []int{422}},
+	{Method: "PUT", Path: "/api/alerts/rules/{id}", Tag: "Alerts", Summary: "Replace an alert rule",
+		Request: AlertRule{}, Response: AlertRule{}, Errors: []int{404, 422}},
+	{Method: "DELETE", Path: "/api/alerts/rules/{id}", Tag: "Alerts", Summary: "Delete an alert rule, keeping its alerts",
+		Status: http.StatusNoContent, Errors: []int{404}},
+
+	// Submissions
+	{Method: "GET", Path: "/api/submissions", Tag: "Submissions", Summary: "Completeness per role and period, listing KPIs without measurements",
+		Response: RoleSubmission{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "period", Type: "string", Description: "Period YYYY-MM, defaults to the last closed month"},
+			apiParam{Name: "from", Type: "string", Description: "First period, YYYY-MM"},
+			apiParam{Name: "to", Type: "string", Description: "Last period, YYYY-MM"},
+			apiParam{Name: "role_id", Type: "integer"},
+			apiParam{Name: "employee_id", Type: "integer", Description: "Roles of an employee"},
+			apiParam{Name: "status", Type: "string", Description: strings.Join(submissionStatuses, ", ")})},
+	{Method: "POST", Path: "/api/submissions/remind", Tag: "Submissions", Summary: "Send reminders for missing measurements through the configured notifiers",
+		Request: ReminderRequest{}, Response: ReminderResponse{}, Errors: []int{409, 422}},
+
+	// Jobs
+	{Method: "GET", Path: "/api/jobs", Tag: "Jobs", Summary: "List scheduled jobs with their next and last run", Response: []JobStatus{}},
+	{Method: "GET", Path: "/api/jobs/{name}", Tag: "Jobs", Summary: "Get a scheduled job", Response: JobStatus{}, Errors: []int{404}},
+	{Method: "POST", Path: "/api/jobs/{name}/run", Tag: "Jobs", Summary: "Run a job now in the background",
+		Response: JobRun{}, Status: http.StatusAccepted, Errors: []int{404, 409}},
+	{Method: "POST", Path: "/api/jobs/{name}/pause", Tag: "Jobs", Summary: "Stop scheduling a job", Response: JobStatus{}, Errors: []int{404}},
+	{Method: "POST", Path: "/api/jobs/{name}/resume", Tag: "Jobs", Summary: "Schedule a paused job again", Response: JobStatus{}, Errors: []int{404}},
+	{Method: "GET", Path: "/api/jobs/runs", Tag: "Jobs", Summary: "Job run history, newest first", Response: JobRun{}, List: true,
+		Query: withListQuery(
+			apiParam{Name: "job", Type: "string", Description: "Job name"},
+			apiParam{Name: "status", Type: "string", Description: strings.Join(jobRunStatuses, ", ")})},
+
+	// Webhooks
+	{Method: "GET", Path: "/api/webhooks", Tag: "Webhooks", Summary: "List webhook subscriptions (secrets omitted)",
+		Response: Webhook{}, List: true, Query: listQuery},
+	{Method: "POST", Path: "/api/webhooks", Tag: "Webhooks", Summary: "Subscribe a URL to events; the response includes the secret",
+		Request: WebhookInput{}, Response: Webhook{}, Status: http.StatusCreated, Errors: []int{422}},
+	{Method: "GET", Path: "/api/webhooks/{id}", Tag: "Webhooks", Summary: "Get a webhook subscription", Response: Webhook{}, Errors: []int{404}},
+	{Method: "PUT", Path: "/api/webhooks/{id}", Tag: "Webhooks", Summary: "Update a webhook subscription",
+		Request: WebhookInput{}, Response: Webhook{}, Errors: []int{404, 422}},
+	{Method: "DELETE", Path: "/api/webhooks/{id}", Tag: "Webhooks", Summary: "Delete a webhook subscription",
+		Status: http.StatusNoContent, Errors: []int{404}},
+	{Method: "GET", Path: "/api/webhooks/{id}/deliveries", Tag: "Webhooks", Summary: "Delivery log of a webhook, newest first",
+		Response: WebhookDelivery{}, List: true, Query: listQuery, Errors: []int{404}},
+	{Method: "POST", Path: "/api/webhooks/{id}/test", Tag: "Webhooks", Summary: "Send a webhook.test event once",
+		Response: WebhookDelivery{}, Errors: []int{404}},
+
+	// Rankings
+	{Method: "GET", Path: "/api/rankings/leaderboard", Tag: "Rankings", Summary: "Leaderboard of roles",
+		Query: append([]apiParam{
+			{Name: "department", Type: "string"},
+			{Name: "limit", Type: "integer"},
+			{Name: "order", Type: "string", Description: "top or bottom"},
+		}, periodRangeQuery...),
+		Response: Ranking{}},
+	{Method: "GET", Path: "/api/rankings/departments", Tag: "Rankings", Summary: "Rankings within each department",
+		Query: periodRangeQuery, Response: []Ranking{}},
+	{Method: "GET", Path: "/api/rankings/competency", Tag: "Rankings", Summary: "Ranking by a shared competency KPI",
+		Query:    append([]apiParam{{Name: "kpi_name", Type: "string", Required: true}}, periodRangeQuery...),
+		Response: Ranking{}, Errors: []int{404}},
+	{Method: "GET", Path: "/api/rankings/employees", Tag: "Rankings", Summary: "Ranking of employees by their role's score while employed",
+		Query: append([]apiParam{
+			{Name: "department", Type: "string"},
+			{Name: "limit", Type: "integer"},
+			{Name: "order", Type: "string", Description: "top or bottom"},
+		}, periodRangeQuery...),
+		Response: Ranking{}},
+
+	// Appraisals and bonus
+	{Method: "GET", Path: "/api/appraisals/{year}", Tag: "Appraisals", Summary: "Final appraisals of a fiscal year",
+		Response: struct {
+			Year        int             `json:"year"`
+			Config      AppraisalConfig `json:"config"`
+			Appraisals  []Appraisal     `json:"appraisals"`
+			GeneratedAt time.Time       `json:"generated_at"`
+		}{}},
+	{Method: "GET", Path: "/api/bonus/{year}", Tag: "Bonus", Summary: "Bonus payouts under the configured policy",
+		Query:    []apiParam{{Name: "format", Type: "string", Description: "json (default) or xlsx"}},
+		Response: BonusRun{}, ContentType: []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}},
+	{Method: "POST", Path: "/api/bonus/{year}/simulate", Tag: "Bonus", Summary: "Dry run of a candidate bonus policy",
+		Request: BonusPolicy{}, Errors: []int{422}},
+
+	// Settings
+	{Method: "GET", Path: "/api/settings", Tag: "Settings", Summary: "Effective settings and where each value came from", Response: Settings{}},
+	{Method: "PUT", Path: "/api/settings", Tag: "Settings", Summary: "Update the settings", Request: Settings{}, Response: Settings{},
+		Errors: []int{422}},
+	{Method: "POST", Path: "/api/settings/reload-excel", Tag: "Settings", Summary: "Reload all data from the Excel database"},
+	{Method: "POST", Path: "/api/settings/save-excel", Tag: "Settings", Summary: "Save all data to the Excel database"},
+
+	// Documentation
+	{Method: "GET", Path: "/api/openapi.json", Tag: "Documentation", Summary: "This OpenAPI document"},
+	{Method: "GET", Path: "/api/docs", Tag: "Documentation", Summary: "API documentation page", ContentType: []string{"text/html"}},
+}
+
+// pathParamPattern matches {name} segments of a route
+var pathParamPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// openAPISchemas collects the component schemas while the document is built
+type openAPISchemas map[string]interface{}
+
+// schemaFor returns the JSON schema of a Go type, registering named structs as components
+func (schemas openAPISchemas) schemaFor(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		return schemas.schemaFor(t.Elem())
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemas.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemas.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return schemas.structSchema(t)
+		}
+		if _, ok := schemas[t.Name()]; !ok {
+			// Register first so recursive types terminate
+			schemas[t.Name()] = map[string]interface{}{}
+			schemas[t.Name()] = schemas.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+
+	// interface{} and anything else
+	return map[string]interface{}{}
+}
+
+// structSchema returns the object schema of a struct from its json tags
+func (schemas openAPISchemas) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if parts := strings.Split(tag, ","); parts[0] != "" {
+				name = parts[0]
+			}
+		} else if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// Embedded structs without a tag are flattened by encoding/json
+			embedded := schemas.structSchema(field.Type)
+			for k, v := range embedded["properties"].(map[string]interface{}) {
+				properties[k] = v
+			}
+			continue
+		}
+
+		properties[name] = schemas.schemaFor(field.Type)
+	}
+
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// buildOpenAPISpec builds the OpenAPI 3 document of the REST API
+func buildOpenAPISpec() map[string]interface{} {
+	schemas := openAPISchemas{}
+	errorSchema := schemas.schemaFor(reflect.TypeOf(ErrorResponse{}))
+	paths := make(map[string]map[string]interface{})
+
+	for _, op := range apiOperations {
+		var parameters []interface{}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			// Job names and YYYY-MM periods are the only path parameters that are not numbers
+			paramType := "integer"
+			if match[1] == "name" || match[1] == "period" {
+				paramType = "string"
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name": match[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": paramType},
+			})
+		}
+		for _, p := range op.Query {
+			param := map[string]interface{}{
+				"name": p.Name, "in": "query", "required": p.Required,
+				"schema": map[string]interface{}{"type": p.Type},
+			}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			parameters = append(parameters, param)
+		}
+
+		responseSchema := map[string]interface{}{"type": "object"}
+		if op.Response != nil {
+			responseSchema = schemas.schemaFor(reflect.TypeOf(op.Response))
+		}
+		if op.List {
+			pagination := schemas.schemaFor(reflect.TypeOf(Pagination{}))
+			responseSchema = map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"data":       map[string]interface{}{"type": "array", "items": responseSchema},
+					"pagination": pagination,
+				},
+			}
+		}
+
+		content := map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": responseSchema},
+		}
+		if op.Stream {
+			content = map[string]interface{}{
+				"text/event-stream": map[string]interface{}{"schema": responseSchema},
+			}
+		}
+		for _, contentType := range op.ContentType {
+			content[contentType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]interface{}{"description": http.StatusText(status)}
+		if status != http.StatusNoContent {
+			success["content"] = content
+		}
+		responses := map[string]interface{}{fmt.Sprint(status): success}
+
+		// Every operation can fail with a malformed request or a server error
+		errorStatuses := append([]int{http.StatusBadRequest, http.StatusInternalServerError}, op.Errors...)
+		for _, code := range errorStatuses {
+			responses[fmt.Sprint(code)] = map[string]interface{}{
+				"description": http.StatusText(code),
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": errorSchema},
+				},
+			}
+		}
+
+		operation := map[string]interface{}{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+			"responses":   responses,
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if op.Multipart {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"multipart/form-data": map[string]interface{}{
+						"schema": map[string]interface{}{
+							"type":     "object",
+							"required": []string{"file"},
+							"properties": map[string]interface{}{
+								"file":        map[string]interface{}{"type": "string", "format": "binary"},
+								"format":      map[string]interface{}{"type": "string", "enum": []string{"csv", "xlsx"}},
+								"sheet":       map[string]interface{}{"type": "string"},
+								"map":         map[string]interface{}{"type": "string", "description": "e.g. value=Actual,period=Month"},
+								"dry_run":     map[string]interface{}{"type": "boolean"},
+								"skip_errors": map[string]interface{}{"type": "boolean"},
+							},
+						},
+					},
+				},
+			}
+		} else if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+
+		if paths[op.Path] == nil {
+			paths[op.Path] = make(map[string]interface{})
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "KPI Tracker API",
+			"version":     "1.0.0",
+			"description": "REST API of the KPI Tracker. Errors use the envelope {\"error\": {\"code\", \"message\", \"details\"}}.",
+		},
+		"servers":    []interface{}{map[string]interface{}{"url": "/"}},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+// operationID builds an operation ID such as "get_api_roles_id"
+func operationID(op apiOperation) string {
+	return strings.ToLower(op.Method) + strings.NewReplacer("/", "_", "{", "", "}", "", "-", "_", ".", "_").Replace(op.Path)
+}
+
+// getOpenAPISpec serves the OpenAPI document
+func getOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(buildOpenAPISpec())
+}
+
+// getAPIDocs serves a self-contained documentation page rendered from the OpenAPI document
+func getAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, apiDocsHTML)
+}
+
+// apiDocsHTML renders /api/openapi.json without any external scripts or stylesheets
+const apiDocsHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>KPI Tracker API</title>
+<style>
+  body { font-family: Arial, sans-serif; margin: 0; color: #222; }
+  header { background: #2c3e50; color: #fff; padding: 16px 24px; }
+  main { padding: 8px 24px 24px; max-width: 1100px; }
+  h2 { border-bottom: 2px solid #C6EFCE; padding-bottom: 4px; margin-top: 32px; }
+  details { border: 1px solid #ddd; border-radius: 4px; margin: 8px 0; }
+  summary { cursor: pointer; padding: 8px; font-family: monospace; font-size: 14px; }
+  .method { display: inline-block; width: 60px; font-weight: bold; }
+  .GET { color: #2e7d32; } .POST { color: #1565c0; } .PUT { color: #ef6c00; }
+  .PATCH { color: #6a1b9a; } .DELETE { color: #c62828; }
+  .body { padding: 0 16px 12px; }
+  table { border-collapse: collapse; margin: 8px 0; }
+  td, th { border: 1px solid #ddd; padding: 4px 8px; text-align: left; font-size: 13px; }
+  pre { background: #f6f8fa; padding: 8px; overflow: auto; font-size: 12px; }
+</style>
+</head>
+<body>
+<header><h1>KPI Tracker API</h1><div>Generated from <a style="color:#fff" href="/api/openapi.json">/api/openapi.json</a></div></header>
+<main id="docs">Loading...</main>
+<script>
+function esc(s) { return String(s).replace(/[&<>"]/g, function (c) { return {"&":"&amp;","<":"&lt;",">":"&gt;","\"":"&quot;"}[c]; }); }
+function resolve(spec, schema, depth) {
+  if (!schema) return {};
+  if (depth > 4) return schema;
+  if (schema.$ref) return resolve(spec, spec.components.schemas[schema.$ref.split("/").pop()], depth + 1);
+  var out = {};
+  for (var k in schema) {
+    var v = schema[k];
+    if (k === "properties") {
+      out[k] = {};
+      for (var p in v) out[k][p] = resolve(spec, v[p], depth + 1);
+    } else if (k === "items" || k === "additionalProperties") {
+      out[k] = resolve(spec, v, depth + 1);
+    } else {
+      out[k] = v;
+    }
+  }
+  return out;
+}
+fetch("/api/openapi.json").then(function (r) { return r.json(); }).then(function (spec) {
+  var byTag = {};
+  Object.keys(spec.paths).sort().forEach(function (path) {
+    Object.keys(spec.paths[path]).forEach(function (method) {
+      var op = spec.paths[path][method];
+      var tag = op.tags[0];
+      (byTag[tag] = byTag[tag] || []).push({ path: path, method: method.toUpperCase(), op: op });
+    });
+  });
+  var html = "<p>" + esc(spec.info.description) + "</p>";
+  Object.keys(byTag).forEach(function (tag) {
+    html += "<h2>" + esc(tag) + "</h2>";
+    byTag[tag].forEach(function (e) {
+      html += "<details><summary><span class='method " + e.method + "'>" + e.method + "</span>" + esc(e.path) +
+        " &mdash; " + esc(e.op.summary) + "</summary><div class='body'>";
+      if (e.op.parameters) {
+        html += "<table><tr><th>Parameter</th><th>In</th><th>Type</th><th>Required</th><th>Description</th></tr>";
+        e.op.parameters.forEach(function (p) {
+          html += "<tr><td>" + esc(p.name) + "</td><td>" + p.in + "</td><td>" + esc(p.schema.type) + "</td><td>" +
+            (p.required ? "yes" : "") + "</td><td>" + esc(p.description || "") + "</td></tr>";
+        });
+        html += "</table>";
+      }
+      if (e.op.requestBody) {
+        var types = e.op.requestBody.content;
+        for (var ct in types) html += "<b>Request (" + esc(ct) + ")</b><pre>" + esc(JSON.stringify(resolve(spec, types[ct].schema, 0), null, 2)) + "</pre>";
+      }
+      Object.keys(e.op.responses).sort().forEach(function (code) {
+        var resp = e.op.responses[code];
+        html += "<b>" + code + " " + esc(resp.description) + "</b>";
+        if (code < 400 && resp.content && resp.content["application/json"]) {
+          html += "<pre>" + esc(JSON.stringify(resolve(spec, resp.content["application/json"].schema, 0), null, 2)) + "</pre>";
+        } else {
+          html += "<br>";
+        }
+      });
+      html += "</div></details>";
+    });
+  });
+  document.getElementById("docs").innerHTML = html;
+}).catch(function (err) {
+  document.getElementById("docs").textContent = "Failed to load the OpenAPI document: " + err;
+});
+</script>
+</body>
+</html>
+`
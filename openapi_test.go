@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// registeredRoutes returns "METHOD path" for every route of the API router
+func registeredRoutes(t *testing.T) map[string]bool {
+	routes := make(map[string]bool)
+	err := newAPIRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if method != "OPTIONS" {
+				routes[method+" "+path] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking routes: %v", err)
+	}
+	return routes
+}
+
+func TestOpenAPISpecCoversEveryRoute(t *testing.T) {
+	paths := buildOpenAPISpec()["paths"].(map[string]map[string]interface{})
+
+	routes := registeredRoutes(t)
+	if len(routes) == 0 {
+		t.Fatal("no routes registered")
+	}
+
+	for route := range routes {
+		parts := strings.SplitN(route, " ", 2)
+		if _, ok := paths[parts[1]][strings.ToLower(parts[0])]; !ok {
+			t.Errorf("route %s is registered but missing from the OpenAPI spec", route)
+		}
+	}
+
+	for path, operations := range paths {
+		for method := range operations {
+			if !routes[strings.ToUpper(method)+" "+path] {
+				t.Errorf("OpenAPI spec documents %s %s which is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPISchemaReferencesResolve(t *testing.T) {
+	spec := buildOpenAPISpec()
+	schemas := spec["components"].(map[string]interface{})["schemas"].(openAPISchemas)
+
+	var check func(value interface{})
+	check = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := schemas[name]; !ok {
+					t.Errorf("schema reference %s does not resolve", ref)
+				}
+			}
+			for _, child := range v {
+				check(child)
+			}
+		case map[string]map[string]interface{}:
+			for _, child := range v {
+				check(child)
+			}
+		case openAPISchemas:
+			for _, child := range v {
+				check(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				check(child)
+			}
+		}
+	}
+	check(spec)
+}
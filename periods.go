@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Closed periods file in the database directory
+const closedPeriodsFile = "closed_periods.json"
+
+// ClosedPeriod is a month whose measurements can no longer be changed
+type ClosedPeriod struct {
+	Period       string    `json:"period"` // YYYY-MM
+	ClosedAt     time.Time `json:"closed_at"`
+	Measurements int       `json:"measurements"` // Measurements of the month when it was closed
+}
+
+// closedPeriods are the closed months, guarded by dataMu
+var closedPeriods []ClosedPeriod
+
+// loadClosedPeriods loads the closed periods from the database directory
+func loadClosedPeriods() error {
+	closedPeriods = []ClosedPeriod{}
+	return readJSONFile(filepath.Join(appSettings.DatabasePath, closedPeriodsFile), &closedPeriods)
+}
+
+// saveClosedPeriods writes the closed periods
+func saveClosedPeriods() error {
+	return writeJSONFile(filepath.Join(appSettings.DatabasePath, closedPeriodsFile), closedPeriods)
+}
+
+// isPeriodClosed reports whether the month of a period is closed
+func isPeriodClosed(period time.Time) bool {
+	key := period.Format("2006-01")
+	for _, closed := range closedPeriods {
+		if closed.Period == key {
+			return true
+		}
+	}
+	return false
+}
+
+// closePeriod closes a month so its measurements can no longer be changed and
+// publishes period.closed
+func closePeriod(period time.Time) (ClosedPeriod, error) {
+	if isPeriodClosed(period) {
+		return ClosedPeriod{}, fmt.Errorf("period %s is already closed", period.Format("2006-01"))
+	}
+
+	closed := ClosedPeriod{Period: period.Format("2006-01"), ClosedAt: time.Now()}
+	for _, m := range measurements {
+		if m.Period.Year() == period.Year() && m.Period.Month() == period.Month() {
+			closed.Measurements++
+		}
+	}
+
+	closedPeriods = append(closedPeriods, closed)
+	sort.Slice(closedPeriods, func(i, j int) bool { return closedPeriods[i].Period < closedPeriods[j].Period })
+	if err := saveClosedPeriods(); err != nil {
+		reopened := closedPeriods[:0]
+		for _, c := range closedPeriods {
+			if c.Period != closed.Period {
+				reopened = append(reopened, c)
+			}
+		}
+		closedPeriods = reopened
+		return ClosedPeriod{}, fmt.Errorf("failed to save closed periods: %v", err)
+	}
+
+	appEvents.Publish(Event{Type: EventPeriodClosed, Period: closed.Period, Data: closed})
+	return closed, nil
+}
+
+// errPeriodNotClosed is returned when an open period is reopened
+var errPeriodNotClosed = fmt.Errorf("period is not closed")
+
+// reopenPeriod opens a closed month for changes again and publishes period.reopened
+func reopenPeriod(period time.Time) (ClosedPeriod, error) {
+	key := period.Format("2006-01")
+	for i, closed := range closedPeriods {
+		if closed.Period != key {
+			continue
+		}
+
+		previous := closedPeriods
+		closedPeriods = append(append([]ClosedPeriod{}, closedPeriods[:i]...), closedPeriods[i+1:]...)
+		if err := saveClosedPeriods(); err != nil {
+			closedPeriods = previous
+			return ClosedPeriod{}, fmt.Errorf("failed to save closed periods: %v", err)
+		}
+
+		appEvents.Publish(Event{Type: EventPeriodReopened, Period: key, Data: closed})
+		return closed, nil
+	}
+	return ClosedPeriod{}, errPeriodNotClosed
+}
+
+// getClosedPeriods lists the closed periods
+func getClosedPeriods(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(closedPeriods)
+}
+
+// closePeriodAPI closes a month, e.g. POST /api/periods/2025-05/close
+func closePeriodAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	period, err := parsePeriodString(mux.Vars(r)["period"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if isPeriodClosed(period) {
+		writeError(w, http.StatusConflict, "Period is already closed")
+		return
+	}
+
+	closed, err := closePeriod(period)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(closed)
+}
+
+// reopenPeriodAPI opens a closed month for changes again
+func reopenPeriodAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	period, err := parsePeriodString(mux.Vars(r)["period"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reopened, err := reopenPeriod(period)
+	if err == errPeriodNotClosed {
+		writeError(w, http.StatusNotFound, "Period is not closed")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(reopened)
+}
+
+// periodUsage is printed for an unknown "period" subcommand
+const periodUsage = `Usage:
+  kpi-tracker period list
+  kpi-tracker period close --period YYYY-MM
+  kpi-tracker period reopen --period YYYY-MM
+`
+
+// runPeriodCommand handles the "period" subcommands
+func runPeriodCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, periodUsage)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("period list", flag.ContinueOnError)
+		if code, ok := parseFlags(fs, args[1:]); !ok {
+			return code
+		}
+		for _, closed := range closedPeriods {
+			fmt.Fprintf(cliOutput, "%s\tclosed %s\t%d measurements\n",
+				closed.Period, closed.ClosedAt.Format("2006-01-02 15:04"), closed.Measurements)
+		}
+		return exitOK
+	case "close", "reopen":
+		fs := flag.NewFlagSet("period "+args[0], flag.ContinueOnError)
+		periodStr := fs.String("period", "", "period YYYY-MM")
+		if code, ok := parseFlags(fs, args[1:]); !ok {
+			return code
+		}
+		period, err := parsePeriodString(*periodStr)
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+
+		action := "closed"
+		if args[0] == "close" {
+			_, err = closePeriod(period)
+		} else {
+			action = "reopened"
+			_, err = reopenPeriod(period)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(cliOutput, "%s %s\n", period.Format("2006-01"), action)
+		return exitOK
+	}
+
+	fmt.Fprint(os.Stderr, periodUsage)
+	return exitUsage
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupPeriodTest resets the test data and stores closed periods in a temporary directory
+func setupPeriodTest(t *testing.T) {
+	setupSubmissionTest(t)
+	previous := closedPeriods
+	t.Cleanup(func() {
+		closedPeriods = previous
+		discardPendingChanges()
+	})
+	appSettings.DatabasePath = t.TempDir()
+	if err := loadClosedPeriods(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClosePeriodPublishesAndBlocksChanges(t *testing.T) {
+	setupPeriodTest(t)
+	ch, unsubscribe := appEvents.Subscribe(EventFilter{Types: []string{EventPeriodClosed, EventPeriodReopened}}, 0)
+	defer unsubscribe()
+
+	february := time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)
+	closed, err := closePeriod(february)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Period != "2026-02" || closed.Measurements != 1 {
+		t.Errorf("closed %+v, want 2026-02 with 1 measurement", closed)
+	}
+	if _, err := closePeriod(february); err == nil {
+		t.Error("closing a closed period should fail")
+	}
+	if _, _, errs := validateMeasurementInput(2, 95, nil, february); len(errs) != 1 || errs[0].Field != "period" {
+		t.Errorf("validation errors %v, want a closed period", errs)
+	}
+
+	// The closed periods survive a reload
+	if err := loadClosedPeriods(); err != nil {
+		t.Fatal(err)
+	}
+	if !isPeriodClosed(february) {
+		t.Fatal("period open after reload")
+	}
+
+	if _, err := reopenPeriod(february); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopenPeriod(february); err != errPeriodNotClosed {
+		t.Errorf("reopening an open period: %v, want errPeriodNotClosed", err)
+	}
+	if _, _, errs := validateMeasurementInput(2, 95, nil, february); len(errs) != 0 {
+		t.Errorf("validation errors %v after reopening", errs)
+	}
+
+	events := receive(ch)
+	if len(events) != 2 || events[0].Type != EventPeriodClosed || events[1].Type != EventPeriodReopened || events[0].Period != "2026-02" {
+		t.Errorf("received %+v, want period.closed and period.reopened", events)
+	}
+}
+
+func TestClosePeriodSaveFailure(t *testing.T) {
+	setupPeriodTest(t)
+	failExcelSaves(t)
+
+	if _, err := closePeriod(month(2)); err == nil {
+		t.Fatal("closing without a writable database directory should fail")
+	}
+	if isPeriodClosed(month(2)) || len(closedPeriods) != 0 {
+		t.Errorf("closed periods %+v, want none after the failed save", closedPeriods)
+	}
+}
+
+func TestPeriodsAPI(t *testing.T) {
+	setupPeriodTest(t)
+	router := newAPIRouter()
+	request := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{"POST", "/api/periods/2026-02/close", http.StatusOK},
+		{"POST", "/api/periods/2026-02/close", http.StatusConflict},
+		{"POST", "/api/periods/2026-13/close", http.StatusBadRequest},
+		{"POST", "/api/periods/2026-03/reopen", http.StatusNotFound},
+		{"GET", "/api/periods/closed", http.StatusOK},
+		{"POST", "/api/periods/2026-02/reopen", http.StatusOK},
+		{"POST", "/api/periods/2026-02/reopen", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := request(tt.method, tt.path)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.path, rec.Code, tt.status, rec.Body)
+		}
+		if tt.method == "GET" {
+			var closed []ClosedPeriod
+			if err := json.NewDecoder(rec.Body).Decode(&closed); err != nil || len(closed) != 1 || closed[0].Period != "2026-02" {
+				t.Errorf("closed periods %+v, %v, want 2026-02", closed, err)
+			}
+		}
+	}
+}
+
+func TestClosedPeriodBlocksChanges(t *testing.T) {
+	setupPeriodTest(t)
+	may := month(5)
+	kpis = append(kpis, KPI{ID: 3, RoleID: 1, Name: "Margin", Operator: "≥", TargetValue: 50, Weight: 25, Formula: "kpi_1 / 2"})
+	saveMeasurement(1, 100, nil, "", may, "")
+	if _, err := closePeriod(may); err != nil {
+		t.Fatal(err)
+	}
+
+	// Imports reject rows of the closed month
+	result, err := importMeasurements(strings.NewReader("kpi_id,period,value\n1,2026-05,120\n1,2026-06,120\n"), "values.csv", ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 || result.Rows[0].Action != importActionError || result.Rows[1].Action == importActionError {
+		t.Errorf("rows %+v, want only the May row rejected", result.Rows)
+	}
+
+	// Derived KPIs keep their values in the closed month
+	getExistingMeasurement(1, may).MetricValue = 300
+	recomputeDependents(1, may)
+	if m := getExistingMeasurement(3, may); m == nil || m.MetricValue != 50 {
+		t.Errorf("margin %+v, want 50 kept", m)
+	}
+}
+
+func TestPeriodCommand(t *testing.T) {
+	setupPeriodTest(t)
+	previous := cliOutput
+	t.Cleanup(func() { cliOutput = previous })
+	var out bytes.Buffer
+	cliOutput = &out
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{nil, exitUsage},
+		{[]string{"archive"}, exitUsage},
+		{[]string{"close"}, exitUsage},
+		{[]string{"close", "--period", "2026-02"}, exitOK},
+		{[]string{"close", "--period", "2026-02"}, exitError},
+		{[]string{"list"}, exitOK},
+		{[]string{"reopen", "--period", "2026-03"}, exitError},
+		{[]string{"reopen", "--period", "2026-02"}, exitOK},
+	}
+	for _, tt := range tests {
+		if code := runPeriodCommand(tt.args); code != tt.code {
+			t.Errorf("period %v: exit code %d, want %d", tt.args, code, tt.code)
+		}
+	}
+	if want := "2026-02 closed\n2026-02\tclosed "; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("output %q, want it to start with %q", out.String(), want)
+	}
+	if !strings.HasSuffix(out.String(), "1 measurements\n2026-02 reopened\n") {
+		t.Errorf("output %q, want the listing and the reopen", out.String())
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMinRankingCoverage is the minimum percentage of KPI weight that must be
+// measured before a role is ranked. calculateOverallScore normalises over the
+// measured weight only, so without this rule a role with a single measured KPI
+// could top the board.
+const defaultMinRankingCoverage = 50.0
+
+// RankingEntry represents a single position on a leaderboard
+type RankingEntry struct {
+	Rank         int       `json:"rank"` // 0 when the entry is not eligible for ranking
+	Role         Role      `json:"role"`
+	Employee     *Employee `json:"employee,omitempty"` // Set on employee rankings
+	Department   string    `json:"department"`
+	Score        float64   `json:"score"`
+	Coverage     float64   `json:"coverage"` // Percentage of KPI weight (or months) measured
+	MeasuredKPIs int       `json:"measured_kpis"`
+	TotalKPIs    int       `json:"total_kpis"`
+	Percentile   float64   `json:"percentile"`
+	Tied         bool      `json:"tied"`
+	Eligible     bool      `json:"eligible"`
+}
+
+// Ranking represents an ordered leaderboard for a period range
+type Ranking struct {
+	Title       string         `json:"title"`
+	Period      PeriodRange    `json:"period"`
+	MinCoverage float64        `json:"min_coverage"`
+	Entries     []RankingEntry `json:"entries"`
+}
+
+// roleRankingEntry computes the average score and coverage of a role over a period range
+func roleRankingEntry(role Role, pr PeriodRange) RankingEntry {
+	roleKPIs := getKPIsByRoleID(role.ID)
+	entry := RankingEntry{
+		Role:       role,
+		Department: role.Department,
+		TotalKPIs:  len(roleKPIs),
+	}
+
+	measuredKPIs := make(map[int]bool)
+	for _, period := range periodsInRange(pr.Start, pr.End) {
+		for _, kpi := range roleKPIs {
+			if getExistingMeasurement(kpi.ID, period) != nil {
+				measuredKPIs[kpi.ID] = true
+			}
+		}
+	}
+
+	entry.Coverage = averageRoleCoverage(roleKPIs, pr)
+	entry.MeasuredKPIs = len(measuredKPIs)
+	entry.Score = averageRoleScore(roleKPIs, pr)
+
+	return entry
+}
+
+// competencyRankingEntry computes a role's average achievement on the KPI with the given name
+func competencyRankingEntry(role Role, kpi KPI, pr PeriodRange) RankingEntry {
+	entry := RankingEntry{
+		Role:       role,
+		Department: role.Department,
+		TotalKPIs:  1,
+	}
+
+	_, achievement, measured := averageKPIValue(kpi, pr)
+	if !measured {
+		return entry
+	}
+
+	// Coverage for a single KPI is the share of months in the range with a value
+	periods := periodsInRange(pr.Start, pr.End)
+	monthsMeasured := 0
+	for _, period := range periods {
+		if getExistingMeasurement(kpi.ID, period) != nil {
+			monthsMeasured++
+		}
+	}
+
+	entry.Score = achievement
+	entry.MeasuredKPIs = 1
+	entry.Coverage = float64(monthsMeasured) / float64(len(periods)) * 100
+
+	return entry
+}
+
+// rankEntries orders entries by score and assigns ranks, ties and percentiles.
+// Entries below the minimum coverage are listed after the ranked entries without a rank.
+func rankEntries(entries []RankingEntry, minCoverage float64) []RankingEntry {
+	for i := range entries {
+		entries[i].Eligible = entries[i].MeasuredKPIs > 0 && entries[i].Coverage >= minCoverage
+		entries[i].Rank = 0
+		entries[i].Tied = false
+		entries[i].Percentile = 0
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Eligible != entries[j].Eligible {
+			return entries[i].Eligible
+		}
+		if roundScore(entries[i].Score) != roundScore(entries[j].Score) {
+			return entries[i].Score > entries[j].Score
+		}
+		// Higher coverage wins the display order between tied scores, rank stays shared
+		if entries[i].Coverage != entries[j].Coverage {
+			return entries[i].Coverage > entries[j].Coverage
+		}
+		return entryName(entries[i]) < entryName(entries[j])
+	})
+
+	eligible := 0
+	for _, entry := range entries {
+		if entry.Eligible {
+			eligible++
+		}
+	}
+
+	// Standard competition ranking: equal scores share a rank and the next rank is skipped
+	for i := 0; i < eligible; i++ {
+		if i > 0 && roundScore(entries[i].Score) == roundScore(entries[i-1].Score) {
+			entries[i].Rank = entries[i-1].Rank
+			entries[i].Tied = true
+			entries[i-1].Tied = true
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+
+	// Percentile is the share of other ranked entries that scored strictly lower
+	for i := 0; i < eligible; i++ {
+		if eligible == 1 {
+			entries[i].Percentile = 100
+			continue
+		}
+		below := 0
+		for j := 0; j < eligible; j++ {
+			if roundScore(entries[j].Score) < roundScore(entries[i].Score) {
+				below++
+			}
+		}
+		entries[i].Percentile = float64(below) / float64(eligible-1) * 100
+	}
+
+	return entries
+}
+
+// entryName returns the employee name of an entry, or the role name on role rankings
+func entryName(entry RankingEntry) string {
+	if entry.Employee != nil {
+		return entry.Employee.Name
+	}
+	return entry.Role.Name
+}
+
+// roundScore rounds a score to two decimals so that display-equal scores tie
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+// buildLeaderboard ranks all roles (optionally within one department) over a period range
+func buildLeaderboard(pr PeriodRange, department string, minCoverage float64) Ranking {
+	title := "Leaderboard"
+	if department != "" {
+		title = fmt.Sprintf("Leaderboard - %s", department)
+	}
+
+	ranking := Ranking{
+		Title:       title,
+		Period:      pr,
+		MinCoverage: minCoverage,
+		Entries:     []RankingEntry{},
+	}
+
+	for _, role := range roles {
+		if department != "" && !strings.EqualFold(role.Department, department) {
+			continue
+		}
+		if len(getKPIsByRoleID(role.ID)) == 0 {
+			continue
+		}
+		ranking.Entries = append(ranking.Entries, roleRankingEntry(role, pr))
+	}
+
+	ranking.Entries = rankEntries(ranking.Entries, minCoverage)
+	return ranking
+}
+
+// buildDepartmentRankings ranks roles within each department
+func buildDepartmentRankings(pr PeriodRange, minCoverage float64) []Ranking {
+	rankings := []Ranking{}
+	for _, department := range getDepartments() {
+		rankings = append(rankings, buildLeaderboard(pr, department, minCoverage))
+	}
+	return rankings
+}
+
+// buildCompetencyRanking ranks roles on a KPI that is shared across roles (e.g. "Innovation")
+func buildCompetencyRanking(kpiName string, pr PeriodRange, minCoverage float64) Ranking {
+	ranking := Ranking{
+		Title:       fmt.Sprintf("Competency - %s", kpiName),
+		Period:      pr,
+		MinCoverage: minCoverage,
+		Entries:     []RankingEntry{},
+	}
+
+	for _, role := range roles {
+		for _, kpi := range getKPIsByRoleID(role.ID) {
+			if strings.EqualFold(kpi.Name, kpiName) {
+				ranking.Title = fmt.Sprintf("Competency - %s", kpi.Name)
+				ranking.Entries = append(ranking.Entries, competencyRankingEntry(role, kpi, pr))
+				break
+			}
+		}
+	}
+
+	ranking.Entries = rankEntries(ranking.Entries, minCoverage)
+	return ranking
+}
+
+// buildEmployeeRanking ranks employees (optionally within one department) by the score of
+// their role over the part of the period range they were employed
+func buildEmployeeRanking(pr PeriodRange, department string, minCoverage float64) Ranking {
+	title := "Employee Ranking"
+	if department != "" {
+		title = fmt.Sprintf("Employee Ranking - %s", department)
+	}
+
+	ranking := Ranking{
+		Title:       title,
+		Period:      pr,
+		MinCoverage: minCoverage,
+		Entries:     []RankingEntry{},
+	}
+
+	for i := range employees {
+		employee := employees[i]
+		role := getRoleByID(employee.RoleID)
+		if role == nil || len(getKPIsByRoleID(role.ID)) == 0 {
+			continue
+		}
+		if department != "" && !strings.EqualFold(role.Department, department) {
+			continue
+		}
+
+		// Months before the hire month do not count towards the employee's score
+		employed := pr
+		hireMonth := time.Date(employee.HireDate.Year(), employee.HireDate.Month(), 1, 0, 0, 0, 0, time.Local)
+		if hireMonth.After(employed.Start) {
+			employed.Start = hireMonth
+		}
+
+		entry := RankingEntry{Role: *role, Department: role.Department, TotalKPIs: len(getKPIsByRoleID(role.ID))}
+		if !employed.Start.After(employed.End) {
+			entry = roleRankingEntry(*role, employed)
+		}
+		entry.Employee = &employee
+		ranking.Entries = append(ranking.Entries, entry)
+	}
+
+	ranking.Entries = rankEntries(ranking.Entries, minCoverage)
+	return ranking
+}
+
+// getDepartments returns the distinct departments of all roles
+func getDepartments() []string {
+	departments := []string{}
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		if role.Department == "" || seen[role.Department] {
+			continue
+		}
+		seen[role.Department] = true
+		departments = append(departments, role.Department)
+	}
+	sort.Strings(departments)
+	return departments
+}
+
+// getSharedKPINames returns KPI names that are used by more than one role
+func getSharedKPINames() []string {
+	roleCount := make(map[string]map[int]bool)
+	for _, kpi := range kpis {
+		if roleCount[kpi.Name] == nil {
+			roleCount[kpi.Name] = make(map[int]bool)
+		}
+		roleCount[kpi.Name][kpi.RoleID] = true
+	}
+
+	var names []string
+	for name, roleIDs := range roleCount {
+		if len(roleIDs) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// topEntries returns the first n ranked entries
+func topEntries(ranking Ranking, n int) []RankingEntry {
+	result := []RankingEntry{}
+	for _, entry := range ranking.Entries {
+		if !entry.Eligible || len(result) >= n {
+			break
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// bottomEntries returns the last n ranked entries, lowest score first
+func bottomEntries(ranking Ranking, n int) []RankingEntry {
+	result := []RankingEntry{}
+	for i := len(ranking.Entries) - 1; i >= 0 && len(result) < n; i-- {
+		if ranking.Entries[i].Eligible {
+			result = append(result, ranking.Entries[i])
+		}
+	}
+	return result
+}
+
+// displayRanking prints a ranking table to the console
+func displayRanking(ranking Ranking) {
+	fmt.Printf("\n=== %s (%s) ===\n", ranking.Title, ranking.Period.Label())
+	fmt.Printf("Minimum coverage: %.0f%%\n\n", ranking.MinCoverage)
+
+	fmt.Printf("%-6s %-40s %-25s %-10s %-10s %-10s\n",
+		"Rank", rankingSubject(ranking), "Department", "Score", "Coverage", "Percentile")
+	fmt.Println(strings.Repeat("-", 106))
+
+	for _, entry := range ranking.Entries {
+		rank := "-"
+		if entry.Eligible {
+			rank = strconv.Itoa(entry.Rank)
+			if entry.Tied {
+				rank = "=" + rank
+			}
+		}
+
+		percentile := "-"
+		if entry.Eligible {
+			percentile = fmt.Sprintf("%.0f", entry.Percentile)
+		}
+
+		fmt.Printf("%-6s %-40s %-25s %-10.2f %-10s %-10s\n",
+			rank, entryName(entry), entry.Department, entry.Score,
+			fmt.Sprintf("%.0f%%", entry.Coverage), percentile)
+	}
+
+	if len(ranking.Entries) == 0 {
+		fmt.Println("Nothing to rank.")
+	}
+}
+
+// rankingSubject returns the column heading for the ranked entries
+func rankingSubject(ranking Ranking) string {
+	if len(ranking.Entries) > 0 && ranking.Entries[0].Employee != nil {
+		return "Employee"
+	}
+	return "Role"
+}
+
+// viewLeaderboard shows rankings across roles from the CLI
+func viewLeaderboard(scanner *bufio.Scanner) {
+	fmt.Println("\n=== Leaderboard ===")
+	fmt.Println("1. Overall Leaderboard")
+	fmt.Println("2. Ranking within Department")
+	fmt.Println("3. Ranking by Competency KPI")
+	fmt.Println("4. Employee Ranking")
+	fmt.Println("0. Back")
+
+	fmt.Print("\nEnter your choice: ")
+	scanner.Scan()
+	choice := scanner.Text()
+
+	if choice == "0" {
+		return
+	}
+	if choice != "1" && choice != "2" && choice != "3" && choice != "4" {
+		fmt.Println("Invalid choice.")
+		return
+	}
+
+	pr, ok := selectPeriodRange(scanner, "ranking")
+	if !ok {
+		return
+	}
+
+	fmt.Printf("Minimum coverage %% (default: %.0f): ", defaultMinRankingCoverage)
+	scanner.Scan()
+	minCoverage := defaultMinRankingCoverage
+	if coverageStr := scanner.Text(); coverageStr != "" {
+		value, err := strconv.ParseFloat(coverageStr, 64)
+		if err != nil || value < 0 || value > 100 {
+			fmt.Println("Invalid coverage. Using default.")
+		} else {
+			minCoverage = value
+		}
+	}
+
+	switch choice {
+	case "1":
+		displayRanking(buildLeaderboard(pr, "", minCoverage))
+	case "2":
+		for _, ranking := range buildDepartmentRankings(pr, minCoverage) {
+			displayRanking(ranking)
+		}
+	case "3":
+		names := getSharedKPINames()
+		if len(names) == 0 {
+			fmt.Println("No KPIs are shared across roles.")
+			return
+		}
+
+		fmt.Println("\nSelect competency KPI:")
+		for i, name := range names {
+			fmt.Printf("%d. %s\n", i+1, name)
+		}
+
+		fmt.Print("\nEnter KPI number: ")
+		scanner.Scan()
+		idx, err := strconv.Atoi(scanner.Text())
+		if err != nil || idx < 1 || idx > len(names) {
+			fmt.Println("Invalid KPI number.")
+			return
+		}
+
+		displayRanking(buildCompetencyRanking(names[idx-1], pr, minCoverage))
+	case "4":
+		displayRanking(buildEmployeeRanking(pr, "", minCoverage))
+	}
+
+	// Wait for user to press enter
+	fmt.Print("\nPress Enter to continue...")
+	scanner.Scan()
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// setupRankingTest sets up three roles in two departments that share an
+// "Innovation" KPI, Sales also has its own Revenue KPI
+func setupRankingTest(t *testing.T) {
+	setupSubmissionTest(t)
+	roles = []Role{
+		{ID: 1, Name: "Sales", Department: "Commercial"},
+		{ID: 2, Name: "Support", Department: "Operations"},
+		{ID: 3, Name: "Marketing", Department: "Commercial"},
+		{ID: 4, Name: "Intern", Department: "Operations"},
+	}
+	kpis = []KPI{
+		{ID: 1, RoleID: 1, Name: "Revenue", Operator: "≥", TargetValue: 100, Weight: 50},
+		{ID: 2, RoleID: 1, Name: "Innovation", Operator: "≥", TargetValue: 10, Weight: 50},
+		{ID: 3, RoleID: 2, Name: "Innovation", Operator: "≥", TargetValue: 10, Weight: 100},
+		{ID: 4, RoleID: 3, Name: "innovation", Operator: "≥", TargetValue: 10, Weight: 100},
+	}
+	measurements = nil
+}
+
+// addValue stores a measurement for a KPI
+func addValue(kpiID int, value float64, period time.Time) {
+	measurements = append(measurements, Measurement{ID: len(measurements) + 1, KPIID: kpiID, MetricValue: value, Period: period})
+}
+
+// rankNames returns the entry names of a ranking in order
+func rankNames(entries []RankingEntry) []string {
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entryName(entry))
+	}
+	return names
+}
+
+func TestRankEntries(t *testing.T) {
+	entry := func(name string, score, coverage float64) RankingEntry {
+		return RankingEntry{Role: Role{Name: name}, Score: score, Coverage: coverage, MeasuredKPIs: 1}
+	}
+
+	for _, tt := range []struct {
+		name        string
+		entries     []RankingEntry
+		ranks       map[string]int
+		tied        map[string]bool
+		percentiles map[string]float64
+		order       []string
+	}{
+		{
+			name:        "ties share a rank and skip the next",
+			entries:     []RankingEntry{entry("C", 70, 100), entry("A", 90, 100), entry("B", 90, 100)},
+			ranks:       map[string]int{"A": 1, "B": 1, "C": 3},
+			tied:        map[string]bool{"A": true, "B": true},
+			percentiles: map[string]float64{"A": 50, "B": 50, "C": 0},
+			order:       []string{"A", "B", "C"},
+		},
+		{
+			name:        "scores equal to two decimals tie",
+			entries:     []RankingEntry{entry("A", 80.001, 100), entry("B", 80.004, 100), entry("C", 80.01, 100)},
+			ranks:       map[string]int{"C": 1, "A": 2, "B": 2},
+			tied:        map[string]bool{"A": true, "B": true},
+			percentiles: map[string]float64{"C": 100, "A": 0, "B": 0},
+			order:       []string{"C", "A", "B"},
+		},
+		{
+			name:    "higher coverage is listed first between ties",
+			entries: []RankingEntry{entry("A", 75, 60), entry("B", 75, 90)},
+			ranks:   map[string]int{"A": 1, "B": 1},
+			tied:    map[string]bool{"A": true, "B": true},
+			order:   []string{"B", "A"},
+		},
+		{
+			name:        "low coverage and no data are not ranked",
+			entries:     []RankingEntry{entry("Low", 100, 40), {Role: Role{Name: "Empty"}, Coverage: 100}, entry("A", 60, 50)},
+			ranks:       map[string]int{"A": 1, "Low": 0, "Empty": 0},
+			percentiles: map[string]float64{"A": 100},
+			order:       []string{"A", "Low", "Empty"},
+		},
+	} {
+		entries := rankEntries(tt.entries, defaultMinRankingCoverage)
+		for _, entry := range entries {
+			name := entryName(entry)
+			if entry.Rank != tt.ranks[name] || entry.Tied != tt.tied[name] || entry.Eligible != (tt.ranks[name] > 0) {
+				t.Errorf("%s: %s rank %d tied %v eligible %v, want %d %v",
+					tt.name, name, entry.Rank, entry.Tied, entry.Eligible, tt.ranks[name], tt.tied[name])
+			}
+			if entry.Percentile != tt.percentiles[name] {
+				t.Errorf("%s: %s percentile %v, want %v", tt.name, name, entry.Percentile, tt.percentiles[name])
+			}
+		}
+		if names := rankNames(entries); !reflect.DeepEqual(names, tt.order) {
+			t.Errorf("%s: order %v, want %v", tt.name, names, tt.order)
+		}
+	}
+}
+
+func TestBuildLeaderboard(t *testing.T) {
+	setupRankingTest(t)
+	addValue(1, 100, month(1))
+	addValue(2, 6, month(1))
+	addValue(3, 9, month(1))
+	addValue(4, 9, month(1))
+	pr := PeriodRange{Start: month(1), End: month(2)}
+
+	// Months without data lower the coverage, not the score
+	leaderboard := buildLeaderboard(pr, "", 50)
+	if names := rankNames(leaderboard.Entries); !reflect.DeepEqual(names, []string{"Marketing", "Support", "Sales"}) {
+		t.Fatalf("order %v, want Marketing, Support, Sales", names)
+	}
+	sales := leaderboard.Entries[2]
+	if sales.Rank != 3 || sales.Score != 80 || sales.Coverage != 50 || sales.MeasuredKPIs != 2 || sales.TotalKPIs != 2 {
+		t.Errorf("sales %+v, want rank 3 with 80%% at 50%% coverage", sales)
+	}
+	if leaderboard.Entries[0].Rank != 1 || leaderboard.Entries[1].Rank != 1 {
+		t.Errorf("entries %+v, want Marketing and Support tied", leaderboard.Entries)
+	}
+
+	// A higher minimum leaves everyone unranked
+	for _, entry := range buildLeaderboard(pr, "", 75).Entries {
+		if entry.Eligible {
+			t.Errorf("%s ranked below the minimum coverage", entryName(entry))
+		}
+	}
+
+	commercial := buildLeaderboard(pr, "commercial", 50)
+	if commercial.Title != "Leaderboard - commercial" || !reflect.DeepEqual(rankNames(commercial.Entries), []string{"Marketing", "Sales"}) {
+		t.Errorf("commercial %+v, want Marketing and Sales", commercial)
+	}
+
+	// Intern has no KPIs and its department still gets a ranking
+	rankings := buildDepartmentRankings(pr, 50)
+	if len(rankings) != 2 || rankings[0].Title != "Leaderboard - Commercial" || len(rankings[1].Entries) != 1 {
+		t.Errorf("department rankings %+v, want Commercial and Operations", rankings)
+	}
+	roles = append(roles, Role{ID: 5, Name: "Legal", Department: "Legal"})
+	if rankings := buildDepartmentRankings(pr, 50); len(rankings) != 3 || rankings[1].Entries == nil || len(rankings[1].Entries) != 0 {
+		t.Errorf("department rankings %+v, want an empty Legal ranking", rankings)
+	}
+}
+
+func TestBuildCompetencyRanking(t *testing.T) {
+	setupRankingTest(t)
+	addValue(2, 10, month(1))
+	addValue(2, 10, month(2))
+	addValue(3, 5, month(1))
+	pr := PeriodRange{Start: month(1), End: month(2)}
+
+	ranking := buildCompetencyRanking("INNOVATION", pr, 0)
+	if ranking.Title != "Competency - innovation" || len(ranking.Entries) != 3 {
+		t.Fatalf("ranking %+v, want all three roles", ranking)
+	}
+	for _, tt := range []struct {
+		name     string
+		rank     int
+		score    float64
+		coverage float64
+	}{
+		{"Sales", 1, 100, 100},
+		{"Support", 2, 50, 50},
+		{"Marketing", 0, 0, 0},
+	} {
+		var entry *RankingEntry
+		for i := range ranking.Entries {
+			if entryName(ranking.Entries[i]) == tt.name {
+				entry = &ranking.Entries[i]
+			}
+		}
+		if entry == nil || entry.Rank != tt.rank || entry.Score != tt.score || entry.Coverage != tt.coverage {
+			t.Errorf("%s: %+v, want rank %d score %v coverage %v", tt.name, entry, tt.rank, tt.score, tt.coverage)
+		}
+	}
+
+	if ranking := buildCompetencyRanking("Revenue", pr, 0); len(ranking.Entries) != 1 {
+		t.Errorf("entries %+v, want only Sales", ranking.Entries)
+	}
+	if names := getSharedKPINames(); !reflect.DeepEqual(names, []string{"Innovation"}) {
+		t.Errorf("shared KPIs %v, want Innovation", names)
+	}
+}
+
+func TestBuildEmployeeRanking(t *testing.T) {
+	setupRankingTest(t)
+	employees = []Employee{
+		{ID: 1, Name: "Ana", RoleID: 2, HireDate: month(1)},
+		{ID: 2, Name: "Budi", RoleID: 2, HireDate: month(3).AddDate(0, 0, 14)},
+		{ID: 3, Name: "Citra", RoleID: 2, HireDate: month(7)},
+		{ID: 4, Name: "Dian", RoleID: 4, HireDate: month(1)},
+	}
+	addValue(3, 5, month(1))
+	addValue(3, 5, month(2))
+	addValue(3, 10, month(3))
+
+	ranking := buildEmployeeRanking(PeriodRange{Start: month(1), End: month(3)}, "", 50)
+	if names := rankNames(ranking.Entries); !reflect.DeepEqual(names, []string{"Budi", "Ana", "Citra"}) {
+		t.Fatalf("order %v, want Budi, Ana, Citra", names)
+	}
+	for i, want := range []struct {
+		rank  int
+		score float64
+	}{{1, 100}, {2, 200.0 / 3}, {0, 0}} {
+		if entry := ranking.Entries[i]; entry.Rank != want.rank || roundScore(entry.Score) != roundScore(want.score) {
+			t.Errorf("%s: rank %d score %v, want %d %v", entryName(entry), entry.Rank, entry.Score, want.rank, want.score)
+		}
+	}
+	if rankingSubject(ranking) != "Employee" {
+		t.Errorf("subject %q, want Employee", rankingSubject(ranking))
+	}
+
+	if ranking := buildEmployeeRanking(PeriodRange{Start: month(1), End: month(3)}, "Commercial", 50); len(ranking.Entries) != 0 {
+		t.Errorf("entries %+v, want none in Commercial", ranking.Entries)
+	}
+}
+
+func TestRankingAPI(t *testing.T) {
+	setupRankingTest(t)
+	addValue(1, 100, month(1))
+	addValue(2, 6, month(1))
+	addValue(3, 9, month(1))
+	addValue(4, 7, month(1))
+
+	for _, tt := range []struct {
+		url    string
+		status int
+		names  []string
+	}{
+		{"/api/rankings/leaderboard?start=2026-01&limit=2", http.StatusOK, []string{"Support", "Sales"}},
+		{"/api/rankings/leaderboard?start=2026-01&limit=1&order=bottom", http.StatusOK, []string{"Marketing"}},
+		{"/api/rankings/leaderboard?start=2026-01&department=Operations", http.StatusOK, []string{"Support"}},
+		{"/api/rankings/leaderboard?start=2026-01&limit=0", http.StatusBadRequest, nil},
+		{"/api/rankings/leaderboard?start=2026-01&limit=1&order=middle", http.StatusBadRequest, nil},
+		{"/api/rankings/leaderboard?start=2026-01&min_coverage=101", http.StatusBadRequest, nil},
+		{"/api/rankings/leaderboard?start=2026-01&min_coverage=abc", http.StatusBadRequest, nil},
+		{"/api/rankings/leaderboard", http.StatusBadRequest, nil},
+		{"/api/rankings/competency?start=2026-01&kpi_name=innovation", http.StatusOK, []string{"Support", "Marketing", "Sales"}},
+		{"/api/rankings/competency?start=2026-01", http.StatusBadRequest, nil},
+		{"/api/rankings/competency?start=2026-01&kpi_name=Quality", http.StatusNotFound, nil},
+		{"/api/rankings/employees?start=2026-01&end=2025-12", http.StatusBadRequest, nil},
+	} {
+		rec := httptest.NewRecorder()
+		newAPIRouter().ServeHTTP(rec, httptest.NewRequest("GET", tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.url, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if tt.names == nil {
+			continue
+		}
+		var ranking Ranking
+		if err := json.NewDecoder(rec.Body).Decode(&ranking); err != nil {
+			t.Fatal(err)
+		}
+		if names := rankNames(ranking.Entries); !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("%s: entries %v, want %v", tt.url, names, tt.names)
+		}
+	}
+}
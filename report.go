@@ -3,11 +3,14 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/xuri/excelize/v2"
 )
 
 // handleGenerateReport manages the report generation flow
@@ -17,7 +20,13 @@ func handleGenerateReport(scanner *bufio.Scanner) {
 	fmt.Println("2. Quarterly Report")
 	fmt.Println("3. Yearly Report")
 	fmt.Println("4. Custom Report")
-	fmt.Println("5. Export Data to Excel") // This already happens automatically
+	fmt.Println("5. Comparison Report")
+	fmt.Println("6. Bonus Payout Worksheet")
+	fmt.Println("7. Export Data to Excel") // This already happens automatically
+	fmt.Println("8. Year-end Forecast")
+	fmt.Println("9. Anomalies Report")
+	fmt.Println("10. What-if Simulation")
+	fmt.Println("11. Missing Submissions")
 	fmt.Println("0. Back to Main Menu")
 
 	fmt.Print("\nEnter your choice: ")
@@ -34,7 +43,19 @@ func handleGenerateReport(scanner *bufio.Scanner) {
 	case "4":
 		generateCustomReport(scanner)
 	case "5":
+		generateComparisonReportMenu(scanner)
+	case "6":
+		generateBonusWorksheet(scanner)
+	case "7":
 		exportToExcel(scanner)
+	case "8":
+		handleForecast(scanner)
+	case "9":
+		viewAnomalies(scanner)
+	case "10":
+		handleSimulation(scanner)
+	case "11":
+		handleSubmissions(scanner)
 	case "0":
 		return
 	default:
@@ -86,12 +107,9 @@ func generateQuarterlyReport(scanner *bufio.Scanner) {
 		return
 	}
 
-	// Calculate start and end periods
-	startMonth := (quarter-1)*3 + 1
-	endMonth := quarter * 3
-
-	startPeriod := time.Date(year, time.Month(startMonth), 1, 0, 0, 0, 0, time.Local)
-	endPeriod := time.Date(year, time.Month(endMonth), 1, 0, 0, 0, 0, time.Local)
+	// Calculate start and end periods of the fiscal quarter
+	pr := fiscalQuarterRange(year, quarter)
+	startPeriod, endPeriod := pr.Start, pr.End
 
 	// Select report format
 	format := selectReportFormat(scanner)
@@ -120,9 +138,9 @@ func generateYearlyReport(scanner *bufio.Scanner) {
 		return
 	}
 
-	// Calculate start and end periods
-	startPeriod := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
-	endPeriod := time.Date(year, 12, 1, 0, 0, 0, 0, time.Local)
+	// Calculate start and end periods of the fiscal year
+	pr := fiscalYearRange(year)
+	startPeriod, endPeriod := pr.Start, pr.End
 
 	// Select report format
 	format := selectReportFormat(scanner)
@@ -267,6 +285,11 @@ func selectReportFormat(scanner *bufio.Scanner) string {
 
 // generateReport generates a report for the given period range and format
 func generateReport(startPeriod, endPeriod time.Time, format string) string {
+	return generateReportForRoles(startPeriod, endPeriod, format, roles)
+}
+
+// generateReportForRoles generates a report limited to some roles
+func generateReportForRoles(startPeriod, endPeriod time.Time, format string, reportRoles []Role) string {
 	// In a real implementation, this would generate a report in the specified format
 	// For demonstration, we'll return a placeholder
 	fmt.Println("MASUK GENERATEREPORTTTTTTTTTTTTTTTTTTTT")
@@ -274,12 +297,12 @@ func generateReport(startPeriod, endPeriod time.Time, format string) string {
 
 	switch format {
 	case "txt":
-		report = generateTextReport(startPeriod, endPeriod)
+		report = generateTextReport(startPeriod, endPeriod, reportRoles)
 	case "csv":
-		report = generateCSVReport(startPeriod, endPeriod)
+		report = generateCSVReport(startPeriod, endPeriod, reportRoles)
 	case "html":
 		fmt.Println("MASUK HTMLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLL")
-		report = generateHTMLReport(startPeriod, endPeriod)
+		report = generateHTMLReport(startPeriod, endPeriod, reportRoles)
 	default:
 		report = "Unsupported format"
 	}
@@ -288,7 +311,7 @@ func generateReport(startPeriod, endPeriod time.Time, format string) string {
 }
 
 // generateTextReport generates a text report
-func generateTextReport(startPeriod, endPeriod time.Time) string {
+func generateTextReport(startPeriod, endPeriod time.Time, reportRoles []Role) string {
 	report := fmt.Sprintf("KPI REPORT: %s - %s\n",
 		startPeriod.Format("January 2006"), endPeriod.Format("January 2006"))
 	report += "==========================================\n\n"
@@ -297,7 +320,7 @@ func generateTextReport(startPeriod, endPeriod time.Time) string {
 	report += fmt.Sprintf("Generated: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
 
 	// For each role
-	for _, role := range roles {
+	for _, role := range reportRoles {
 		report += fmt.Sprintf("ROLE: %s\n", role.Name)
 		report += "----------------------------------------\n\n"
 
@@ -372,15 +395,14 @@ func generateTextReport(startPeriod, endPeriod time.Time) string {
 		// Overall score
 		report += "OVERALL SCORES\n"
 		report += "----------------------------------------\n"
+		report += fmt.Sprintf("Missing-data policy: %s\n", getScoringPolicy(role.ID))
 
-		// Loop through each month in the period range
+		// Loop through each month in the period range, including months without data
 		currentPeriod := startPeriod
 		for currentPeriod.Before(endPeriod) || currentPeriod.Equal(endPeriod) {
-			score := calculateOverallScore(roleKPIs, currentPeriod)
-			if score > 0 {
-				report += fmt.Sprintf("  %s: %.2f%%\n",
-					currentPeriod.Format("Jan 2006"), score)
-			}
+			result := calculateScoreResult(roleKPIs, currentPeriod)
+			report += fmt.Sprintf("  %s: %s\n",
+				currentPeriod.Format("Jan 2006"), result)
 
 			// Move to the next month
 			currentPeriod = currentPeriod.AddDate(0, 1, 0)
@@ -393,7 +415,7 @@ func generateTextReport(startPeriod, endPeriod time.Time) string {
 }
 
 // generateCSVReport generates a CSV report
-func generateCSVReport(startPeriod, endPeriod time.Time) string {
+func generateCSVReport(startPeriod, endPeriod time.Time, reportRoles []Role) string {
 	// This is a simplified CSV generator
 	// In a real implementation, this would be more sophisticated
 
@@ -409,7 +431,7 @@ func generateCSVReport(startPeriod, endPeriod time.Time) string {
 	report += "\n"
 
 	// Add data rows
-	for _, role := range roles {
+	for _, role := range reportRoles {
 		roleKPIs := getKPIsByRoleID(role.ID)
 
 		for _, kpi := range roleKPIs {
@@ -434,29 +456,34 @@ func generateCSVReport(startPeriod, endPeriod time.Time) string {
 			report += "\n"
 		}
 
-		// Add overall score row
-		report += fmt.Sprintf("%s,OVERALL SCORE,,,", role.Name)
+		// Add overall score and coverage rows
+		scoreRow := fmt.Sprintf("%s,OVERALL SCORE,,,", role.Name)
+		coverageRow := fmt.Sprintf("%s,COVERAGE,,,", role.Name)
 
 		currentPeriod = startPeriod
 		for currentPeriod.Before(endPeriod) || currentPeriod.Equal(endPeriod) {
-			score := calculateOverallScore(roleKPIs, currentPeriod)
-			if score > 0 {
-				report += fmt.Sprintf(",%.2f%%", score)
+			result := calculateScoreResult(roleKPIs, currentPeriod)
+			if result.HasData() {
+				scoreRow += fmt.Sprintf(",%.2f%%", result.Score)
+				if result.Incomplete {
+					scoreRow += " (incomplete)"
+				}
 			} else {
-				report += ","
+				scoreRow += ","
 			}
+			coverageRow += fmt.Sprintf(",%.0f%%", result.Coverage)
 
 			currentPeriod = currentPeriod.AddDate(0, 1, 0)
 		}
 
-		report += "\n"
+		report += scoreRow + "\n" + coverageRow + "\n"
 	}
 
 	return report
 }
 
 // generateHTMLReport generates an HTML report
-func generateHTMLReport(startPeriod, endPeriod time.Time) string {
+func generateHTMLReport(startPeriod, endPeriod time.Time, reportRoles []Role) string {
 	// This is a simplified HTML generator
 	// In a real implementation, this would be more sophisticated
 
@@ -487,7 +514,7 @@ func generateHTMLReport(startPeriod, endPeriod time.Time) string {
 	report += fmt.Sprintf("<p>Generated: %s</p>", time.Now().Format("2006-01-02 15:04:05"))
 
 	// For each role
-	for _, role := range roles {
+	for _, role := range reportRoles {
 		report += fmt.Sprintf("<h2>%s</h2>", role.Name)
 
 		roleKPIs := getKPIsByRoleID(role.ID)
@@ -594,22 +621,31 @@ func generateHTMLReport(startPeriod, endPeriod time.Time) string {
 
 		// Overall score
 		report += "<h3>Overall Scores</h3>"
-		report += "<table><tr><th>Period</th><th>Score</th></tr>"
+		report += fmt.Sprintf("<p>Missing-data policy: %s</p>", getScoringPolicy(role.ID))
+		report += "<table><tr><th>Period</th><th>Score</th><th>Coverage</th></tr>"
 
 		currentPeriod = startPeriod
 		for currentPeriod.Before(endPeriod) || currentPeriod.Equal(endPeriod) {
-			score := calculateOverallScore(roleKPIs, currentPeriod)
-			if score > 0 {
+			result := calculateScoreResult(roleKPIs, currentPeriod)
+			if result.HasData() {
 				// Add color class based on score
 				colorClass := "good"
-				if score < 70 {
+				if result.Score < 70 {
 					colorClass = "bad"
-				} else if score < 90 {
+				} else if result.Score < 90 {
 					colorClass = "warning"
 				}
 
-				report += fmt.Sprintf("<tr><td>%s</td><td class=\"%s\">%.2f%%</td></tr>",
-					currentPeriod.Format("Jan 2006"), colorClass, score)
+				scoreText := fmt.Sprintf("%.2f%%", result.Score)
+				if result.Incomplete {
+					scoreText += " (incomplete)"
+				}
+
+				report += fmt.Sprintf("<tr><td>%s</td><td class=\"%s\">%s</td><td>%.0f%%</td></tr>",
+					currentPeriod.Format("Jan 2006"), colorClass, scoreText, result.Coverage)
+			} else {
+				report += fmt.Sprintf("<tr><td>%s</td><td>-</td><td>0%%</td></tr>",
+					currentPeriod.Format("Jan 2006"))
 			}
 
 			currentPeriod = currentPeriod.AddDate(0, 1, 0)
@@ -624,6 +660,67 @@ func generateHTMLReport(startPeriod, endPeriod time.Time) string {
 	return report
 }
 
+// reportSheet is the sheet of xlsx reports
+const reportSheet = "Report"
+
+// generateXLSXReport generates the CSV report layout as an xlsx workbook, with
+// achievements stored as numbers so they can be charted and summed
+func generateXLSXReport(startPeriod, endPeriod time.Time, reportRoles []Role) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	f.NewSheet(reportSheet)
+	f.DeleteSheet("Sheet1")
+
+	periods := periodsInRange(startPeriod, endPeriod)
+	header := []interface{}{"Role", "KPI", "Category", "Weight", "Target"}
+	for _, period := range periods {
+		header = append(header, period.Format("Jan 2006"))
+	}
+	f.SetSheetRow(reportSheet, "A1", &header)
+
+	row := 2
+	for _, role := range reportRoles {
+		roleKPIs := getKPIsByRoleID(role.ID)
+
+		for _, kpi := range roleKPIs {
+			values := []interface{}{role.Name, kpi.Name, kpi.Category, kpi.Weight, kpi.Target}
+			for _, period := range periods {
+				if measurement := getExistingMeasurement(kpi.ID, period); measurement != nil {
+					values = append(values, math.Round(calculateAchievement(kpi, measurement)*100)/100)
+				} else {
+					values = append(values, nil)
+				}
+			}
+			f.SetSheetRow(reportSheet, fmt.Sprintf("A%d", row), &values)
+			row++
+		}
+
+		score := []interface{}{role.Name, "OVERALL SCORE", "", "", ""}
+		coverage := []interface{}{role.Name, "COVERAGE", "", "", ""}
+		for _, period := range periods {
+			result := calculateScoreResult(roleKPIs, period)
+			if result.HasData() {
+				score = append(score, math.Round(result.Score*100)/100)
+			} else {
+				score = append(score, nil)
+			}
+			coverage = append(coverage, math.Round(result.Coverage))
+		}
+		f.SetSheetRow(reportSheet, fmt.Sprintf("A%d", row), &score)
+		f.SetSheetRow(reportSheet, fmt.Sprintf("A%d", row+1), &coverage)
+		row += 2
+	}
+
+	formatAsTable(f, reportSheet, row-1, len(header))
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write xlsx report: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // saveReport saves a report to a file
 func saveReport(report, filename string) {
 	reportsDir := filepath.Join(appSettings.DatabasePath, "reports")
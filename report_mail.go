@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report formats that can be attached to emailed reports
+var reportAttachmentFormats = []string{"csv", "xlsx"}
+
+// Report types that can be emailed
+var mailReportTypes = []string{"monthly", "quarterly", "yearly", "custom"}
+
+// ReportSendRequest selects a report to email. Every field is optional: the
+// period defaults to the last closed month, quarter or year, and the report
+// goes to the configured distribution lists.
+type ReportSendRequest struct {
+	Type        string   `json:"type"` // monthly (default), quarterly, yearly or custom
+	Year        int      `json:"year,omitempty"`
+	Month       int      `json:"month,omitempty"`
+	Quarter     int      `json:"quarter,omitempty"`
+	From        string   `json:"from,omitempty"` // YYYY-MM, custom reports
+	To          string   `json:"to,omitempty"`   // YYYY-MM, custom reports
+	RoleIDs     []int    `json:"role_ids,omitempty"`
+	Recipients  []string `json:"recipients,omitempty"`  // One report to these addresses instead of the distribution lists, not accepted by the API
+	Attachments []string `json:"attachments,omitempty"` // Defaults to the configured attachments
+	DryRun      bool     `json:"dry_run"`
+}
+
+// ReportDelivery is one emailed report
+type ReportDelivery struct {
+	RoleID      int      `json:"role_id,omitempty"` // Unset for reports covering several roles
+	RoleName    string   `json:"role_name,omitempty"`
+	Subject     string   `json:"subject"`
+	Recipients  []string `json:"recipients"`
+	Attachments []string `json:"attachments"`
+	Sent        bool     `json:"sent"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// ReportSendResponse lists the emails sent for a report
+type ReportSendResponse struct {
+	Period     string           `json:"period"`
+	DryRun     bool             `json:"dry_run"`
+	Deliveries []ReportDelivery `json:"deliveries"`
+}
+
+// validateReportSendRequest validates a report send request
+func validateReportSendRequest(req ReportSendRequest) ValidationErrors {
+	var errs ValidationErrors
+
+	if req.Type != "" && !containsString(mailReportTypes, req.Type) {
+		errs.Add("type", "Report type must be one of %s", strings.Join(mailReportTypes, ", "))
+	}
+	if req.Year != 0 && (req.Year < 2000 || req.Year > 2100) {
+		errs.Add("year", "Year must be between 2000 and 2100")
+	}
+	if req.Month < 0 || req.Month > 12 {
+		errs.Add("month", "Month must be between 1 and 12")
+	}
+	if req.Quarter < 0 || req.Quarter > 4 {
+		errs.Add("quarter", "Quarter must be between 1 and 4")
+	}
+	if req.Type == "custom" && req.From == "" {
+		errs.Add("from", "Custom reports need a start period")
+	}
+	for i, id := range req.RoleIDs {
+		if getRoleByID(id) == nil {
+			errs.Add(fmt.Sprintf("role_ids[%d]", i), "Role %d not found", id)
+		}
+	}
+	for i, address := range req.Recipients {
+		if _, err := mail.ParseAddress(address); err != nil {
+			errs.Add(fmt.Sprintf("recipients[%d]", i), "Invalid email address '%s'", address)
+		}
+	}
+	for i, format := range req.Attachments {
+		if !containsString(reportAttachmentFormats, format) {
+			errs.Add(fmt.Sprintf("attachments[%d]", i), "Attachment must be one of %s", strings.Join(reportAttachmentFormats, ", "))
+		}
+	}
+
+	return errs
+}
+
+// resolveReportPeriod returns the months covered by a report request. Periods
+// left out default to the last closed month, fiscal quarter or fiscal year.
+func resolveReportPeriod(req ReportSendRequest, now time.Time) (PeriodRange, error) {
+	last := lastClosedPeriod(now)
+	year := req.Year
+
+	switch req.Type {
+	case "", "monthly":
+		if year == 0 && req.Month == 0 {
+			return PeriodRange{Start: last, End: last}, nil
+		}
+		if year == 0 {
+			year = now.Year()
+		}
+		if req.Month == 0 {
+			return PeriodRange{}, fmt.Errorf("month is required with year")
+		}
+		period := time.Date(year, time.Month(req.Month), 1, 0, 0, 0, 0, time.Local)
+		return PeriodRange{Start: period, End: period}, nil
+	case "quarterly":
+		quarter := req.Quarter
+		if year == 0 && quarter == 0 {
+			// The quarter of the last closed month, if that month ends it
+			closed := last
+			if pr := fiscalQuarterRange(fiscalYearOf(last), fiscalQuarterOf(last)); !pr.End.Equal(last) {
+				closed = pr.Start.AddDate(0, -1, 0)
+			}
+			quarter, year = fiscalQuarterOf(closed), fiscalYearOf(closed)
+		}
+		if year == 0 {
+			year = fiscalYearOf(now)
+		}
+		if quarter == 0 {
+			return PeriodRange{}, fmt.Errorf("quarter is required with year")
+		}
+		return fiscalQuarterRange(year, quarter), nil
+	case "yearly":
+		if year == 0 {
+			// The fiscal year of the last closed month, if that month ends it
+			year = fiscalYearOf(last)
+			if !fiscalYearRange(year).End.Equal(last) {
+				year--
+			}
+		}
+		return fiscalYearRange(year), nil
+	case "custom":
+		return parseCLIPeriodRange(req.From, req.To)
+	}
+	return PeriodRange{}, fmt.Errorf("unknown report type %s", req.Type)
+}
+
+// smtpConfigured reports whether email can be sent
+func smtpConfigured() bool {
+	return appSettings.SMTP.Host != "" && appSettings.SMTP.From != ""
+}
+
+// buildReportMail renders a report for some roles as an HTML email with attachments
+func buildReportMail(pr PeriodRange, reportRoles []Role, title string, formats []string, to []string) (Notification, error) {
+	subject := "KPI Report " + pr.Label()
+	baseName := "KPI_Report_" + pr.Start.Format("Jan2006")
+	if pr.End != pr.Start {
+		baseName += "_to_" + pr.End.Format("Jan2006")
+	}
+	if title != "" {
+		subject += " - " + title
+		baseName += "_" + strings.Map(func(r rune) rune {
+			if r == ' ' || r == '/' || r == '\\' {
+				return '_'
+			}
+			return r
+		}, title)
+	}
+
+	n := Notification{
+		Subject: subject,
+		Body:    generateReportForRoles(pr.Start, pr.End, "txt", reportRoles),
+		HTML:    generateReportForRoles(pr.Start, pr.End, "html", reportRoles),
+		To:      to,
+	}
+
+	for _, format := range formats {
+		switch format {
+		case "csv":
+			n.Attachments = append(n.Attachments, Attachment{
+				Filename:    baseName + ".csv",
+				ContentType: "text/csv; charset=utf-8",
+				Data:        []byte(generateReportForRoles(pr.Start, pr.End, "csv", reportRoles)),
+			})
+		case "xlsx":
+			data, err := generateXLSXReport(pr.Start, pr.End, reportRoles)
+			if err != nil {
+				return n, err
+			}
+			n.Attachments = append(n.Attachments, Attachment{
+				Filename:    baseName + ".xlsx",
+				ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+				Data:        data,
+			})
+		}
+	}
+
+	return n, nil
+}
+
+// reportMail is a rendered report email with the delivery it is recorded as
+type reportMail struct {
+	delivery     ReportDelivery
+	notification Notification
+}
+
+// sendReport emails a report. Without explicit recipients every role with a
+// distribution list receives the report of its role and the default recipients
+// receive the report of all roles (or of the requested roles).
+func sendReport(pr PeriodRange, req ReportSendRequest, notifier Notifier) ([]ReportDelivery, error) {
+	mails, err := buildReportMails(pr, req)
+	if err != nil {
+		return nil, err
+	}
+	return deliverReportMails(mails, notifier, req.DryRun), nil
+}
+
+// buildReportMails renders the emails of a report without sending them. It reads
+// the data, so the caller holds dataMu; deliverReportMails does not need it.
+func buildReportMails(pr PeriodRange, req ReportSendRequest) ([]reportMail, error) {
+	formats := req.Attachments
+	if formats == nil {
+		formats = appSettings.ReportDistribution.Attachments
+	}
+
+	type recipientList struct {
+		role  *Role
+		roles []Role
+		to    []string
+	}
+	var lists []recipientList
+
+	selected := roles
+	if len(req.RoleIDs) > 0 {
+		selected = nil
+		for _, id := range req.RoleIDs {
+			selected = append(selected, *getRoleByID(id))
+		}
+	}
+
+	if len(req.Recipients) > 0 {
+		lists = append(lists, recipientList{roles: selected, to: req.Recipients})
+	} else {
+		for i, role := range selected {
+			if to := appSettings.ReportDistribution.Recipients[role.ID]; len(to) > 0 {
+				lists = append(lists, recipientList{role: &selected[i], roles: []Role{role}, to: to})
+			}
+		}
+		if to := appSettings.ReportDistribution.DefaultRecipients; len(to) > 0 {
+			lists = append(lists, recipientList{roles: selected, to: to})
+		}
+	}
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("no report recipients configured")
+	}
+
+	mails := []reportMail{}
+	for _, m := range lists {
+		title := ""
+		delivery := ReportDelivery{Recipients: m.to, Attachments: formats}
+		if m.role != nil {
+			title = m.role.Name
+			delivery.RoleID, delivery.RoleName = m.role.ID, m.role.Name
+		} else if len(req.RoleIDs) == 1 {
+			title = m.roles[0].Name
+		}
+
+		n, err := buildReportMail(pr, m.roles, title, formats, m.to)
+		delivery.Subject = n.Subject
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		mails = append(mails, reportMail{delivery: delivery, notification: n})
+	}
+
+	return mails, nil
+}
+
+// deliverReportMails sends the rendered emails of a report. Emails that failed to
+// render and all emails of a dry run are only recorded.
+func deliverReportMails(mails []reportMail, notifier Notifier, dryRun bool) []ReportDelivery {
+	deliveries := []ReportDelivery{}
+	for _, m := range mails {
+		delivery := m.delivery
+		if delivery.Error == "" && !dryRun {
+			if err := notifier.Notify(m.notification); err != nil {
+				delivery.Error = err.Error()
+			} else {
+				delivery.Sent = true
+			}
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+// printReportDeliveries prints the outcome of emailing a report
+func printReportDeliveries(w io.Writer, deliveries []ReportDelivery) {
+	for _, d := range deliveries {
+		outcome := "sent"
+		switch {
+		case d.Error != "":
+			outcome = "failed: " + d.Error
+		case !d.Sent:
+			outcome = "would be sent"
+		}
+		fmt.Fprintf(w, "%s to %s [%s]: %s\n", d.Subject, strings.Join(d.Recipients, ", "), strings.Join(d.Attachments, ", "), outcome)
+	}
+}
+
+// splitList splits a comma-separated flag value, skipping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// runSendReportCommand emails a report to the distribution lists or given recipients
+func runSendReportCommand(args []string) int {
+	var req ReportSendRequest
+	fs := flag.NewFlagSet("send-report", flag.ContinueOnError)
+	fs.StringVar(&req.Type, "type", "monthly", "report type: monthly, quarterly, yearly or custom")
+	fs.IntVar(&req.Year, "year", 0, "report year (default: the last closed period)")
+	fs.IntVar(&req.Month, "month", 0, "month (1-12) for monthly reports")
+	fs.IntVar(&req.Quarter, "quarter", 0, "quarter (1-4) for quarterly reports")
+	fs.StringVar(&req.From, "from", "", "start period YYYY-MM for custom reports")
+	fs.StringVar(&req.To, "to", "", "end period YYYY-MM for custom reports")
+	rolesFlag := fs.String("roles", "", "comma-separated role IDs (default: all roles)")
+	recipients := fs.String("recipients", "", "comma-separated addresses receiving one report instead of the distribution lists")
+	attach := fs.String("attach", "", "comma-separated attachments: csv, xlsx (default from the settings, \"none\" for none)")
+	fs.BoolVar(&req.DryRun, "dry-run", false, "list the emails without sending them")
+	format := fs.String("format", "txt", "output format: txt or json")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	for _, value := range splitList(*rolesFlag) {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return usageError(fs, "invalid role ID %s", value)
+		}
+		req.RoleIDs = append(req.RoleIDs, id)
+	}
+	req.Recipients = splitList(*recipients)
+	if *attach == "none" {
+		req.Attachments = []string{}
+	} else if *attach != "" {
+		req.Attachments = splitList(*attach)
+	}
+	if *format != "txt" && *format != "json" {
+		return usageError(fs, "unsupported format %s", *format)
+	}
+
+	if errs := validateReportSendRequest(req); len(errs) > 0 {
+		return usageError(fs, "%v", errs)
+	}
+	pr, err := resolveReportPeriod(req, time.Now())
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if !req.DryRun && !smtpConfigured() {
+		fmt.Fprintln(os.Stderr, "Error: SMTP is not configured, set --smtp-host and --smtp-from")
+		return exitError
+	}
+
+	deliveries, err := sendReport(pr, req, SMTPNotifier{Settings: appSettings.SMTP})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *format == "json" {
+		json.NewEncoder(cliOutput).Encode(ReportSendResponse{Period: pr.Label(), DryRun: req.DryRun, Deliveries: deliveries})
+	} else {
+		printReportDeliveries(cliOutput, deliveries)
+	}
+	for _, d := range deliveries {
+		if d.Error != "" {
+			return exitError
+		}
+	}
+	return exitOK
+}
+
+// sendReportAPI emails a report, see ReportSendRequest
+func sendReportAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ReportSendRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	errs := validateReportSendRequest(req)
+	// The API is not authenticated, so it only sends to the configured
+	// distribution lists. Other addresses are given on the command line.
+	if len(req.Recipients) > 0 {
+		errs.Add("recipients", "Recipients can only be given with the send-report command, the API sends to the distribution lists")
+	}
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+	pr, err := resolveReportPeriod(req, time.Now())
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "Validation failed", FieldError{Field: "period", Message: err.Error()})
+		return
+	}
+	if !req.DryRun && !smtpConfigured() {
+		writeError(w, http.StatusConflict, "SMTP is not configured")
+		return
+	}
+
+	deliveries, err := sendReport(pr, req, SMTPNotifier{Settings: appSettings.SMTP})
+	if err != nil {
+		writeError(w, http.StatusConflict, "No report recipients configured")
+		return
+	}
+
+	json.NewEncoder(w).Encode(ReportSendResponse{Period: pr.Label(), DryRun: req.DryRun, Deliveries: deliveries})
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// receiveMail waits for a message from the fake SMTP server
+func receiveMail(t *testing.T, server *fakeSMTPServer) fakeMail {
+	select {
+	case m := <-server.messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	return fakeMail{}
+}
+
+// mailParts returns the decoded parts of a multipart message by content type,
+// attachments by file name
+func mailParts(t *testing.T, data string) map[string][]byte {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("content type %s, want multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+
+	parts := make(map[string][]byte)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			body, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(string(body), "\r\n", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		key := part.FileName()
+		if key == "" {
+			key, _, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+		}
+		parts[key] = body
+	}
+	return parts
+}
+
+func TestSendReportToDistributionLists(t *testing.T) {
+	setupSubmissionTest(t)
+	server := startFakeSMTPServer(t)
+
+	appSettings.ReportDistribution = ReportDistributionSettings{
+		Recipients:        map[int][]string{1: {"sales@example.com"}},
+		DefaultRecipients: []string{"board@example.com"},
+		Attachments:       []string{"csv", "xlsx"},
+	}
+	notifier := SMTPNotifier{Settings: SMTPSettings{Host: "127.0.0.1", Port: server.port(), From: "kpi@example.com"}}
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	measurements = append(measurements, Measurement{ID: 2, KPIID: 1, MetricValue: 80, Period: march})
+
+	deliveries, err := sendReport(PeriodRange{Start: march, End: march}, ReportSendRequest{}, notifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || !deliveries[0].Sent || !deliveries[1].Sent {
+		t.Fatalf("deliveries %+v, want two sent emails", deliveries)
+	}
+	if deliveries[0].RoleID != 1 || deliveries[0].Subject != "KPI Report Mar 2026 - Sales" {
+		t.Errorf("first delivery %+v, want the Sales report", deliveries[0])
+	}
+
+	roleMail := receiveMail(t, server)
+	if len(roleMail.To) != 1 || roleMail.To[0] != "sales@example.com" {
+		t.Errorf("role report sent to %v", roleMail.To)
+	}
+	parts := mailParts(t, roleMail.Data)
+	if !strings.Contains(string(parts["text/html"]), "<h2>Sales</h2>") {
+		t.Error("HTML body does not contain the role report")
+	}
+	if !strings.Contains(string(parts["KPI_Report_Mar2026_Sales.csv"]), "Sales,Revenue") {
+		t.Errorf("CSV attachment missing or wrong, parts: %v", keys(parts))
+	}
+
+	xlsx, err := excelize.OpenReader(bytes.NewReader(parts["KPI_Report_Mar2026_Sales.xlsx"]))
+	if err != nil {
+		t.Fatalf("xlsx attachment: %v", err)
+	}
+	defer xlsx.Close()
+	if value, _ := xlsx.GetCellValue(reportSheet, "F2"); value != "80" {
+		t.Errorf("xlsx achievement of Revenue %q, want 80", value)
+	}
+
+	if boardMail := receiveMail(t, server); boardMail.To[0] != "board@example.com" {
+		t.Errorf("full report sent to %v", boardMail.To)
+	}
+}
+
+func TestSendReportWithoutRecipients(t *testing.T) {
+	setupSubmissionTest(t)
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+
+	if _, err := sendReport(PeriodRange{Start: march, End: march}, ReportSendRequest{DryRun: true}, SMTPNotifier{}); err == nil {
+		t.Error("expected an error without distribution lists")
+	}
+}
+
+func TestSendReportAPIRejectsRecipients(t *testing.T) {
+	setupSubmissionTest(t)
+
+	body := strings.NewReader(`{"dry_run": true, "recipients": ["someone@example.com"]}`)
+	rec := httptest.NewRecorder()
+	sendReportAPI(rec, httptest.NewRequest("POST", "/api/reports/send", body))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"recipients"`) {
+		t.Errorf("status %d %s, want 422 for recipients", rec.Code, rec.Body.String())
+	}
+}
+
+func TestResolveReportPeriodDefaults(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		req        ReportSendRequest
+		start, end string
+	}{
+		{ReportSendRequest{}, "2026-09", "2026-09"},
+		{ReportSendRequest{Type: "quarterly"}, "2026-07", "2026-09"},
+		{ReportSendRequest{Type: "yearly"}, "2025-01", "2025-12"},
+		{ReportSendRequest{Type: "monthly", Year: 2025, Month: 5}, "2025-05", "2025-05"},
+		{ReportSendRequest{Type: "custom", From: "2025-02", To: "2025-04"}, "2025-02", "2025-04"},
+	}
+	for _, tt := range tests {
+		pr, err := resolveReportPeriod(tt.req, now)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.req, err)
+			continue
+		}
+		if pr.Start.Format("2006-01") != tt.start || pr.End.Format("2006-01") != tt.end {
+			t.Errorf("%+v: %s..%s, want %s..%s", tt.req, pr.Start.Format("2006-01"), pr.End.Format("2006-01"), tt.start, tt.end)
+		}
+	}
+
+	// The last closed month does not end a quarter yet
+	pr, _ := resolveReportPeriod(ReportSendRequest{Type: "quarterly"}, time.Date(2026, 2, 10, 0, 0, 0, 0, time.Local))
+	if pr.Start.Format("2006-01") != "2025-10" {
+		t.Errorf("quarter starts %s, want 2025-10", pr.Start.Format("2006-01"))
+	}
+}
+
+func TestResolveReportPeriodFiscalYear(t *testing.T) {
+	previous := appSettings.FiscalYearStartMonth
+	appSettings.FiscalYearStartMonth = 4
+	defer func() { appSettings.FiscalYearStartMonth = previous }()
+
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		req        ReportSendRequest
+		start, end string
+	}{
+		{ReportSendRequest{Type: "quarterly"}, "2026-07", "2026-09"},
+		{ReportSendRequest{Type: "quarterly", Year: 2025, Quarter: 4}, "2026-01", "2026-03"},
+		{ReportSendRequest{Type: "yearly"}, "2025-04", "2026-03"},
+		{ReportSendRequest{Type: "yearly", Year: 2026}, "2026-04", "2027-03"},
+	}
+	for _, tt := range tests {
+		pr, err := resolveReportPeriod(tt.req, now)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.req, err)
+			continue
+		}
+		if pr.Start.Format("2006-01") != tt.start || pr.End.Format("2006-01") != tt.end {
+			t.Errorf("%+v: %s..%s, want %s..%s", tt.req, pr.Start.Format("2006-01"), pr.End.Format("2006-01"), tt.start, tt.end)
+		}
+	}
+
+	// March closes the fiscal year and its last quarter
+	march := time.Date(2026, 4, 2, 0, 0, 0, 0, time.Local)
+	if pr, _ := resolveReportPeriod(ReportSendRequest{Type: "yearly"}, march); pr.Start.Format("2006-01") != "2025-04" {
+		t.Errorf("fiscal year starts %s, want 2025-04", pr.Start.Format("2006-01"))
+	}
+	if pr, _ := resolveReportPeriod(ReportSendRequest{Type: "quarterly"}, march); pr.Start.Format("2006-01") != "2026-01" {
+		t.Errorf("quarter starts %s, want 2026-01", pr.Start.Format("2006-01"))
+	}
+}
+
+// keys returns the keys of a map of parts, for error messages
+func keys(parts map[string][]byte) []string {
+	var names []string
+	for name := range parts {
+		names = append(names, name)
+	}
+	return names
+}
@@ -1,34 +1,115 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 )
 
-// startRESTServer starts the REST API server
+// startRESTServer starts the REST API server used alongside the interactive menu
 func startRESTServer() {
+	config := serverConfigFromSettings()
+	server := newHTTPServer(config)
+
+	listener, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		fmt.Printf("Error starting REST API server on %s: %v\n", config.Addr, err)
+		return
+	}
+
+	fmt.Printf("Starting REST API server on %s\n", listenURL(config))
+	startWebhookDispatcher()
+	if err := serve(server, listener, config); err != nil && err != http.ErrServerClosed {
+		fmt.Printf("REST API server stopped: %v\n", err)
+	}
+}
+
+// newRouter registers all API routes and wraps them with CORS
+func newRouter() http.Handler {
+	router := newAPIRouter()
+
+	// Setup CORS
+	c := cors.New(cors.Options{
+		AllowedOrigins:   appSettings.CORSOrigins, // "*" by default, see config.go
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowCredentials: true,
+	})
+
+	// Wrap the router with CORS middleware
+	return c.Handler(router)
+}
+
+// lockData runs a request under dataMu. Reads share the lock and changes hold it
+// alone, so a read-modify-save sequence never interleaves with another request.
+// The event stream stays open and does not read the data, so it is not locked.
+func lockData(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/events":
+		case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+			dataMu.RLock()
+			defer dataMu.RUnlock()
+		default:
+			dataMu.Lock()
+			defer dataMu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newAPIRouter registers all API routes. Every route must also be described in
+// apiOperations (openapi.go), which openapi_test.go checks.
+func newAPIRouter() *mux.Router {
 	router := mux.NewRouter()
+	// The web dashboard serves every path the API does not. It is not a catch-all
+	// route, which would turn a wrong method on an API route into a 404.
+	router.NotFoundHandler = webHandler()
+	router.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	router.Use(lockData)
 
 	// Define API routes
 	// Roles endpoints
 	router.HandleFunc("/api/roles", getRoles).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/roles/{id}", getRole).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/roles/{id}", updateRole).Methods("PUT", "OPTIONS")
+
+	// Employees endpoints
+	router.HandleFunc("/api/employees", getEmployees).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/employees", createEmployee).Methods("POST", "OPTIONS")
 
 	// KPIs endpoints
 	router.HandleFunc("/api/kpis", getKPIs).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/kpis", createKPI).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/kpis/{id}", getKPI).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/kpis/{id}", updateKPI).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/kpis/{id}", deleteKPI).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/kpis/{id}/formula", getKPIFormula).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/roles/{id}/kpis", getKPIsByRole).Methods("GET", "OPTIONS")
 
 	// Measurements endpoints
 	router.HandleFunc("/api/measurements", getMeasurements).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/measurements", createMeasurement).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/measurements/import", importMeasurementsAPI).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/measurements/batch", createMeasurementBatch).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/measurements/anomalies", getAnomalies).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/measurements/{id}", getMeasurement).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/measurements/{id}", updateMeasurement).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/measurements/{id}", patchMeasurement).Methods("PATCH", "OPTIONS")
 	router.HandleFunc("/api/kpis/{id}/measurements", getMeasurementsByKPI).Methods("GET", "OPTIONS")
 
 	// Reports endpoints
@@ -36,10 +117,64 @@ func startRESTServer() {
 	router.HandleFunc("/api/reports/quarterly/{year}/{quarter}", getQuarterlyReport).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/reports/yearly/{year}", getYearlyReport).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/reports/custom", getCustomReport).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/reports/compare", getComparisonReport).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/reports/send", sendReportAPI).Methods("POST", "OPTIONS")
 
 	// Dashboard endpoints
 	router.HandleFunc("/api/dashboard/overview", getDashboardOverview).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/dashboard/trends", getDashboardTrends).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/forecast", getForecast).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/simulate", simulateAPI).Methods("POST", "OPTIONS")
+
+	// Live updates
+	router.HandleFunc("/api/events", streamEvents).Methods("GET", "OPTIONS")
+
+	// Period close endpoints
+	router.HandleFunc("/api/periods/closed", getClosedPeriods).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/periods/{period}/close", closePeriodAPI).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/periods/{period}/reopen", reopenPeriodAPI).Methods("POST", "OPTIONS")
+
+	// Alerts, rules first so "rules" is not taken for an alert ID
+	router.HandleFunc("/api/alerts/rules", getAlertRules).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/alerts/rules", createAlertRule).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/alerts/rules/{id}", updateAlertRule).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/alerts/rules/{id}", deleteAlertRule).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/alerts", getAlerts).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/alerts/{id}/acknowledge", acknowledgeAlertAPI).Methods("POST", "OPTIONS")
+
+	// Missing submissions and reminders
+	router.HandleFunc("/api/submissions", getSubmissions).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/submissions/remind", remindSubmissions).Methods("POST", "OPTIONS")
+
+	// Scheduled jobs, history first so "runs" is not taken for a job name
+	router.HandleFunc("/api/jobs/runs", getJobRuns).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/jobs", getJobs).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/jobs/{name}", getJob).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/jobs/{name}/run", runJob).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/jobs/{name}/pause", pauseJob).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/jobs/{name}/resume", resumeJob).Methods("POST", "OPTIONS")
+
+	// Webhooks
+	router.HandleFunc("/api/webhooks", getWebhooks).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/webhooks", createWebhook).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/webhooks/{id}", getWebhook).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/webhooks/{id}", updateWebhook).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/webhooks/{id}", deleteWebhook).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/webhooks/{id}/deliveries", getWebhookDeliveries).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/webhooks/{id}/test", testWebhook).Methods("POST", "OPTIONS")
+
+	// Ranking endpoints
+	router.HandleFunc("/api/rankings/leaderboard", getLeaderboard).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/rankings/departments", getDepartmentRankings).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/rankings/competency", getCompetencyRanking).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/rankings/employees", getEmployeeRanking).Methods("GET", "OPTIONS")
+
+	// Appraisal endpoints
+	router.HandleFunc("/api/appraisals/{year}", getAppraisals).Methods("GET", "OPTIONS")
+
+	// Bonus endpoints
+	router.HandleFunc("/api/bonus/{year}", getBonus).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/bonus/{year}/simulate", simulateBonus).Methods("POST", "OPTIONS")
 
 	// Settings endpoints
 	router.HandleFunc("/api/settings", getSettings).Methods("GET", "OPTIONS")
@@ -47,28 +182,70 @@ func startRESTServer() {
 	router.HandleFunc("/api/settings/reload-excel", reloadExcel).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/settings/save-excel", saveExcelAPI).Methods("POST", "OPTIONS")
 
-	// Setup CORS
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"}, // Allow all origins for development
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: true,
-	})
+	// API documentation
+	router.HandleFunc("/api/openapi.json", getOpenAPISpec).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/docs", getAPIDocs).Methods("GET", "OPTIONS")
 
-	// Wrap the router with CORS middleware
-	handler := c.Handler(router)
+	return router
+}
+
+// MeasurementInput is the request body for creating a measurement
+type MeasurementInput struct {
+	KPIID       int                `json:"kpi_id"`
+	MetricValue float64            `json:"metric_value"`
+	Unit        string             `json:"unit"`
+	Period      time.Time          `json:"period"`
+	Notes       string             `json:"notes"`
+	Inputs      map[string]float64 `json:"inputs,omitempty"` // Raw inputs of a derived KPI, its metric_value is computed
+}
 
-	// Start the server
-	fmt.Println("Starting REST API server on http://localhost:8080")
-	http.ListenAndServe(":8080", handler)
+// MeasurementUpdate is the request body for replacing a measurement
+type MeasurementUpdate struct {
+	MetricValue float64            `json:"metric_value"`
+	Notes       string             `json:"notes"`
+	Inputs      map[string]float64 `json:"inputs,omitempty"`
+}
+
+// MeasurementPatch is the request body for a partial measurement update
+type MeasurementPatch struct {
+	MetricValue *float64           `json:"metric_value,omitempty"`
+	Notes       *string            `json:"notes,omitempty"`
+	Inputs      map[string]float64 `json:"inputs,omitempty"`
+}
+
+// CustomReportRequest is the request body for a custom report
+type CustomReportRequest struct {
+	StartPeriod time.Time `json:"start_period"`
+	EndPeriod   time.Time `json:"end_period"`
+	RoleIDs     []int     `json:"role_ids,omitempty"` // Optional role IDs filter
+	Format      string    `json:"format"`
 }
 
 // Handler functions
 
-// getRoles returns all roles
+// getRoles returns the roles, filtered by role_id or q and sorted and paged
 func getRoles(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(roles)
+
+	params, perr := parseListParams(r.URL.Query(), []string{"role_id"}, sortFields(roleComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	filtered := []Role{}
+	for _, role := range roles {
+		if params.RoleID != 0 && role.ID != params.RoleID {
+			continue
+		}
+		if !matchesText(params.Query, role.Name, role.Description, role.Department) {
+			continue
+		}
+		filtered = append(filtered, role)
+	}
+
+	page, pagination := sortAndPage(filtered, params, roleComparators, "id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
 }
 
 // getRole returns a specific role by ID
@@ -77,7 +254,7 @@ func getRole(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid role ID")
 		return
 	}
 
@@ -88,13 +265,122 @@ func getRole(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	http.Error(w, "Role not found", http.StatusNotFound)
+	writeError(w, http.StatusNotFound, "Role not found")
 }
 
-// getKPIs returns all KPIs
+// updateRole replaces the name, description and department of a role
+func updateRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	existing := getRoleByID(id)
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "Role not found")
+		return
+	}
+
+	var role Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	role.ID = id
+
+	if errs := validateRole(role); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	previous := *existing
+	*existing = role
+
+	if err := saveToExcel(); err != nil {
+		*existing = previous
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(role)
+}
+
+// getEmployees returns the employees, filtered by role_id or q and sorted and paged
+func getEmployees(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, perr := parseListParams(r.URL.Query(), []string{"role_id"}, sortFields(employeeComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	filtered := []Employee{}
+	for _, e := range employees {
+		if params.RoleID != 0 && e.RoleID != params.RoleID {
+			continue
+		}
+		if !matchesText(params.Query, e.Name) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+
+	page, pagination := sortAndPage(filtered, params, employeeComparators, "id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
+
+// createEmployee adds a new employee
+func createEmployee(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var employee Employee
+	err := json.NewDecoder(r.Body).Decode(&employee)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if errs := validateEmployee(employee); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	// Generate new ID
+	employee.ID = 1
+	for _, e := range employees {
+		if e.ID >= employee.ID {
+			employee.ID = e.ID + 1
+		}
+	}
+	employees = append(employees, employee)
+
+	// Save to Excel
+	err = saveToExcel()
+	if err != nil {
+		employees = employees[:len(employees)-1]
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(employee)
+}
+
+// getKPIs returns the KPIs, filtered by role_id, kpi_id, category or q and sorted and paged
 func getKPIs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(kpis)
+
+	params, perr := parseListParams(r.URL.Query(), []string{"role_id", "kpi_id", "category"}, sortFields(kpiComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	page, pagination := sortAndPage(filterKPIs(params), params, kpiComparators, "id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
 }
 
 // getKPI returns a specific KPI by ID
@@ -103,7 +389,7 @@ func getKPI(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		http.Error(w, "Invalid KPI ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid KPI ID")
 		return
 	}
 
@@ -114,211 +400,700 @@ func getKPI(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	http.Error(w, "KPI not found", http.StatusNotFound)
+	writeError(w, http.StatusNotFound, "KPI not found")
+}
+
+// createKPI adds a KPI to a role
+func createKPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var kpi KPI
+	err := json.NewDecoder(r.Body).Decode(&kpi)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if errs := validateKPI(kpi); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	// Generate new ID
+	kpi.ID = 1
+	for _, k := range kpis {
+		if k.ID >= kpi.ID {
+			kpi.ID = k.ID + 1
+		}
+	}
+	kpis = append(kpis, kpi)
+
+	// A formula over other KPIs gets values for the periods they have in common
+	previousMeasurements := append([]Measurement{}, measurements...)
+	recomputeKPI(kpi)
+
+	if err := saveToExcel(); err != nil {
+		kpis = kpis[:len(kpis)-1]
+		measurements = previousMeasurements
+		discardPendingChanges()
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
+		return
+	}
+
+	publishKPIEvent(EventKPICreated, kpi)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(kpi)
+}
+
+// updateKPI replaces the definition of a KPI. Its measurements are kept.
+func updateKPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid KPI ID")
+		return
+	}
+
+	existing := getKPIByID(id)
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "KPI not found")
+		return
+	}
+
+	var kpi KPI
+	err = json.NewDecoder(r.Body).Decode(&kpi)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	kpi.ID = id
+
+	if errs := validateKPI(kpi); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	previous := *existing
+	*existing = kpi
+
+	// Stored values follow a changed formula
+	previousMeasurements := append([]Measurement{}, measurements...)
+	if kpi.Formula != previous.Formula {
+		recomputeKPI(kpi)
+	}
+
+	if err := saveToExcel(); err != nil {
+		*existing = previous
+		measurements = previousMeasurements
+		discardPendingChanges()
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
+		return
+	}
+
+	publishKPIEvent(EventKPIUpdated, kpi)
+	json.NewEncoder(w).Encode(kpi)
 }
 
-// getKPIsByRole returns all KPIs for a specific role
+// deleteKPI removes a KPI that has no measurements
+func deleteKPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid KPI ID")
+		return
+	}
+
+	index := -1
+	for i, kpi := range kpis {
+		if kpi.ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "KPI not found")
+		return
+	}
+
+	count := 0
+	for _, m := range measurements {
+		if m.KPIID == id {
+			count++
+		}
+	}
+	if count > 0 {
+		writeError(w, http.StatusConflict, fmt.Sprintf("KPI has %d measurements and cannot be deleted", count))
+		return
+	}
+	if dependents := getKPIDependents(id); len(dependents) > 0 {
+		writeError(w, http.StatusConflict, fmt.Sprintf("KPI is used in the formula of KPI %d and cannot be deleted", dependents[0].ID))
+		return
+	}
+
+	previous := append([]KPI{}, kpis...)
+	deleted := kpis[index]
+	kpis = append(kpis[:index], kpis[index+1:]...)
+
+	if err := saveToExcel(); err != nil {
+		kpis = previous
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
+		return
+	}
+
+	publishKPIEvent(EventKPIDeleted, deleted)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getKPIsByRole returns the KPIs of a specific role with the same filters as getKPIs
 func getKPIsByRole(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	params := mux.Vars(r)
 	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid role ID")
 		return
 	}
 
-	roleKPIs := getKPIsByRoleID(id)
-	json.NewEncoder(w).Encode(roleKPIs)
+	listParams, perr := parseListParams(r.URL.Query(), []string{"kpi_id", "category"}, sortFields(kpiComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+	listParams.RoleID = id
+
+	page, pagination := sortAndPage(filterKPIs(listParams), listParams, kpiComparators, "id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
 }
 
-// getMeasurements returns all measurements with optional filtering
+// getMeasurements returns the measurements filtered by role_id, kpi_id, category,
+// from/to, year/month or q (notes and KPI name), sorted and paged
 func getMeasurements(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get query parameters for filtering
-	query := r.URL.Query()
-	yearStr := query.Get("year")
-	monthStr := query.Get("month")
-
-	// If no filters, return all measurements
-	if yearStr == "" && monthStr == "" {
-		json.NewEncoder(w).Encode(measurements)
+	params, perr := parseListParams(r.URL.Query(),
+		[]string{"role_id", "kpi_id", "category", "from", "to", "year", "month"},
+		sortFields(measurementComparators))
+	if perr != nil {
+		writeParamError(w, perr)
 		return
 	}
 
-	// Apply filters
-	var filteredMeasurements []Measurement
-	for _, m := range measurements {
-		// Filter by year if provided
-		if yearStr != "" {
-			year, err := strconv.Atoi(yearStr)
-			if err != nil {
-				http.Error(w, "Invalid year", http.StatusBadRequest)
-				return
-			}
-			if m.Period.Year() != year {
-				continue
-			}
+	page, pagination := sortAndPage(filterMeasurements(params), params, measurementComparators, "id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
+
+// measurementETag returns an entity tag that changes whenever the measurement changes
+func measurementETag(m Measurement) string {
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// checkIfMatch compares the If-Match header with the current measurement. A request
+// without If-Match is allowed; a stale tag gets 412 with the current record.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, m Measurement) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+
+	etag := measurementETag(m)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
 		}
+	}
 
-		// Filter by month if provided
-		if monthStr != "" {
-			month, err := strconv.Atoi(monthStr)
-			if err != nil {
-				http.Error(w, "Invalid month", http.StatusBadRequest)
-				return
-			}
-			if int(m.Period.Month()) != month {
-				continue
-			}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(struct {
+		Error   APIError    `json:"error"`
+		Current Measurement `json:"current"`
+	}{
+		Error:   newAPIError(http.StatusPreconditionFailed, "Measurement was changed by someone else, reload and try again"),
+		Current: m,
+	})
+	return false
+}
+
+// getMeasurementByID returns the measurement with the given ID or nil
+func getMeasurementByID(id int) *Measurement {
+	for i := range measurements {
+		if measurements[i].ID == id {
+			return &measurements[i]
 		}
+	}
+	return nil
+}
+
+// getMeasurement returns a single measurement with its ETag
+func getMeasurement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid measurement ID")
+		return
+	}
 
-		filteredMeasurements = append(filteredMeasurements, m)
+	measurement := getMeasurementByID(id)
+	if measurement == nil {
+		writeError(w, http.StatusNotFound, "Measurement not found")
+		return
 	}
 
-	json.NewEncoder(w).Encode(filteredMeasurements)
+	w.Header().Set("ETag", measurementETag(*measurement))
+	json.NewEncoder(w).Encode(measurement)
 }
 
-// createMeasurement adds a new measurement
+// createMeasurement adds a new measurement. If the KPI already has a measurement for
+// the period it returns 409 with the existing record, unless ?upsert=true is given.
 func createMeasurement(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var measurementRequest struct {
-		KPIID       int       `json:"kpi_id"`
-		MetricValue float64   `json:"metric_value"`
-		Unit        string    `json:"unit"`
-		Period      time.Time `json:"period"`
-		Notes       string    `json:"notes"`
-	}
+	var measurementRequest MeasurementInput
 
 	// Decode the request body
 	err := json.NewDecoder(r.Body).Decode(&measurementRequest)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// Validate the KPI ID
-	validKPI := false
-	for _, kpi := range kpis {
-		if kpi.ID == measurementRequest.KPIID {
-			validKPI = true
-			break
+	// Validate the KPI ID, value and period
+	kpi, value, errs := validateMeasurementInput(measurementRequest.KPIID, measurementRequest.MetricValue, measurementRequest.Inputs, measurementRequest.Period)
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	status := http.StatusCreated
+	if existing := getExistingMeasurement(measurementRequest.KPIID, measurementRequest.Period); existing != nil {
+		if r.URL.Query().Get("upsert") != "true" {
+			w.Header().Set("ETag", measurementETag(*existing))
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(struct {
+				Error    APIError    `json:"error"`
+				Existing Measurement `json:"existing"`
+			}{
+				Error:    newAPIError(http.StatusConflict, "A measurement for this KPI and period already exists, use PUT or ?upsert=true"),
+				Existing: *existing,
+			})
+			return
 		}
+		status = http.StatusOK
 	}
-	if !validKPI {
-		http.Error(w, "Invalid KPI ID", http.StatusBadRequest)
-		return
+
+	if r.URL.Query().Get("confirm") != "true" {
+		if anomalies := checkMeasurementValue(*kpi, value, measurementRequest.Period); len(anomalies) > 0 {
+			writeAnomalyWarning(w, anomalies)
+			return
+		}
 	}
 
+	// Keep a copy so a failed save also undoes the recomputed derived values
+	previous := make([]Measurement, len(measurements))
+	copy(previous, measurements)
+
 	// Save the measurement
 	saveMeasurement(
 		measurementRequest.KPIID,
-		measurementRequest.MetricValue,
+		value,
+		measurementRequest.Inputs,
 		measurementRequest.Unit,
 		measurementRequest.Period,
 		measurementRequest.Notes,
 	)
 
-	// Save to Excel
-	err = saveToExcel()
+	// Save to Excel
+	err = saveToExcel()
+	if err != nil {
+		measurements = previous
+		discardPendingChanges()
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
+		return
+	}
+
+	// Return the newly created measurement
+	existingMeasurement := getExistingMeasurement(measurementRequest.KPIID, measurementRequest.Period)
+	w.Header().Set("ETag", measurementETag(*existingMeasurement))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(existingMeasurement)
+}
+
+// importMeasurementsAPI imports measurements from an uploaded CSV or xlsx file.
+// Form fields: file, format, sheet, map, dry_run, skip_errors.
+func importMeasurementsAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid multipart form: "+err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Missing file upload")
+		return
+	}
+	defer file.Close()
+
+	mapping, err := parseImportMapping(r.FormValue("map"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := importMeasurements(file, header.Filename, ImportOptions{
+		Format:     r.FormValue("format"),
+		Sheet:      r.FormValue("sheet"),
+		Mapping:    mapping,
+		DryRun:     r.FormValue("dry_run") == "true",
+		SkipErrors: r.FormValue("skip_errors") == "true",
+	})
+	if errors.Is(err, errImportNotSaved) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Rows with errors stop the import unless skip_errors is set
+	if result.Failed > 0 && !result.DryRun && !result.Applied {
+		var errs ValidationErrors
+		for _, row := range result.Rows {
+			if row.Action == importActionError {
+				errs.Add(fmt.Sprintf("row %d", row.Row), "%s", row.Error)
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(struct {
+			Error APIError `json:"error"`
+			ImportResult
+		}{
+			Error:        newAPIError(http.StatusUnprocessableEntity, "Import rejected, fix the rows with errors or set skip_errors", errs...),
+			ImportResult: result,
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// BatchResponse is returned by the measurement batch endpoint
+type BatchResponse struct {
+	Error   *APIError         `json:"error,omitempty"`
+	Applied bool              `json:"applied"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// BatchItemResult is the outcome of one entry of a measurement batch
+type BatchItemResult struct {
+	Index     int       `json:"index"`
+	ID        int       `json:"id,omitempty"`
+	KPIID     int       `json:"kpi_id"`
+	Period    string    `json:"period"`
+	Action    string    `json:"action,omitempty"` // "created" or "updated"
+	Error     string    `json:"error,omitempty"`
+	Anomalies []Anomaly `json:"anomalies,omitempty"` // Values that need ?confirm=true
+}
+
+// createMeasurementBatch creates or updates several measurements at once. All entries
+// are validated first and either all of them are applied or none, with a single save.
+func createMeasurementBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var batch []MeasurementInput
+
+	// Decode the request body
+	err := json.NewDecoder(r.Body).Decode(&batch)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body, expected an array of measurements")
+		return
+	}
+	if len(batch) == 0 {
+		writeError(w, http.StatusBadRequest, "Batch cannot be empty")
+		return
+	}
+
+	response := BatchResponse{Results: make([]BatchItemResult, len(batch))}
+
+	// Validate every entry before changing anything. Derived entries are validated
+	// after the entries they refer to, against the measurements with the batch
+	// applied so far, so their formulas see the new values. Anomalies are checked
+	// once the whole batch is applied, so entries for several months count as history.
+	var allErrs ValidationErrors
+	var warnings []FieldError
+	confirmed := r.URL.Query().Get("confirm") == "true"
+	seen := make(map[string]int)
+	data := make([]Measurement, len(measurements))
+	copy(data, measurements)
+	for _, i := range batchValidationOrder(batch) {
+		item := batch[i]
+		result := BatchItemResult{Index: i, KPIID: item.KPIID}
+		period := time.Date(item.Period.Year(), item.Period.Month(), 1, 0, 0, 0, 0, time.Local)
+		if !item.Period.IsZero() {
+			result.Period = period.Format("2006-01")
+		}
+
+		kpi, value, errs := validateMeasurementInputFrom(item.KPIID, item.MetricValue, item.Inputs, item.Period, data)
+		batch[i].MetricValue = value
+		key := fmt.Sprintf("%d/%s", item.KPIID, result.Period)
+		if first, ok := seen[key]; ok && len(errs) == 0 {
+			errs.Add("period", "Duplicate of entry %d", first)
+		} else if !ok {
+			seen[key] = i
+		}
+		if len(errs) == 0 {
+			data = setScenarioValue(data, *kpi, period, value)
+		}
+
+		if len(errs) > 0 {
+			result.Error = errs.Error()
+			allErrs = append(allErrs, errs.Prefix(fmt.Sprintf("[%d].", i))...)
+			response.Failed++
+		}
+		response.Results[i] = result
+	}
+	for i, item := range batch {
+		if confirmed || response.Results[i].Error != "" {
+			continue
+		}
+		period := time.Date(item.Period.Year(), item.Period.Month(), 1, 0, 0, 0, 0, time.Local)
+		response.Results[i].Anomalies = checkMeasurementValueFrom(*getKPIByID(item.KPIID), item.MetricValue, period, data)
+		for _, a := range response.Results[i].Anomalies {
+			warnings = append(warnings, FieldError{Field: fmt.Sprintf("[%d].metric_value", i), Message: a.Message})
+		}
+	}
+
+	if response.Failed > 0 {
+		apiErr := newAPIError(http.StatusUnprocessableEntity, "Batch rejected, no measurements were changed", allErrs...)
+		response.Error = &apiErr
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	if len(warnings) > 0 {
+		apiErr := newAPIError(http.StatusPreconditionRequired, "Some values look anomalous, check them and resend with ?confirm=true, no measurements were changed", warnings...)
+		response.Error = &apiErr
+		w.WriteHeader(http.StatusPreconditionRequired)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Keep a copy so a failed save leaves the data unchanged
+	previous := make([]Measurement, len(measurements))
+	copy(previous, measurements)
+
+	for i, item := range batch {
+		period := time.Date(item.Period.Year(), item.Period.Month(), 1, 0, 0, 0, 0, time.Local)
+		unit := item.Unit
+		if unit == "" {
+			unit = getKPIByID(item.KPIID).Unit
+		}
+
+		response.Results[i].Action = "created"
+		if getExistingMeasurement(item.KPIID, period) != nil {
+			response.Results[i].Action = "updated"
+			response.Updated++
+		} else {
+			response.Created++
+		}
+
+		saveMeasurement(item.KPIID, item.MetricValue, item.Inputs, unit, period, item.Notes)
+		response.Results[i].ID = getExistingMeasurement(item.KPIID, period).ID
+	}
+
+	// Save to Excel once for the whole batch
+	err = saveToExcel()
+	if err != nil {
+		measurements = previous
+		discardPendingChanges()
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel, no measurements were changed: "+err.Error())
+		return
+	}
+
+	response.Applied = true
+	json.NewEncoder(w).Encode(response)
+}
+
+// batchValidationOrder returns the indexes of a measurement batch with every
+// derived entry after the entries of the same period its formula refers to.
+// Other entries keep their order.
+func batchValidationOrder(batch []MeasurementInput) []int {
+	entries := make(map[string][]int)
+	key := func(kpiID int, period time.Time) string {
+		return fmt.Sprintf("%d/%s", kpiID, period.Format("2006-01"))
+	}
+	for i, item := range batch {
+		entries[key(item.KPIID, item.Period)] = append(entries[key(item.KPIID, item.Period)], i)
+	}
+
+	order := make([]int, 0, len(batch))
+	visited := make([]bool, len(batch))
+	var visit func(i int)
+	visit = func(i int) {
+		// validateFormula rules out cycles, visited also stops them
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		if kpi := getKPIByID(batch[i].KPIID); kpi != nil {
+			_, refs := kpiFormulaNames(*kpi)
+			for _, ref := range refs {
+				for _, j := range entries[key(ref, batch[i].Period)] {
+					visit(j)
+				}
+			}
+		}
+		order = append(order, i)
+	}
+	for i := range batch {
+		visit(i)
+	}
+	return order
+}
+
+// updateMeasurement replaces the value and notes of an existing measurement.
+// Send the ETag from a previous GET in If-Match to avoid overwriting another change.
+func updateMeasurement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid measurement ID")
+		return
+	}
+
+	var measurementRequest MeasurementUpdate
+
+	// Decode the request body
+	err = json.NewDecoder(r.Body).Decode(&measurementRequest)
 	if err != nil {
-		http.Error(w, "Failed to save to Excel: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// Return the newly created measurement
-	existingMeasurement := getExistingMeasurement(measurementRequest.KPIID, measurementRequest.Period)
-	json.NewEncoder(w).Encode(existingMeasurement)
+	applyMeasurementChange(w, r, id, &measurementRequest.MetricValue, measurementRequest.Inputs, &measurementRequest.Notes)
 }
 
-// updateMeasurement updates an existing measurement
-func updateMeasurement(w http.ResponseWriter, r *http.Request) {
+// patchMeasurement updates only the fields present in the request body
+func patchMeasurement(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	params := mux.Vars(r)
 	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		http.Error(w, "Invalid measurement ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid measurement ID")
 		return
 	}
 
-	var measurementRequest struct {
-		MetricValue float64 `json:"metric_value"`
-		Notes       string  `json:"notes"`
-	}
+	var measurementRequest MeasurementPatch
 
 	// Decode the request body
 	err = json.NewDecoder(r.Body).Decode(&measurementRequest)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if measurementRequest.MetricValue == nil && measurementRequest.Inputs == nil && measurementRequest.Notes == nil {
+		writeError(w, http.StatusUnprocessableEntity, "Nothing to update, send metric_value, inputs and/or notes")
 		return
 	}
 
-	// Find and update the measurement
-	found := false
-	for i := range measurements {
-		if measurements[i].ID == id {
-			measurements[i].MetricValue = measurementRequest.MetricValue
-			measurements[i].Notes = measurementRequest.Notes
-			found = true
-			break
-		}
+	applyMeasurementChange(w, r, id, measurementRequest.MetricValue, measurementRequest.Inputs, measurementRequest.Notes)
+}
+
+// applyMeasurementChange checks If-Match, validates and saves a change to a measurement.
+// Nil fields are left unchanged. The value of a derived KPI is recomputed from the
+// inputs, or from the stored inputs when only metric_value is sent.
+func applyMeasurementChange(w http.ResponseWriter, r *http.Request, id int, value *float64, inputs map[string]float64, notes *string) {
+	measurement := getMeasurementByID(id)
+	if measurement == nil {
+		writeError(w, http.StatusNotFound, "Measurement not found")
+		return
 	}
 
-	if !found {
-		http.Error(w, "Measurement not found", http.StatusNotFound)
+	if !checkIfMatch(w, r, *measurement) {
 		return
 	}
 
+	if value != nil || inputs != nil {
+		if kpi := getKPIByID(measurement.KPIID); kpi != nil && isDerivedKPI(*kpi) && inputs == nil {
+			inputs = measurement.Inputs
+		}
+		var newValue float64
+		if value != nil {
+			newValue = *value
+		}
+		kpi, computed, errs := validateMeasurementInput(measurement.KPIID, newValue, inputs, measurement.Period)
+		if len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+		if r.URL.Query().Get("confirm") != "true" {
+			if anomalies := checkMeasurementValue(*kpi, computed, measurement.Period); len(anomalies) > 0 {
+				writeAnomalyWarning(w, anomalies)
+				return
+			}
+		}
+		value = &computed
+	}
+
+	// Keep a copy so a failed save also undoes the recomputed derived values
+	previousAll := make([]Measurement, len(measurements))
+	copy(previousAll, measurements)
+	previous := *measurement
+	if value != nil {
+		measurement.MetricValue = *value
+		measurement.Inputs = inputs
+	}
+	if notes != nil {
+		measurement.Notes = *notes
+	}
+	recordMeasurementChange(*measurement, &previous)
+	if value != nil {
+		recomputeDependents(measurement.KPIID, measurement.Period)
+	}
+
 	// Save to Excel
-	err = saveToExcel()
+	err := saveToExcel()
 	if err != nil {
-		http.Error(w, "Failed to save to Excel: "+err.Error(), http.StatusInternalServerError)
+		measurements = previousAll
+		discardPendingChanges()
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
 		return
 	}
 
 	// Return the updated measurement
-	for i := range measurements {
-		if measurements[i].ID == id {
-			json.NewEncoder(w).Encode(measurements[i])
-			break
-		}
-	}
+	updated := getMeasurementByID(id)
+	w.Header().Set("ETag", measurementETag(*updated))
+	json.NewEncoder(w).Encode(updated)
 }
 
-// getMeasurementsByKPI returns all measurements for a specific KPI
+// getMeasurementsByKPI returns the measurements of a specific KPI with the same
+// filters as getMeasurements
 func getMeasurementsByKPI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	params := mux.Vars(r)
 	kpiID, err := strconv.Atoi(params["id"])
 	if err != nil {
-		http.Error(w, "Invalid KPI ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid KPI ID")
 		return
 	}
 
-	// Get query parameters for filtering
-	query := r.URL.Query()
-	yearStr := query.Get("year")
-
-	var filteredMeasurements []Measurement
-	for _, m := range measurements {
-		if m.KPIID == kpiID {
-			// Filter by year if provided
-			if yearStr != "" {
-				year, err := strconv.Atoi(yearStr)
-				if err != nil {
-					http.Error(w, "Invalid year", http.StatusBadRequest)
-					return
-				}
-				if m.Period.Year() != year {
-					continue
-				}
-			}
-			filteredMeasurements = append(filteredMeasurements, m)
-		}
+	listParams, perr := parseListParams(r.URL.Query(), []string{"from", "to", "year", "month"}, sortFields(measurementComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
 	}
+	listParams.KPIID = kpiID
 
-	json.NewEncoder(w).Encode(filteredMeasurements)
+	page, pagination := sortAndPage(filterMeasurements(listParams), listParams, measurementComparators, "period")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
 }
 
 // getMonthlyReport generates a monthly report
@@ -327,13 +1102,13 @@ func getMonthlyReport(w http.ResponseWriter, r *http.Request) {
 
 	year, err := strconv.Atoi(params["year"])
 	if err != nil || year < 2000 || year > 2100 {
-		http.Error(w, "Invalid year", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid year")
 		return
 	}
 
 	month, err := strconv.Atoi(params["month"])
 	if err != nil || month < 1 || month > 12 {
-		http.Error(w, "Invalid month", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid month")
 		return
 	}
 
@@ -359,6 +1134,10 @@ func getMonthlyReport(w http.ResponseWriter, r *http.Request) {
 		type RoleReport struct {
 			Role         Role             `json:"role"`
 			TotalScore   float64          `json:"total_score"`
+			Coverage     float64          `json:"coverage"`
+			Incomplete   bool             `json:"incomplete"`
+			Policy       string           `json:"scoring_policy"`
+			Rating       string           `json:"rating"`
 			Achievements []KPIAchievement `json:"achievements"`
 		}
 
@@ -394,7 +1173,15 @@ func getMonthlyReport(w http.ResponseWriter, r *http.Request) {
 				})
 			}
 
-			roleReport.TotalScore = calculateOverallScore(roleKPIs, period)
+			result := calculateScoreResult(roleKPIs, period)
+			roleReport.TotalScore = result.Score
+			roleReport.Coverage = result.Coverage
+			roleReport.Incomplete = result.Incomplete
+			roleReport.Policy = result.Policy
+			roleReport.Rating = notRated
+			if result.HasData() {
+				roleReport.Rating = getRating(role.ID, result.Score)
+			}
 			report = append(report, roleReport)
 		}
 
@@ -423,22 +1210,19 @@ func getQuarterlyReport(w http.ResponseWriter, r *http.Request) {
 
 	year, err := strconv.Atoi(params["year"])
 	if err != nil || year < 2000 || year > 2100 {
-		http.Error(w, "Invalid year", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid year")
 		return
 	}
 
 	quarter, err := strconv.Atoi(params["quarter"])
 	if err != nil || quarter < 1 || quarter > 4 {
-		http.Error(w, "Invalid quarter", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid quarter")
 		return
 	}
 
-	// Calculate start and end periods
-	startMonth := (quarter-1)*3 + 1
-	endMonth := quarter * 3
-
-	startPeriod := time.Date(year, time.Month(startMonth), 1, 0, 0, 0, 0, time.Local)
-	endPeriod := time.Date(year, time.Month(endMonth), 1, 0, 0, 0, 0, time.Local)
+	// Calculate start and end periods of the fiscal quarter
+	pr := fiscalQuarterRange(year, quarter)
+	startPeriod, endPeriod := pr.Start, pr.End
 
 	// Get the report format from query params (default to JSON)
 	format := r.URL.Query().Get("format")
@@ -487,13 +1271,13 @@ func getYearlyReport(w http.ResponseWriter, r *http.Request) {
 
 	year, err := strconv.Atoi(params["year"])
 	if err != nil || year < 2000 || year > 2100 {
-		http.Error(w, "Invalid year", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid year")
 		return
 	}
 
-	// Calculate start and end periods
-	startPeriod := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
-	endPeriod := time.Date(year, 12, 1, 0, 0, 0, 0, time.Local)
+	// Calculate start and end periods of the fiscal year
+	pr := fiscalYearRange(year)
+	startPeriod, endPeriod := pr.Start, pr.End
 
 	// Get the report format from query params (default to JSON)
 	format := r.URL.Query().Get("format")
@@ -538,23 +1322,18 @@ func getYearlyReport(w http.ResponseWriter, r *http.Request) {
 func getCustomReport(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var customReportRequest struct {
-		StartPeriod time.Time `json:"start_period"`
-		EndPeriod   time.Time `json:"end_period"`
-		RoleIDs     []int     `json:"role_ids,omitempty"` // Optional role IDs filter
-		Format      string    `json:"format"`
-	}
+	var customReportRequest CustomReportRequest
 
 	// Decode the request body
 	err := json.NewDecoder(r.Body).Decode(&customReportRequest)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate periods
 	if customReportRequest.EndPeriod.Before(customReportRequest.StartPeriod) {
-		http.Error(w, "End period cannot be before start period", http.StatusBadRequest)
+		writeError(w, http.StatusUnprocessableEntity, "Validation failed", FieldError{Field: "end_period", Message: "End period cannot be before start period"})
 		return
 	}
 
@@ -595,6 +1374,215 @@ func getCustomReport(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getComparisonReport compares two period ranges, e.g.
+// /api/reports/compare?base_start=2025-01&base_end=2025-03&compare_start=2025-04&compare_end=2025-06
+func getComparisonReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	base, err := parsePeriodRangeParams(query, "base_start", "base_end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	compare, err := parsePeriodRangeParams(query, "compare_start", "compare_end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Get the report format from query params (default to JSON)
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildComparisonReport(base, compare))
+		return
+	}
+
+	reportContent := generateComparisonReport(base, compare, format)
+
+	// Set appropriate content type
+	switch format {
+	case "txt":
+		w.Header().Set("Content-Type", "text/plain")
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+	case "html":
+		w.Header().Set("Content-Type", "text/html")
+	}
+
+	fmt.Fprint(w, reportContent)
+}
+
+// parsePeriodRangeParams parses a "YYYY-MM" period range from query parameters.
+// The end period defaults to the start period so single months need only one parameter.
+func parsePeriodRangeParams(query url.Values, startKey, endKey string) (PeriodRange, error) {
+	start, err := parsePeriodString(query.Get(startKey))
+	if err != nil {
+		return PeriodRange{}, fmt.Errorf("%s: %v", startKey, err)
+	}
+
+	end := start
+	if query.Get(endKey) != "" {
+		end, err = parsePeriodString(query.Get(endKey))
+		if err != nil {
+			return PeriodRange{}, fmt.Errorf("%s: %v", endKey, err)
+		}
+	}
+
+	if end.Before(start) {
+		return PeriodRange{}, fmt.Errorf("%s cannot be before %s", endKey, startKey)
+	}
+
+	return PeriodRange{Start: start, End: end}, nil
+}
+
+// parseMinCoverageParam parses the optional min_coverage query parameter
+func parseMinCoverageParam(query url.Values) (float64, error) {
+	value := query.Get("min_coverage")
+	if value == "" {
+		return defaultMinRankingCoverage, nil
+	}
+
+	minCoverage, err := strconv.ParseFloat(value, 64)
+	if err != nil || minCoverage < 0 || minCoverage > 100 {
+		return 0, fmt.Errorf("min_coverage must be a number between 0 and 100")
+	}
+
+	return minCoverage, nil
+}
+
+// getLeaderboard ranks roles for a period range, optionally limited to the top or bottom performers
+func getLeaderboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	pr, err := parsePeriodRangeParams(query, "start", "end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	minCoverage, err := parseMinCoverageParam(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ranking := buildLeaderboard(pr, query.Get("department"), minCoverage)
+	if err := applyRankingLimit(&ranking, query); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(ranking)
+}
+
+// applyRankingLimit keeps only the top or bottom performers when a limit is given
+func applyRankingLimit(ranking *Ranking, query url.Values) error {
+	limitStr := query.Get("limit")
+	if limitStr == "" {
+		return nil
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		return fmt.Errorf("Invalid limit")
+	}
+
+	switch query.Get("order") {
+	case "", "top":
+		ranking.Entries = topEntries(*ranking, limit)
+	case "bottom":
+		ranking.Entries = bottomEntries(*ranking, limit)
+	default:
+		return fmt.Errorf("Invalid order, expected top or bottom")
+	}
+	return nil
+}
+
+// getDepartmentRankings ranks roles within each department
+func getDepartmentRankings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	pr, err := parsePeriodRangeParams(query, "start", "end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	minCoverage, err := parseMinCoverageParam(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(buildDepartmentRankings(pr, minCoverage))
+}
+
+// getCompetencyRanking ranks roles on a KPI shared across roles, e.g. ?kpi_name=Innovation
+func getCompetencyRanking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	kpiName := query.Get("kpi_name")
+	if kpiName == "" {
+		writeError(w, http.StatusBadRequest, "kpi_name is required")
+		return
+	}
+
+	pr, err := parsePeriodRangeParams(query, "start", "end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	minCoverage, err := parseMinCoverageParam(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ranking := buildCompetencyRanking(kpiName, pr, minCoverage)
+	if len(ranking.Entries) == 0 {
+		writeError(w, http.StatusNotFound, "KPI not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(ranking)
+}
+
+// getEmployeeRanking ranks employees by their role's score over the months they were employed
+func getEmployeeRanking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	pr, err := parsePeriodRangeParams(query, "start", "end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	minCoverage, err := parseMinCoverageParam(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ranking := buildEmployeeRanking(pr, query.Get("department"), minCoverage)
+	if err := applyRankingLimit(&ranking, query); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(ranking)
+}
+
 // getDashboardOverview returns an overview of KPI achievements for the dashboard
 func getDashboardOverview(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -630,8 +1618,18 @@ func getDashboardOverview(w http.ResponseWriter, r *http.Request) {
 		RoleID     int     `json:"role_id"`
 		RoleName   string  `json:"role_name"`
 		TotalScore float64 `json:"total_score"`
+		Coverage   float64 `json:"coverage"`
+		Incomplete bool    `json:"incomplete"`
+		Policy     string  `json:"scoring_policy"`
+		Rating     string  `json:"rating"`
 		KPICount   int     `json:"kpi_count"`
 		Measured   int     `json:"measured_kpis"`
+
+		// Submission completeness, see submissions.go
+		SubmissionStatus string    `json:"submission_status"`
+		Deadline         time.Time `json:"deadline"`
+		MissingKPIs      int       `json:"missing_kpis"`
+		Overdue          bool      `json:"overdue"`
 	}
 
 	var overview []RoleOverview
@@ -642,22 +1640,30 @@ func getDashboardOverview(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		measured := 0
-		for _, kpi := range roleKPIs {
-			measurement := getExistingMeasurement(kpi.ID, period)
-			if measurement != nil {
-				measured++
-			}
+		result := calculateScoreResult(roleKPIs, period)
+
+		rating := notRated
+		if result.HasData() {
+			rating = getRating(role.ID, result.Score)
 		}
 
-		totalScore := calculateOverallScore(roleKPIs, period)
+		submission := roleSubmission(role, period, time.Now())
 
 		overview = append(overview, RoleOverview{
 			RoleID:     role.ID,
 			RoleName:   role.Name,
-			TotalScore: totalScore,
+			TotalScore: result.Score,
+			Coverage:   result.Coverage,
+			Incomplete: result.Incomplete,
+			Policy:     result.Policy,
+			Rating:     rating,
 			KPICount:   len(roleKPIs),
-			Measured:   measured,
+			Measured:   result.MeasuredKPIs,
+
+			SubmissionStatus: submission.Status,
+			Deadline:         submission.Deadline,
+			MissingKPIs:      len(submission.Missing),
+			Overdue:          submission.Status == SubmissionOverdue,
 		})
 	}
 
@@ -685,9 +1691,10 @@ func getDashboardTrends(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare trends data
 	type MonthlyTrend struct {
-		Month      int                `json:"month"`
-		MonthName  string             `json:"month_name"`
-		RoleScores map[string]float64 `json:"role_scores"`
+		Month        int                `json:"month"`
+		MonthName    string             `json:"month_name"`
+		RoleScores   map[string]float64 `json:"role_scores"`
+		RoleCoverage map[string]float64 `json:"role_coverage"`
 	}
 
 	var trends []MonthlyTrend
@@ -698,9 +1705,10 @@ func getDashboardTrends(w http.ResponseWriter, r *http.Request) {
 		monthName := period.Format("January")
 
 		trend := MonthlyTrend{
-			Month:      month,
-			MonthName:  monthName,
-			RoleScores: make(map[string]float64),
+			Month:        month,
+			MonthName:    monthName,
+			RoleScores:   make(map[string]float64),
+			RoleCoverage: make(map[string]float64),
 		}
 
 		// For each role
@@ -710,8 +1718,9 @@ func getDashboardTrends(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 
-			score := calculateOverallScore(roleKPIs, period)
-			trend.RoleScores[role.Name] = score
+			result := calculateScoreResult(roleKPIs, period)
+			trend.RoleScores[role.Name] = result.Score
+			trend.RoleCoverage[role.Name] = result.Coverage
 		}
 
 		trends = append(trends, trend)
@@ -721,39 +1730,245 @@ func getDashboardTrends(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(trends)
 }
 
+// getAppraisals returns the final annual appraisals for a year
+func getAppraisals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	params := mux.Vars(r)
+
+	year, err := strconv.Atoi(params["year"])
+	if err != nil || year < 2000 || year > 2100 {
+		writeError(w, http.StatusBadRequest, "Invalid year")
+		return
+	}
+
+	response := struct {
+		Year        int             `json:"year"`
+		Config      AppraisalConfig `json:"config"`
+		Appraisals  []Appraisal     `json:"appraisals"`
+		GeneratedAt time.Time       `json:"generated_at"`
+	}{
+		Year:        year,
+		Config:      getAppraisalConfig(),
+		Appraisals:  buildAppraisals(year),
+		GeneratedAt: time.Now(),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// getBonus evaluates the configured bonus policy for a year.
+// Use ?format=xlsx to download the payout worksheet.
+func getBonus(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	year, err := strconv.Atoi(params["year"])
+	if err != nil || year < 2000 || year > 2100 {
+		writeError(w, http.StatusBadRequest, "Invalid year")
+		return
+	}
+
+	run := runBonusPolicy(getBonusPolicy(), year, false)
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(run)
+	case "xlsx":
+		tmpFile, err := os.CreateTemp("", "bonus-*.xlsx")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to create worksheet: "+err.Error())
+			return
+		}
+		tmpFile.Close()
+		defer os.Remove(tmpFile.Name())
+
+		if err := writeBonusWorksheet(run, tmpFile.Name()); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"Bonus_Payout_%d.xlsx\"", year))
+		http.ServeFile(w, r, tmpFile.Name())
+	default:
+		writeError(w, http.StatusBadRequest, "Unsupported format")
+	}
+}
+
+// simulateBonus evaluates a candidate bonus policy without changing the configured one
+func simulateBonus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	params := mux.Vars(r)
+
+	year, err := strconv.Atoi(params["year"])
+	if err != nil || year < 2000 || year > 2100 {
+		writeError(w, http.StatusBadRequest, "Invalid year")
+		return
+	}
+
+	var policy BonusPolicy
+	err = json.NewDecoder(r.Body).Decode(&policy)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validateBonusPolicy(policy); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "Invalid bonus policy", FieldError{Field: "bonus_policy", Message: err.Error()})
+		return
+	}
+
+	current := runBonusPolicy(getBonusPolicy(), year, false)
+	simulated := runBonusPolicy(policy, year, true)
+
+	response := struct {
+		Simulation   BonusRun `json:"simulation"`
+		CurrentTotal float64  `json:"current_total"`
+		Difference   float64  `json:"difference"`
+	}{
+		Simulation:   simulated,
+		CurrentTotal: current.TotalPayout,
+		Difference:   simulated.TotalPayout - current.TotalPayout,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
 // getSettings returns the application settings
 func getSettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(appSettings)
+
+	// Effective settings after merging defaults, file, environment and flags
+	response := struct {
+		Settings
+		SettingsFile string            `json:"settings_file"`
+		Sources      map[string]string `json:"sources"`
+	}{
+		Settings:     maskNotificationSecrets(appSettings),
+		SettingsFile: settingsPath,
+		Sources:      getConfigSources(),
+	}
+
+	json.NewEncoder(w).Encode(response)
 }
 
 // updateSettings updates the application settings
 func updateSettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var newSettings Settings
+	// Fields left out of the request keep their value. Plain values are decoded
+	// over the current ones; maps, slices and pointers are only replaced when the
+	// request has them, so decoding never writes into the current settings.
+	newSettings := Settings{
+		DatabasePath:    appSettings.DatabasePath,
+		ScoringPolicy:   appSettings.ScoringPolicy,
+		BackupRetention: appSettings.BackupRetention,
+		Anomalies:       appSettings.Anomalies,
+	}
+	var present map[string]json.RawMessage
 
 	// Decode the request body
-	err := json.NewDecoder(r.Body).Decode(&newSettings)
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &present)
+	}
+	if err == nil {
+		err = json.Unmarshal(body, &newSettings)
+	}
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	has := func(field string) bool {
+		_, ok := present[field]
+		return ok
+	}
 
 	// Validate settings
-	if newSettings.DatabasePath == "" {
-		http.Error(w, "Database path cannot be empty", http.StatusBadRequest)
+	if errs := validateSettingsUpdate(newSettings); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	// Build the updated settings, configuration fields left out of the request are kept
+	updated := appSettings
+	updated.DatabasePath = newSettings.DatabasePath
+	updated.ScoringPolicy = newSettings.ScoringPolicy
+	updated.BackupRetention = newSettings.BackupRetention
+	if has("bonus_policy") {
+		updated.BonusPolicy = newSettings.BonusPolicy
+	}
+	if has("rating_bands") {
+		updated.RatingBands = newSettings.RatingBands
+	}
+	if has("role_rating_bands") {
+		updated.RoleRatingBands = newSettings.RoleRatingBands
+	}
+	if has("appraisal") {
+		updated.Appraisal = newSettings.Appraisal
+	}
+	if has("role_scoring_policies") {
+		updated.RoleScoringPolicies = newSettings.RoleScoringPolicies
+	}
+	if updated.ScoringPolicy == "" {
+		updated.ScoringPolicy = PolicyExclude
+	}
+	if newSettings.Server.Addr != "" {
+		updated.Server = newSettings.Server
+	}
+	if len(newSettings.CORSOrigins) > 0 {
+		updated.CORSOrigins = newSettings.CORSOrigins
+	}
+	if newSettings.StorageBackend != "" {
+		updated.StorageBackend = newSettings.StorageBackend
+	}
+	if newSettings.FiscalYearStartMonth != 0 {
+		updated.FiscalYearStartMonth = newSettings.FiscalYearStartMonth
+	}
+	if newSettings.SubmissionDeadlineDay != 0 {
+		updated.SubmissionDeadlineDay = newSettings.SubmissionDeadlineDay
+	}
+	if newSettings.SMTP.Host != "" {
+		updated.SMTP = newSettings.SMTP
+		if updated.SMTP.Port == 0 {
+			updated.SMTP.Port = appSettings.SMTP.Port
+		}
+		if updated.SMTP.Password == secretMask {
+			updated.SMTP.Password = appSettings.SMTP.Password
+		}
+	}
+	if newSettings.Reminders.Notifiers != nil {
+		updated.Reminders = newSettings.Reminders
+		if updated.Reminders.WebhookSecret == secretMask {
+			updated.Reminders.WebhookSecret = appSettings.Reminders.WebhookSecret
+		}
+	}
+	if distribution := newSettings.ReportDistribution; distribution.Recipients != nil ||
+		distribution.DefaultRecipients != nil || distribution.Attachments != nil {
+		updated.ReportDistribution = distribution
+		if distribution.Attachments == nil {
+			updated.ReportDistribution.Attachments = appSettings.ReportDistribution.Attachments
+		}
+	}
+	if newSettings.Jobs != nil {
+		updated.Jobs = newSettings.Jobs
+	}
+	updated.Anomalies = newSettings.Anomalies
+
+	if err := validateSettings(updated); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "Invalid configuration", FieldError{Field: "settings", Message: err.Error()})
 		return
 	}
 
-	// Update settings
-	appSettings.DatabasePath = newSettings.DatabasePath
+	// Server settings take effect on the next start
+	appSettings = updated
 
 	// Save settings
 	saveSettings()
+	appScheduler.Reload(appSettings.Jobs)
 
 	// Return the updated settings
-	json.NewEncoder(w).Encode(appSettings)
+	getSettings(w, r)
 }
 
 // reloadExcel reloads data from Excel
@@ -762,7 +1977,7 @@ func reloadExcel(w http.ResponseWriter, r *http.Request) {
 
 	err := loadFromExcel()
 	if err != nil {
-		http.Error(w, "Failed to reload from Excel: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Failed to reload from Excel: "+err.Error())
 		return
 	}
 
@@ -784,7 +1999,7 @@ func saveExcelAPI(w http.ResponseWriter, r *http.Request) {
 
 	err := saveToExcel()
 	if err != nil {
-		http.Error(w, "Failed to save to Excel: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Failed to save to Excel: "+err.Error())
 		return
 	}
 
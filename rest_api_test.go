@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupMeasurementAPITest uses the submission test data with a temporary database
+func setupMeasurementAPITest(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.DatabasePath = t.TempDir()
+	t.Cleanup(discardPendingChanges)
+}
+
+// postBatch sends a measurement batch and decodes the response
+func postBatch(t *testing.T, body string) (int, BatchResponse) {
+	rec := httptest.NewRecorder()
+	createMeasurementBatch(rec, httptest.NewRequest("POST", "/api/measurements/batch?confirm=true", strings.NewReader(body)))
+	var response BatchResponse
+	if rec.Code != http.StatusBadRequest && rec.Code != http.StatusInternalServerError {
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, response
+}
+
+func TestMeasurementBatch(t *testing.T) {
+	setupMeasurementAPITest(t)
+
+	status, response := postBatch(t, `[
+		{"kpi_id": 1, "period": "2026-03-01T00:00:00Z", "metric_value": 120, "notes": "March"},
+		{"kpi_id": 2, "period": "2026-02-01T00:00:00Z", "metric_value": 95},
+		{"kpi_id": 1, "period": "2026-04-01T00:00:00Z", "metric_value": 130}
+	]`)
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %+v", status, response)
+	}
+	if !response.Applied || response.Created != 2 || response.Updated != 1 || response.Failed != 0 || response.Error != nil {
+		t.Errorf("response %+v, want 2 created and 1 updated", response)
+	}
+	for i, want := range []BatchItemResult{
+		{Index: 0, ID: 2, KPIID: 1, Period: "2026-03", Action: "created"},
+		{Index: 1, ID: 1, KPIID: 2, Period: "2026-02", Action: "updated"},
+		{Index: 2, ID: 3, KPIID: 1, Period: "2026-04", Action: "created"},
+	} {
+		if !reflect.DeepEqual(response.Results[i], want) {
+			t.Errorf("result %d: %+v, want %+v", i, response.Results[i], want)
+		}
+	}
+	if m := getExistingMeasurement(2, month(2)); m == nil || m.MetricValue != 95 {
+		t.Errorf("audit %+v, want 95", m)
+	}
+	if _, err := os.Stat(getExcelDBPath()); err != nil {
+		t.Errorf("batch not saved: %v", err)
+	}
+}
+
+func TestMeasurementBatchAllOrNothing(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		body   string
+		errors map[int]string
+		fields []string
+	}{
+		{
+			name:   "unknown KPI",
+			body:   `[{"kpi_id": 1, "period": "2026-03-01T00:00:00Z", "metric_value": 120}, {"kpi_id": 9, "period": "2026-03-01T00:00:00Z", "metric_value": 1}]`,
+			errors: map[int]string{1: "kpi_id: KPI 9 not found"},
+			fields: []string{"[1].kpi_id"},
+		},
+		{
+			name:   "missing period and negative value",
+			body:   `[{"kpi_id": 1, "metric_value": -5}, {"kpi_id": 2, "period": "2026-03-01T00:00:00Z", "metric_value": -1}]`,
+			errors: map[int]string{0: "period", 1: "metric_value: Value cannot be negative"},
+			fields: []string{"[0].period", "[0].metric_value", "[1].metric_value"},
+		},
+		{
+			name:   "duplicate entries",
+			body:   `[{"kpi_id": 1, "period": "2026-03-01T00:00:00Z", "metric_value": 120}, {"kpi_id": 1, "period": "2026-03-15T00:00:00Z", "metric_value": 125}]`,
+			errors: map[int]string{1: "period: Duplicate of entry 0"},
+			fields: []string{"[1].period"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMeasurementAPITest(t)
+			before := append([]Measurement(nil), measurements...)
+
+			status, response := postBatch(t, tt.body)
+			if status != http.StatusUnprocessableEntity || response.Applied || response.Failed != len(tt.errors) {
+				t.Fatalf("status %d, response %+v, want %d failed entries", status, response, len(tt.errors))
+			}
+			for i, result := range response.Results {
+				if !strings.HasPrefix(result.Error, tt.errors[i]) || (tt.errors[i] == "") != (result.Error == "") || result.Action != "" {
+					t.Errorf("entry %d: %+v, want error %q", i, result, tt.errors[i])
+				}
+			}
+			var fields []string
+			for _, detail := range response.Error.Details {
+				fields = append(fields, detail.Field)
+			}
+			if response.Error.Code != errorCodes[http.StatusUnprocessableEntity] || !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("error %+v, want fields %v", response.Error, tt.fields)
+			}
+			if !reflect.DeepEqual(measurements, before) {
+				t.Errorf("measurements %+v, want none changed", measurements)
+			}
+		})
+	}
+}
+
+func TestMeasurementBatchRequestErrors(t *testing.T) {
+	setupMeasurementAPITest(t)
+	for _, body := range []string{`[]`, `{"kpi_id": 1}`, `[{"kpi_id": "one"}]`, ``} {
+		if status, _ := postBatch(t, body); status != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", body, status)
+		}
+	}
+
+	failExcelSaves(t)
+	status, _ := postBatch(t, `[{"kpi_id": 1, "period": "2026-03-01T00:00:00Z", "metric_value": 120}]`)
+	if status != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500 when the save fails", status)
+	}
+	if len(measurements) != 1 || getExistingMeasurement(1, month(3)) != nil {
+		t.Errorf("measurements %+v, want the batch rolled back", measurements)
+	}
+}
+
+// serveAPI sends a request through the API router
+func serveAPI(method, url, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	newAPIRouter().ServeHTTP(rec, r)
+	return rec
+}
+
+func TestCreateMeasurementConflict(t *testing.T) {
+	setupMeasurementAPITest(t)
+	audit := `{"kpi_id": 2, "period": "2026-02-01T00:00:00Z", "metric_value": 95}`
+
+	rec := serveAPI("POST", "/api/measurements", audit, nil)
+	var conflict struct {
+		Error    APIError    `json:"error"`
+		Existing Measurement `json:"existing"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&conflict); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusConflict || conflict.Error.Code != errorCodes[http.StatusConflict] || conflict.Existing.ID != 1 || conflict.Existing.MetricValue != 90 {
+		t.Errorf("status %d, %+v, want 409 with the existing measurement", rec.Code, conflict)
+	}
+	if rec.Header().Get("ETag") != measurementETag(measurements[0]) {
+		t.Errorf("ETag %q, want the tag of the existing measurement", rec.Header().Get("ETag"))
+	}
+	if measurements[0].MetricValue != 90 {
+		t.Errorf("measurement %+v changed by a conflicting POST", measurements[0])
+	}
+
+	for _, tt := range []struct {
+		url, body string
+		status    int
+		id        int
+		value     float64
+	}{
+		{"/api/measurements?upsert=true", audit, http.StatusOK, 1, 95},
+		{"/api/measurements", `{"kpi_id": 1, "period": "2026-03-01T00:00:00Z", "metric_value": 120}`, http.StatusCreated, 2, 120},
+		{"/api/measurements?upsert=true", `{"kpi_id": 1, "period": "2026-04-01T00:00:00Z", "metric_value": 110}`, http.StatusCreated, 3, 110},
+	} {
+		rec := serveAPI("POST", tt.url, tt.body, nil)
+		var m Measurement
+		json.NewDecoder(rec.Body).Decode(&m)
+		if rec.Code != tt.status || m.ID != tt.id || m.MetricValue != tt.value || rec.Header().Get("ETag") != measurementETag(m) {
+			t.Errorf("%s %s: status %d, %+v, want %d with measurement %d = %v", tt.url, tt.body, rec.Code, m, tt.status, tt.id, tt.value)
+		}
+	}
+	if len(measurements) != 3 {
+		t.Errorf("%d measurements, want 3", len(measurements))
+	}
+}
+
+func TestUpdateMeasurementIfMatch(t *testing.T) {
+	setupMeasurementAPITest(t)
+	rec := serveAPI("GET", "/api/measurements/1", "", nil)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, ETag %q", rec.Code, etag)
+	}
+
+	rec = serveAPI("PUT", "/api/measurements/1", `{"metric_value": 92, "notes": "checked"}`, map[string]string{"If-Match": etag})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("status %d, ETag %q, want a new tag", rec.Code, rec.Header().Get("ETag"))
+	}
+	current := rec.Header().Get("ETag")
+
+	// The old tag is stale now and nothing changes
+	rec = serveAPI("PUT", "/api/measurements/1", `{"metric_value": 99}`, map[string]string{"If-Match": etag})
+	var stale struct {
+		Error   APIError    `json:"error"`
+		Current Measurement `json:"current"`
+	}
+	json.NewDecoder(rec.Body).Decode(&stale)
+	if rec.Code != http.StatusPreconditionFailed || stale.Current.MetricValue != 92 || rec.Header().Get("ETag") != current {
+		t.Errorf("status %d, %+v, want 412 with the current measurement", rec.Code, stale)
+	}
+	if measurements[0].MetricValue != 92 || measurements[0].Notes != "checked" {
+		t.Errorf("measurement %+v, want the stale update rejected", measurements[0])
+	}
+
+	for _, tt := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{`"0000000000000000", ` + current, http.StatusOK},
+		{"*", http.StatusOK},
+		{"", http.StatusOK},
+		{`"0000000000000000"`, http.StatusPreconditionFailed},
+	} {
+		rec := serveAPI("PUT", "/api/measurements/1", `{"metric_value": 93}`, map[string]string{"If-Match": tt.ifMatch})
+		if rec.Code != tt.status {
+			t.Errorf("If-Match %q: status %d, want %d", tt.ifMatch, rec.Code, tt.status)
+		}
+	}
+
+	for _, tt := range []struct {
+		method, url, body string
+		status            int
+	}{
+		{"GET", "/api/measurements/99", "", http.StatusNotFound},
+		{"GET", "/api/measurements/x", "", http.StatusBadRequest},
+		{"PUT", "/api/measurements/99", `{"metric_value": 1}`, http.StatusNotFound},
+		{"PUT", "/api/measurements/1", `{"metric_value": -1}`, http.StatusUnprocessableEntity},
+		{"PUT", "/api/measurements/1", `{"metric_value": `, http.StatusBadRequest},
+	} {
+		if rec := serveAPI(tt.method, tt.url, tt.body, nil); rec.Code != tt.status {
+			t.Errorf("%s %s %s: status %d, want %d", tt.method, tt.url, tt.body, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestPatchMeasurement(t *testing.T) {
+	setupMeasurementAPITest(t)
+	measurements[0].Notes = "first count"
+
+	for _, tt := range []struct {
+		body   string
+		status int
+		value  float64
+		notes  string
+	}{
+		{`{"notes": "recounted"}`, http.StatusOK, 90, "recounted"},
+		{`{"metric_value": 97}`, http.StatusOK, 97, "recounted"},
+		{`{"metric_value": 0, "notes": ""}`, http.StatusOK, 0, ""},
+		{`{}`, http.StatusUnprocessableEntity, 0, ""},
+		{`{"metric_value": -3}`, http.StatusUnprocessableEntity, 0, ""},
+		{`{"notes": 5}`, http.StatusBadRequest, 0, ""},
+	} {
+		rec := serveAPI("PATCH", "/api/measurements/1", tt.body, nil)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.body, rec.Code, tt.status, rec.Body)
+		}
+		if m := measurements[0]; m.MetricValue != tt.value || m.Notes != tt.notes {
+			t.Errorf("%s: measurement %+v, want %v %q", tt.body, m, tt.value, tt.notes)
+		}
+	}
+
+	rec := serveAPI("PATCH", "/api/measurements/1", `{"notes": "late"}`, map[string]string{"If-Match": `"stale"`})
+	if rec.Code != http.StatusPreconditionFailed || measurements[0].Notes != "" {
+		t.Errorf("status %d, notes %q, want 412 and no change", rec.Code, measurements[0].Notes)
+	}
+}
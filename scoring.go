@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Missing-data policies for overall scores
+const (
+	// PolicyExclude drops unmeasured KPIs and renormalises over the measured weight
+	PolicyExclude = "exclude"
+	// PolicyZero counts unmeasured KPIs as 0% achievement
+	PolicyZero = "zero"
+	// PolicyCarryForward reuses the latest earlier measurement of an unmeasured KPI
+	PolicyCarryForward = "carry_forward"
+	// PolicyIncomplete renormalises like PolicyExclude but marks the period incomplete
+	PolicyIncomplete = "incomplete"
+)
+
+// scoringPolicies lists the valid missing-data policies with a short description
+var scoringPolicies = []struct {
+	Name        string
+	Description string
+}{
+	{PolicyExclude, "Exclude unmeasured KPIs and renormalise"},
+	{PolicyZero, "Treat unmeasured KPIs as zero"},
+	{PolicyCarryForward, "Carry forward the last measured value"},
+	{PolicyIncomplete, "Mark the period incomplete"},
+}
+
+// ScoreResult holds an overall score together with how much of it was measured
+type ScoreResult struct {
+	Score        float64 `json:"score"`
+	Coverage     float64 `json:"coverage"` // Percentage of KPI weight with a measurement
+	Policy       string  `json:"policy"`
+	Incomplete   bool    `json:"incomplete"`
+	MeasuredKPIs int     `json:"measured_kpis"`
+	CarriedKPIs  int     `json:"carried_kpis,omitempty"`
+	TotalKPIs    int     `json:"total_kpis"`
+}
+
+// HasData reports whether any value (measured or carried forward) contributed to the score
+func (s ScoreResult) HasData() bool {
+	return s.MeasuredKPIs > 0 || s.CarriedKPIs > 0
+}
+
+// String formats the score with its coverage, e.g. "83.40% (coverage 60%, incomplete)"
+func (s ScoreResult) String() string {
+	if !s.HasData() {
+		return "- (no data)"
+	}
+
+	result := fmt.Sprintf("%.2f%% (coverage %.0f%%", s.Score, s.Coverage)
+	if s.CarriedKPIs > 0 {
+		result += fmt.Sprintf(", %d carried forward", s.CarriedKPIs)
+	}
+	if s.Incomplete {
+		result += ", incomplete"
+	}
+	return result + ")"
+}
+
+// isValidScoringPolicy checks if a policy name is known
+func isValidScoringPolicy(policy string) bool {
+	for _, p := range scoringPolicies {
+		if p.Name == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// getScoringPolicy returns the missing-data policy for a role, falling back to the global policy
+func getScoringPolicy(roleID int) string {
+	if policy, ok := appSettings.RoleScoringPolicies[roleID]; ok && isValidScoringPolicy(policy) {
+		return policy
+	}
+	if isValidScoringPolicy(appSettings.ScoringPolicy) {
+		return appSettings.ScoringPolicy
+	}
+	return PolicyExclude
+}
+
+// getLatestMeasurementBefore returns the most recent measurement of a KPI before the period
+func getLatestMeasurementBefore(kpiID int, period time.Time) *Measurement {
+	return latestMeasurementBeforeIn(measurements, kpiID, period)
+}
+
+// latestMeasurementBeforeIn returns the most recent measurement of a KPI before the
+// period from a set of measurements
+func latestMeasurementBeforeIn(data []Measurement, kpiID int, period time.Time) *Measurement {
+	var latest *Measurement
+	for i, m := range data {
+		if m.KPIID != kpiID || !m.Period.Before(period) {
+			continue
+		}
+		if latest == nil || m.Period.After(latest.Period) {
+			latest = &data[i]
+		}
+	}
+	return latest
+}
+
+// calculateScoreResult calculates the overall score for a set of KPIs applying the
+// missing-data policy of their role
+func calculateScoreResult(kpis []KPI, period time.Time) ScoreResult {
+	return calculateScoreResultFrom(kpis, period, measurements)
+}
+
+// calculateScoreResultFrom calculates the overall score from a given set of
+// measurements, e.g. a what-if scenario that is not saved
+func calculateScoreResultFrom(kpis []KPI, period time.Time, data []Measurement) ScoreResult {
+	policy := PolicyExclude
+	if len(kpis) > 0 {
+		policy = getScoringPolicy(kpis[0].RoleID)
+	}
+
+	result := ScoreResult{
+		Policy:    policy,
+		TotalKPIs: len(kpis),
+	}
+
+	var totalScore float64
+	var scoredWeight float64
+	var measuredWeight float64
+	var totalWeight float64
+
+	for _, kpi := range kpis {
+		// Derived KPIs without a target are tracked but not scored
+		if !hasNumericTarget(kpi) {
+			result.TotalKPIs--
+			continue
+		}
+		totalWeight += kpi.Weight
+
+		measurement := findMeasurementIn(data, kpi.ID, period)
+		if measurement != nil {
+			result.MeasuredKPIs++
+			measuredWeight += kpi.Weight
+		} else {
+			switch policy {
+			case PolicyZero:
+				// Unmeasured KPIs count with their full weight and no achievement
+				scoredWeight += kpi.Weight
+				continue
+			case PolicyCarryForward:
+				measurement = latestMeasurementBeforeIn(data, kpi.ID, period)
+				if measurement == nil {
+					continue
+				}
+				result.CarriedKPIs++
+			default:
+				continue
+			}
+		}
+
+		achievementPct := calculateAchievement(kpi, measurement)
+		totalScore += achievementPct * kpi.Weight / 100
+		scoredWeight += kpi.Weight
+	}
+
+	if totalWeight > 0 {
+		result.Coverage = (measuredWeight / totalWeight) * 100
+	}
+
+	if !result.HasData() {
+		return result
+	}
+
+	if scoredWeight > 0 {
+		// Normalize to 100%
+		result.Score = (totalScore / scoredWeight) * 100
+	}
+
+	result.Incomplete = policy == PolicyIncomplete && result.MeasuredKPIs < result.TotalKPIs
+
+	return result
+}
+
+// handleScoringPolicy manages the missing-data policy settings
+func handleScoringPolicy(scanner *bufio.Scanner) {
+	fmt.Println("\n=== Missing-Data Scoring Policy ===")
+	fmt.Printf("Global policy: %s\n", getScoringPolicy(0))
+	for _, role := range roles {
+		if policy, ok := appSettings.RoleScoringPolicies[role.ID]; ok {
+			fmt.Printf("  %s: %s\n", role.Name, policy)
+		}
+	}
+
+	fmt.Println("\n1. Change Global Policy")
+	fmt.Println("2. Change Policy for a Role")
+	fmt.Println("3. Clear Policy for a Role")
+	fmt.Println("0. Back")
+
+	fmt.Print("\nEnter your choice: ")
+	scanner.Scan()
+	choice := scanner.Text()
+
+	switch choice {
+	case "1":
+		policy := selectScoringPolicy(scanner)
+		if policy == "" {
+			return
+		}
+		appSettings.ScoringPolicy = policy
+	case "2":
+		role := selectRole(scanner)
+		if role == nil {
+			return
+		}
+		policy := selectScoringPolicy(scanner)
+		if policy == "" {
+			return
+		}
+		if appSettings.RoleScoringPolicies == nil {
+			appSettings.RoleScoringPolicies = make(map[int]string)
+		}
+		appSettings.RoleScoringPolicies[role.ID] = policy
+	case "3":
+		role := selectRole(scanner)
+		if role == nil {
+			return
+		}
+		delete(appSettings.RoleScoringPolicies, role.ID)
+	case "0":
+		return
+	default:
+		fmt.Println("Invalid choice.")
+		return
+	}
+
+	saveSettings()
+	fmt.Println("Scoring policy updated.")
+}
+
+// selectScoringPolicy asks the user to choose a missing-data policy
+func selectScoringPolicy(scanner *bufio.Scanner) string {
+	fmt.Println("\nSelect policy:")
+	for i, p := range scoringPolicies {
+		fmt.Printf("%d. %s (%s)\n", i+1, p.Description, p.Name)
+	}
+
+	fmt.Print("\nEnter your choice (0 to cancel): ")
+	scanner.Scan()
+	idx, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil || idx < 0 || idx > len(scoringPolicies) {
+		fmt.Println("Invalid choice.")
+		return ""
+	}
+	if idx == 0 {
+		return ""
+	}
+
+	return scoringPolicies[idx-1].Name
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestScoringPolicies(t *testing.T) {
+	setupSubmissionTest(t)
+	addRevenue(80, month(3))
+	roleKPIs := getKPIsByRoleID(1)
+
+	// Revenue is measured in March, Audit only in February
+	for _, tt := range []struct {
+		policy     string
+		score      float64
+		carried    int
+		incomplete bool
+		text       string
+	}{
+		{PolicyExclude, 80, 0, false, "80.00% (coverage 50%)"},
+		{PolicyZero, 40, 0, false, "40.00% (coverage 50%)"},
+		{PolicyCarryForward, 85, 1, false, "85.00% (coverage 50%, 1 carried forward)"},
+		{PolicyIncomplete, 80, 0, true, "80.00% (coverage 50%, incomplete)"},
+	} {
+		appSettings.ScoringPolicy = tt.policy
+		result := calculateScoreResult(roleKPIs, month(3))
+		if result.Policy != tt.policy || result.Score != tt.score || result.CarriedKPIs != tt.carried || result.Incomplete != tt.incomplete {
+			t.Errorf("%s: %+v, want score %v carried %d incomplete %v", tt.policy, result, tt.score, tt.carried, tt.incomplete)
+		}
+		if result.Coverage != 50 || result.MeasuredKPIs != 1 || result.TotalKPIs != 2 {
+			t.Errorf("%s: %+v, want 1 of 2 KPIs and 50%% coverage", tt.policy, result)
+		}
+		if text := result.String(); text != tt.text {
+			t.Errorf("%s: %q, want %q", tt.policy, text, tt.text)
+		}
+
+		// A month without any data has no score, whatever the policy
+		if result := calculateScoreResult(roleKPIs, month(1)); result.HasData() || result.Score != 0 || result.String() != "- (no data)" {
+			t.Errorf("%s: January %+v, want no data", tt.policy, result)
+		}
+	}
+}
+
+func TestCarryForwardUsesLatestEarlierValue(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.ScoringPolicy = PolicyCarryForward
+	addRevenue(60, month(1))
+	addRevenue(100, month(3))
+	addRevenue(20, month(6))
+
+	// Audit is carried from February, Revenue from March and nothing from June
+	result := calculateScoreResult(getKPIsByRoleID(1), month(5))
+	if result.Score != 95 || result.CarriedKPIs != 2 || result.MeasuredKPIs != 0 || result.Coverage != 0 {
+		t.Errorf("result %+v, want 95%% from two carried values", result)
+	}
+	if !result.HasData() {
+		t.Error("carried values should count as data")
+	}
+}
+
+func TestFullyMeasuredScoreIgnoresPolicy(t *testing.T) {
+	setupSubmissionTest(t)
+	addRevenue(50, month(2))
+
+	for _, policy := range []string{PolicyExclude, PolicyZero, PolicyCarryForward, PolicyIncomplete} {
+		appSettings.ScoringPolicy = policy
+		result := calculateScoreResult(getKPIsByRoleID(1), month(2))
+		if result.Score != 70 || result.Coverage != 100 || result.Incomplete || result.CarriedKPIs != 0 {
+			t.Errorf("%s: %+v, want 70%% at full coverage", policy, result)
+		}
+	}
+}
+
+func TestGetScoringPolicy(t *testing.T) {
+	setupSubmissionTest(t)
+	for _, tt := range []struct {
+		global string
+		role   map[int]string
+		want   string
+	}{
+		{"", nil, PolicyExclude},
+		{PolicyZero, nil, PolicyZero},
+		{"average", nil, PolicyExclude},
+		{PolicyZero, map[int]string{1: PolicyCarryForward}, PolicyCarryForward},
+		{PolicyZero, map[int]string{1: "average"}, PolicyZero},
+		{PolicyZero, map[int]string{2: PolicyIncomplete}, PolicyZero},
+	} {
+		appSettings.ScoringPolicy = tt.global
+		appSettings.RoleScoringPolicies = tt.role
+		if policy := getScoringPolicy(1); policy != tt.want {
+			t.Errorf("global %q role %v: %q, want %q", tt.global, tt.role, policy, tt.want)
+		}
+	}
+
+	// The role's policy is used for its score
+	appSettings.ScoringPolicy = PolicyExclude
+	appSettings.RoleScoringPolicies = map[int]string{1: PolicyZero}
+	addRevenue(80, month(3))
+	if result := calculateScoreResult(getKPIsByRoleID(1), month(3)); result.Policy != PolicyZero || result.Score != 40 {
+		t.Errorf("result %+v, want the zero policy of the role", result)
+	}
+}
+
+func TestValidateScoringPolicies(t *testing.T) {
+	settings := defaultSettings()
+	settings.ScoringPolicy = "average"
+	settings.RoleScoringPolicies = map[int]string{3: PolicyZero, 4: "median"}
+
+	errs := validateSettingsUpdate(settings)
+	fields := map[string]bool{}
+	for _, err := range errs {
+		fields[err.Field] = true
+	}
+	if len(errs) != 2 || !fields["scoring_policy"] || !fields["role_scoring_policies.4"] {
+		t.Errorf("errors %v, want scoring_policy and role_scoring_policies.4", errs)
+	}
+}
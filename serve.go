@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ServerConfig holds the HTTP server settings
+type ServerConfig struct {
+	Addr            string        `json:"addr"`
+	TLSCertFile     string        `json:"tls_cert_file,omitempty"`
+	TLSKeyFile      string        `json:"tls_key_file,omitempty"`
+	ReadTimeout     time.Duration `json:"read_timeout"`
+	WriteTimeout    time.Duration `json:"write_timeout"`
+	IdleTimeout     time.Duration `json:"idle_timeout"`
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+}
+
+// defaultServerConfig returns the server settings used when nothing else is configured
+func defaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Addr:            ":8080",
+		ReadTimeout:     15 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		IdleTimeout:     60 * time.Second,
+		ShutdownTimeout: 15 * time.Second,
+	}
+}
+
+// newHTTPServer creates an HTTP server for the REST API
+func newHTTPServer(config ServerConfig) *http.Server {
+	server := &http.Server{
+		Addr:         config.Addr,
+		Handler:      newRouter(),
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		IdleTimeout:  config.IdleTimeout,
+	}
+	// End open event streams so they do not hold up a graceful shutdown
+	server.RegisterOnShutdown(appEvents.CloseAll)
+	return server
+}
+
+// serve serves HTTP or, when a certificate is configured, HTTPS on the listener
+func serve(server *http.Server, listener net.Listener, config ServerConfig) error {
+	if config.TLSCertFile != "" {
+		return server.ServeTLS(listener, config.TLSCertFile, config.TLSKeyFile)
+	}
+	return server.Serve(listener)
+}
+
+// listenURL returns a printable URL for the configured listen address
+func listenURL(config ServerConfig) string {
+	scheme := "http://"
+	if config.TLSCertFile != "" {
+		scheme = "https://"
+	}
+	if strings.HasPrefix(config.Addr, ":") {
+		return scheme + "localhost" + config.Addr
+	}
+	return scheme + config.Addr
+}
+
+// runServer runs the REST API until SIGINT or SIGTERM. In-flight requests are
+// drained with http.Server.Shutdown and running jobs are waited for before the data
+// is saved to Excel one last time.
+// An error is returned if the address cannot be bound or the final save fails.
+func runServer(config ServerConfig) error {
+	server := newHTTPServer(config)
+
+	// Bind first so a busy port is reported instead of silently ignored
+	listener, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", config.Addr, err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(server, listener, config)
+	}()
+	fmt.Printf("REST API server listening on %s\n", listenURL(config))
+	startWebhookDispatcher()
+	appScheduler.Start()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("server error: %v", err)
+		}
+	case sig := <-signals:
+		fmt.Printf("Received %v, shutting down...\n", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			fmt.Printf("Warning: requests still running after %v: %v\n", config.ShutdownTimeout, err)
+		}
+	}
+
+	// Let running jobs finish before the final save
+	appScheduler.Stop()
+
+	fmt.Println("Saving data before exit...")
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	if err := saveToExcel(); err != nil {
+		return fmt.Errorf("failed to save data to Excel: %v", err)
+	}
+
+	fmt.Println("Server stopped.")
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServeCommandPortInUse(t *testing.T) {
+	setupSubmissionTest(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	if code := runServeCommand([]string{"--addr", listener.Addr().String()}); code != exitError {
+		t.Errorf("exit code %d, want %d for a busy port", code, exitError)
+	}
+	for _, args := range [][]string{{"--read-timeout", "soon"}, {"--addr"}, {"now"}} {
+		if code := runServeCommand(args); code != exitUsage {
+			t.Errorf("%v: exit code %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+func TestServerConfigFromSettings(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.Server.Addr = "127.0.0.1:9090"
+	appSettings.Server.ReadTimeout = "5s"
+	appSettings.Server.WriteTimeout = "forever"
+	appSettings.Server.ShutdownTimeout = "1m"
+
+	config := serverConfigFromSettings()
+	defaults := defaultServerConfig()
+	if config.Addr != "127.0.0.1:9090" || config.ReadTimeout != 5*time.Second || config.ShutdownTimeout != time.Minute {
+		t.Errorf("config %+v, want the configured address and timeouts", config)
+	}
+	if config.WriteTimeout != defaults.WriteTimeout || config.IdleTimeout != defaults.IdleTimeout {
+		t.Errorf("config %+v, want the default write and idle timeouts", config)
+	}
+
+	server := newHTTPServer(config)
+	if server.ReadTimeout != config.ReadTimeout || server.WriteTimeout != config.WriteTimeout || server.IdleTimeout != config.IdleTimeout {
+		t.Errorf("server timeouts %v %v %v, want %+v", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout, config)
+	}
+}
+
+func TestListenURL(t *testing.T) {
+	for _, tt := range []struct {
+		config ServerConfig
+		want   string
+	}{
+		{ServerConfig{Addr: ":8080"}, "http://localhost:8080"},
+		{ServerConfig{Addr: "0.0.0.0:80"}, "http://0.0.0.0:80"},
+		{ServerConfig{Addr: ":8443", TLSCertFile: "cert.pem"}, "https://localhost:8443"},
+	} {
+		if url := listenURL(tt.config); url != tt.want {
+			t.Errorf("%+v: %q, want %q", tt.config, url, tt.want)
+		}
+	}
+}
+
+func TestRunServerShutsDownOnSignal(t *testing.T) {
+	setupSubmissionTest(t)
+	appSettings.DatabasePath = t.TempDir()
+
+	// Keep SIGTERM from ending the test binary if it arrives before runServer listens for it
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGTERM)
+	defer signal.Stop(guard)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	config := defaultServerConfig()
+	config.Addr = addr
+	done := make(chan error, 1)
+	go func() { done <- runServer(config) }()
+
+	url := "http://" + addr + "/api/roles"
+	for i := 0; ; i++ {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 50 {
+			t.Fatalf("server not reachable: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("runServer: %v", err)
+			}
+			if _, err := os.Stat(getExcelDBPath()); err != nil {
+				t.Errorf("data not saved on shutdown: %v", err)
+			}
+			if _, err := http.Get(url); err == nil {
+				t.Error("server still accepting requests after shutdown")
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+			// runServer was not listening for signals yet
+		case <-deadline:
+			t.Fatal("server did not stop")
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -115,44 +114,9 @@ func getRoleKPIsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(roleKPIs)
 }
 
+// createMeasurementHandler uses the same create/conflict rules as the REST API
 func createMeasurementHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	var measurement Measurement
-	err := json.NewDecoder(r.Body).Decode(&measurement)
-	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Generate new ID
-	newID := 1
-	if len(measurements) > 0 {
-		for _, m := range measurements {
-			if m.ID >= newID {
-				newID = m.ID + 1
-			}
-		}
-	}
-
-	measurement.ID = newID
-	measurement.CreatedAt = time.Now()
-
-	// Check if measurement already exists
-	existing := getExistingMeasurement(measurement.KPIID, measurement.Period)
-	if existing != nil {
-		existing.MetricValue = measurement.MetricValue
-		existing.Unit = measurement.Unit
-		existing.Notes = measurement.Notes
-		json.NewEncoder(w).Encode(existing)
-	} else {
-		// Add new measurement
-		measurements = append(measurements, measurement)
-		json.NewEncoder(w).Encode(measurement)
-	}
-
-	// Save to Excel
-	saveToExcel()
+	createMeasurement(w, r)
 }
 
 // More handlers would be implemented here...
@@ -12,6 +12,7 @@ func handleSettings(scanner *bufio.Scanner) {
 	fmt.Println("1. Change Database Path")
 	fmt.Println("2. Reload Data from Excel")
 	fmt.Println("3. Force Save Data to Excel")
+	fmt.Println("4. Missing-Data Scoring Policy")
 	fmt.Println("0. Back to Main Menu")
 
 	fmt.Print("\nEnter your choice: ")
@@ -25,6 +26,8 @@ func handleSettings(scanner *bufio.Scanner) {
 		reloadFromExcel(scanner)
 	case "3":
 		forceSaveToExcel(scanner)
+	case "4":
+		handleScoringPolicy(scanner)
 	case "0":
 		return
 	default:
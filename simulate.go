@@ -0,0 +1,604 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds of a solved value
+const (
+	SolveMinimum = "minimum" // Higher is better, at least this value is needed
+	SolveMaximum = "maximum" // Lower is better, at most this value is allowed
+)
+
+// solveIterations is the number of bisection steps when solving for a value
+const solveIterations = 100
+
+// SimulationValue is a hypothetical measurement value of a KPI without a formula.
+// Derived KPIs referring to it are recomputed.
+type SimulationValue struct {
+	KPIID  int     `json:"kpi_id"`
+	Period string  `json:"period,omitempty"` // YYYY-MM, every period of the range when empty
+	Value  float64 `json:"value"`
+}
+
+// SimulationKPIChange changes the weight or target of a KPI for the simulation
+type SimulationKPIChange struct {
+	KPIID       int      `json:"kpi_id"`
+	Weight      *float64 `json:"weight,omitempty"`
+	TargetValue *float64 `json:"target_value,omitempty"`
+}
+
+// SimulationSolve asks for the value a KPI needs to reach a score
+type SimulationSolve struct {
+	KPIID       int      `json:"kpi_id"`
+	TargetScore float64  `json:"target_score"`
+	Periods     []string `json:"periods,omitempty"` // YYYY-MM, by default the periods of the range without a value for the KPI
+}
+
+// SimulationRequest describes a what-if scenario for a role. Nothing is saved.
+type SimulationRequest struct {
+	RoleID int                   `json:"role_id"`
+	Start  string                `json:"start"`         // YYYY-MM
+	End    string                `json:"end,omitempty"` // YYYY-MM, defaults to start
+	Values []SimulationValue     `json:"values,omitempty"`
+	KPIs   []SimulationKPIChange `json:"kpis,omitempty"`
+	Solve  *SimulationSolve      `json:"solve,omitempty"`
+}
+
+// SimulatedKPI compares a KPI with and without the scenario over the range
+type SimulatedKPI struct {
+	KPIID               int     `json:"kpi_id"`
+	Name                string  `json:"name"`
+	Operator            string  `json:"operator"`
+	Weight              float64 `json:"weight"`
+	TargetValue         float64 `json:"target_value"`
+	Value               float64 `json:"value"` // Average over the periods with a value
+	Achievement         float64 `json:"achievement"`
+	BaselineAchievement float64 `json:"baseline_achievement"`
+	Measured            bool    `json:"measured"`
+	Hypothetical        bool    `json:"hypothetical"` // A scenario value was used
+}
+
+// SimulatedPeriod compares the score of one month with and without the scenario
+type SimulatedPeriod struct {
+	Period        string  `json:"period"`
+	BaselineScore float64 `json:"baseline_score"`
+	Score         float64 `json:"score"`
+	Coverage      float64 `json:"coverage"`
+}
+
+// SimulationSolution is the value a KPI needs in the solved periods
+type SimulationSolution struct {
+	KPIID         int      `json:"kpi_id"`
+	TargetScore   float64  `json:"target_score"`
+	Periods       []string `json:"periods"`
+	Achievable    bool     `json:"achievable"`
+	RequiredValue *float64 `json:"required_value,omitempty"`
+	Bound         string   `json:"bound,omitempty"` // minimum or maximum
+	Score         float64  `json:"score"`           // Score with the required value, or the best possible score
+	Note          string   `json:"note,omitempty"`
+}
+
+// SimulationResult holds the scores of a scenario next to the saved data
+type SimulationResult struct {
+	Role          Role                `json:"role"`
+	Period        PeriodRange         `json:"period"`
+	BaselineScore float64             `json:"baseline_score"`
+	Score         float64             `json:"score"`
+	ScoreDelta    float64             `json:"score_delta"`
+	Periods       []SimulatedPeriod   `json:"periods"`
+	KPIs          []SimulatedKPI      `json:"kpis"`
+	Solution      *SimulationSolution `json:"solution,omitempty"`
+}
+
+// setScenarioValue sets the value of a KPI for a period in a scenario's measurements
+func setScenarioValue(data []Measurement, kpi KPI, period time.Time, value float64) []Measurement {
+	if m := findMeasurementIn(data, kpi.ID, period); m != nil {
+		m.MetricValue = value
+		return data
+	}
+	return append(data, Measurement{KPIID: kpi.ID, MetricValue: value, Unit: kpi.Unit, Period: period})
+}
+
+// recomputeScenarioDependents recomputes the derived KPIs that refer to a KPI in a
+// scenario's measurements after its value for a period changed, like
+// recomputeDependents does for the saved data
+func recomputeScenarioDependents(data []Measurement, kpiID int, period time.Time) []Measurement {
+	for _, dependent := range getKPIDependents(kpiID) {
+		stored, ok := storedFormulaInputs(dependent, findMeasurementIn(data, dependent.ID, period))
+		if !ok {
+			continue
+		}
+		value, err := computeDerivedValueFrom(dependent, stored, period, data)
+		if err != nil {
+			continue
+		}
+		data = setScenarioValue(data, dependent, period, value)
+		data = recomputeScenarioDependents(data, dependent.ID, period)
+	}
+	return data
+}
+
+// parseSimulationPeriod parses a period that must lie within the simulated range
+func parseSimulationPeriod(value string, pr PeriodRange) (time.Time, error) {
+	period, err := parsePeriodString(value)
+	if err != nil {
+		return period, err
+	}
+	if period.Before(pr.Start) || period.After(pr.End) {
+		return period, fmt.Errorf("period %s is outside %s", value, pr.Label())
+	}
+	return period, nil
+}
+
+// simulate runs a what-if scenario through the scoring without saving anything
+func simulate(req SimulationRequest) (SimulationResult, ValidationErrors) {
+	var result SimulationResult
+	var errs ValidationErrors
+
+	role := getRoleByID(req.RoleID)
+	if role == nil {
+		errs.Add("role_id", "Role %d not found", req.RoleID)
+		return result, errs
+	}
+
+	start, err := parsePeriodString(req.Start)
+	if err != nil {
+		errs.Add("start", "%v", err)
+		return result, errs
+	}
+	end := start
+	if req.End != "" {
+		if end, err = parsePeriodString(req.End); err != nil {
+			errs.Add("end", "%v", err)
+			return result, errs
+		}
+	}
+	if end.Before(start) {
+		errs.Add("end", "End period cannot be before the start period")
+		return result, errs
+	}
+	pr := PeriodRange{Start: start, End: end}
+	periods := periodsInRange(start, end)
+
+	// The role's KPIs with the scenario's weight and target changes
+	baselineKPIs := getKPIsByRoleID(role.ID)
+	kpis := make([]KPI, len(baselineKPIs))
+	copy(kpis, baselineKPIs)
+	index := make(map[int]int)
+	for i, kpi := range kpis {
+		index[kpi.ID] = i
+	}
+	for i, change := range req.KPIs {
+		field := fmt.Sprintf("kpis[%d]", i)
+		k, ok := index[change.KPIID]
+		if !ok {
+			errs.Add(field+".kpi_id", "KPI %d does not belong to %s", change.KPIID, role.Name)
+			continue
+		}
+		if change.Weight != nil {
+			if *change.Weight < 0 || *change.Weight > 100 {
+				errs.Add(field+".weight", "Weight must be between 0 and 100")
+			}
+			kpis[k].Weight = *change.Weight
+		}
+		if change.TargetValue != nil {
+			if *change.TargetValue <= 0 {
+				errs.Add(field+".target_value", "Target value must be positive")
+			}
+			kpis[k].TargetValue = *change.TargetValue
+		}
+	}
+
+	// A copy of the measurements with the hypothetical values
+	data := make([]Measurement, len(measurements))
+	copy(data, measurements)
+	hypothetical := make(map[int]bool)
+	for i, v := range req.Values {
+		field := fmt.Sprintf("values[%d]", i)
+		k, ok := index[v.KPIID]
+		if !ok {
+			errs.Add(field+".kpi_id", "KPI %d does not belong to %s", v.KPIID, role.Name)
+			continue
+		}
+		if isDerivedKPI(kpis[k]) {
+			errs.Add(field+".kpi_id", "KPI %d is computed from %s, give values for the KPIs it refers to", v.KPIID, kpis[k].Formula)
+			continue
+		}
+		if err := validateMeasurementValue(kpis[k], v.Value); err != nil {
+			errs.Add(field+".value", "%v", err)
+			continue
+		}
+		valuePeriods := periods
+		if v.Period != "" {
+			period, err := parseSimulationPeriod(v.Period, pr)
+			if err != nil {
+				errs.Add(field+".period", "%v", err)
+				continue
+			}
+			valuePeriods = []time.Time{period}
+		}
+		for _, period := range valuePeriods {
+			data = setScenarioValue(data, kpis[k], period, v.Value)
+			data = recomputeScenarioDependents(data, v.KPIID, period)
+		}
+		hypothetical[v.KPIID] = true
+	}
+
+	var solveKPI KPI
+	var solvePeriods []time.Time
+	if s := req.Solve; s != nil {
+		k, ok := index[s.KPIID]
+		if !ok {
+			errs.Add("solve.kpi_id", "KPI %d does not belong to %s", s.KPIID, role.Name)
+		} else if solveKPI = kpis[k]; isDerivedKPI(solveKPI) {
+			errs.Add("solve.kpi_id", "KPI %d is computed from %s and cannot be solved for", s.KPIID, solveKPI.Formula)
+		} else if solveKPI.TargetValue <= 0 {
+			errs.Add("solve.kpi_id", "KPI %d has no numeric target to solve against", s.KPIID)
+		}
+		if s.TargetScore <= 0 || s.TargetScore > 100 {
+			errs.Add("solve.target_score", "Target score must be above 0 and at most 100")
+		}
+		for i, value := range s.Periods {
+			period, err := parseSimulationPeriod(value, pr)
+			if err != nil {
+				errs.Add(fmt.Sprintf("solve.periods[%d]", i), "%v", err)
+				continue
+			}
+			solvePeriods = append(solvePeriods, period)
+		}
+	}
+
+	if len(errs) > 0 {
+		return result, errs
+	}
+
+	result = SimulationResult{
+		Role:          *role,
+		Period:        pr,
+		BaselineScore: averageRoleScore(baselineKPIs, pr),
+		Score:         averageRoleScoreFrom(kpis, pr, data),
+		Periods:       []SimulatedPeriod{},
+		KPIs:          []SimulatedKPI{},
+	}
+	result.ScoreDelta = result.Score - result.BaselineScore
+
+	for _, period := range periods {
+		scenario := calculateScoreResultFrom(kpis, period, data)
+		result.Periods = append(result.Periods, SimulatedPeriod{
+			Period:        period.Format("2006-01"),
+			BaselineScore: calculateScoreResultFrom(baselineKPIs, period, measurements).Score,
+			Score:         scenario.Score,
+			Coverage:      scenario.Coverage,
+		})
+	}
+
+	for i, kpi := range kpis {
+		value, achievement, measured := averageKPIValueFrom(kpi, pr, data)
+		_, baselineAchievement, _ := averageKPIValue(baselineKPIs[i], pr)
+		result.KPIs = append(result.KPIs, SimulatedKPI{
+			KPIID:               kpi.ID,
+			Name:                kpi.Name,
+			Operator:            kpi.Operator,
+			Weight:              kpi.Weight,
+			TargetValue:         kpi.TargetValue,
+			Value:               value,
+			Achievement:         achievement,
+			BaselineAchievement: baselineAchievement,
+			Measured:            measured,
+			Hypothetical:        hypothetical[kpi.ID],
+		})
+	}
+
+	if req.Solve != nil {
+		if len(solvePeriods) == 0 {
+			// The remaining periods, or the whole range if the KPI has a value everywhere
+			for _, period := range periods {
+				if findMeasurementIn(data, solveKPI.ID, period) == nil {
+					solvePeriods = append(solvePeriods, period)
+				}
+			}
+			if len(solvePeriods) == 0 {
+				solvePeriods = periods
+			}
+		}
+		solution := solveForValue(kpis, solveKPI, req.Solve.TargetScore, pr, solvePeriods, data)
+		result.Solution = &solution
+	}
+
+	return result, nil
+}
+
+// solveForValue finds the least favourable value of a KPI in the given periods
+// that still reaches the target score. The score never falls as the KPI's
+// achievement rises, so a bisection over the value converges.
+func solveForValue(kpis []KPI, kpi KPI, targetScore float64, pr PeriodRange, solvePeriods []time.Time, data []Measurement) SimulationSolution {
+	solution := SimulationSolution{KPIID: kpi.ID, TargetScore: targetScore, Periods: []string{}}
+	for _, period := range solvePeriods {
+		solution.Periods = append(solution.Periods, period.Format("2006-01"))
+	}
+
+	scenario := make([]Measurement, len(data))
+	scoreWith := func(value float64) float64 {
+		copy(scenario, data)
+		trial := scenario
+		for _, period := range solvePeriods {
+			trial = setScenarioValue(trial, kpi, period, value)
+			trial = recomputeScenarioDependents(trial, kpi.ID, period)
+		}
+		return averageRoleScoreFrom(kpis, pr, trial)
+	}
+	setRequired := func(value float64) {
+		value = math.Round(value*100) / 100
+		solution.RequiredValue = &value
+		solution.Score = scoreWith(value)
+	}
+
+	// The KPI reaches full achievement at its target value. Derived KPIs referring
+	// to it may need a better value, so go on while the score still rises.
+	bestValue := kpi.TargetValue
+	best := scoreWith(bestValue)
+	if len(getKPIDependents(kpi.ID)) > 0 {
+		for i := 0; i < solveIterations && best < targetScore-1e-9; i++ {
+			next := bestValue * 2
+			if lowerIsBetter(kpi) {
+				next = bestValue / 2
+			}
+			score := scoreWith(next)
+			if score <= best {
+				break
+			}
+			bestValue, best = next, score
+		}
+	}
+	if best < targetScore-1e-9 {
+		solution.Score = best
+		solution.Note = fmt.Sprintf("Not reachable: the best possible score is %.2f%%", best)
+		return solution
+	}
+	solution.Achievable = true
+
+	if lowerIsBetter(kpi) {
+		solution.Bound = SolveMaximum
+		low, high := bestValue, bestValue*2
+		for scoreWith(high) >= targetScore {
+			if high > kpi.TargetValue*1e6 {
+				solution.Score = scoreWith(high)
+				solution.Note = "Reached with any value"
+				return solution
+			}
+			low, high = high, high*2
+		}
+		for i := 0; i < solveIterations; i++ {
+			mid := (low + high) / 2
+			if scoreWith(mid) >= targetScore {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+		// Round down so the value still reaches the target
+		setRequired(math.Floor(low*100) / 100)
+		return solution
+	}
+
+	solution.Bound = SolveMinimum
+	if scoreWith(0) >= targetScore {
+		setRequired(0)
+		solution.Note = "Reached even with 0"
+		return solution
+	}
+	low, high := 0.0, bestValue
+	for i := 0; i < solveIterations; i++ {
+		mid := (low + high) / 2
+		if scoreWith(mid) >= targetScore {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	// Round up so the value still reaches the target
+	setRequired(math.Ceil(high*100) / 100)
+	return solution
+}
+
+// simulateAPI runs a what-if scenario without saving it
+func simulateAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req SimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, errs := simulate(req)
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// printSimulation prints a simulation result
+func printSimulation(w io.Writer, result SimulationResult) {
+	fmt.Fprintf(w, "\nWhat-if simulation for %s, %s\n", result.Role.Name, result.Period.Label())
+	fmt.Fprintf(w, "Score: %.2f%% -> %.2f%% (%+.2f)\n", result.BaselineScore, result.Score, result.ScoreDelta)
+
+	fmt.Fprintf(w, "\n%-5s %-40s %8s %10s %12s %14s\n", "ID", "KPI", "Weight", "Value", "Achievement", "Was")
+	fmt.Fprintln(w, strings.Repeat("-", 95))
+	for _, kpi := range result.KPIs {
+		value := "-"
+		if kpi.Measured {
+			value = fmt.Sprintf("%.2f", kpi.Value)
+		}
+		if kpi.Hypothetical {
+			value += "*"
+		}
+		fmt.Fprintf(w, "%-5d %-40s %7.1f%% %10s %11.2f%% %13.2f%%\n", kpi.KPIID, truncateString(kpi.Name, 40),
+			kpi.Weight, value, kpi.Achievement, kpi.BaselineAchievement)
+	}
+	fmt.Fprintln(w, "* hypothetical value")
+
+	if s := result.Solution; s != nil {
+		fmt.Fprintf(w, "\nTo reach %.2f%% with KPI %d in %s: ", s.TargetScore, s.KPIID, strings.Join(s.Periods, ", "))
+		switch {
+		case s.RequiredValue != nil:
+			fmt.Fprintf(w, "%s %.2f (score %.2f%%)\n", s.Bound, *s.RequiredValue, s.Score)
+		case s.Note != "":
+			fmt.Fprintln(w, s.Note)
+		}
+	}
+}
+
+// parseKPIValues parses a list such as "3=95,4=88@2026-08" of KPI IDs with values
+// and optional periods
+func parseKPIValues(value string) ([]SimulationValue, error) {
+	var values []SimulationValue
+	for _, item := range splitList(value) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected KPI=value, got '%s'", item)
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid KPI ID in '%s'", item)
+		}
+		number, period, _ := strings.Cut(parts[1], "@")
+		v, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in '%s'", item)
+		}
+		values = append(values, SimulationValue{KPIID: id, Value: v, Period: strings.TrimSpace(period)})
+	}
+	return values, nil
+}
+
+// runSimulateCommand runs a what-if scenario
+func runSimulateCommand(args []string) int {
+	var req SimulationRequest
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	fs.IntVar(&req.RoleID, "role", 0, "role ID")
+	fs.StringVar(&req.Start, "from", lastClosedPeriod(time.Now()).Format("2006-01"), "first period YYYY-MM")
+	fs.StringVar(&req.End, "to", "", "last period YYYY-MM (defaults to --from)")
+	set := fs.String("set", "", "hypothetical values, e.g. 3=95,4=88@2026-08")
+	weights := fs.String("weight", "", "weight changes, e.g. 3=20,4=10")
+	targets := fs.String("target", "", "target value changes, e.g. 3=90")
+	solve := fs.Int("solve", 0, "KPI ID to solve the needed value for")
+	goal := fs.Float64("goal", 0, "with --solve, the score to reach")
+	format := fs.String("format", "txt", "output format: txt or json")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if req.RoleID == 0 {
+		return usageError(fs, "--role is required")
+	}
+	if *format != "txt" && *format != "json" {
+		return usageError(fs, "unsupported format %s", *format)
+	}
+
+	var err error
+	if req.Values, err = parseKPIValues(*set); err != nil {
+		return usageError(fs, "--set: %v", err)
+	}
+	for _, change := range []struct {
+		flag  string
+		value string
+	}{{"weight", *weights}, {"target", *targets}} {
+		values, err := parseKPIValues(change.value)
+		if err != nil {
+			return usageError(fs, "--%s: %v", change.flag, err)
+		}
+		for _, v := range values {
+			number := v.Value
+			if change.flag == "weight" {
+				req.KPIs = append(req.KPIs, SimulationKPIChange{KPIID: v.KPIID, Weight: &number})
+			} else {
+				req.KPIs = append(req.KPIs, SimulationKPIChange{KPIID: v.KPIID, TargetValue: &number})
+			}
+		}
+	}
+	if *solve != 0 {
+		req.Solve = &SimulationSolve{KPIID: *solve, TargetScore: *goal}
+	}
+
+	result, errs := simulate(req)
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", errs)
+		return exitError
+	}
+	if *format == "json" {
+		json.NewEncoder(cliOutput).Encode(result)
+	} else {
+		printSimulation(cliOutput, result)
+	}
+	return exitOK
+}
+
+// handleSimulation runs a what-if scenario from the interactive menu
+func handleSimulation(scanner *bufio.Scanner) {
+	fmt.Println("\n=== What-if Simulation ===")
+
+	role := selectRole(scanner)
+	if role == nil {
+		return
+	}
+	pr, ok := selectPeriodRange(scanner, "simulation")
+	if !ok {
+		return
+	}
+
+	req := SimulationRequest{RoleID: role.ID, Start: pr.Start.Format("2006-01"), End: pr.End.Format("2006-01")}
+	result, errs := simulate(req)
+	if len(errs) > 0 {
+		fmt.Printf("Error: %v\n", errs)
+		return
+	}
+	printSimulation(os.Stdout, result)
+
+	fmt.Print("\nHypothetical values as KPI=value, comma-separated (enter to skip): ")
+	scanner.Scan()
+	values, err := parseKPIValues(scanner.Text())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	req.Values = values
+
+	fmt.Print("KPI ID to solve the needed value for (enter to skip): ")
+	scanner.Scan()
+	if value := strings.TrimSpace(scanner.Text()); value != "" {
+		kpiID, err := strconv.Atoi(value)
+		if err != nil {
+			fmt.Println("Invalid KPI ID.")
+			return
+		}
+		fmt.Print("Score to reach (%): ")
+		scanner.Scan()
+		goal, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+		if err != nil {
+			fmt.Println("Invalid score.")
+			return
+		}
+		req.Solve = &SimulationSolve{KPIID: kpiID, TargetScore: goal}
+	}
+
+	if len(req.Values) == 0 && req.Solve == nil {
+		return
+	}
+	if result, errs = simulate(req); len(errs) > 0 {
+		fmt.Printf("Error: %v\n", errs)
+		return
+	}
+	printSimulation(os.Stdout, result)
+}
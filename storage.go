@@ -15,65 +15,109 @@ const (
 
 var appSettings Settings
 
-// initDatabase initializes the database directory
+// settingsPath is the settings file, changed with --config or KPI_CONFIG
+var settingsPath = filepath.Join(dbDir, settingsFile)
+
+// fileSettings holds the settings as read from the settings file, before environment
+// and flag overrides. Overridden options are saved with these values so an override
+// never ends up in the file.
+var fileSettings Settings
+
+// initDatabase loads the settings and initializes the database directory
 func initDatabase() error {
+	if err := loadSettings(); err != nil {
+		return err
+	}
+
 	// Create data directory if it doesn't exist
-	if _, err := os.Stat(dbDir); os.IsNotExist(err) {
-		err = os.MkdirAll(dbDir, 0755)
+	if _, err := os.Stat(appSettings.DatabasePath); os.IsNotExist(err) {
+		err = os.MkdirAll(appSettings.DatabasePath, 0755)
 		if err != nil {
 			return fmt.Errorf("failed to create data directory: %v", err)
 		}
 	}
 
-	// Load settings
-	loadSettings()
-
 	return nil
 }
 
-// loadSettings loads application settings from file
-func loadSettings() {
-	settingsPath := filepath.Join(dbDir, settingsFile)
-
-	// Default settings
-	appSettings = Settings{
-		DatabasePath: dbDir,
+// loadSettings builds the effective settings from defaults, the settings file,
+// KPI_* environment variables and command-line flags, in increasing precedence
+func loadSettings() error {
+	if env := os.Getenv("KPI_CONFIG"); env != "" && settingsPath == filepath.Join(dbDir, settingsFile) {
+		settingsPath = env
 	}
 
+	settings := defaultSettings()
+
 	// Try to load existing settings
 	data, err := os.ReadFile(settingsPath)
 	if err == nil {
-		err = json.Unmarshal(data, &appSettings)
-		if err != nil {
-			fmt.Printf("Error parsing settings file: %v\n", err)
-			// Use default settings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("invalid settings file %s: %v", settingsPath, err)
 		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read settings file %s: %v", settingsPath, err)
 	}
+	fileSettings = settings
 
-	// Ensure the database directory exists
-	if _, err := os.Stat(appSettings.DatabasePath); os.IsNotExist(err) {
-		err = os.MkdirAll(appSettings.DatabasePath, 0755)
-		if err != nil {
-			fmt.Printf("Error creating database directory: %v\n", err)
-		}
+	if err := applyConfigLayers(&settings); err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
+	}
+	if err := validateSettings(settings); err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
 	}
 
-	// Save settings (creates the file if it doesn't exist)
-	saveSettings()
+	appSettings = settings
+	return nil
 }
 
 // saveSettings saves application settings to file
 func saveSettings() {
-	settingsPath := filepath.Join(dbDir, settingsFile)
+	// Keep the file values of options overridden by the environment or flags
+	settings := appSettings
+	for _, option := range configOptions {
+		if isOverridden(option.Name) {
+			option.set(&settings, option.get(&fileSettings))
+		}
+	}
 
-	data, err := json.MarshalIndent(appSettings, "", "  ")
-	if err != nil {
-		fmt.Printf("Error serializing settings: %v\n", err)
+	if dir := filepath.Dir(settingsPath); dir != "" {
+		os.MkdirAll(dir, 0755)
+	}
+
+	// The settings hold the SMTP password, so they are written like the other
+	// data files, readable by the owner only
+	if err := writeJSONFile(settingsPath, settings); err != nil {
+		fmt.Printf("Error saving settings: %v\n", err)
 		return
 	}
+	fileSettings = settings
+}
+
+// readJSONFile decodes a JSON file, leaving v unchanged when the file does not exist
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return nil
+}
 
-	err = os.WriteFile(settingsPath, data, 0644)
+// writeJSONFile writes v as indented JSON, replacing the file atomically
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		fmt.Printf("Error saving settings: %v\n", err)
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
 	}
+	return os.Rename(tmp, path)
 }
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Measurement frequencies of KPIs
+const (
+	FrequencyMonthly   = "monthly"
+	FrequencyQuarterly = "quarterly"
+	FrequencyYearly    = "yearly"
+)
+
+var kpiFrequencies = []string{FrequencyMonthly, FrequencyQuarterly, FrequencyYearly}
+
+// Submission status of a role for a period
+const (
+	SubmissionComplete = "complete"
+	SubmissionPending  = "pending" // Measurements missing, the deadline has not passed
+	SubmissionOverdue  = "overdue" // Measurements missing after the deadline
+	SubmissionNotDue   = "not_due" // None of the role's KPIs is due in the period
+)
+
+var submissionStatuses = []string{SubmissionComplete, SubmissionPending, SubmissionOverdue, SubmissionNotDue}
+
+// EventSubmissionReminder is the event type of reminders sent through the webhook notifier
+const EventSubmissionReminder = "submission.reminder"
+
+// MissingKPI is a KPI without a measurement for a period in which it is due
+type MissingKPI struct {
+	KPIID     int    `json:"kpi_id"`
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	Frequency string `json:"frequency"`
+}
+
+// SubmissionEmployee is an employee of a role, responsible for its submissions
+type SubmissionEmployee struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// RoleSubmission is the submission completeness of a role for one period
+type RoleSubmission struct {
+	RoleID       int                  `json:"role_id"`
+	RoleName     string               `json:"role_name"`
+	Department   string               `json:"department,omitempty"`
+	Period       string               `json:"period"` // YYYY-MM
+	Deadline     time.Time            `json:"deadline"`
+	Status       string               `json:"status"`
+	DaysOverdue  int                  `json:"days_overdue,omitempty"`
+	DueKPIs      int                  `json:"due_kpis"`
+	Submitted    int                  `json:"submitted_kpis"`
+	Completeness float64              `json:"completeness"` // Percentage of due KPIs with a measurement
+	Missing      []MissingKPI         `json:"missing"`
+	Employees    []SubmissionEmployee `json:"employees"`
+}
+
+// kpiFrequency returns the frequency of a KPI, monthly when not set
+func kpiFrequency(kpi KPI) string {
+	if kpi.Frequency == "" {
+		return FrequencyMonthly
+	}
+	return kpi.Frequency
+}
+
+// frequencyMonths returns the number of months one measurement of a frequency covers
+func frequencyMonths(frequency string) int {
+	switch frequency {
+	case FrequencyQuarterly:
+		return 3
+	case FrequencyYearly:
+		return 12
+	}
+	return 1
+}
+
+// isKPIDue reports whether a measurement of the KPI is due for a period. Quarterly
+// and yearly KPIs are due in the last month of each fiscal quarter or fiscal year.
+func isKPIDue(kpi KPI, period time.Time) bool {
+	months := frequencyMonths(kpiFrequency(kpi))
+	offset := (int(period.Month()) - appSettings.FiscalYearStartMonth + 12) % 12
+	return (offset+1)%months == 0
+}
+
+// isKPISubmitted reports whether the KPI has a measurement in any month covered
+// by the period in which it is due
+func isKPISubmitted(kpi KPI, period time.Time) bool {
+	for i := 0; i < frequencyMonths(kpiFrequency(kpi)); i++ {
+		if getExistingMeasurement(kpi.ID, period.AddDate(0, -i, 0)) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// submissionDeadline returns the end of the deadline day in the month after a period
+func submissionDeadline(period time.Time) time.Time {
+	return time.Date(period.Year(), period.Month()+1, appSettings.SubmissionDeadlineDay, 23, 59, 59, 0, time.Local)
+}
+
+// roleSubmission computes the submission completeness of a role for a period as of now
+func roleSubmission(role Role, period, now time.Time) RoleSubmission {
+	period = time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, time.Local)
+	submission := RoleSubmission{
+		RoleID:     role.ID,
+		RoleName:   role.Name,
+		Department: role.Department,
+		Period:     period.Format("2006-01"),
+		Deadline:   submissionDeadline(period),
+		Missing:    []MissingKPI{},
+		Employees:  []SubmissionEmployee{},
+	}
+
+	for _, kpi := range getKPIsByRoleID(role.ID) {
+		if !isKPIDue(kpi, period) {
+			continue
+		}
+		submission.DueKPIs++
+		if isKPISubmitted(kpi, period) {
+			submission.Submitted++
+			continue
+		}
+		submission.Missing = append(submission.Missing, MissingKPI{
+			KPIID:     kpi.ID,
+			Name:      kpi.Name,
+			Category:  kpi.Category,
+			Frequency: kpiFrequency(kpi),
+		})
+	}
+
+	for _, employee := range employees {
+		if employee.RoleID == role.ID {
+			submission.Employees = append(submission.Employees, SubmissionEmployee{ID: employee.ID, Name: employee.Name})
+		}
+	}
+
+	switch {
+	case submission.DueKPIs == 0:
+		submission.Status = SubmissionNotDue
+		submission.Completeness = 100
+		return submission
+	case len(submission.Missing) == 0:
+		submission.Status = SubmissionComplete
+	case now.After(submission.Deadline):
+		submission.Status = SubmissionOverdue
+		submission.DaysOverdue = int(now.Sub(submission.Deadline).Hours()/24) + 1
+	default:
+		submission.Status = SubmissionPending
+	}
+	submission.Completeness = float64(submission.Submitted) / float64(submission.DueKPIs) * 100
+
+	return submission
+}
+
+// buildSubmissions returns the completeness of every role with KPIs for each
+// period from the first to the last month, in period and role order
+func buildSubmissions(from, to, now time.Time) []RoleSubmission {
+	result := []RoleSubmission{}
+	for period := from; !period.After(to); period = period.AddDate(0, 1, 0) {
+		for _, role := range roles {
+			if len(getKPIsByRoleID(role.ID)) == 0 {
+				continue
+			}
+			result = append(result, roleSubmission(role, period, now))
+		}
+	}
+	return result
+}
+
+// lastClosedPeriod returns the month before the current one, the latest period
+// whose measurements are normally expected
+func lastClosedPeriod(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local)
+}
+
+// ReminderResult is the outcome of one reminder sent for a role
+type ReminderResult struct {
+	RoleID     int      `json:"role_id"`
+	RoleName   string   `json:"role_name"`
+	Period     string   `json:"period"`
+	Status     string   `json:"status"`
+	Missing    int      `json:"missing_kpis"`
+	Notifier   string   `json:"notifier"`
+	Recipients []string `json:"recipients,omitempty"`
+	Sent       bool     `json:"sent"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// reminderNotification builds the reminder for a role with missing measurements
+func reminderNotification(s RoleSubmission) Notification {
+	subject := fmt.Sprintf("KPI submissions for %s due %s", s.Period, s.Deadline.Format("2006-01-02"))
+	if s.Status == SubmissionOverdue {
+		subject = fmt.Sprintf("KPI submissions for %s overdue by %d days", s.Period, s.DaysOverdue)
+	}
+	subject += " - " + s.RoleName
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d KPIs of %s have no measurement for %s.\n", len(s.Missing), s.DueKPIs, s.RoleName, s.Period)
+	fmt.Fprintf(&b, "Deadline: %s\n\nMissing KPIs:\n", s.Deadline.Format("2006-01-02"))
+	for _, kpi := range s.Missing {
+		fmt.Fprintf(&b, "  - %s (%s)\n", kpi.Name, kpi.Frequency)
+	}
+	if len(s.Employees) > 0 {
+		names := make([]string, len(s.Employees))
+		for i, e := range s.Employees {
+			names[i] = e.Name
+		}
+		fmt.Fprintf(&b, "\nEmployees: %s\n", strings.Join(names, ", "))
+	}
+
+	return Notification{
+		Type:    EventSubmissionReminder,
+		Subject: subject,
+		Body:    b.String(),
+		To:      reminderRecipients(s.RoleID),
+		Data:    s,
+	}
+}
+
+// sendReminders notifies every role with missing measurements for a period through
+// the given notifiers. A dry run returns the reminders that would be sent.
+func sendReminders(period time.Time, roleIDs []int, overdueOnly, dryRun bool, notifiers []Notifier) []ReminderResult {
+	return deliverReminders(buildReminders(period, roleIDs, overdueOnly), dryRun, notifiers)
+}
+
+// reminder is the reminder for a role's missing submissions, ready to send
+type reminder struct {
+	submission   RoleSubmission
+	notification Notification
+}
+
+// buildReminders builds the reminders for the roles with missing measurements for
+// a period. It reads the data, so the caller holds dataMu; deliverReminders does
+// not need it.
+func buildReminders(period time.Time, roleIDs []int, overdueOnly bool) []reminder {
+	var reminders []reminder
+	for _, s := range buildSubmissions(period, period, time.Now()) {
+		if len(roleIDs) > 0 && !containsInt(roleIDs, s.RoleID) {
+			continue
+		}
+		if s.Status != SubmissionOverdue && (overdueOnly || s.Status != SubmissionPending) {
+			continue
+		}
+		reminders = append(reminders, reminder{submission: s, notification: reminderNotification(s)})
+	}
+	return reminders
+}
+
+// deliverReminders sends reminders through each notifier. A dry run only records them.
+func deliverReminders(reminders []reminder, dryRun bool, notifiers []Notifier) []ReminderResult {
+	results := []ReminderResult{}
+	for _, r := range reminders {
+		s, n := r.submission, r.notification
+		for _, notifier := range notifiers {
+			result := ReminderResult{
+				RoleID:   s.RoleID,
+				RoleName: s.RoleName,
+				Period:   s.Period,
+				Status:   s.Status,
+				Missing:  len(s.Missing),
+				Notifier: notifier.Name(),
+			}
+			if notifier.Name() == NotifierSMTP {
+				result.Recipients = n.To
+			}
+			if !dryRun {
+				if err := notifier.Notify(n); err != nil {
+					result.Error = err.Error()
+				} else {
+					result.Sent = true
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// containsInt reports whether a slice contains a value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSubmissions shows the missing submissions of a period and offers to send reminders
+func handleSubmissions(scanner *bufio.Scanner) {
+	period := lastClosedPeriod(time.Now())
+	fmt.Printf("\nEnter period (YYYY-MM, default %s): ", period.Format("2006-01"))
+	scanner.Scan()
+	if value := strings.TrimSpace(scanner.Text()); value != "" {
+		parsed, err := parsePeriodString(value)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		period = parsed
+	}
+
+	submissions := buildSubmissions(period, period, time.Now())
+	printSubmissions(os.Stdout, submissions)
+
+	open := 0
+	for _, s := range submissions {
+		if s.Status == SubmissionPending || s.Status == SubmissionOverdue {
+			open++
+		}
+	}
+	if open == 0 {
+		return
+	}
+
+	notifiers := reminderNotifiers()
+	if len(notifiers) == 0 {
+		fmt.Println("\nNo reminder notifiers configured, see the reminders section of the settings file.")
+		return
+	}
+
+	fmt.Printf("\nSend reminders for %d roles? (y/n): ", open)
+	scanner.Scan()
+	if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+		return
+	}
+	printReminderResults(os.Stdout, sendReminders(period, nil, false, false, notifiers))
+}
+
+// printSubmissions prints a completeness table
+func printSubmissions(w io.Writer, submissions []RoleSubmission) {
+	if len(submissions) == 0 {
+		fmt.Fprintln(w, "\nNo roles with KPIs.")
+		return
+	}
+
+	fmt.Fprintf(w, "\n%-8s %-30s %-9s %-10s %-12s %s\n", "Period", "Role", "Status", "Submitted", "Deadline", "Missing KPIs")
+	fmt.Fprintln(w, strings.Repeat("-", 100))
+	for _, s := range submissions {
+		status := s.Status
+		if s.Status == SubmissionOverdue {
+			status = fmt.Sprintf("overdue %dd", s.DaysOverdue)
+		}
+		names := make([]string, len(s.Missing))
+		for i, kpi := range s.Missing {
+			names[i] = kpi.Name
+		}
+		fmt.Fprintf(w, "%-8s %-30s %-9s %-10s %-12s %s\n", s.Period, truncateString(s.RoleName, 30), status,
+			fmt.Sprintf("%d/%d", s.Submitted, s.DueKPIs), s.Deadline.Format("2006-01-02"), truncateString(strings.Join(names, "; "), 60))
+	}
+}
+
+// printReminderResults prints the outcome of sending reminders
+func printReminderResults(w io.Writer, results []ReminderResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No reminders to send.")
+		return
+	}
+	for _, r := range results {
+		outcome := "sent"
+		switch {
+		case r.Error != "":
+			outcome = "failed: " + r.Error
+		case !r.Sent:
+			outcome = "would be sent"
+		}
+		recipients := ""
+		if len(r.Recipients) > 0 {
+			recipients = " to " + strings.Join(r.Recipients, ", ")
+		}
+		fmt.Fprintf(w, "%s %s via %s%s: %s\n", r.Period, r.RoleName, r.Notifier, recipients, outcome)
+	}
+}
+
+// runSubmissionsCommand lists missing submissions and optionally sends reminders
+func runSubmissionsCommand(args []string) int {
+	fs := flag.NewFlagSet("submissions", flag.ContinueOnError)
+	period := fs.String("period", lastClosedPeriod(time.Now()).Format("2006-01"), "period YYYY-MM")
+	status := fs.String("status", "", "only show roles with this status: "+strings.Join(submissionStatuses, ", "))
+	format := fs.String("format", "txt", "output format: txt or json")
+	remind := fs.Bool("remind", false, "send reminders for roles with missing measurements")
+	overdueOnly := fs.Bool("overdue-only", false, "with --remind, only remind roles past the deadline")
+	dryRun := fs.Bool("dry-run", false, "with --remind, list the reminders without sending them")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	p, err := parsePeriodString(*period)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if *status != "" && !containsString(submissionStatuses, *status) {
+		return usageError(fs, "unknown status %s", *status)
+	}
+	if *format != "txt" && *format != "json" {
+		return usageError(fs, "unsupported format %s", *format)
+	}
+
+	if *remind {
+		notifiers := reminderNotifiers()
+		if len(notifiers) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no reminder notifiers configured")
+			return exitError
+		}
+		results := sendReminders(p, nil, *overdueOnly, *dryRun, notifiers)
+		if *format == "json" {
+			json.NewEncoder(cliOutput).Encode(results)
+		} else {
+			printReminderResults(cliOutput, results)
+		}
+		for _, r := range results {
+			if r.Error != "" {
+				return exitError
+			}
+		}
+		return exitOK
+	}
+
+	submissions := []RoleSubmission{}
+	for _, s := range buildSubmissions(p, p, time.Now()) {
+		if *status == "" || s.Status == *status {
+			submissions = append(submissions, s)
+		}
+	}
+	if *format == "json" {
+		json.NewEncoder(cliOutput).Encode(submissions)
+	} else {
+		printSubmissions(cliOutput, submissions)
+	}
+	return exitOK
+}
+
+var submissionComparators = map[string]func(a, b RoleSubmission) int{
+	"period":       func(a, b RoleSubmission) int { return compareStrings(a.Period, b.Period) },
+	"role_id":      func(a, b RoleSubmission) int { return compareInts(a.RoleID, b.RoleID) },
+	"status":       func(a, b RoleSubmission) int { return compareStrings(a.Status, b.Status) },
+	"completeness": func(a, b RoleSubmission) int { return compareFloats(a.Completeness, b.Completeness) },
+	"missing":      func(a, b RoleSubmission) int { return compareInts(len(a.Missing), len(b.Missing)) },
+}
+
+// getSubmissions returns the submission completeness per role and period. The
+// period defaults to the last closed month; from and to select a range.
+func getSubmissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	params, perr := parseListParams(query, []string{"period", "role_id", "employee_id", "status", "from", "to"}, sortFields(submissionComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	from, to := params.From, params.To
+	if value := query.Get("period"); value != "" {
+		period, err := parsePeriodString(value)
+		if err != nil {
+			writeParamError(w, &ParamError{"period", err.Error()})
+			return
+		}
+		from, to = period, period
+	}
+	if from.IsZero() && to.IsZero() {
+		from = lastClosedPeriod(time.Now())
+		to = from
+	} else if from.IsZero() {
+		from = to
+	} else if to.IsZero() {
+		to = from
+	}
+	if to.Sub(from) > 5*366*24*time.Hour {
+		writeParamError(w, &ParamError{"to", "range cannot be longer than 5 years"})
+		return
+	}
+
+	status := query.Get("status")
+	if status != "" && !containsString(submissionStatuses, status) {
+		writeParamError(w, &ParamError{"status", "must be one of " + strings.Join(submissionStatuses, ", ")})
+		return
+	}
+
+	roleID := params.RoleID
+	if value := query.Get("employee_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		var employee *Employee
+		for i := range employees {
+			if err == nil && employees[i].ID == id {
+				employee = &employees[i]
+			}
+		}
+		if employee == nil {
+			writeParamError(w, &ParamError{"employee_id", fmt.Sprintf("unknown employee '%s'", value)})
+			return
+		}
+		if roleID != 0 && roleID != employee.RoleID {
+			writeParamError(w, &ParamError{"employee_id", "employee does not have the role given in role_id"})
+			return
+		}
+		roleID = employee.RoleID
+	}
+
+	filtered := []RoleSubmission{}
+	for _, s := range buildSubmissions(from, to, time.Now()) {
+		if roleID != 0 && s.RoleID != roleID {
+			continue
+		}
+		if status != "" && s.Status != status {
+			continue
+		}
+		if !matchesText(params.Query, s.RoleName, s.Department) {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+
+	page, pagination := sortAndPage(filtered, params, submissionComparators, "period")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
+
+// ReminderRequest is the body of POST /api/submissions/remind. Every field is optional.
+type ReminderRequest struct {
+	Period      string `json:"period"` // YYYY-MM, defaults to the last closed month
+	RoleIDs     []int  `json:"role_ids"`
+	OverdueOnly bool   `json:"overdue_only"`
+	DryRun      bool   `json:"dry_run"`
+}
+
+// ReminderResponse lists the reminders sent for a period
+type ReminderResponse struct {
+	Period    string           `json:"period"`
+	DryRun    bool             `json:"dry_run"`
+	Reminders []ReminderResult `json:"reminders"`
+}
+
+// remindSubmissions sends reminders for roles with missing measurements
+func remindSubmissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ReminderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	var errs ValidationErrors
+	period := lastClosedPeriod(time.Now())
+	if req.Period != "" {
+		parsed, err := parsePeriodString(req.Period)
+		if err != nil {
+			errs.Add("period", "%v", err)
+		}
+		period = parsed
+	}
+	for i, id := range req.RoleIDs {
+		if getRoleByID(id) == nil {
+			errs.Add(fmt.Sprintf("role_ids[%d]", i), "Role %d not found", id)
+		}
+	}
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	notifiers := reminderNotifiers()
+	if len(notifiers) == 0 {
+		writeError(w, http.StatusConflict, "No reminder notifiers configured")
+		return
+	}
+
+	json.NewEncoder(w).Encode(ReminderResponse{
+		Period:    period.Format("2006-01"),
+		DryRun:    req.DryRun,
+		Reminders: sendReminders(period, req.RoleIDs, req.OverdueOnly, req.DryRun, notifiers),
+	})
+}
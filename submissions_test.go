@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a minimal SMTP stand-in that accepts every message
+type fakeSMTPServer struct {
+	listener net.Listener
+	messages chan fakeMail
+}
+
+// fakeMail is a message received by the fake SMTP server
+type fakeMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// startFakeSMTPServer listens on a local port until the test ends
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTPServer{listener: listener, messages: make(chan fakeMail, 10)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// serve handles one SMTP session
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var mail fakeMail
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			mail = fakeMail{From: strings.Trim(strings.TrimSpace(line)[10:], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			mail.To = append(mail.To, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			mail.Data = data.String()
+			s.messages <- mail
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// port returns the port the server listens on
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// setupSubmissionTest replaces the data with one role and restores it after the test
+func setupSubmissionTest(t *testing.T) {
+	previousRoles, previousKPIs, previousMeasurements, previousEmployees := roles, kpis, measurements, employees
+	previousSettings := appSettings
+	t.Cleanup(func() {
+		roles, kpis, measurements, employees = previousRoles, previousKPIs, previousMeasurements, previousEmployees
+		appSettings = previousSettings
+	})
+
+	appSettings = defaultSettings()
+	roles = []Role{{ID: 1, Name: "Sales"}}
+	employees = []Employee{{ID: 7, Name: "Dewi", RoleID: 1}}
+	kpis = []KPI{
+		{ID: 1, RoleID: 1, Name: "Revenue", Operator: "≥", TargetValue: 100, Weight: 50},
+		{ID: 2, RoleID: 1, Name: "Audit", Operator: "≥", TargetValue: 100, Weight: 50, Frequency: FrequencyQuarterly},
+	}
+	measurements = []Measurement{
+		{ID: 1, KPIID: 2, MetricValue: 90, Period: time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+	}
+}
+
+func TestIsKPIDueFollowsFiscalQuarters(t *testing.T) {
+	setupSubmissionTest(t)
+	quarterly := KPI{Frequency: FrequencyQuarterly}
+	yearly := KPI{Frequency: FrequencyYearly}
+
+	appSettings.FiscalYearStartMonth = 4
+	for month, want := range map[time.Month]bool{3: true, 4: false, 6: true, 9: true, 12: true, 1: false} {
+		if got := isKPIDue(quarterly, time.Date(2026, month, 1, 0, 0, 0, 0, time.Local)); got != want {
+			t.Errorf("quarterly KPI due in %s: %v, want %v", month, got, want)
+		}
+	}
+	if !isKPIDue(yearly, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)) || isKPIDue(yearly, time.Date(2026, 12, 1, 0, 0, 0, 0, time.Local)) {
+		t.Error("yearly KPI should only be due in the last month of the fiscal year")
+	}
+}
+
+func TestRoleSubmissionStatus(t *testing.T) {
+	setupSubmissionTest(t)
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+
+	// The quarterly KPI was measured in February, the monthly one is missing
+	s := roleSubmission(roles[0], march, time.Date(2026, 4, 5, 12, 0, 0, 0, time.Local))
+	if s.Status != SubmissionPending || s.DueKPIs != 2 || len(s.Missing) != 1 || s.Missing[0].KPIID != 1 {
+		t.Fatalf("submission %+v, want pending with KPI 1 missing", s)
+	}
+	if len(s.Employees) != 1 || s.Employees[0].ID != 7 {
+		t.Errorf("employees %+v, want employee 7", s.Employees)
+	}
+
+	s = roleSubmission(roles[0], march, time.Date(2026, 4, 8, 0, 0, 0, 0, time.Local))
+	if s.Status != SubmissionOverdue || s.DaysOverdue != 3 {
+		t.Errorf("status %s, %d days overdue, want overdue by 3 days", s.Status, s.DaysOverdue)
+	}
+
+	measurements = append(measurements, Measurement{ID: 2, KPIID: 1, MetricValue: 120, Period: march})
+	if s = roleSubmission(roles[0], march, time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local)); s.Status != SubmissionComplete {
+		t.Errorf("status %s, want complete", s.Status)
+	}
+}
+
+func TestSendRemindersOverSMTP(t *testing.T) {
+	setupSubmissionTest(t)
+	server := startFakeSMTPServer(t)
+
+	appSettings.Reminders.Recipients = map[int][]string{1: {"Sales Lead <lead@example.com>"}}
+	notifier := SMTPNotifier{Settings: SMTPSettings{Host: "127.0.0.1", Port: server.port(), From: "kpi@example.com"}}
+
+	results := sendReminders(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), nil, false, false, []Notifier{notifier})
+	if len(results) != 1 || !results[0].Sent {
+		t.Fatalf("results %+v, want one sent reminder", results)
+	}
+
+	select {
+	case mail := <-server.messages:
+		if mail.From != "kpi@example.com" || len(mail.To) != 1 || mail.To[0] != "lead@example.com" {
+			t.Errorf("envelope from %s to %v", mail.From, mail.To)
+		}
+		if !strings.Contains(mail.Data, "overdue") || !strings.Contains(mail.Data, "  - Revenue (monthly)") {
+			t.Errorf("message does not list the overdue KPI:\n%s", mail.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestSendRemindersDryRun(t *testing.T) {
+	setupSubmissionTest(t)
+
+	// The notifier would fail, a dry run must not use it
+	notifier := SMTPNotifier{Settings: SMTPSettings{Host: "127.0.0.1", Port: 1, From: "kpi@example.com"}}
+	results := sendReminders(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), nil, false, true, []Notifier{notifier})
+	if len(results) != 1 || results[0].Sent || results[0].Error != "" {
+		t.Errorf("results %+v, want one unsent reminder without error", results)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Validation rules shared by the interactive input, the CLI commands, the importer
+// and the REST API, so every entry point accepts and rejects the same data.
+
+// FieldError describes a problem with one input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects the field errors of one input
+type ValidationErrors []FieldError
+
+// Error joins the field errors, e.g. "metric_value: Percentage must be between 0 and 100"
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, e := range v {
+		messages[i] = e.Field + ": " + e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add records a field error
+func (v *ValidationErrors) Add(field, format string, a ...interface{}) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+// Prefix returns the errors with their field names prefixed, e.g. "[2].metric_value"
+func (v ValidationErrors) Prefix(prefix string) ValidationErrors {
+	prefixed := make(ValidationErrors, len(v))
+	for i, e := range v {
+		prefixed[i] = FieldError{Field: prefix + e.Field, Message: e.Message}
+	}
+	return prefixed
+}
+
+// validateMeasurementValue checks a value against the rules for the KPI's unit
+func validateMeasurementValue(kpi KPI, value float64) error {
+	switch kpi.Unit {
+	case "%":
+		if value < 0 || value > 100 {
+			return fmt.Errorf("Percentage must be between 0 and 100")
+		}
+	case "score":
+		if value < 0 || value > 10 {
+			return fmt.Errorf("Score must be between 0 and 10")
+		}
+	case "days":
+		if value < 0 {
+			return fmt.Errorf("Days cannot be negative")
+		}
+	default:
+		// For other units, just ensure it's not negative
+		if value < 0 && !strings.Contains(kpi.Metric, "error") { // Errors can be negative
+			return fmt.Errorf("Value cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// validatePeriod checks that a period is set and within the supported years
+func validatePeriod(period time.Time) error {
+	if period.IsZero() {
+		return fmt.Errorf("Period is required")
+	}
+	if period.Year() < 2000 || period.Year() > 2100 {
+		return fmt.Errorf("Period year must be between 2000 and 2100")
+	}
+	return nil
+}
+
+// validateMeasurementInput validates a new or changed measurement and returns its KPI
+// and the value to store, which for a derived KPI is computed from the inputs
+func validateMeasurementInput(kpiID int, value float64, inputs map[string]float64, period time.Time) (*KPI, float64, ValidationErrors) {
+	return validateMeasurementInputFrom(kpiID, value, inputs, period, measurements)
+}
+
+// validateMeasurementInputFrom validates a measurement whose formula, if any, takes
+// the values of other KPIs from data, e.g. the measurements with a batch applied
+func validateMeasurementInputFrom(kpiID int, value float64, inputs map[string]float64, period time.Time, data []Measurement) (*KPI, float64, ValidationErrors) {
+	var errs ValidationErrors
+
+	kpi := getKPIByID(kpiID)
+	if kpi == nil {
+		errs.Add("kpi_id", "KPI %d not found", kpiID)
+	}
+
+	periodErr := validatePeriod(period)
+	if periodErr != nil {
+		errs.Add("period", "%v", periodErr)
+	} else if isPeriodClosed(period) {
+		errs.Add("period", "Period %s is closed, reopen it to change its measurements", period.Format("2006-01"))
+	}
+
+	if kpi != nil && periodErr == nil {
+		// The period is needed for the values of KPIs in a formula
+		resolved, err := resolveMeasurementValue(*kpi, value, inputs, period, data)
+		if err != nil {
+			errs.Add("inputs", "%v", err)
+			return kpi, value, errs
+		}
+		value = resolved
+	}
+	if kpi != nil {
+		if err := validateMeasurementValue(*kpi, value); err != nil {
+			errs.Add("metric_value", "%v", err)
+		}
+	}
+
+	return kpi, value, errs
+}
+
+// validateRole validates a changed role
+func validateRole(role Role) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(role.Name) == "" {
+		errs.Add("name", "Role name cannot be empty")
+	}
+
+	return errs
+}
+
+// validateEmployee validates a new employee
+func validateEmployee(employee Employee) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(employee.Name) == "" {
+		errs.Add("name", "Employee name cannot be empty")
+	}
+	if getRoleByID(employee.RoleID) == nil {
+		errs.Add("role_id", "Role %d not found", employee.RoleID)
+	}
+	if employee.HireDate.IsZero() {
+		errs.Add("hire_date", "Hire date is required")
+	}
+	if employee.BaseSalary < 0 {
+		errs.Add("base_salary", "Base salary cannot be negative")
+	}
+
+	return errs
+}
+
+// kpiOperators are the comparison operators a KPI target can use
+var kpiOperators = []string{"≤", "≥", "=", "<=", ">="}
+
+// validateKPI validates a new or changed KPI
+func validateKPI(kpi KPI) ValidationErrors {
+	var errs ValidationErrors
+
+	if getRoleByID(kpi.RoleID) == nil {
+		errs.Add("role_id", "Role %d not found", kpi.RoleID)
+	}
+	if kpi.Category != "Quantitative" && kpi.Category != "Qualitative" {
+		errs.Add("category", "Category must be Quantitative or Qualitative")
+	}
+	if strings.TrimSpace(kpi.Name) == "" {
+		errs.Add("name", "KPI name cannot be empty")
+	}
+	if strings.TrimSpace(kpi.Unit) == "" {
+		errs.Add("unit", "Unit cannot be empty")
+	}
+	if !containsString(kpiOperators, kpi.Operator) {
+		errs.Add("operator", "Operator must be one of %s", strings.Join(kpiOperators, " "))
+	}
+	if kpi.Weight <= 0 || kpi.Weight > 100 {
+		errs.Add("weight", "Weight must be greater than 0 and at most 100")
+	}
+	// Achievement divides by the target, only derived KPIs may go without one
+	if kpi.TargetValue < 0 || (kpi.TargetValue == 0 && !isDerivedKPI(kpi)) {
+		errs.Add("target_value", "Target value must be greater than 0")
+	}
+	if kpi.Frequency != "" && !containsString(kpiFrequencies, kpi.Frequency) {
+		errs.Add("frequency", "Frequency must be one of %s", strings.Join(kpiFrequencies, ", "))
+	}
+	if isDerivedKPI(kpi) {
+		if err := validateFormula(kpi); err != nil {
+			errs.Add("formula", "%v", err)
+		}
+	}
+
+	return errs
+}
+
+// validateWebhook validates a new or changed webhook subscription
+func validateWebhook(input WebhookInput) ValidationErrors {
+	var errs ValidationErrors
+
+	if u, err := url.Parse(input.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.Add("url", "URL must be an absolute http or https URL")
+	}
+	for i, eventType := range input.Events {
+		if !containsString(eventTypes, eventType) {
+			errs.Add(fmt.Sprintf("events[%d]", i), "Unknown event type '%s', allowed: %s", eventType, strings.Join(eventTypes, ", "))
+		}
+	}
+	if input.Secret != "" && len(input.Secret) < 16 {
+		errs.Add("secret", "Secret must be at least 16 characters")
+	}
+
+	return errs
+}
+
+// validateAlertRule validates a new or changed alert rule
+func validateAlertRule(rule AlertRule) ValidationErrors {
+	var errs ValidationErrors
+
+	if !containsString(alertRuleTypes, rule.Type) {
+		errs.Add("type", "Type must be one of %s", strings.Join(alertRuleTypes, ", "))
+	}
+
+	switch {
+	case rule.KPIID != 0:
+		kpi := getKPIByID(rule.KPIID)
+		if kpi == nil {
+			errs.Add("kpi_id", "KPI %d not found", rule.KPIID)
+		} else if rule.RoleID != 0 && rule.RoleID != kpi.RoleID {
+			errs.Add("role_id", "KPI %d does not belong to role %d", rule.KPIID, rule.RoleID)
+		}
+		if rule.Type == AlertScoreDrop {
+			errs.Add("kpi_id", "Score drop rules apply to a role, not a KPI")
+		}
+	case rule.RoleID != 0:
+		if getRoleByID(rule.RoleID) == nil {
+			errs.Add("role_id", "Role %d not found", rule.RoleID)
+		}
+	default:
+		errs.Add("role_id", "Either role_id or kpi_id is required")
+	}
+
+	switch rule.Type {
+	case AlertAchievementBelow:
+		if rule.Threshold <= 0 || rule.Threshold > 100 {
+			errs.Add("threshold", "Achievement threshold must be greater than 0 and at most 100")
+		}
+	case AlertConsecutiveMisses:
+		if rule.Threshold != float64(int(rule.Threshold)) || rule.Threshold < 2 || rule.Threshold > 24 {
+			errs.Add("threshold", "Number of consecutive misses must be a whole number from 2 to 24")
+		}
+	case AlertScoreDrop:
+		if rule.Threshold <= 0 || rule.Threshold > 100 {
+			errs.Add("threshold", "Score drop must be greater than 0 and at most 100 points")
+		}
+	}
+
+	return errs
+}
+
+// validateSettingsUpdate validates the scoring, rating, appraisal and bonus sections
+// of a settings update
+func validateSettingsUpdate(settings Settings) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(settings.DatabasePath) == "" {
+		errs.Add("database_path", "Database path cannot be empty")
+	}
+
+	if settings.ScoringPolicy != "" && !isValidScoringPolicy(settings.ScoringPolicy) {
+		errs.Add("scoring_policy", "Invalid scoring policy: %s", settings.ScoringPolicy)
+	}
+	for roleID, policy := range settings.RoleScoringPolicies {
+		if !isValidScoringPolicy(policy) {
+			errs.Add(fmt.Sprintf("role_scoring_policies.%d", roleID), "Invalid scoring policy: %s", policy)
+		}
+	}
+
+	if err := validateRatingBands(settings.RatingBands); err != nil {
+		errs.Add("rating_bands", "%v", err)
+	}
+	for roleID, bands := range settings.RoleRatingBands {
+		if err := validateRatingBands(bands); err != nil {
+			errs.Add(fmt.Sprintf("role_rating_bands.%d", roleID), "%v", err)
+		}
+	}
+
+	if settings.Appraisal.Mode != "" {
+		if err := validateAppraisalConfig(settings.Appraisal); err != nil {
+			errs.Add("appraisal", "%v", err)
+		}
+	}
+
+	if settings.BonusPolicy != nil {
+		if err := validateBonusPolicy(*settings.BonusPolicy); err != nil {
+			errs.Add("bonus_policy", "%v", err)
+		}
+	}
+
+	return errs
+}
+
+// getRoleByID returns the role with the given ID or nil
+func getRoleByID(id int) *Role {
+	for i := range roles {
+		if roles[i].ID == id {
+			return &roles[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidationErrors(t *testing.T) {
+	var errs ValidationErrors
+	errs.Add("kpi_id", "KPI %d not found", 9)
+	errs.Add("metric_value", "Value cannot be negative")
+
+	if text := errs.Error(); text != "kpi_id: KPI 9 not found; metric_value: Value cannot be negative" {
+		t.Errorf("error %q", text)
+	}
+	want := ValidationErrors{{Field: "[2].kpi_id", Message: "KPI 9 not found"}, {Field: "[2].metric_value", Message: "Value cannot be negative"}}
+	if prefixed := errs.Prefix("[2]."); !reflect.DeepEqual(prefixed, want) {
+		t.Errorf("prefixed %+v, want %+v", prefixed, want)
+	}
+	if errs[0].Field != "kpi_id" {
+		t.Errorf("errors %+v, want Prefix to leave the original unchanged", errs)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	for _, tt := range []struct {
+		write  func(w http.ResponseWriter)
+		status int
+		want   APIError
+	}{
+		{
+			func(w http.ResponseWriter) { writeError(w, http.StatusNotFound, "KPI not found") },
+			http.StatusNotFound, APIError{Code: "not_found", Message: "KPI not found"},
+		},
+		{
+			func(w http.ResponseWriter) {
+				writeValidationError(w, ValidationErrors{{Field: "name", Message: "Role name cannot be empty"}})
+			},
+			http.StatusUnprocessableEntity, APIError{Code: "validation_failed", Message: "Validation failed", Details: []FieldError{{Field: "name", Message: "Role name cannot be empty"}}},
+		},
+		{
+			func(w http.ResponseWriter) {
+				writeParamError(w, &ParamError{"limit", "must be a number between 1 and 1000"})
+			},
+			http.StatusBadRequest, APIError{Code: "bad_request", Message: "Invalid query parameter", Details: []FieldError{{Field: "limit", Message: "must be a number between 1 and 1000"}}},
+		},
+		// Statuses without a code of their own get a generic one
+		{
+			func(w http.ResponseWriter) {
+				methodNotAllowedHandler(w, httptest.NewRequest("DELETE", "/api/roles", nil))
+			},
+			http.StatusMethodNotAllowed, APIError{Code: "method_not_allowed", Message: "Method DELETE is not allowed for /api/roles"},
+		},
+		{
+			func(w http.ResponseWriter) { writeError(w, http.StatusTeapot, "No coffee") },
+			http.StatusTeapot, APIError{Code: "error", Message: "No coffee"},
+		},
+	} {
+		rec := httptest.NewRecorder()
+		tt.write(rec)
+		var response ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.status || rec.Header().Get("Content-Type") != "application/json" || !reflect.DeepEqual(response.Error, tt.want) {
+			t.Errorf("status %d, %+v, want %d %+v", rec.Code, response.Error, tt.status, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newAPIRouter().ServeHTTP(rec, httptest.NewRequest("GET", "/api/nothing", nil))
+	var response ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if rec.Code != http.StatusNotFound || response.Error.Code != "not_found" {
+		t.Errorf("status %d, %+v, want the not found envelope for an unknown route", rec.Code, response)
+	}
+}
+
+func TestValidateMeasurementValue(t *testing.T) {
+	for _, tt := range []struct {
+		kpi   KPI
+		value float64
+		ok    bool
+	}{
+		{KPI{Unit: "%"}, 100, true},
+		{KPI{Unit: "%"}, 100.5, false},
+		{KPI{Unit: "%"}, -1, false},
+		{KPI{Unit: "score"}, 10, true},
+		{KPI{Unit: "score"}, 11, false},
+		{KPI{Unit: "days"}, 0, true},
+		{KPI{Unit: "days"}, -2, false},
+		{KPI{Unit: "IDR"}, 5e9, true},
+		{KPI{Unit: "IDR"}, -1, false},
+		{KPI{Unit: "count", Metric: "Booking error rate"}, -3, true},
+	} {
+		if err := validateMeasurementValue(tt.kpi, tt.value); (err == nil) != tt.ok {
+			t.Errorf("%s %v: error %v, want ok %v", tt.kpi.Unit, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateMeasurementInput(t *testing.T) {
+	setupSubmissionTest(t)
+	previous := closedPeriods
+	t.Cleanup(func() { closedPeriods = previous })
+	closedPeriods = []ClosedPeriod{{Period: "2026-01"}}
+
+	for _, tt := range []struct {
+		kpiID  int
+		value  float64
+		period time.Time
+		fields []string
+	}{
+		{1, 90, month(2), nil},
+		{9, 90, month(2), []string{"kpi_id"}},
+		{1, -1, month(2), []string{"metric_value"}},
+		{1, 90, time.Time{}, []string{"period"}},
+		{1, 90, time.Date(1999, 12, 1, 0, 0, 0, 0, time.Local), []string{"period"}},
+		{1, 90, month(1), []string{"period"}},
+		// Every problem is reported, not just the first
+		{9, -1, time.Time{}, []string{"kpi_id", "period"}},
+		{1, -1, month(1), []string{"period", "metric_value"}},
+	} {
+		kpi, value, errs := validateMeasurementInput(tt.kpiID, tt.value, nil, tt.period)
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		if !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("KPI %d value %v period %v: errors %v, want %v", tt.kpiID, tt.value, tt.period, errs, tt.fields)
+		}
+		if (kpi == nil) != (tt.kpiID == 9) || value != tt.value {
+			t.Errorf("KPI %d: %v %v, want the KPI and value back", tt.kpiID, kpi, value)
+		}
+	}
+}
+
+func TestValidateEntities(t *testing.T) {
+	setupSubmissionTest(t)
+	valid := KPI{RoleID: 1, Category: "Quantitative", Name: "Calls", Unit: "count", Operator: ">=", TargetValue: 50, Weight: 20}
+
+	fields := func(errs ValidationErrors) []string {
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		return fields
+	}
+
+	for _, tt := range []struct {
+		name string
+		errs ValidationErrors
+		want []string
+	}{
+		{"role", validateRole(Role{Name: "Support"}), nil},
+		{"blank role", validateRole(Role{Name: "  "}), []string{"name"}},
+		{"employee", validateEmployee(Employee{Name: "Eka", RoleID: 1, HireDate: month(1)}), nil},
+		{"bad employee", validateEmployee(Employee{Name: " ", RoleID: 2, BaseSalary: -1}), []string{"name", "role_id", "hire_date", "base_salary"}},
+		{"KPI", validateKPI(valid), nil},
+		{"bad KPI", validateKPI(KPI{RoleID: 2, Category: "Other", Operator: ">", Weight: 101, TargetValue: -1, Frequency: "weekly"}),
+			[]string{"role_id", "category", "name", "unit", "operator", "weight", "target_value", "frequency"}},
+		{"zero target", validateKPI(KPI{RoleID: 1, Category: "Qualitative", Name: "Care", Unit: "score", Operator: "≥", Weight: 100}), []string{"target_value"}},
+		{"webhook", validateWebhook(WebhookInput{URL: "https://hooks.example.com/kpi", Events: eventTypes[:1]}), nil},
+		{"bad webhook", validateWebhook(WebhookInput{URL: "ftp://example.com", Events: []string{eventTypes[0], "kpi.renamed"}, Secret: "short"}),
+			[]string{"url", "events[1]", "secret"}},
+	} {
+		if got := fields(tt.errs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: errors %v, want fields %v", tt.name, tt.errs, tt.want)
+		}
+	}
+}
@@ -16,6 +16,8 @@ func handleViewKPIs(scanner *bufio.Scanner) {
 	fmt.Println("2. View by Month")
 	fmt.Println("3. View Year-to-Date")
 	fmt.Println("4. View Trends")
+	fmt.Println("5. Leaderboard")
+	fmt.Println("6. Annual Appraisals")
 	fmt.Println("0. Back to Main Menu")
 
 	fmt.Print("\nEnter your choice: ")
@@ -31,6 +33,10 @@ func handleViewKPIs(scanner *bufio.Scanner) {
 		viewYearToDate(scanner)
 	case "4":
 		viewTrends(scanner)
+	case "5":
+		viewLeaderboard(scanner)
+	case "6":
+		viewAppraisals(scanner)
 	case "0":
 		return
 	default:
@@ -69,8 +75,12 @@ func viewByRole(scanner *bufio.Scanner) {
 	displayKPIsByCategory(roleKPIs, "Qualitative", period)
 
 	// Calculate and display overall score
-	totalScore := calculateOverallScore(roleKPIs, period)
-	fmt.Printf("\nOVERALL SCORE: %.2f%%\n", totalScore)
+	result := calculateScoreResult(roleKPIs, period)
+	fmt.Printf("\nOVERALL SCORE: %s\n", result)
+	if result.HasData() {
+		fmt.Printf("RATING: %s\n", getRating(role.ID, result.Score))
+	}
+	fmt.Printf("Missing-data policy: %s\n", result.Policy)
 
 	// Wait for user to press enter
 	fmt.Print("\nPress Enter to continue...")
@@ -124,8 +134,8 @@ func viewByMonth(scanner *bufio.Scanner) {
 			continue
 		}
 
-		totalScore := calculateOverallScore(roleKPIs, period)
-		fmt.Printf("Overall Score: %.2f%%\n\n", totalScore)
+		result := calculateScoreResult(roleKPIs, period)
+		fmt.Printf("Overall Score: %s\n\n", result)
 	}
 
 	// Wait for user to press enter
@@ -176,15 +186,19 @@ func viewYearToDate(scanner *bufio.Scanner) {
 		fmt.Printf("%-15s", "Score (%)")
 
 		var totalYearScore float64
+		var totalYearCoverage float64
 		var monthsWithData int
+		var monthResults []ScoreResult
 
 		for i := 1; i <= int(currentMonth); i++ {
 			period := time.Date(year, time.Month(i), 1, 0, 0, 0, 0, time.Local)
-			score := calculateOverallScore(roleKPIs, period)
+			result := calculateScoreResult(roleKPIs, period)
+			monthResults = append(monthResults, result)
 
-			if score > 0 {
-				fmt.Printf("%-8.2f", score)
-				totalYearScore += score
+			if result.HasData() {
+				fmt.Printf("%-8.2f", result.Score)
+				totalYearScore += result.Score
+				totalYearCoverage += result.Coverage
 				monthsWithData++
 			} else {
 				fmt.Printf("%-8s", "-")
@@ -193,11 +207,24 @@ func viewYearToDate(scanner *bufio.Scanner) {
 
 		// Calculate average
 		avgScore := 0.0
+		avgCoverage := 0.0
 		if monthsWithData > 0 {
 			avgScore = totalYearScore / float64(monthsWithData)
+			avgCoverage = totalYearCoverage / float64(monthsWithData)
 		}
 
-		fmt.Printf("%-8.2f\n\n", avgScore)
+		fmt.Printf("%-8.2f\n", avgScore)
+
+		// Display coverage next to the scores
+		fmt.Printf("%-15s", "Coverage (%)")
+		for _, result := range monthResults {
+			if result.HasData() {
+				fmt.Printf("%-8.0f", result.Coverage)
+			} else {
+				fmt.Printf("%-8s", "-")
+			}
+		}
+		fmt.Printf("%-8.0f\n\n", avgCoverage)
 	}
 
 	// Wait for user to press enter
@@ -266,18 +293,30 @@ func viewTrends(scanner *bufio.Scanner) {
 
 		fmt.Printf("%-15s", "Score (%)")
 
+		var monthResults []ScoreResult
 		for i := 1; i <= int(currentMonth); i++ {
 			period := time.Date(year, time.Month(i), 1, 0, 0, 0, 0, time.Local)
-			score := calculateOverallScore(roleKPIs, period)
+			result := calculateScoreResult(roleKPIs, period)
+			monthResults = append(monthResults, result)
+
+			if result.HasData() {
+				fmt.Printf("%-8.2f", result.Score)
+			} else {
+				fmt.Printf("%-8s", "-")
+			}
+		}
+		fmt.Println()
 
-			if score > 0 {
-				fmt.Printf("%-8.2f", score)
+		fmt.Printf("%-15s", "Coverage (%)")
+		for _, result := range monthResults {
+			if result.HasData() {
+				fmt.Printf("%-8.0f", result.Coverage)
 			} else {
 				fmt.Printf("%-8s", "-")
 			}
 		}
 
-		fmt.Println("\n")
+		fmt.Print("\n\n")
 
 		// Display ASCII chart
 		displayASCIIChart(roleKPIs, year, 0)
@@ -309,7 +348,7 @@ func viewTrends(scanner *bufio.Scanner) {
 			}
 		}
 
-		fmt.Println("\n")
+		fmt.Print("\n\n")
 
 		// Display ASCII chart
 		displayASCIIChart(roleKPIs, year, kpi.ID)
@@ -425,7 +464,7 @@ func getKPIsByRoleID(roleID int) []KPI {
 
 // calculateAchievement calculates achievement percentage for a KPI
 func calculateAchievement(kpi KPI, measurement *Measurement) float64 {
-	if measurement == nil {
+	if measurement == nil || !hasNumericTarget(kpi) {
 		return 0
 	}
 
@@ -468,17 +507,20 @@ func calculateAchievement(kpi KPI, measurement *Measurement) float64 {
 }
 
 // calculateOverallScore calculates the overall score for a set of KPIs
+// using the missing-data policy of their role (see calculateScoreResult)
 func calculateOverallScore(kpis []KPI, period time.Time) float64 {
-	var totalScore float64
+	return calculateScoreResult(kpis, period).Score
+}
+
+// calculateCoverage returns the percentage of KPI weight that has a measurement for the period
+func calculateCoverage(kpis []KPI, period time.Time) float64 {
+	var measuredWeight float64
 	var totalWeight float64
 
 	for _, kpi := range kpis {
-		measurement := getExistingMeasurement(kpi.ID, period)
-		if measurement != nil {
-			achievementPct := calculateAchievement(kpi, measurement)
-			score := achievementPct * kpi.Weight / 100
-			totalScore += score
-			totalWeight += kpi.Weight
+		totalWeight += kpi.Weight
+		if getExistingMeasurement(kpi.ID, period) != nil {
+			measuredWeight += kpi.Weight
 		}
 	}
 
@@ -486,6 +528,5 @@ func calculateOverallScore(kpis []KPI, period time.Time) float64 {
 		return 0
 	}
 
-	// Normalize to 100%
-	return (totalScore / totalWeight) * 100
+	return (measuredWeight / totalWeight) * 100
 }
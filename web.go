@@ -0,0 +1,49 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// The web dashboard is compiled into the binary so it works offline and needs
+// no separate deployment. It only talks to the REST API.
+//
+//go:embed web
+var webFiles embed.FS
+
+// webHandler serves the embedded web dashboard
+func webHandler() http.Handler {
+	root, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err) // The embedded directory always exists
+	}
+	files := http.FileServer(http.FS(root))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Unknown API routes still get a JSON error
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			notFoundHandler(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowedHandler(w, r)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name == "" {
+			name = "index.html"
+		}
+		if _, err := fs.Stat(root, name); err != nil {
+			notFoundHandler(w, r)
+			return
+		}
+
+		// Assets change with the binary, so browsers must revalidate them
+		w.Header().Set("Cache-Control", "no-cache")
+		files.ServeHTTP(w, r)
+	})
+}
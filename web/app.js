@@ -0,0 +1,500 @@
+// KPI Tracker web dashboard. Plain JavaScript against the REST API, no external
+// libraries, so it keeps working without internet access.
+"use strict";
+
+var COLORS = ["#1565c0", "#2e7d32", "#ef6c00", "#6a1b9a", "#c62828", "#00838f", "#5d4037", "#ad1457"];
+
+var state = { roles: [] };
+
+// ---------- helpers ----------
+
+function $(selector) {
+  return document.querySelector(selector);
+}
+
+function esc(value) {
+  return String(value == null ? "" : value).replace(/[&<>"]/g, function (c) {
+    return { "&": "&amp;", "<": "&lt;", ">": "&gt;", "\"": "&quot;" }[c];
+  });
+}
+
+function fmt(value) {
+  return Number(value || 0).toFixed(1);
+}
+
+function showMessage(text, isError) {
+  var el = $("#message");
+  el.textContent = text;
+  el.className = isError ? "message error" : "message";
+  el.hidden = false;
+  clearTimeout(showMessage.timer);
+  showMessage.timer = setTimeout(function () { el.hidden = true; }, 6000);
+}
+
+// api calls the REST API and rejects with the message of the error envelope
+function api(method, path, body) {
+  var options = { method: method, headers: {} };
+  if (body !== undefined) {
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body);
+  }
+  return fetch(path, options).then(function (response) {
+    if (response.status === 204) {
+      return null;
+    }
+    return response.json().then(function (data) {
+      if (!response.ok) {
+        var err = data && data.error ? data.error : { message: response.statusText };
+        var details = (err.details || []).map(function (d) { return d.field + ": " + d.message; });
+        var error = new Error(err.message + (details.length ? " (" + details.join("; ") + ")" : ""));
+        error.status = response.status;
+        throw error;
+      }
+      return data;
+    });
+  });
+}
+
+// fetchAll follows next_cursor until every item of a list endpoint is loaded
+function fetchAll(path) {
+  var items = [];
+  function page(cursor) {
+    var url = path + (path.indexOf("?") < 0 ? "?" : "&") + "limit=1000" + (cursor ? "&cursor=" + encodeURIComponent(cursor) : "");
+    return api("GET", url).then(function (data) {
+      items = items.concat(data.data);
+      return data.pagination.next_cursor ? page(data.pagination.next_cursor) : items;
+    });
+  }
+  return page("");
+}
+
+function currentPeriod() {
+  var now = new Date();
+  return now.getFullYear() + "-" + String(now.getMonth() + 1).padStart(2, "0");
+}
+
+function fillRoleSelect(select) {
+  select.innerHTML = state.roles.map(function (role) {
+    return "<option value='" + role.id + "'>" + esc(role.name) + "</option>";
+  }).join("");
+}
+
+function svg(width, height, content) {
+  return "<svg viewBox='0 0 " + width + " " + height + "' xmlns='http://www.w3.org/2000/svg' font-size='11'>" + content + "</svg>";
+}
+
+// ---------- overview ----------
+
+function loadOverview() {
+  var period = $("#overview-form").period.value.split("-");
+  api("GET", "/api/dashboard/overview?year=" + period[0] + "&month=" + Number(period[1])).then(function (rows) {
+    rows = rows || [];
+    $("#overview-rows").innerHTML = rows.map(function (row) {
+      return "<tr><td>" + esc(row.role_name) + "</td><td class='number'>" + fmt(row.total_score) + "%</td>" +
+        "<td>" + esc(row.rating) + "</td>" +
+        "<td class='number" + (row.incomplete ? " incomplete" : "") + "'>" + fmt(row.coverage) + "%</td>" +
+        "<td class='number'>" + row.measured_kpis + " / " + row.kpi_count + "</td>" +
+        "<td" + (row.overdue ? " class='incomplete'" : "") + ">" + submissionText(row) + "</td></tr>";
+    }).join("") || "<tr><td colspan='6'>No roles with KPIs</td></tr>";
+    $("#overview-chart").innerHTML = barChart(rows);
+  }).catch(function (err) { showMessage(err.message, true); });
+}
+
+function submissionText(row) {
+  var deadline = row.deadline.slice(0, 10);
+  switch (row.submission_status) {
+    case "overdue": return row.missing_kpis + " missing, overdue since " + deadline;
+    case "pending": return row.missing_kpis + " missing, due " + deadline;
+    case "complete": return "complete";
+  }
+  return "not due";
+}
+
+function barChart(rows) {
+  if (!rows.length) {
+    return "";
+  }
+  var width = 900, barHeight = 26, left = 220, height = rows.length * barHeight + 30;
+  var scale = (width - left - 60) / 100;
+  var content = "";
+  for (var x = 0; x <= 100; x += 25) {
+    content += "<line x1='" + (left + x * scale) + "' y1='0' x2='" + (left + x * scale) + "' y2='" + (height - 20) + "' stroke='#eee'/>" +
+      "<text x='" + (left + x * scale) + "' y='" + (height - 6) + "' text-anchor='middle' fill='#666'>" + x + "%</text>";
+  }
+  rows.forEach(function (row, i) {
+    var y = i * barHeight + 4;
+    var value = Math.min(row.total_score, 100);
+    content += "<text x='" + (left - 8) + "' y='" + (y + 15) + "' text-anchor='end'>" + esc(row.role_name) + "</text>" +
+      "<rect x='" + left + "' y='" + y + "' width='" + Math.max(value * scale, 0) + "' height='" + (barHeight - 8) + "' fill='" +
+      (row.incomplete ? "#FFC7CE" : COLORS[0]) + "'/>" +
+      "<text x='" + (left + Math.max(value * scale, 0) + 6) + "' y='" + (y + 15) + "'>" + fmt(row.total_score) + "%</text>";
+  });
+  return svg(width, height, content);
+}
+
+// ---------- trends ----------
+
+function loadTrends() {
+  var year = $("#trends-form").year.value;
+  api("GET", "/api/dashboard/trends?year=" + year).then(function (months) {
+    $("#trends-chart").innerHTML = lineChart(months || []);
+  }).catch(function (err) { showMessage(err.message, true); });
+}
+
+function lineChart(months) {
+  var names = [];
+  months.forEach(function (m) {
+    Object.keys(m.role_scores).forEach(function (name) {
+      if (names.indexOf(name) < 0) {
+        names.push(name);
+      }
+    });
+  });
+  if (!names.length) {
+    return "<p class='hint'>No data</p>";
+  }
+  names.sort();
+
+  var width = 900, height = 320, left = 40, right = 20, top = 10, bottom = 30;
+  var stepX = (width - left - right) / 11;
+  var y = function (value) { return top + (height - top - bottom) * (1 - Math.min(value, 100) / 100); };
+
+  var content = "";
+  for (var v = 0; v <= 100; v += 25) {
+    content += "<line x1='" + left + "' y1='" + y(v) + "' x2='" + (width - right) + "' y2='" + y(v) + "' stroke='#eee'/>" +
+      "<text x='" + (left - 6) + "' y='" + (y(v) + 4) + "' text-anchor='end' fill='#666'>" + v + "</text>";
+  }
+  months.forEach(function (m, i) {
+    content += "<text x='" + (left + i * stepX) + "' y='" + (height - 10) + "' text-anchor='middle' fill='#666'>" +
+      esc(m.month_name.slice(0, 3)) + "</text>";
+  });
+
+  names.forEach(function (name, n) {
+    var color = COLORS[n % COLORS.length];
+    var points = [];
+    months.forEach(function (m, i) {
+      // Months without measurements are gaps rather than zero scores
+      if (m.role_coverage[name] > 0) {
+        points.push([left + i * stepX, y(m.role_scores[name]), m]);
+      }
+    });
+    if (points.length > 1) {
+      content += "<polyline fill='none' stroke='" + color + "' stroke-width='2' points='" +
+        points.map(function (p) { return p[0] + "," + p[1]; }).join(" ") + "'/>";
+    }
+    points.forEach(function (p) {
+      content += "<circle cx='" + p[0] + "' cy='" + p[1] + "' r='3' fill='" + color + "'><title>" +
+        esc(name) + " " + esc(p[2].month_name) + ": " + fmt(p[2].role_scores[name]) + "%</title></circle>";
+    });
+  });
+
+  var legend = names.map(function (name, n) {
+    return "<span style='--color:" + COLORS[n % COLORS.length] + "'>" + esc(name) + "</span>";
+  }).join("");
+  return svg(width, height, content) + "<div class='legend'>" + legend + "</div>";
+}
+
+// ---------- data entry ----------
+
+function loadEntry() {
+  var form = $("#entry-select");
+  var roleID = form.role.value;
+  var period = form.period.value;
+  Promise.all([
+    fetchAll("/api/roles/" + roleID + "/kpis?sort=category,id"),
+    fetchAll("/api/measurements?role_id=" + roleID + "&from=" + period + "&to=" + period)
+  ]).then(function (results) {
+    var values = {};
+    results[1].forEach(function (m) { values[m.kpi_id] = m; });
+    $("#entry-rows").innerHTML = results[0].map(function (kpi) {
+      var m = values[kpi.id];
+      // Derived KPIs take their raw inputs, the value is computed by the server
+      var value = kpi.formula ?
+        "<input name='inputs' placeholder='" + esc(kpi.formula) + "' title='" + esc(kpi.formula) + "' value='" +
+          esc(m ? formatInputs(m.inputs) : "") + "'>" + (m ? " = " + fmt(m.metric_value) : "") :
+        "<input type='number' step='any' name='value' value='" + (m ? m.metric_value : "") + "'>";
+      return "<tr data-kpi='" + kpi.id + "' data-unit='" + esc(kpi.unit) + "'><td>" + esc(kpi.name) + "</td>" +
+        "<td>" + esc(kpi.category) + "</td><td>" + esc(kpi.target) + "</td><td>" + esc(kpi.unit) + "</td>" +
+        "<td>" + value + "</td>" +
+        "<td><input name='notes' value='" + esc(m ? m.notes : "") + "'></td></tr>";
+    }).join("");
+    $("#entry-form").hidden = false;
+  }).catch(function (err) { showMessage(err.message, true); });
+}
+
+// formatInputs formats the raw inputs of a derived KPI as "name=value, ..."
+function formatInputs(inputs) {
+  return Object.keys(inputs || {}).sort().map(function (name) { return name + "=" + inputs[name]; }).join(", ");
+}
+
+// parseInputs parses "name=value, ..." into an object, or returns null if a value is not a number
+function parseInputs(text) {
+  var inputs = {};
+  var valid = text.split(",").every(function (item) {
+    var parts = item.split("=");
+    var value = parts.length === 2 ? parts[1].trim() : "";
+    inputs[parts[0].trim()] = Number(value);
+    return value !== "" && !isNaN(Number(value));
+  });
+  return valid ? inputs : null;
+}
+
+function saveEntry(event) {
+  event.preventDefault();
+  var period = $("#entry-select").period.value + "-01T00:00:00Z";
+  var batch = [];
+  var invalid = false;
+  document.querySelectorAll("#entry-rows tr").forEach(function (row) {
+    var entry = {
+      kpi_id: Number(row.dataset.kpi),
+      unit: row.dataset.unit,
+      period: period,
+      notes: row.querySelector("[name=notes]").value
+    };
+    var inputs = row.querySelector("[name=inputs]");
+    var value = inputs ? inputs.value.trim() : row.querySelector("[name=value]").value;
+    if (value === "") {
+      return;
+    }
+    if (inputs) {
+      entry.inputs = parseInputs(value);
+      invalid = invalid || !entry.inputs;
+    } else {
+      entry.metric_value = Number(value);
+    }
+    batch.push(entry);
+  });
+  if (invalid) {
+    showMessage("Enter the inputs of derived KPIs as name=value, separated by commas", true);
+    return;
+  }
+  if (!batch.length) {
+    showMessage("Enter at least one value", true);
+    return;
+  }
+  postEntries(batch, false);
+}
+
+// postEntries saves a batch, asking before saving values that look anomalous
+function postEntries(batch, confirmed) {
+  api("POST", "/api/measurements/batch" + (confirmed ? "?confirm=true" : ""), batch).then(function (result) {
+    showMessage("Saved " + result.results.length + " measurements");
+    loadEntry();
+  }).catch(function (err) {
+    if (err.status === 428 && !confirmed && window.confirm(err.message + "\n\nSave anyway?")) {
+      postEntries(batch, true);
+      return;
+    }
+    showMessage(err.message, true);
+  });
+}
+
+// ---------- reports ----------
+
+function updateReportFields() {
+  var form = $("#reports-form");
+  var kind = form.kind.value;
+  form.querySelectorAll("label[data-for]").forEach(function (label) {
+    label.hidden = label.dataset.for.split(" ").indexOf(kind) < 0;
+  });
+}
+
+function downloadReport(event) {
+  event.preventDefault();
+  var form = $("#reports-form");
+  var year = form.year.value, kind = form.kind.value, format = form.format.value;
+  var url, filename;
+  switch (kind) {
+    case "monthly":
+      url = "/api/reports/monthly/" + year + "/" + (form.month.value || 1);
+      filename = "KPI_Report_" + year + "_" + String(form.month.value || 1).padStart(2, "0");
+      break;
+    case "quarterly":
+      url = "/api/reports/quarterly/" + year + "/" + (form.quarter.value || 1);
+      filename = "KPI_Report_" + year + "_Q" + (form.quarter.value || 1);
+      break;
+    case "yearly":
+      url = "/api/reports/yearly/" + year;
+      filename = "KPI_Report_" + year;
+      break;
+    case "bonus":
+      url = "/api/bonus/" + year;
+      format = "xlsx";
+      filename = "Bonus_Payout_" + year;
+      break;
+  }
+  var link = document.createElement("a");
+  link.href = url + "?format=" + format;
+  link.download = filename + "." + format;
+  document.body.appendChild(link);
+  link.click();
+  link.remove();
+}
+
+// ---------- KPI management ----------
+
+function loadKPIs() {
+  var roleID = $("#kpis-select").role.value;
+  fetchAll("/api/roles/" + roleID + "/kpis?sort=category,id").then(function (kpis) {
+    state.kpis = kpis;
+    var total = 0;
+    $("#kpi-rows").innerHTML = kpis.map(function (kpi) {
+      total += kpi.weight;
+      return "<tr><td>" + kpi.id + "</td><td>" + esc(kpi.name) + "</td><td>" + esc(kpi.category) + "</td>" +
+        "<td>" + esc(kpi.target) + "</td><td>" + esc(kpi.unit) + "</td><td class='number'>" + fmt(kpi.weight) + "%</td>" +
+        "<td><button class='link' data-edit='" + kpi.id + "'>Edit</button>" +
+        "<button class='link' data-delete='" + kpi.id + "'>Delete</button></td></tr>";
+    }).join("");
+    $("#kpi-weight").textContent = fmt(total) + "%";
+    $("#kpi-weight").className = Math.abs(total - 100) > 0.01 ? "number incomplete" : "number";
+  }).catch(function (err) { showMessage(err.message, true); });
+}
+
+function resetKPIForm() {
+  var form = $("#kpi-form");
+  form.reset();
+  form.id.value = "";
+  $("#kpi-form-title").textContent = "Add KPI";
+}
+
+function editKPI(id) {
+  var kpi = state.kpis.filter(function (k) { return k.id === id; })[0];
+  var form = $("#kpi-form");
+  ["id", "name", "category", "description", "metric", "unit", "target", "operator", "target_value", "weight"].forEach(function (field) {
+    form[field].value = kpi[field];
+  });
+  form.frequency.value = kpi.frequency || "monthly";
+  form.formula.value = kpi.formula || "";
+  $("#kpi-form-title").textContent = "Edit KPI " + kpi.id;
+  form.scrollIntoView();
+}
+
+function saveKPI(event) {
+  event.preventDefault();
+  var form = $("#kpi-form");
+  var kpi = {
+    role_id: Number($("#kpis-select").role.value),
+    name: form.name.value,
+    category: form.category.value,
+    description: form.description.value,
+    metric: form.metric.value,
+    unit: form.unit.value,
+    target: form.target.value,
+    operator: form.operator.value,
+    target_value: Number(form.target_value.value),
+    weight: Number(form.weight.value),
+    frequency: form.frequency.value,
+    formula: form.formula.value.trim()
+  };
+  var request = form.id.value ? api("PUT", "/api/kpis/" + form.id.value, kpi) : api("POST", "/api/kpis", kpi);
+  request.then(function (saved) {
+    showMessage("Saved KPI " + saved.id + " " + saved.name);
+    resetKPIForm();
+    loadKPIs();
+  }).catch(function (err) { showMessage(err.message, true); });
+}
+
+function deleteKPI(id) {
+  if (!confirm("Delete KPI " + id + "?")) {
+    return;
+  }
+  api("DELETE", "/api/kpis/" + id).then(function () {
+    showMessage("Deleted KPI " + id);
+    loadKPIs();
+  }).catch(function (err) { showMessage(err.message, true); });
+}
+
+// ---------- live updates ----------
+
+var EVENT_TYPES = ["measurement.created", "measurement.updated", "kpi.created", "kpi.updated", "kpi.deleted", "data.reloaded"];
+
+// refreshLater reloads a view once after a burst of events, e.g. during an import
+function refreshLater(name, load) {
+  clearTimeout(refreshLater[name]);
+  refreshLater[name] = setTimeout(load, 300);
+}
+
+function onEvent(message) {
+  var event = JSON.parse(message.data);
+  var view = (location.hash || "#overview").slice(1);
+
+  if (view === "overview" && (!event.period || event.period === $("#overview-form").period.value)) {
+    refreshLater("overview", loadOverview);
+  } else if (view === "trends" && (!event.period || event.period.slice(0, 4) === $("#trends-form").year.value)) {
+    refreshLater("trends", loadTrends);
+  } else if (view === "kpis" && event.type !== "measurement.created" && event.type !== "measurement.updated" &&
+    (!event.role_id || String(event.role_id) === $("#kpis-select").role.value)) {
+    refreshLater("kpis", loadKPIs);
+  }
+}
+
+function listenForEvents() {
+  if (!window.EventSource) {
+    return;
+  }
+  // EventSource reconnects by itself and resumes with Last-Event-ID
+  var source = new EventSource("/api/events");
+  EVENT_TYPES.forEach(function (type) {
+    source.addEventListener(type, onEvent);
+  });
+}
+
+// ---------- navigation ----------
+
+function showView() {
+  var name = (location.hash || "#overview").slice(1);
+  if (!$("#view-" + name)) {
+    name = "overview";
+  }
+  document.querySelectorAll(".view").forEach(function (view) {
+    view.hidden = view.id !== "view-" + name;
+  });
+  document.querySelectorAll("nav a").forEach(function (link) {
+    link.className = link.getAttribute("href") === "#" + name ? "active" : "";
+  });
+  if (name === "overview") {
+    loadOverview();
+  } else if (name === "trends") {
+    loadTrends();
+  } else if (name === "kpis") {
+    loadKPIs();
+  }
+}
+
+function init() {
+  var period = currentPeriod();
+  $("#overview-form").period.value = period;
+  $("#trends-form").year.value = period.slice(0, 4);
+  $("#entry-select").period.value = period;
+  $("#reports-form").year.value = period.slice(0, 4);
+  $("#reports-form").month.value = Number(period.slice(5));
+
+  $("#overview-form").addEventListener("submit", function (e) { e.preventDefault(); loadOverview(); });
+  $("#trends-form").addEventListener("submit", function (e) { e.preventDefault(); loadTrends(); });
+  $("#entry-select").addEventListener("submit", function (e) { e.preventDefault(); loadEntry(); });
+  $("#entry-form").addEventListener("submit", saveEntry);
+  $("#reports-form").addEventListener("submit", downloadReport);
+  $("#reports-form").kind.addEventListener("change", updateReportFields);
+  $("#kpis-select").role.addEventListener("change", function () { resetKPIForm(); loadKPIs(); });
+  $("#kpi-form").addEventListener("submit", saveKPI);
+  $("#kpi-cancel").addEventListener("click", resetKPIForm);
+  $("#kpi-rows").addEventListener("click", function (e) {
+    if (e.target.dataset.edit) {
+      editKPI(Number(e.target.dataset.edit));
+    } else if (e.target.dataset.delete) {
+      deleteKPI(Number(e.target.dataset.delete));
+    }
+  });
+  window.addEventListener("hashchange", showView);
+  updateReportFields();
+
+  fetchAll("/api/roles?sort=id").then(function (roles) {
+    state.roles = roles;
+    fillRoleSelect($("#entry-select").role);
+    fillRoleSelect($("#kpis-select").role);
+    showView();
+    listenForEvents();
+  }).catch(function (err) { showMessage(err.message, true); });
+}
+
+init();
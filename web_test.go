@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWebDashboard(t *testing.T) {
+	for _, tt := range []struct {
+		method, url string
+		status      int
+		contentType string
+		want        string
+	}{
+		{"GET", "/", http.StatusOK, "text/html", "<title>KPI Tracker</title>"},
+		{"GET", "/index.html", http.StatusMovedPermanently, "", ""},
+		{"GET", "/app.js", http.StatusOK, "javascript", "/api/"},
+		{"GET", "/style.css", http.StatusOK, "text/css", ""},
+		{"HEAD", "/", http.StatusOK, "text/html", ""},
+		{"GET", "/missing.js", http.StatusNotFound, "application/json", `"not_found"`},
+		{"POST", "/", http.StatusMethodNotAllowed, "application/json", `"method_not_allowed"`},
+		// API paths never fall through to the dashboard
+		{"GET", "/api/nothing", http.StatusNotFound, "application/json", `"not_found"`},
+		{"DELETE", "/api/measurements", http.StatusMethodNotAllowed, "application/json", `"method_not_allowed"`},
+		{"GET", "/api/roles", http.StatusOK, "application/json", `"data"`},
+	} {
+		rec := httptest.NewRecorder()
+		newAPIRouter().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+		if rec.Code != tt.status || !strings.Contains(rec.Header().Get("Content-Type"), tt.contentType) || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s %s: status %d, %q, want %d %q containing %q", tt.method, tt.url, rec.Code, rec.Header().Get("Content-Type"), tt.status, tt.contentType, tt.want)
+		}
+		if rec.Code == http.StatusOK && !strings.HasPrefix(tt.url, "/api/") && rec.Header().Get("Cache-Control") != "no-cache" {
+			t.Errorf("%s: Cache-Control %q, want no-cache", tt.url, rec.Header().Get("Cache-Control"))
+		}
+	}
+}
+
+func TestKPIManagementAPI(t *testing.T) {
+	setupMeasurementAPITest(t)
+	calls := `{"role_id": 1, "category": "Quantitative", "name": "Calls", "unit": "count", "operator": "≥", "target_value": 50, "weight": 20}`
+
+	for _, tt := range []struct {
+		method, url, body string
+		status            int
+		fields            []string
+	}{
+		{"POST", "/api/kpis", calls, http.StatusCreated, nil},
+		{"POST", "/api/kpis", `{"role_id": 9, "name": " ", "weight": 0}`, http.StatusUnprocessableEntity,
+			[]string{"role_id", "category", "name", "unit", "operator", "weight", "target_value"}},
+		{"POST", "/api/kpis", `{"name": `, http.StatusBadRequest, nil},
+		{"PUT", "/api/kpis/1", strings.Replace(calls, "Calls", "Revenue (IDR m)", 1), http.StatusOK, nil},
+		{"PUT", "/api/kpis/1", `{"role_id": 1, "name": "Revenue"}`, http.StatusUnprocessableEntity, []string{"category", "unit", "operator", "weight", "target_value"}},
+		{"PUT", "/api/kpis/9", calls, http.StatusNotFound, nil},
+		{"PUT", "/api/kpis/x", calls, http.StatusBadRequest, nil},
+		// Audit has a measurement
+		{"DELETE", "/api/kpis/2", "", http.StatusConflict, nil},
+		{"DELETE", "/api/kpis/9", "", http.StatusNotFound, nil},
+		{"DELETE", "/api/kpis/3", "", http.StatusNoContent, nil},
+	} {
+		rec := serveAPI(tt.method, tt.url, tt.body, nil)
+		var response ErrorResponse
+		if rec.Code >= 400 {
+			json.NewDecoder(rec.Body).Decode(&response)
+		}
+		var fields []string
+		for _, detail := range response.Error.Details {
+			fields = append(fields, detail.Field)
+		}
+		if rec.Code != tt.status || strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s %s %s: status %d, %+v, want %d with fields %v", tt.method, tt.url, tt.body, rec.Code, response, tt.status, tt.fields)
+		}
+	}
+
+	if kpi := getKPIByID(1); kpi == nil || kpi.Name != "Revenue (IDR m)" || kpi.Unit != "count" {
+		t.Errorf("KPI 1 %+v, want the update applied", kpi)
+	}
+	if getKPIByID(2) == nil || getKPIByID(3) != nil || len(measurements) != 1 {
+		t.Errorf("KPIs %+v, measurements %+v, want only the new KPI deleted", kpis, measurements)
+	}
+
+	kpis = append(kpis, KPI{ID: 4, RoleID: 1, Category: "Quantitative", Name: "Half revenue", Unit: "count", Operator: "≥", Weight: 10, Formula: "kpi_1 / 2"})
+	if rec := serveAPI("DELETE", "/api/kpis/1", "", nil); rec.Code != http.StatusConflict || getKPIByID(1) == nil {
+		t.Errorf("status %d, want 409 for a KPI used in a formula", rec.Code)
+	}
+}
+
+func TestKPIManagementSaveFailure(t *testing.T) {
+	setupMeasurementAPITest(t)
+	before := append([]KPI(nil), kpis...)
+	failExcelSaves(t)
+
+	for _, tt := range []struct {
+		method, url, body string
+	}{
+		{"POST", "/api/kpis", `{"role_id": 1, "category": "Quantitative", "name": "Calls", "unit": "count", "operator": "≥", "target_value": 50, "weight": 20}`},
+		{"PUT", "/api/kpis/1", `{"role_id": 1, "category": "Quantitative", "name": "Sales", "unit": "IDR", "operator": "≥", "target_value": 80, "weight": 50}`},
+		{"DELETE", "/api/kpis/1", ""},
+	} {
+		if rec := serveAPI(tt.method, tt.url, tt.body, nil); rec.Code != http.StatusInternalServerError {
+			t.Errorf("%s %s: status %d, want 500 when the save fails", tt.method, tt.url, rec.Code)
+		}
+		if !reflect.DeepEqual(kpis, before) {
+			t.Errorf("%s %s: KPIs %+v, want them rolled back", tt.method, tt.url, kpis)
+		}
+	}
+}
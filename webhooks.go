@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Webhook storage files in the database directory
+const (
+	webhooksFile          = "webhooks.json"
+	webhookDeliveriesFile = "webhook_deliveries.json"
+)
+
+// Delivery settings, variables so tests can shorten them
+var (
+	webhookMaxAttempts  = 5
+	webhookBackoff      = 2 * time.Second // Doubled after every failed attempt
+	webhookTimeout      = 10 * time.Second
+	webhookDeliveryKept = 1000 // Delivery log entries kept
+)
+
+// EventWebhookTest is sent by POST /api/webhooks/{id}/test and is not published on the bus
+const EventWebhookTest = "webhook.test"
+
+// Webhook is a subscription that receives matching events as signed HTTP POSTs
+type Webhook struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"` // Event types, empty for all
+	Secret      string    `json:"secret,omitempty"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID         int64      `json:"id"`
+	WebhookID  int        `json:"webhook_id"`
+	EventID    int64      `json:"event_id"`
+	EventType  string     `json:"event_type"`
+	URL        string     `json:"url"`
+	Attempt    int        `json:"attempt"`
+	StatusCode int        `json:"status_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+	Success    bool       `json:"success"`
+	NextRetry  *time.Time `json:"next_retry,omitempty"`
+	Time       time.Time  `json:"time"`
+}
+
+// Webhook subscriptions and their delivery log, guarded by webhooksMu
+var (
+	webhooksMu        sync.Mutex
+	webhooks          []Webhook
+	webhookDeliveries []WebhookDelivery
+	nextDeliveryID    int64
+)
+
+// webhookClient sends the deliveries
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// redacted returns the webhook without its secret, for API responses
+func (h Webhook) redacted() Webhook {
+	h.Secret = ""
+	return h
+}
+
+// wants reports whether the webhook subscribes to an event type
+func (h Webhook) wants(eventType string) bool {
+	return h.Active && (len(h.Events) == 0 || containsString(h.Events, eventType))
+}
+
+// loadWebhooks loads the webhook subscriptions and delivery log from the database directory
+func loadWebhooks() error {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	webhooks = []Webhook{}
+	webhookDeliveries = []WebhookDelivery{}
+
+	if err := readJSONFile(filepath.Join(appSettings.DatabasePath, webhooksFile), &webhooks); err != nil {
+		return err
+	}
+	if err := readJSONFile(filepath.Join(appSettings.DatabasePath, webhookDeliveriesFile), &webhookDeliveries); err != nil {
+		return err
+	}
+
+	nextDeliveryID = 0
+	for _, d := range webhookDeliveries {
+		if d.ID > nextDeliveryID {
+			nextDeliveryID = d.ID
+		}
+	}
+	return nil
+}
+
+// saveWebhooks writes the subscriptions. The caller holds webhooksMu.
+func saveWebhooks() error {
+	return writeJSONFile(filepath.Join(appSettings.DatabasePath, webhooksFile), webhooks)
+}
+
+// saveWebhookDeliveries writes the delivery log. The caller holds webhooksMu.
+func saveWebhookDeliveries() error {
+	return writeJSONFile(filepath.Join(appSettings.DatabasePath, webhookDeliveriesFile), webhookDeliveries)
+}
+
+// generateWebhookSecret returns a random secret for signing payloads
+func generateWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// signWebhookPayload returns the signature sent in X-KPI-Signature: the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret
+func signWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// startWebhookDispatcher delivers the events of the bus to the webhook
+// subscriptions until the bus is closed
+func startWebhookDispatcher() {
+	ch, _ := appEvents.Subscribe(EventFilter{}, 0)
+	go func() {
+		for event := range ch {
+			webhooksMu.Lock()
+			var targets []Webhook
+			for _, hook := range webhooks {
+				if hook.wants(event.Type) {
+					targets = append(targets, hook)
+				}
+			}
+			webhooksMu.Unlock()
+
+			for _, hook := range targets {
+				go deliverWebhook(hook, event)
+			}
+		}
+	}()
+}
+
+// deliverWebhook posts an event to a webhook, retrying with exponential backoff on
+// network errors, 5xx, 408 and 429 responses. Every attempt is logged. It returns
+// the last attempt.
+func deliverWebhook(hook Webhook, event Event) WebhookDelivery {
+	body, _ := json.Marshal(event)
+	backoff := webhookBackoff
+
+	var delivery WebhookDelivery
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		delivery = attemptWebhookDelivery(hook, event, body, attempt)
+
+		retry := !delivery.Success && attempt < webhookMaxAttempts &&
+			(delivery.StatusCode == 0 || delivery.StatusCode >= 500 ||
+				delivery.StatusCode == http.StatusRequestTimeout || delivery.StatusCode == http.StatusTooManyRequests)
+		if retry {
+			next := time.Now().Add(backoff)
+			delivery.NextRetry = &next
+		}
+		recordWebhookDelivery(&delivery)
+
+		if !retry {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	if !delivery.Success {
+		fmt.Printf("Webhook %d: giving up on event %d (%s) after %d attempts\n", hook.ID, event.ID, event.Type, delivery.Attempt)
+	}
+	return delivery
+}
+
+// attemptWebhookDelivery makes one delivery attempt
+func attemptWebhookDelivery(hook Webhook, event Event, body []byte, attempt int) WebhookDelivery {
+	delivery := WebhookDelivery{
+		WebhookID: hook.ID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		URL:       hook.URL,
+		Attempt:   attempt,
+		Time:      time.Now(),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KPI-Tracker-Webhooks/1.0")
+	req.Header.Set("X-KPI-Event", event.Type)
+	req.Header.Set("X-KPI-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-KPI-Timestamp", strconv.FormatInt(timestamp, 10))
+	if hook.Secret != "" {
+		req.Header.Set("X-KPI-Signature", signWebhookPayload(hook.Secret, timestamp, body))
+	}
+
+	start := time.Now()
+	resp, err := webhookClient.Do(req)
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = resp.Status
+	}
+	return delivery
+}
+
+// recordWebhookDelivery assigns the delivery an ID and appends it to the log
+func recordWebhookDelivery(delivery *WebhookDelivery) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	nextDeliveryID++
+	delivery.ID = nextDeliveryID
+	webhookDeliveries = append(webhookDeliveries, *delivery)
+	if len(webhookDeliveries) > webhookDeliveryKept {
+		webhookDeliveries = webhookDeliveries[len(webhookDeliveries)-webhookDeliveryKept:]
+	}
+
+	if err := saveWebhookDeliveries(); err != nil {
+		fmt.Printf("Error saving webhook delivery log: %v\n", err)
+	}
+}
+
+// webhookIndex returns the index of the webhook with the given ID or -1.
+// The caller holds webhooksMu.
+func webhookIndex(id int) int {
+	for i := range webhooks {
+		if webhooks[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// WebhookInput is the request body for creating or replacing a webhook
+type WebhookInput struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret"` // Generated when empty on create, kept when empty on update
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"` // Defaults to true on create, kept when unset on update
+}
+
+var webhookComparators = map[string]func(a, b Webhook) int{
+	"id":         func(a, b Webhook) int { return compareInts(a.ID, b.ID) },
+	"url":        func(a, b Webhook) int { return compareStrings(a.URL, b.URL) },
+	"created_at": func(a, b Webhook) int { return compareTimes(a.CreatedAt, b.CreatedAt) },
+}
+
+var webhookDeliveryComparators = map[string]func(a, b WebhookDelivery) int{
+	"id":   func(a, b WebhookDelivery) int { return compareInts(int(a.ID), int(b.ID)) },
+	"time": func(a, b WebhookDelivery) int { return compareTimes(a.Time, b.Time) },
+}
+
+// webhookIDFromRequest parses the {id} of a webhook route and returns the index
+// of the webhook, writing an error response when it is not found. The caller holds webhooksMu.
+func webhookIDFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return -1, false
+	}
+	index := webhookIndex(id)
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return -1, false
+	}
+	return index, true
+}
+
+// getWebhooks returns the webhook subscriptions without their secrets
+func getWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, perr := parseListParams(r.URL.Query(), nil, sortFields(webhookComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	webhooksMu.Lock()
+	filtered := []Webhook{}
+	for _, hook := range webhooks {
+		if matchesText(params.Query, hook.URL, hook.Description) {
+			filtered = append(filtered, hook.redacted())
+		}
+	}
+	webhooksMu.Unlock()
+
+	page, pagination := sortAndPage(filtered, params, webhookComparators, "id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
+
+// createWebhook adds a webhook subscription. The response is the only one that
+// includes the secret.
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := validateWebhook(input); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	hook := Webhook{
+		URL:         input.URL,
+		Events:      input.Events,
+		Secret:      input.Secret,
+		Description: input.Description,
+		Active:      input.Active == nil || *input.Active,
+		CreatedAt:   time.Now(),
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	if hook.Secret == "" {
+		hook.Secret = generateWebhookSecret()
+	}
+
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	hook.ID = 1
+	for _, h := range webhooks {
+		if h.ID >= hook.ID {
+			hook.ID = h.ID + 1
+		}
+	}
+	webhooks = append(webhooks, hook)
+
+	if err := saveWebhooks(); err != nil {
+		webhooks = webhooks[:len(webhooks)-1]
+		writeError(w, http.StatusInternalServerError, "Failed to save webhooks: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// getWebhook returns a webhook subscription without its secret
+func getWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	index, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(webhooks[index].redacted())
+}
+
+// updateWebhook replaces the URL, events, description and, when given, the secret
+// and active flag of a webhook
+func updateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	index, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if errs := validateWebhook(input); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	previous := webhooks[index]
+	hook := &webhooks[index]
+	hook.URL = input.URL
+	hook.Events = input.Events
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	hook.Description = input.Description
+	if input.Secret != "" {
+		hook.Secret = input.Secret
+	}
+	if input.Active != nil {
+		hook.Active = *input.Active
+	}
+
+	if err := saveWebhooks(); err != nil {
+		webhooks[index] = previous
+		writeError(w, http.StatusInternalServerError, "Failed to save webhooks: "+err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(hook.redacted())
+}
+
+// deleteWebhook removes a webhook subscription. Its delivery log is kept.
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	index, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	previous := append([]Webhook{}, webhooks...)
+	webhooks = append(webhooks[:index], webhooks[index+1:]...)
+
+	if err := saveWebhooks(); err != nil {
+		webhooks = previous
+		writeError(w, http.StatusInternalServerError, "Failed to save webhooks: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWebhookDeliveries returns the delivery log of a webhook, newest first by default
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, perr := parseListParams(r.URL.Query(), nil, sortFields(webhookDeliveryComparators))
+	if perr != nil {
+		writeParamError(w, perr)
+		return
+	}
+
+	webhooksMu.Lock()
+	index, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		webhooksMu.Unlock()
+		return
+	}
+	id := webhooks[index].ID
+	filtered := []WebhookDelivery{}
+	for _, d := range webhookDeliveries {
+		if d.WebhookID == id && matchesText(params.Query, d.EventType, d.Error) {
+			filtered = append(filtered, d)
+		}
+	}
+	webhooksMu.Unlock()
+
+	page, pagination := sortAndPage(filtered, params, webhookDeliveryComparators, "-id")
+	json.NewEncoder(w).Encode(ListResponse{Data: page, Pagination: pagination})
+}
+
+// testWebhook sends a webhook.test event to a webhook once, without retries,
+// and returns the logged delivery
+func testWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	webhooksMu.Lock()
+	index, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		webhooksMu.Unlock()
+		return
+	}
+	hook := webhooks[index]
+	webhooksMu.Unlock()
+
+	event := Event{Type: EventWebhookTest, Time: time.Now(), Data: map[string]string{"message": "Test delivery from KPI Tracker"}}
+	body, _ := json.Marshal(event)
+	delivery := attemptWebhookDelivery(hook, event, body, 1)
+	recordWebhookDelivery(&delivery)
+
+	json.NewEncoder(w).Encode(delivery)
+}
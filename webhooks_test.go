@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupWebhookTest stores webhook files in a temporary directory and shortens the backoff
+func setupWebhookTest(t *testing.T) {
+	previousPath, previousBackoff := appSettings.DatabasePath, webhookBackoff
+	appSettings.DatabasePath = t.TempDir()
+	webhookBackoff = time.Millisecond
+	t.Cleanup(func() {
+		appSettings.DatabasePath, webhookBackoff = previousPath, previousBackoff
+	})
+	if err := loadWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookDeliverySignsAndRetries(t *testing.T) {
+	setupWebhookTest(t)
+
+	const secret = "0123456789abcdef0123"
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-KPI-Timestamp"), 10, 64)
+		if got, want := r.Header.Get("X-KPI-Signature"), signWebhookPayload(secret, timestamp, body); got != want {
+			t.Errorf("signature %q, want %q", got, want)
+		}
+		if r.Header.Get("X-KPI-Event") != EventMeasurementCreated {
+			t.Errorf("X-KPI-Event %q", r.Header.Get("X-KPI-Event"))
+		}
+
+		// Fail twice before accepting
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := Webhook{ID: 1, URL: server.URL, Secret: secret, Active: true}
+	delivery := deliverWebhook(hook, Event{ID: 7, Type: EventMeasurementCreated})
+
+	if !delivery.Success || delivery.Attempt != 3 {
+		t.Fatalf("delivery %+v, want success on attempt 3", delivery)
+	}
+	if len(webhookDeliveries) != 3 {
+		t.Fatalf("%d deliveries logged, want 3", len(webhookDeliveries))
+	}
+	if webhookDeliveries[0].StatusCode != http.StatusServiceUnavailable || webhookDeliveries[0].NextRetry == nil {
+		t.Errorf("first attempt %+v, want 503 with a retry", webhookDeliveries[0])
+	}
+
+	// The log survives a reload
+	if err := loadWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhookDeliveries) != 3 {
+		t.Errorf("%d deliveries after reload, want 3", len(webhookDeliveries))
+	}
+}
+
+func TestWebhookDeliveryDoesNotRetryClientErrors(t *testing.T) {
+	setupWebhookTest(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	delivery := deliverWebhook(Webhook{ID: 1, URL: server.URL, Active: true}, Event{ID: 1, Type: EventKPIUpdated})
+
+	if delivery.Success || calls != 1 {
+		t.Errorf("delivery %+v after %d calls, want one failed attempt", delivery, calls)
+	}
+}
+
+func TestWebhookWants(t *testing.T) {
+	all := Webhook{Active: true}
+	some := Webhook{Active: true, Events: []string{EventBelowTarget}}
+	inactive := Webhook{Events: []string{EventBelowTarget}}
+
+	if !all.wants(EventKPICreated) || !some.wants(EventBelowTarget) {
+		t.Error("active webhooks should receive subscribed events")
+	}
+	if some.wants(EventKPICreated) || inactive.wants(EventBelowTarget) {
+		t.Error("webhooks should not receive unsubscribed events or while inactive")
+	}
+}